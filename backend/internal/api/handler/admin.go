@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+)
+
+// migrateBatchSize is the number of entities re-saved per PutMulti (the Datastore limit is 500).
+const migrateBatchSize = 500
+
+// schemaFetcher re-reads entities from GitHub to fill fields added after they were stored.
+type schemaFetcher interface {
+	GetRepository(ctx context.Context, owner, repo string) (*model.Repository, error)
+	GetPullRequest(ctx context.Context, owner, repo string, number int) (*model.PullRequest, error)
+}
+
+// AdminHandler handles storage stats, schema status, and migration.
+type AdminHandler struct {
+	ds     SchemaStore
+	gh     schemaFetcher // nil disables re-enrichment
+	logger *slog.Logger
+	cache  *middleware.ResponseCache
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(ds SchemaStore, gh *github.Client, logger *slog.Logger, cache *middleware.ResponseCache) *AdminHandler {
+	h := &AdminHandler{
+		ds:     ds,
+		logger: logger,
+		cache:  cache,
+	}
+	if gh != nil {
+		h.gh = gh
+	}
+	return h
+}
+
+// SchemaStatusResponse response for schema status
+type SchemaStatusResponse struct {
+	CurrentVersion int                `json:"currentVersion"`
+	Kinds          []SchemaKindStatus `json:"kinds"`
+}
+
+// SchemaKindStatus entity counts per schema version for one kind
+type SchemaKindStatus struct {
+	Kind     string        `json:"kind"`
+	Versions map[int]int64 `json:"versions"` // version -> entity count; 0 means saved before versioning
+	Outdated int64         `json:"outdated"` // entities below the current version
+}
+
+// SchemaStatus reports entity counts per schema version for each versioned kind.
+func (h *AdminHandler) SchemaStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	kinds := make([]SchemaKindStatus, 0, len(datastore.SchemaVersionedKinds))
+	for _, kind := range datastore.SchemaVersionedKinds {
+		versions, err := h.ds.CountBySchemaVersion(ctx, kind)
+		if err != nil {
+			h.logger.Error("failed to count schema versions", "kind", kind, "error", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to count schema versions")
+			return
+		}
+		status := SchemaKindStatus{Kind: kind, Versions: versions}
+		for v, n := range versions {
+			if v < model.CurrentSchemaVersion {
+				status.Outdated += n
+			}
+		}
+		kinds = append(kinds, status)
+	}
+
+	respondJSON(w, http.StatusOK, &SchemaStatusResponse{
+		CurrentVersion: model.CurrentSchemaVersion,
+		Kinds:          kinds,
+	})
+}
+
+// StatsResponse response for storage stats
+type StatsResponse struct {
+	RepositoryID string      `json:"repositoryId,omitempty"` // empty when counting every repository
+	Kinds        []KindCount `json:"kinds"`
+	CountedAt    time.Time   `json:"countedAt"`
+}
+
+// KindCount entity count for one kind
+type KindCount struct {
+	Kind  string `json:"kind"`
+	Count int64  `json:"count"`
+}
+
+// Stats reports the number of stored entities per kind (?repository= limits the counts to one repository).
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.URL.Query().Get("repository")
+
+	if id != "" {
+		repos, err := h.ds.ListRepositories(ctx)
+		if err != nil {
+			h.logger.Error("failed to list repositories", "error", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
+			return
+		}
+		if len(filterRepositoriesByID(repos, id)) == 0 {
+			respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+			return
+		}
+	}
+
+	kinds := make([]KindCount, 0, len(datastore.StatsKinds))
+	for _, kind := range datastore.StatsKinds {
+		n, err := h.ds.CountKind(ctx, kind, id)
+		if err != nil {
+			h.logger.Error("failed to count entities", "kind", kind, "repository", id, "error", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to count entities")
+			return
+		}
+		kinds = append(kinds, KindCount{Kind: kind, Count: n})
+	}
+
+	respondJSON(w, http.StatusOK, &StatsResponse{
+		RepositoryID: id,
+		Kinds:        kinds,
+		CountedAt:    time.Now(),
+	})
+}
+
+// MigrateResponse response for schema migration
+type MigrateResponse struct {
+	Repositories int       `json:"repositories"` // repository entities re-saved
+	PullRequests int       `json:"pullRequests"`
+	Reviews      int       `json:"reviews"`
+	Deployments  int       `json:"deployments"`
+	Enriched     int       `json:"enriched"`     // entities re-read from GitHub
+	EnrichFailed int       `json:"enrichFailed"` // entities re-saved without enrichment after a GitHub error
+	MigratedAt   time.Time `json:"migratedAt"`
+}
+
+// Migrate re-saves entities below the current schema version, which stamps them with it.
+// Unversioned repositories and PRs are re-read from GitHub to fill Topics and Labels
+// (?enrich=false skips this); ?repository= limits the migration to one repository.
+func (h *AdminHandler) Migrate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	enrich := h.gh != nil && q.Get("enrich") != "false"
+
+	repos, err := h.ds.ListRepositories(ctx)
+	if err != nil {
+		h.logger.Error("failed to list repositories", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
+		return
+	}
+	if id := q.Get("repository"); id != "" {
+		repos = filterRepositoriesByID(repos, id)
+		if len(repos) == 0 {
+			respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+			return
+		}
+	}
+
+	resp := &MigrateResponse{}
+	for _, repo := range repos {
+		if err := h.migrateRepository(ctx, repo, enrich, resp); err != nil {
+			h.logger.Error("failed to migrate repository", "repository", repo.FullName, "error", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("failed to migrate %s", repo.FullName))
+			return
+		}
+	}
+	resp.MigratedAt = time.Now()
+	h.logger.Info("schema migration completed",
+		"version", model.CurrentSchemaVersion,
+		"repositories", resp.Repositories,
+		"pull_requests", resp.PullRequests,
+		"reviews", resp.Reviews,
+		"deployments", resp.Deployments,
+		"enriched", resp.Enriched,
+	)
+
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// migrateRepository re-saves the outdated entities of one repository, counting them into resp.
+func (h *AdminHandler) migrateRepository(ctx context.Context, repo *model.Repository, enrich bool, resp *MigrateResponse) error {
+	if repo.SchemaVersion < model.CurrentSchemaVersion {
+		if enrich && repo.SchemaVersion == 0 {
+			h.enrichRepository(ctx, repo, resp)
+		}
+		if err := h.ds.SaveRepository(ctx, repo); err != nil {
+			return fmt.Errorf("save repository: %w", err)
+		}
+		resp.Repositories++
+	}
+
+	var prs []*model.PullRequest
+	err := h.ds.EachPullRequest(ctx, repo.ID, func(pr *model.PullRequest) error {
+		if pr.SchemaVersion >= model.CurrentSchemaVersion {
+			return nil
+		}
+		if enrich && pr.SchemaVersion == 0 {
+			h.enrichPullRequest(ctx, repo, pr, resp)
+		}
+		prs = append(prs, pr)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read pull requests: %w", err)
+	}
+	if err := saveInBatches(ctx, prs, h.ds.SavePullRequests); err != nil {
+		return fmt.Errorf("save pull requests: %w", err)
+	}
+	resp.PullRequests += len(prs)
+
+	var reviews []*model.Review
+	err = h.ds.EachReview(ctx, repo.ID, func(rv *model.Review) error {
+		if rv.SchemaVersion < model.CurrentSchemaVersion {
+			reviews = append(reviews, rv)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read reviews: %w", err)
+	}
+	if err := saveInBatches(ctx, reviews, h.ds.SaveReviews); err != nil {
+		return fmt.Errorf("save reviews: %w", err)
+	}
+	resp.Reviews += len(reviews)
+
+	var deployments []*model.Deployment
+	err = h.ds.EachDeployment(ctx, repo.ID, func(d *model.Deployment) error {
+		if d.SchemaVersion < model.CurrentSchemaVersion {
+			deployments = append(deployments, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read deployments: %w", err)
+	}
+	if err := saveInBatches(ctx, deployments, h.ds.SaveDeployments); err != nil {
+		return fmt.Errorf("save deployments: %w", err)
+	}
+	resp.Deployments += len(deployments)
+	return nil
+}
+
+// enrichRepository fills Topics from GitHub; on failure the repository is migrated as stored.
+func (h *AdminHandler) enrichRepository(ctx context.Context, repo *model.Repository, resp *MigrateResponse) {
+	fresh, err := h.gh.GetRepository(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		h.logger.Warn("failed to re-read repository", "repository", repo.FullName, "error", err)
+		resp.EnrichFailed++
+		return
+	}
+	repo.Topics = fresh.Topics
+	resp.Enriched++
+}
+
+// enrichPullRequest fills Labels (and BaseRef, if missing) from GitHub;
+// on failure the PR is migrated as stored.
+func (h *AdminHandler) enrichPullRequest(ctx context.Context, repo *model.Repository, pr *model.PullRequest, resp *MigrateResponse) {
+	fresh, err := h.gh.GetPullRequest(ctx, repo.Owner, repo.Name, pr.Number)
+	if err != nil {
+		h.logger.Warn("failed to re-read pull request", "repository", repo.FullName, "number", pr.Number, "error", err)
+		resp.EnrichFailed++
+		return
+	}
+	pr.Labels = fresh.Labels
+	if pr.BaseRef == "" {
+		pr.BaseRef = fresh.BaseRef
+	}
+	resp.Enriched++
+}
+
+// saveInBatches saves items in chunks of migrateBatchSize.
+func saveInBatches[T any](ctx context.Context, items []T, save func(context.Context, []T) error) error {
+	for start := 0; start < len(items); start += migrateBatchSize {
+		end := min(start+migrateBatchSize, len(items))
+		if err := save(ctx, items[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterRepositoriesByID returns the repository with the given ID, if present.
+func filterRepositoriesByID(repos []*model.Repository, id string) []*model.Repository {
+	for _, repo := range repos {
+		if repo.ID == id {
+			return []*model.Repository{repo}
+		}
+	}
+	return nil
+}
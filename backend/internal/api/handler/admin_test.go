@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// fakeSchemaFetcher serves fresh PRs by number; unknown numbers fail.
+type fakeSchemaFetcher struct {
+	prs map[int]*model.PullRequest
+}
+
+func (f *fakeSchemaFetcher) GetRepository(_ context.Context, owner, repo string) (*model.Repository, error) {
+	return &model.Repository{Topics: []string{"api"}}, nil
+}
+
+func (f *fakeSchemaFetcher) GetPullRequest(_ context.Context, owner, repo string, number int) (*model.PullRequest, error) {
+	if pr, ok := f.prs[number]; ok {
+		return pr, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// newSchemaTestStore holds a mix of unversioned entities and entities at the current version.
+func newSchemaTestStore() *memStore {
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", Owner: "o", Name: "a", FullName: "o/a"}
+	store.pullRequests["pr-1"] = &model.PullRequest{ID: "pr-1", RepositoryID: "repo-a", Number: 1}
+	store.pullRequests["pr-2"] = &model.PullRequest{ID: "pr-2", RepositoryID: "repo-a", Number: 2}
+	store.pullRequests["pr-3"] = &model.PullRequest{ID: "pr-3", RepositoryID: "repo-a", Number: 3, SchemaVersion: model.CurrentSchemaVersion}
+	store.reviews["rv-1"] = &model.Review{ID: "rv-1", RepositoryID: "repo-a"}
+	store.deployments["d-1"] = &model.Deployment{ID: "d-1", RepositoryID: "repo-a", SchemaVersion: model.CurrentSchemaVersion}
+	return store
+}
+
+func getSchemaStatus(t *testing.T, h *AdminHandler) map[string]SchemaKindStatus {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.SchemaStatus(w, httptest.NewRequest("GET", "/api/admin/schema-status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp SchemaStatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.CurrentVersion != model.CurrentSchemaVersion {
+		t.Errorf("currentVersion = %d, want %d", resp.CurrentVersion, model.CurrentSchemaVersion)
+	}
+	kinds := make(map[string]SchemaKindStatus, len(resp.Kinds))
+	for _, k := range resp.Kinds {
+		kinds[k.Kind] = k
+	}
+	return kinds
+}
+
+func TestAdminHandler_SchemaStatus(t *testing.T) {
+	h := NewAdminHandler(newSchemaTestStore(), nil, slog.Default(), nil)
+
+	kinds := getSchemaStatus(t, h)
+	tests := []struct {
+		kind         string
+		wantVersions map[int]int64
+		wantOutdated int64
+	}{
+		{datastore.KindRepository, map[int]int64{0: 1}, 1},
+		{datastore.KindPullRequest, map[int]int64{0: 2, 1: 1}, 2},
+		{datastore.KindReview, map[int]int64{0: 1}, 1},
+		{datastore.KindDeployment, map[int]int64{1: 1}, 0},
+	}
+	for _, tt := range tests {
+		got, ok := kinds[tt.kind]
+		if !ok {
+			t.Errorf("%s missing from status", tt.kind)
+			continue
+		}
+		if len(got.Versions) != len(tt.wantVersions) {
+			t.Errorf("%s versions = %v, want %v", tt.kind, got.Versions, tt.wantVersions)
+		}
+		for v, n := range tt.wantVersions {
+			if got.Versions[v] != n {
+				t.Errorf("%s versions = %v, want %v", tt.kind, got.Versions, tt.wantVersions)
+				break
+			}
+		}
+		if got.Outdated != tt.wantOutdated {
+			t.Errorf("%s outdated = %d, want %d", tt.kind, got.Outdated, tt.wantOutdated)
+		}
+	}
+}
+
+func TestAdminHandler_Migrate(t *testing.T) {
+	store := newSchemaTestStore()
+	h := NewAdminHandler(store, nil, slog.Default(), nil)
+	// PR 2 cannot be re-read and is migrated as stored
+	h.gh = &fakeSchemaFetcher{prs: map[int]*model.PullRequest{1: {Labels: []string{"feature"}, BaseRef: "main"}}}
+
+	w := httptest.NewRecorder()
+	h.Migrate(w, httptest.NewRequest("POST", "/api/admin/migrate", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp MigrateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Repositories != 1 || resp.PullRequests != 2 || resp.Reviews != 1 || resp.Deployments != 0 {
+		t.Errorf("migrated %d repos, %d PRs, %d reviews, %d deployments; want 1, 2, 1, 0",
+			resp.Repositories, resp.PullRequests, resp.Reviews, resp.Deployments)
+	}
+	if resp.Enriched != 2 || resp.EnrichFailed != 1 {
+		t.Errorf("enriched = %d, failed = %d; want 2, 1", resp.Enriched, resp.EnrichFailed)
+	}
+
+	if pr := store.pullRequests["pr-1"]; !slices.Equal(pr.Labels, []string{"feature"}) || pr.BaseRef != "main" {
+		t.Errorf("pr-1 labels = %v, baseRef = %q; want re-read from GitHub", pr.Labels, pr.BaseRef)
+	}
+	if topics := store.repos["repo-a"].Topics; !slices.Equal(topics, []string{"api"}) {
+		t.Errorf("repository topics = %v, want [api]", topics)
+	}
+	for kind, status := range getSchemaStatus(t, h) {
+		if status.Outdated != 0 {
+			t.Errorf("%s outdated = %d after migration, want 0", kind, status.Outdated)
+		}
+	}
+}
+
+func TestAdminHandler_Migrate_UnknownRepository(t *testing.T) {
+	h := NewAdminHandler(newSchemaTestStore(), nil, slog.Default(), nil)
+
+	w := httptest.NewRecorder()
+	h.Migrate(w, httptest.NewRequest("POST", "/api/admin/migrate?repository=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestAdminHandler_Stats(t *testing.T) {
+	store := newSchemaTestStore()
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b", Owner: "o", Name: "b", FullName: "o/b"}
+	store.pullRequests["pr-4"] = &model.PullRequest{ID: "pr-4", RepositoryID: "repo-b", Number: 4}
+	store.dailyMetrics["m-1"] = &model.DailyMetrics{ID: "m-1", RepositoryID: "repo-a"}
+	h := NewAdminHandler(store, nil, slog.Default(), nil)
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]int64
+	}{
+		{"all repositories", "", map[string]int64{
+			datastore.KindRepository: 2, datastore.KindPullRequest: 4, datastore.KindReview: 1,
+			datastore.KindDeployment: 1, datastore.KindDailyMetrics: 1,
+		}},
+		{"one repository", "?repository=repo-b", map[string]int64{
+			datastore.KindRepository: 1, datastore.KindPullRequest: 1, datastore.KindReview: 0,
+			datastore.KindDeployment: 0, datastore.KindDailyMetrics: 0,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Stats(w, httptest.NewRequest("GET", "/api/admin/stats"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var resp StatsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Kinds) != len(datastore.StatsKinds) {
+				t.Fatalf("got %d kinds, want %d", len(resp.Kinds), len(datastore.StatsKinds))
+			}
+			for _, k := range resp.Kinds {
+				if k.Count != tt.want[k.Kind] {
+					t.Errorf("%s count = %d, want %d", k.Kind, k.Count, tt.want[k.Kind])
+				}
+			}
+		})
+	}
+
+	t.Run("unknown repository", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.Stats(w, httptest.NewRequest("GET", "/api/admin/stats?repository=nope", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
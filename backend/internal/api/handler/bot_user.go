@@ -2,22 +2,24 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
-	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 )
 
 // BotUserHandler handles custom bot user management.
 type BotUserHandler struct {
-	ds     *datastore.Client
+	ds     BotUserStore
 	logger *slog.Logger
 }
 
 // NewBotUserHandler creates a new BotUserHandler
-func NewBotUserHandler(ds *datastore.Client, logger *slog.Logger) *BotUserHandler {
+func NewBotUserHandler(ds BotUserStore, logger *slog.Logger) *BotUserHandler {
 	return &BotUserHandler{
 		ds:     ds,
 		logger: logger,
@@ -27,6 +29,7 @@ func NewBotUserHandler(ds *datastore.Client, logger *slog.Logger) *BotUserHandle
 // addBotUserRequest is a request to add a bot user.
 type addBotUserRequest struct {
 	Username string `json:"username"`
+	Pattern  bool   `json:"pattern"` // treat Username as a glob pattern (e.g. "*-bot")
 }
 
 // List returns the list of custom bot users.
@@ -36,7 +39,7 @@ func (h *BotUserHandler) List(w http.ResponseWriter, r *http.Request) {
 	botUsers, err := h.ds.ListBotUsers(ctx)
 	if err != nil {
 		h.logger.Error("failed to list bot users", "error", err)
-		http.Error(w, "failed to list bot users", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list bot users")
 		return
 	}
 
@@ -49,42 +52,145 @@ func (h *BotUserHandler) Add(w http.ResponseWriter, r *http.Request) {
 
 	var req addBotUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
 		return
 	}
 
-	if req.Username == "" {
-		http.Error(w, "username is required", http.StatusBadRequest)
+	if err := validateBotEntry(req.Username, req.Pattern); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
 	botUser := &model.BotUser{
 		Username:  req.Username,
+		Pattern:   req.Pattern,
 		CreatedAt: time.Now(),
 	}
 
 	if err := h.ds.SaveBotUser(ctx, botUser); err != nil {
 		h.logger.Error("failed to save bot user", "error", err)
-		http.Error(w, "failed to save bot user", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save bot user")
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, botUser)
 }
 
+// maxBotUserBatchSize caps the number of usernames accepted by BatchAdd.
+const maxBotUserBatchSize = 100
+
+// batchAddBotUsersRequest is a request to add multiple bot users.
+type batchAddBotUsersRequest struct {
+	Usernames []string `json:"usernames"`
+}
+
+// BatchAddBotUserResult represents an individual result of a bot user batch add.
+type BatchAddBotUserResult struct {
+	Username string         `json:"username"`
+	Success  bool           `json:"success"`
+	Error    string         `json:"error,omitempty"`
+	BotUser  *model.BotUser `json:"botUser,omitempty"`
+}
+
+// BatchAdd adds multiple custom bot users, reporting success or failure per username.
+func (h *BotUserHandler) BatchAdd(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req batchAddBotUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	if len(req.Usernames) == 0 {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "usernames are required")
+		return
+	}
+	if len(req.Usernames) > maxBotUserBatchSize {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("too many usernames (max %d)", maxBotUserBatchSize))
+		return
+	}
+
+	results := validateBotUsernames(req.Usernames)
+	now := time.Now()
+	for i := range results {
+		result := &results[i]
+		if result.Error != "" {
+			continue
+		}
+
+		botUser := &model.BotUser{
+			Username:  result.Username,
+			CreatedAt: now,
+		}
+		if err := h.ds.SaveBotUser(ctx, botUser); err != nil {
+			h.logger.Error("failed to save bot user", "error", err, "username", result.Username)
+			result.Error = "failed to save bot user"
+			continue
+		}
+
+		result.Success = true
+		result.BotUser = botUser
+	}
+
+	respondJSON(w, http.StatusOK, results)
+}
+
+// validateBotUsernames trims usernames and returns one result per input,
+// with Error set for invalid usernames and repeats within the batch.
+// Batch entries are exact usernames; patterns are added one at a time via Add.
+func validateBotUsernames(usernames []string) []BatchAddBotUserResult {
+	results := make([]BatchAddBotUserResult, len(usernames))
+	seen := make(map[string]bool, len(usernames))
+	for i, username := range usernames {
+		username = strings.TrimSpace(username)
+		results[i].Username = username
+		if err := validateBotEntry(username, false); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if seen[username] {
+			results[i].Error = "duplicate username in batch"
+			continue
+		}
+		seen[username] = true
+	}
+	return results
+}
+
+// validateBotEntry checks a custom bot entry. Wildcards are only allowed (and required) for patterns.
+func validateBotEntry(username string, pattern bool) error {
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if !pattern {
+		if model.IsBotPattern(username) {
+			return fmt.Errorf("username contains wildcards; set pattern to true")
+		}
+		return nil
+	}
+	if !model.IsBotPattern(username) {
+		return fmt.Errorf("pattern must contain * or ?")
+	}
+	if _, err := path.Match(username, ""); err != nil {
+		return fmt.Errorf("invalid pattern: %s", username)
+	}
+	return nil
+}
+
 // Delete removes a custom bot user.
 func (h *BotUserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	username := r.URL.Query().Get("username")
 
 	if username == "" {
-		http.Error(w, "username query parameter is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "username query parameter is required")
 		return
 	}
 
 	if err := h.ds.DeleteBotUser(ctx, username); err != nil {
 		h.logger.Error("failed to delete bot user", "error", err)
-		http.Error(w, "failed to delete bot user", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete bot user")
 		return
 	}
 
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBotUserHandler_BatchAdd_RejectsInvalidBatch(t *testing.T) {
+	tooMany := make([]string, maxBotUserBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprintf("bot-%d", i))
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", `{"usernames":`},
+		{"missing usernames", `{}`},
+		{"empty usernames", `{"usernames":[]}`},
+		{"too many usernames", `{"usernames":[` + strings.Join(tooMany, ",") + `]}`},
+	}
+
+	// Rejected before any Datastore access, so no client is needed
+	h := NewBotUserHandler(nil, slog.Default())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/bot-users/batch", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.BatchAdd(w, r)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestValidateBotUsernames(t *testing.T) {
+	got := validateBotUsernames([]string{"dependabot[bot]", " renovate[bot] ", "", "dependabot[bot]", "   ", "ci-*"})
+
+	want := []BatchAddBotUserResult{
+		{Username: "dependabot[bot]"},
+		{Username: "renovate[bot]"},
+		{Username: "", Error: "username is required"},
+		{Username: "dependabot[bot]", Error: "duplicate username in batch"},
+		{Username: "", Error: "username is required"},
+		{Username: "ci-*", Error: "username contains wildcards; set pattern to true"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidateBotEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		pattern  bool
+		wantErr  bool
+	}{
+		{"exact name", "renovate", false, false},
+		{"glob pattern", "*-bot", true, false},
+		{"empty", "", false, true},
+		{"wildcard without pattern flag", "ci-*", false, true},
+		{"pattern flag without wildcard", "renovate", true, true},
+		{"malformed pattern", "ci-[*", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBotEntry(tt.username, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBotEntry(%q, %v) error = %v, wantErr %v", tt.username, tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+const (
+	// defaultRetentionDays is the retention applied when older_than_days is not specified.
+	defaultRetentionDays = 365
+	// minRetentionDays keeps cleanup away from the data recent metrics are computed from.
+	minRetentionDays = 30
+)
+
+// JobCleanupResponse is the cleanup job response.
+type JobCleanupResponse struct {
+	Status        string    `json:"status"`
+	OlderThanDays int       `json:"olderThanDays"`
+	Cutoff        time.Time `json:"cutoff"`
+	Repositories  int       `json:"repositories"`
+	PullRequests  int       `json:"pullRequests"`
+	Reviews       int       `json:"reviews"`
+	Deployments   int       `json:"deployments"`
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+	DurationSec   float64   `json:"durationSec"`
+}
+
+// parseRetentionDays parses older_than_days, defaulting to defaultRetentionDays.
+func parseRetentionDays(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("older_than_days")
+	if raw == "" {
+		return defaultRetentionDays, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < minRetentionDays {
+		return 0, fmt.Errorf("invalid older_than_days %q (minimum %d)", raw, minRetentionDays)
+	}
+	return days, nil
+}
+
+// cleanupCutoff returns the start of the day `days` days before now, so that
+// entities from the whole cutoff day are kept.
+func cleanupCutoff(now time.Time, days int) time.Time {
+	now = now.In(timeutil.Location())
+	return time.Date(now.Year(), now.Month(), now.Day()-days, 0, 0, 0, 0, now.Location())
+}
+
+// Cleanup deletes PRs, reviews, and deployments older than older_than_days (default 365)
+// across all repositories. Daily metrics are kept as the aggregate of the deleted data.
+// Runs under the sync lock so it never overlaps a sync job.
+func (h *JobHandler) Cleanup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startedAt := time.Now()
+
+	days, err := parseRetentionDays(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	cutoff := cleanupCutoff(timeutil.Now(), days)
+
+	instanceID := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	if err := h.ds.AcquireSyncLock(ctx, syncLockID, instanceID, h.cfg.SyncLockTTL()); err != nil {
+		h.logger.Warn("cleanup job skipped: lock already held", "error", err)
+		respondJSON(w, http.StatusConflict, map[string]string{
+			"status":  "skipped",
+			"message": fmt.Sprintf("sync job already running: %s", err.Error()),
+		})
+		return
+	}
+	defer func() {
+		if err := h.ds.ReleaseSyncLock(ctx, syncLockID, instanceID); err != nil {
+			h.logger.Error("failed to release sync lock", "error", err)
+		}
+	}()
+
+	repos, err := h.ds.ListRepositories(ctx)
+	if err != nil {
+		h.logger.Error("failed to list repositories", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
+		return
+	}
+
+	resp := &JobCleanupResponse{
+		Status:        "completed",
+		OlderThanDays: days,
+		Cutoff:        cutoff,
+		Repositories:  len(repos),
+		StartedAt:     startedAt,
+	}
+	counts := map[string]*int{
+		datastore.KindPullRequest: &resp.PullRequests,
+		datastore.KindReview:      &resp.Reviews,
+		datastore.KindDeployment:  &resp.Deployments,
+	}
+	for _, repo := range repos {
+		for _, kind := range datastore.RetentionKinds {
+			n, err := h.ds.DeleteOlderThan(ctx, repo.ID, kind, cutoff)
+			*counts[kind] += n
+			if err != nil {
+				h.logger.Error("failed to delete old entities", "repository", repo.FullName, "kind", kind, "error", err)
+				respondError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("failed to clean up %s", repo.FullName))
+				return
+			}
+		}
+	}
+
+	if h.cache != nil && resp.PullRequests+resp.Reviews+resp.Deployments > 0 {
+		h.cache.Invalidate()
+	}
+
+	resp.FinishedAt = time.Now()
+	resp.DurationSec = resp.FinishedAt.Sub(startedAt).Seconds()
+	h.logger.Info("cleanup job completed",
+		"cutoff", cutoff,
+		"repositories", resp.Repositories,
+		"pull_requests", resp.PullRequests,
+		"reviews", resp.Reviews,
+		"deployments", resp.Deployments,
+		"durationSec", resp.DurationSec,
+	)
+
+	respondJSON(w, http.StatusOK, resp)
+}
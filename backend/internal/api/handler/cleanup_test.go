@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+func TestParseRetentionDays(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultRetentionDays, false},
+		{"older_than_days=90", 90, false},
+		{"older_than_days=30", 30, false},
+		{"older_than_days=29", 0, true},
+		{"older_than_days=0", 0, true},
+		{"older_than_days=abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseRetentionDays(httptest.NewRequest("PUT", "/api/job/cleanup?"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("days = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupCutoff(t *testing.T) {
+	loc := timeutil.Location()
+	now := time.Date(2025, 3, 1, 15, 30, 0, 0, loc)
+
+	tests := []struct {
+		days int
+		want time.Time
+	}{
+		{30, time.Date(2025, 1, 30, 0, 0, 0, 0, loc)},
+		{365, time.Date(2024, 3, 1, 0, 0, 0, 0, loc)}, // 2024 is a leap year
+	}
+	for _, tt := range tests {
+		if got := cleanupCutoff(now, tt.days); !got.Equal(tt.want) {
+			t.Errorf("cleanupCutoff(%v, %d) = %v, want %v", now, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestJobHandler_Cleanup(t *testing.T) {
+	now := timeutil.Now()
+	old := now.AddDate(0, 0, -400)
+	recent := now.AddDate(0, 0, -10)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "o/a"}
+	store.pullRequests["pr-old"] = &model.PullRequest{ID: "pr-old", RepositoryID: "repo-a", UpdatedAt: old}
+	store.pullRequests["pr-active"] = &model.PullRequest{ID: "pr-active", RepositoryID: "repo-a", CreatedAt: old, UpdatedAt: recent}
+	store.reviews["rv-old"] = &model.Review{ID: "rv-old", RepositoryID: "repo-a", SubmittedAt: old}
+	store.reviews["rv-new"] = &model.Review{ID: "rv-new", RepositoryID: "repo-a", SubmittedAt: recent}
+	store.deployments["d-old"] = &model.Deployment{ID: "d-old", RepositoryID: "repo-a", CreatedAt: old}
+	store.dailyMetrics["m-old"] = &model.DailyMetrics{ID: "m-old", RepositoryID: "repo-a", Date: old}
+	h := &JobHandler{ds: store, logger: slog.Default(), cfg: &config.Config{SyncLockTTLMinutes: 10}}
+
+	w := httptest.NewRecorder()
+	h.Cleanup(w, httptest.NewRequest("PUT", "/api/job/cleanup?older_than_days=365", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp JobCleanupResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.PullRequests != 1 || resp.Reviews != 1 || resp.Deployments != 1 {
+		t.Errorf("deleted = %d PRs, %d reviews, %d deployments; want 1 each", resp.PullRequests, resp.Reviews, resp.Deployments)
+	}
+	if _, ok := store.pullRequests["pr-active"]; !ok {
+		t.Error("PR updated within the retention period was deleted")
+	}
+	if _, ok := store.reviews["rv-new"]; !ok {
+		t.Error("recent review was deleted")
+	}
+	if _, ok := store.dailyMetrics["m-old"]; !ok {
+		t.Error("daily metrics must be kept")
+	}
+	if len(store.locks) != 0 {
+		t.Errorf("sync lock not released: %v", store.locks)
+	}
+
+	t.Run("lock held", func(t *testing.T) {
+		store.locks[syncLockID] = "other"
+		defer delete(store.locks, syncLockID)
+		w := httptest.NewRecorder()
+		h.Cleanup(w, httptest.NewRequest("PUT", "/api/job/cleanup", nil))
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+}
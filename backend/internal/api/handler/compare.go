@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/metrics"
+)
+
+// Comparison is the response envelope for compare=previous requests.
+// Delta holds the percentage change from the previous to the current period per metric.
+type Comparison struct {
+	Current  any                `json:"current"`
+	Previous any                `json:"previous"`
+	Delta    map[string]float64 `json:"delta"`
+}
+
+// compareWithPrevious reports whether the request asks for a previous-period comparison.
+func compareWithPrevious(r *http.Request) bool {
+	return r.URL.Query().Get("compare") == "previous"
+}
+
+// previousPeriod returns the window of equal length immediately preceding [start, end].
+// The range is treated as inclusive at second granularity, matching parseDateRange.
+func previousPeriod(start, end time.Time) (time.Time, time.Time) {
+	length := end.Sub(start) + time.Second
+	return start.Add(-length), end.Add(-length)
+}
+
+// cycleTimeDelta computes percentage deltas for the headline cycle time metrics.
+func cycleTimeDelta(current, previous *model.CycleTimeMetrics) map[string]float64 {
+	return map[string]float64{
+		"totalPRs":        metrics.PercentChange(float64(previous.TotalPRs), float64(current.TotalPRs)),
+		"avgCycleTime":    metrics.PercentChange(previous.AvgCycleTime, current.AvgCycleTime),
+		"avgCodingTime":   metrics.PercentChange(previous.AvgCodingTime, current.AvgCodingTime),
+		"avgPickupTime":   metrics.PercentChange(previous.AvgPickupTime, current.AvgPickupTime),
+		"avgReviewTime":   metrics.PercentChange(previous.AvgReviewTime, current.AvgReviewTime),
+		"avgMergeTime":    metrics.PercentChange(previous.AvgMergeTime, current.AvgMergeTime),
+		"medianCycleTime": metrics.PercentChange(previous.MedianCycleTime, current.MedianCycleTime),
+		"p90CycleTime":    metrics.PercentChange(previous.P90CycleTime, current.P90CycleTime),
+	}
+}
+
+// doraDelta computes percentage deltas for the headline DORA metrics.
+func doraDelta(current, previous *model.DORAMetrics) map[string]float64 {
+	return map[string]float64{
+		"deploymentCount":       metrics.PercentChange(float64(previous.DeploymentCount), float64(current.DeploymentCount)),
+		"avgDeploysPerDay":      metrics.PercentChange(previous.AvgDeploysPerDay, current.AvgDeploysPerDay),
+		"deploymentSuccessRate": metrics.PercentChange(previous.DeploymentSuccessRate, current.DeploymentSuccessRate),
+		"avgLeadTime":           metrics.PercentChange(previous.AvgLeadTime, current.AvgLeadTime),
+		"medianLeadTime":        metrics.PercentChange(previous.MedianLeadTime, current.MedianLeadTime),
+		"p90LeadTime":           metrics.PercentChange(previous.P90LeadTime, current.P90LeadTime),
+		"changeFailureRate":     metrics.PercentChange(previous.ChangeFailureRate, current.ChangeFailureRate),
+		"avgMTTR":               metrics.PercentChange(previous.AvgMTTR, current.AvgMTTR),
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestPreviousPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     time.Time
+		end       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "30-day range shifts back 30 days",
+			start:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:       time.Date(2026, 1, 30, 23, 59, 59, 0, time.UTC),
+			wantStart: time.Date(2025, 12, 2, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name:      "single day shifts to the day before",
+			start:     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			end:       time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC),
+			wantStart: time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStart, gotEnd := previousPeriod(tt.start, tt.end)
+			if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantEnd) {
+				t.Errorf("previousPeriod = [%v, %v], want [%v, %v]", gotStart, gotEnd, tt.wantStart, tt.wantEnd)
+			}
+			if gotEnd.Sub(gotStart) != tt.end.Sub(tt.start) {
+				t.Errorf("previous window length = %v, want %v", gotEnd.Sub(gotStart), tt.end.Sub(tt.start))
+			}
+			if !gotEnd.Before(tt.start) {
+				t.Errorf("previous window end %v overlaps current start %v", gotEnd, tt.start)
+			}
+		})
+	}
+}
+
+func TestCycleTimeDelta(t *testing.T) {
+	current := &model.CycleTimeMetrics{TotalPRs: 12, AvgCycleTime: 22, MedianCycleTime: 10}
+	previous := &model.CycleTimeMetrics{TotalPRs: 10, AvgCycleTime: 25, MedianCycleTime: 0}
+
+	delta := cycleTimeDelta(current, previous)
+	if math.Abs(delta["totalPRs"]-20) > 1e-9 {
+		t.Errorf("totalPRs delta = %v, want 20", delta["totalPRs"])
+	}
+	if math.Abs(delta["avgCycleTime"]-(-12)) > 1e-9 {
+		t.Errorf("avgCycleTime delta = %v, want -12", delta["avgCycleTime"])
+	}
+	if delta["medianCycleTime"] != 0 {
+		t.Errorf("medianCycleTime delta = %v, want 0", delta["medianCycleTime"])
+	}
+}
+
+func TestCompareWithPrevious(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"compare=previous", true},
+		{"compare=yes", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/metrics/cycle-time?"+tt.query, nil)
+		if got := compareWithPrevious(r); got != tt.want {
+			t.Errorf("compareWithPrevious(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+	"github.com/compasstechlab/dora-yaki/internal/metrics"
+)
+
+// activity is the PR, review and deployment data daily metrics are aggregated from.
+type activity struct {
+	pullRequests []*model.PullRequest
+	reviews      []*model.Review
+	deployments  []*model.Deployment
+}
+
+// recomputeDailyMetrics aggregates daily metrics for [start, end] from the stored data merged with
+// the freshly collected data. A short sync only collects recently updated PRs, so aggregating the
+// collected subset alone would overwrite earlier days in the window with under-counted rows.
+func recomputeDailyMetrics(ctx context.Context, ds activityStore, logger *slog.Logger, minActivity int, repoID string, start, end time.Time, data *github.CollectedData) []*model.DailyMetrics {
+	fresh := activity{
+		pullRequests: data.PullRequests,
+		reviews:      data.Reviews,
+		deployments:  data.Deployments,
+	}
+
+	stored, err := loadStoredActivity(ctx, ds, repoID, startOfDay(start), end)
+	if err != nil {
+		logger.Warn("failed to load stored data for daily metrics, using collected data only",
+			"repository", repoID,
+			"error", err,
+		)
+		stored = activity{}
+	}
+
+	merged := mergeActivity(stored, fresh)
+	return dailyAggregator(ctx, ds, logger, minActivity).AggregateRange(repoID, start, end, merged.pullRequests, merged.reviews, merged.deployments)
+}
+
+// dailyAggregator returns the aggregator for daily metrics, leaving the registered bot users and
+// contributors with fewer than minActivity activities a day out of the active contributors.
+// When bot users fail to load, only "[bot]" accounts are left out.
+func dailyAggregator(ctx context.Context, ds activityStore, logger *slog.Logger, minActivity int) *metrics.Aggregator {
+	botUsernames, err := ds.ListBotUsernames(ctx)
+	if err != nil {
+		logger.Warn("failed to get bot usernames for daily metrics", "error", err)
+	}
+	return metrics.NewAggregator().WithBotUsernames(botUsernames).WithMinActivity(minActivity)
+}
+
+// loadStoredActivity loads the stored data that can affect daily metrics in [start, end]:
+// PRs updated since start (opened, merged or closed in the window), currently open PRs
+// (for open PR counts), and reviews and deployments within the window.
+func loadStoredActivity(ctx context.Context, ds activityStore, repoID string, start, end time.Time) (activity, error) {
+	updated, err := ds.ListPullRequestsUpdatedSince(ctx, repoID, start)
+	if err != nil {
+		return activity{}, err
+	}
+	open, err := ds.ListOpenPullRequests(ctx, repoID)
+	if err != nil {
+		return activity{}, err
+	}
+	reviews, err := ds.ListReviewsByDateRange(ctx, repoID, start, end)
+	if err != nil {
+		return activity{}, err
+	}
+	deployments, err := ds.ListDeployments(ctx, repoID, &datastore.QueryOptions{Since: start})
+	if err != nil {
+		return activity{}, err
+	}
+	return activity{
+		pullRequests: mergeByID(updated, open, func(pr *model.PullRequest) string { return pr.ID }),
+		reviews:      reviews,
+		deployments:  deployments,
+	}, nil
+}
+
+// mergeActivity merges stored and freshly collected data; fresh items replace stored ones with the same ID.
+func mergeActivity(stored, fresh activity) activity {
+	return activity{
+		pullRequests: mergeByID(stored.pullRequests, fresh.pullRequests, func(pr *model.PullRequest) string { return pr.ID }),
+		reviews:      mergeByID(stored.reviews, fresh.reviews, func(r *model.Review) string { return r.ID }),
+		deployments:  mergeByID(stored.deployments, fresh.deployments, func(d *model.Deployment) string { return d.ID }),
+	}
+}
+
+// mergeByID returns base with overrides applied: items sharing an ID are replaced, others appended.
+func mergeByID[T any](base, overrides []T, id func(T) string) []T {
+	index := make(map[string]int, len(base)+len(overrides))
+	result := make([]T, 0, len(base)+len(overrides))
+	for _, items := range [][]T{base, overrides} {
+		for _, item := range items {
+			if i, ok := index[id(item)]; ok {
+				result[i] = item
+				continue
+			}
+			index[id(item)] = len(result)
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// startOfDay returns midnight of t's day in t's location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+	"github.com/compasstechlab/dora-yaki/internal/metrics"
+)
+
+func TestMergeActivity_ShortSyncKeepsPriorDays(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 0, 0, time.UTC) }
+	mergedAt := at(10, 9)
+
+	// Stored before the sync: two PRs opened yesterday, one reviewed yesterday
+	stored := activity{
+		pullRequests: []*model.PullRequest{
+			{ID: "a", State: "open", CreatedAt: at(9, 10), UpdatedAt: at(9, 10)},
+			{ID: "b", State: "open", CreatedAt: at(9, 11), UpdatedAt: at(9, 15)},
+		},
+		reviews: []*model.Review{
+			{ID: "r1", PullRequestID: "b", Reviewer: "carol", SubmittedAt: at(9, 15)},
+		},
+	}
+	// A "day" sync only sees PRs updated since: b (merged today) and c (opened today)
+	fresh := activity{
+		pullRequests: []*model.PullRequest{
+			{ID: "b", State: "closed", CreatedAt: at(9, 11), UpdatedAt: mergedAt, MergedAt: &mergedAt, ClosedAt: &mergedAt},
+			{ID: "c", State: "open", CreatedAt: at(10, 8), UpdatedAt: at(10, 8)},
+		},
+	}
+
+	merged := mergeActivity(stored, fresh)
+	if len(merged.pullRequests) != 3 {
+		t.Fatalf("merged %d PRs, want 3 (fresh replaces stored by ID)", len(merged.pullRequests))
+	}
+	for _, pr := range merged.pullRequests {
+		if pr.ID == "b" && pr.MergedAt == nil {
+			t.Error("PR b kept the stored (open) version, want the freshly collected one")
+		}
+	}
+
+	syncStart, syncEnd := at(9, 12), at(10, 12)
+	agg := metrics.NewAggregator()
+	days := agg.AggregateRange("repo", syncStart, syncEnd, merged.pullRequests, merged.reviews, merged.deployments)
+	if len(days) != 2 {
+		t.Fatalf("got %d days, want 2", len(days))
+	}
+
+	yesterday, today := days[0], days[1]
+	if yesterday.PRsOpened != 2 || yesterday.ReviewsSubmitted != 1 || yesterday.OpenPRCount != 2 {
+		t.Errorf("yesterday = opened %d, reviews %d, open %d; want 2, 1, 2",
+			yesterday.PRsOpened, yesterday.ReviewsSubmitted, yesterday.OpenPRCount)
+	}
+	if today.PRsOpened != 1 || today.PRsMerged != 1 || today.OpenPRCount != 2 {
+		t.Errorf("today = opened %d, merged %d, open %d; want 1, 1, 2",
+			today.PRsOpened, today.PRsMerged, today.OpenPRCount)
+	}
+
+	// Aggregating the collected subset alone under-counts the earlier day
+	freshOnly := agg.AggregateRange("repo", syncStart, syncEnd, fresh.pullRequests, fresh.reviews, fresh.deployments)
+	if freshOnly[0].PRsOpened == yesterday.PRsOpened {
+		t.Errorf("fresh-only aggregation unexpectedly matches: opened %d", freshOnly[0].PRsOpened)
+	}
+}
+
+func TestRecomputeDailyMetrics_ExcludesBotUsers(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 6, 10, hour, 0, 0, 0, time.UTC) }
+	store := newMemStore()
+	_ = store.SaveBotUser(t.Context(), &model.BotUser{Username: "release-robot"})
+
+	data := &github.CollectedData{
+		PullRequests: []*model.PullRequest{
+			{ID: "1", RepositoryID: "repo", Author: "alice", CreatedAt: at(9), UpdatedAt: at(9)},
+			{ID: "2", RepositoryID: "repo", Author: "release-robot", CreatedAt: at(10), UpdatedAt: at(10)},
+			{ID: "3", RepositoryID: "repo", Author: "renovate[bot]", CreatedAt: at(11), UpdatedAt: at(11)},
+		},
+	}
+
+	days := recomputeDailyMetrics(t.Context(), store, slog.Default(), 1, "repo", at(0), at(23), data)
+	if len(days) != 1 {
+		t.Fatalf("got %d days, want 1", len(days))
+	}
+	if days[0].PRsOpened != 3 || days[0].ActiveContributors != 1 {
+		t.Errorf("opened %d, active contributors %d; want 3 PRs by 1 non-bot contributor", days[0].PRsOpened, days[0].ActiveContributors)
+	}
+}
+
+func TestMergeByID(t *testing.T) {
+	id := func(s [2]string) string { return s[0] }
+	base := [][2]string{{"1", "old"}, {"2", "old"}}
+	overrides := [][2]string{{"2", "new"}, {"3", "new"}}
+
+	got := mergeByID(base, overrides, id)
+	want := [][2]string{{"1", "old"}, {"2", "new"}, {"3", "new"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// Export formats
+const (
+	exportFormatJSON   = "json"   // one object with pullRequests, reviews, and deployments arrays
+	exportFormatNDJSON = "ndjson" // one {"type", "data"} record per line
+)
+
+// exportRecord is one line of an NDJSON export.
+type exportRecord struct {
+	Type string `json:"type"` // pullRequest, review, deployment
+	Data any    `json:"data"`
+}
+
+// exportSection is one entity kind of an export, streamed per repository.
+type exportSection struct {
+	key  string // JSON object key
+	kind string // NDJSON record type
+	each func(ctx context.Context, repositoryID string, emit func(any) error) error
+}
+
+// Export streams all stored PRs, reviews, and deployments for the period as a raw data dump
+// for external BI tools. Entities are read from Datastore iterators and written as they arrive,
+// so the export is never buffered in memory. Bot filters do not apply.
+func (h *MetricsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = exportFormatJSON
+	case exportFormatJSON, exportFormatNDJSON:
+	default:
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid format %q (use json or ndjson)", format))
+		return
+	}
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	sections := []exportSection{
+		{"pullRequests", "pullRequest", func(ctx context.Context, id string, emit func(any) error) error {
+			return h.ds.EachPullRequestByDateRange(ctx, id, startDate, endDate, func(pr *model.PullRequest) error { return emit(pr) })
+		}},
+		{"reviews", "review", func(ctx context.Context, id string, emit func(any) error) error {
+			return h.ds.EachReviewByDateRange(ctx, id, startDate, endDate, func(rv *model.Review) error { return emit(rv) })
+		}},
+		{"deployments", "deployment", func(ctx context.Context, id string, emit func(any) error) error {
+			return h.ds.EachDeploymentByDateRange(ctx, id, startDate, endDate, func(d *model.Deployment) error { return emit(d) })
+		}},
+	}
+
+	if format == exportFormatNDJSON {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s-%s.%s"`,
+		startDate.Format(time.DateOnly), endDate.Format(time.DateOnly), format))
+	w.WriteHeader(http.StatusOK)
+
+	ew := newExportWriter(w, format == exportFormatNDJSON)
+	for _, s := range sections {
+		ew.beginSection(s.key)
+		for _, id := range repoIDs {
+			if err := s.each(ctx, id, func(v any) error { return ew.write(s.kind, v) }); err != nil {
+				// The status line is already sent; the client sees a truncated body
+				h.logger.Error("export failed", "section", s.key, "repository", id, "error", err)
+				return
+			}
+		}
+		ew.endSection()
+	}
+	ew.close()
+}
+
+// exportWriter writes export entities as NDJSON records or as arrays of one JSON object.
+type exportWriter struct {
+	w        io.Writer
+	enc      *json.Encoder
+	ndjson   bool
+	sections int // sections begun
+	written  int // values written in the current section
+}
+
+func newExportWriter(w io.Writer, ndjson bool) *exportWriter {
+	return &exportWriter{w: w, enc: json.NewEncoder(w), ndjson: ndjson}
+}
+
+// beginSection opens the array for key in the JSON object.
+func (e *exportWriter) beginSection(key string) {
+	if e.ndjson {
+		return
+	}
+	sep := "{"
+	if e.sections > 0 {
+		sep = ","
+	}
+	_, _ = fmt.Fprintf(e.w, "%s%q:[", sep, key)
+	e.sections++
+	e.written = 0
+}
+
+// write writes one entity; kind labels the record in NDJSON.
+func (e *exportWriter) write(kind string, v any) error {
+	if e.ndjson {
+		return e.enc.Encode(exportRecord{Type: kind, Data: v})
+	}
+	if e.written > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.written++
+	return e.enc.Encode(v)
+}
+
+// endSection closes the current array.
+func (e *exportWriter) endSection() {
+	if !e.ndjson {
+		_, _ = io.WriteString(e.w, "]")
+	}
+}
+
+// close ends the JSON object.
+func (e *exportWriter) close() {
+	if !e.ndjson {
+		_, _ = io.WriteString(e.w, "}\n")
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+func newExportTestHandler(t *testing.T) *MetricsHandler {
+	t.Helper()
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 9, 0, 0, 0, timeutil.Location()) }
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "pr-1", RepositoryID: "repo-a", Number: 1, Title: "Line\nbreak", CreatedAt: at(2)},
+		{ID: "pr-2", RepositoryID: "repo-a", Number: 2, CreatedAt: at(5)},
+		{ID: "pr-old", RepositoryID: "repo-a", Number: 0, CreatedAt: at(2).AddDate(0, -2, 0)}, // outside the range
+	})
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		{ID: "rv-1", RepositoryID: "repo-a", PullRequestID: "repo-a#1", Reviewer: "bob", SubmittedAt: at(3)},
+	})
+	_ = store.SaveDeployments(t.Context(), []*model.Deployment{
+		{ID: "d-1", RepositoryID: "repo-a", Environment: "production", CreatedAt: at(6)},
+	})
+	return NewMetricsHandler(store, slog.Default(), &config.Config{})
+}
+
+func TestMetricsHandler_Export_NDJSON(t *testing.T) {
+	h := newExportTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.Export(w, httptest.NewRequest("GET", "/api/metrics/export?repository=repo-a&start=2025-06-01&end=2025-06-30&format=ndjson", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	// One record per line, grouped by type; newlines inside values stay escaped
+	var types []string
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var record struct {
+			Type string `json:"type"`
+			Data struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d %q: %v", len(types)+1, scanner.Text(), err)
+		}
+		types = append(types, record.Type)
+		ids[record.Data.ID] = true
+	}
+
+	want := []string{"pullRequest", "pullRequest", "review", "deployment"}
+	if len(types) != len(want) {
+		t.Fatalf("record types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("record types = %v, want %v", types, want)
+			break
+		}
+	}
+	for _, id := range []string{"pr-1", "pr-2", "rv-1", "d-1"} {
+		if !ids[id] {
+			t.Errorf("record %s missing from export", id)
+		}
+	}
+}
+
+func TestMetricsHandler_Export_JSON(t *testing.T) {
+	h := newExportTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.Export(w, httptest.NewRequest("GET", "/api/metrics/export?start=2025-06-01&end=2025-06-30", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	var got struct {
+		PullRequests []*model.PullRequest `json:"pullRequests"`
+		Reviews      []*model.Review      `json:"reviews"`
+		Deployments  []*model.Deployment  `json:"deployments"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v\n%s", err, w.Body.String())
+	}
+	if len(got.PullRequests) != 2 || len(got.Reviews) != 1 || len(got.Deployments) != 1 {
+		t.Errorf("exported %d PRs, %d reviews, %d deployments; want 2, 1, 1", len(got.PullRequests), len(got.Reviews), len(got.Deployments))
+	}
+}
+
+func TestMetricsHandler_Export_InvalidFormat(t *testing.T) {
+	h := newExportTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.Export(w, httptest.NewRequest("GET", "/api/metrics/export?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
@@ -28,7 +28,7 @@ func (h *GitHubHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	user, err := h.gh.GetAuthenticatedUser(ctx)
 	if err != nil {
 		h.logger.Error("failed to get authenticated user", "error", err)
-		http.Error(w, "failed to get authenticated user", http.StatusInternalServerError)
+		respondError(w, http.StatusBadGateway, errCodeUpstream, "failed to get authenticated user")
 		return
 	}
 
@@ -41,7 +41,7 @@ func (h *GitHubHandler) ListOwnerRepos(w http.ResponseWriter, r *http.Request) {
 	owner := r.PathValue("owner")
 
 	if owner == "" {
-		http.Error(w, "owner is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "owner is required")
 		return
 	}
 
@@ -53,7 +53,7 @@ func (h *GitHubHandler) ListOwnerRepos(w http.ResponseWriter, r *http.Request) {
 	repos, err := h.gh.ListOwnerRepos(ctx, owner, opts)
 	if err != nil {
 		h.logger.Error("failed to list owner repos", "error", err, "owner", owner)
-		http.Error(w, "failed to list repos", http.StatusInternalServerError)
+		respondError(w, http.StatusBadGateway, errCodeUpstream, "failed to list repos")
 		return
 	}
 
@@ -7,16 +7,18 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
 	"github.com/compasstechlab/dora-yaki/internal/config"
-	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 	"github.com/compasstechlab/dora-yaki/internal/github"
-	"github.com/compasstechlab/dora-yaki/internal/metrics"
 	"github.com/compasstechlab/dora-yaki/internal/timeutil"
 )
 
@@ -24,30 +26,54 @@ const (
 	syncLockID = "sync-job"
 	// processStartGuard is the minimum elapsed time from ProcessStartAt (prevents premature re-execution).
 	processStartGuard = 10 * time.Minute
+	// maxSyncParallel caps the number of repositories synced concurrently in parallel mode.
+	maxSyncParallel = 10
 )
 
+// Collector collects GitHub data for a repository.
+// *github.Collector implements it; tests substitute a fake to exercise the sync flow without GitHub.
+type Collector interface {
+	CollectAll(ctx context.Context, owner, repo string, opts *github.CollectOptions) (*github.CollectedData, error)
+	EstimatePullRequests(ctx context.Context, owner, repo string, opts *github.CollectOptions) (*github.SyncEstimate, error)
+	EnrichPullRequestTimes(ctx context.Context, owner, repo string, prs []*model.PullRequest, repositoryID string) []*model.Review
+}
+
+var _ Collector = (*github.Collector)(nil)
+
 // JobHandler handles batch job API requests.
 type JobHandler struct {
-	ds        *datastore.Client
+	ds        JobStore
 	gh        *github.Client
-	collector *github.Collector
+	collector Collector
 	logger    *slog.Logger
 	cache     *middleware.ResponseCache
 	cfg       *config.Config
+
+	minActivity int // activities a day for an active contributor in daily metrics
 }
 
 // NewJobHandler creates a new JobHandler.
-func NewJobHandler(ds *datastore.Client, gh *github.Client, logger *slog.Logger, cache *middleware.ResponseCache, cfg *config.Config) *JobHandler {
+func NewJobHandler(ds JobStore, gh *github.Client, logger *slog.Logger, cache *middleware.ResponseCache, cfg *config.Config) *JobHandler {
 	return &JobHandler{
 		ds:        ds,
 		gh:        gh,
-		collector: github.NewCollector(gh, logger),
+		collector: newCollector(gh, logger, cfg),
 		logger:    logger,
 		cache:     cache,
 		cfg:       cfg,
+
+		minActivity: cfg.ActiveContributorMinActivity,
 	}
 }
 
+// newCollector creates a Collector that labels file stats with the configured language map
+// and records last approvals when configured.
+func newCollector(gh *github.Client, logger *slog.Logger, cfg *config.Config) *github.Collector {
+	return github.NewCollector(gh, logger).
+		WithLanguageMap(github.DefaultLanguageMap().Merge(cfg.LanguageMap)).
+		WithLastApproval(cfg.MergeTimeFromLastApproval)
+}
+
 // jobSyncRequest is a request parsed from both query parameters and JSON body.
 type jobSyncRequest struct {
 	Range      string `json:"range"`
@@ -56,6 +82,13 @@ type jobSyncRequest struct {
 	NoLock     bool   `json:"nolock"`      // Skip Datastore lock mechanism
 	Force      bool   `json:"force"`       // Disable ProcessStartAt validation when repo is specified
 	ClearCache bool   `json:"clear_cache"` // Invalidate response cache after sync (default: false)
+
+	SkipFileStats bool   `json:"skip_file_stats"` // Skip per-PR file listing (no extension stats)
+	UseReleases   bool   `json:"use_releases"`    // Also record published releases as deployments
+	TagPattern    string `json:"tag_pattern"`     // Also record tags matching this glob as deployments
+	Backend       string `json:"backend"`         // PR collection API: rest (default) or graphql
+	MaxPages      int    `json:"max_pages"`       // Override the range's page limit when > 0 (deep backfill)
+	Parallel      int    `json:"parallel"`        // Sync up to N eligible repositories concurrently (0/1 = one repository)
 }
 
 // JobSyncResponse is the sync job response.
@@ -80,6 +113,10 @@ type RepoSyncResult struct {
 	PullRequests int    `json:"pullRequests"`
 	Reviews      int    `json:"reviews"`
 	Deployments  int    `json:"deployments"`
+
+	// Partial is set when some collection stages failed; whatever was collected is still saved.
+	Partial bool     `json:"partial,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
 }
 
 // parseSyncRequest parses parameters from both query parameters and JSON body.
@@ -91,6 +128,10 @@ func parseSyncRequest(r *http.Request) jobSyncRequest {
 	nolock, _ := strconv.ParseBool(q.Get("nolock"))
 	force, _ := strconv.ParseBool(q.Get("force"))
 	clearCache, _ := strconv.ParseBool(q.Get("clear_cache"))
+	skipFileStats, _ := strconv.ParseBool(q.Get("skip_file_stats"))
+	useReleases, _ := strconv.ParseBool(q.Get("use_releases"))
+	maxPages, _ := strconv.Atoi(q.Get("max_pages"))
+	parallel, _ := strconv.Atoi(q.Get("parallel"))
 
 	req := jobSyncRequest{
 		Range:      q.Get("range"),
@@ -99,6 +140,13 @@ func parseSyncRequest(r *http.Request) jobSyncRequest {
 		NoLock:     nolock,
 		Force:      force,
 		ClearCache: clearCache,
+
+		SkipFileStats: skipFileStats,
+		UseReleases:   useReleases,
+		TagPattern:    q.Get("tag_pattern"),
+		Backend:       q.Get("backend"),
+		MaxPages:      maxPages,
+		Parallel:      parallel,
 	}
 
 	// Override with JSON body if present
@@ -123,16 +171,36 @@ func parseSyncRequest(r *http.Request) jobSyncRequest {
 			if body.ClearCache {
 				req.ClearCache = true
 			}
+			if body.SkipFileStats {
+				req.SkipFileStats = true
+			}
+			if body.UseReleases {
+				req.UseReleases = true
+			}
+			if body.TagPattern != "" {
+				req.TagPattern = body.TagPattern
+			}
+			if body.Backend != "" {
+				req.Backend = body.Backend
+			}
+			if body.MaxPages > 0 {
+				req.MaxPages = body.MaxPages
+			}
+			if body.Parallel > 0 {
+				req.Parallel = body.Parallel
+			}
 		}
 	}
 
 	if req.Range == "" {
 		req.Range = "day"
 	}
+	req.Parallel = max(1, min(req.Parallel, maxSyncParallel))
 	return req
 }
 
-// Sync synchronizes a single repository that matches the criteria.
+// Sync synchronizes the repository that matches the criteria, or up to req.Parallel
+// repositories concurrently in parallel mode, under a single sync lock.
 // Designed to be invoked periodically by Cloud Scheduler.
 func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -140,6 +208,16 @@ func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request parameters (query + JSON body)
 	req := parseSyncRequest(r)
+	tagPattern, err := parseTagPattern(req.TagPattern)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	backend, err := parseCollectBackend(req.Backend)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
 
 	// Generate instance ID
 	instanceID := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
@@ -152,6 +230,7 @@ func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 		"nolock", req.NoLock,
 		"force", req.Force,
 		"clear_cache", req.ClearCache,
+		"parallel", req.Parallel,
 	)
 
 	// Acquire exclusive lock (skip if nolock=true)
@@ -175,14 +254,14 @@ func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 	repos, err := h.ds.ListRepositories(ctx)
 	if err != nil {
 		h.logger.Error("failed to list repositories", "error", err)
-		http.Error(w, "failed to list repositories", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
 		return
 	}
 
-	// Select one sync target
-	target := h.pickSyncTarget(repos, req)
+	// Select sync targets
+	targets := h.pickSyncTargets(repos, req, req.Parallel)
 
-	if target == nil {
+	if len(targets) == 0 {
 		finishedAt := time.Now()
 		h.logger.Info("sync job completed: no eligible repository found")
 		respondJSON(w, http.StatusOK, &JobSyncResponse{
@@ -200,33 +279,43 @@ func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 
 	// Update ProcessStartAt (sync start marker)
 	now := time.Now()
-	target.ProcessStartAt = &now
-	if err := h.ds.SaveRepository(ctx, target); err != nil {
-		h.logger.Error("failed to update process_start_at", "error", err)
+	for _, target := range targets {
+		target.ProcessStartAt = &now
+		if err := h.ds.SaveRepository(ctx, target); err != nil {
+			h.logger.Error("failed to update process_start_at", "repository", target.FullName, "error", err)
+		}
 	}
 
 	// Execute sync
-	result := h.syncSingleRepo(ctx, target, req.Range)
+	opts := syncCollectOptions(req.Range, req.MaxPages, req.SkipFileStats)
+	opts.UseReleases = req.UseReleases
+	opts.TagPattern = tagPattern
+	opts.Backend = backend
+	results := syncConcurrently(ctx, targets, req.Parallel, func(ctx context.Context, repo *model.Repository) RepoSyncResult {
+		return h.syncSingleRepo(ctx, repo, opts)
+	})
+
+	syncedCount := 0
+	for _, result := range results {
+		if result.Success {
+			syncedCount++
+		}
+	}
 
 	// Invalidate cache only when explicitly requested
-	if req.ClearCache && result.Success && h.cache != nil {
+	if req.ClearCache && syncedCount > 0 && h.cache != nil {
 		h.cache.Invalidate()
 		h.logger.Info("response cache invalidated after job sync")
 	}
 
 	finishedAt := time.Now()
-	syncedCount := 0
-	if result.Success {
-		syncedCount = 1
-	}
-
 	response := &JobSyncResponse{
 		Status:       "completed",
 		Message:      fmt.Sprintf("synced %d/%d repositories", syncedCount, len(repos)),
 		TotalRepos:   len(repos),
 		SyncedRepos:  syncedCount,
-		SkippedRepos: len(repos) - 1,
-		Results:      []RepoSyncResult{result},
+		SkippedRepos: len(repos) - len(targets),
+		Results:      results,
 		StartedAt:    startedAt,
 		FinishedAt:   finishedAt,
 		DurationSec:  finishedAt.Sub(startedAt).Seconds(),
@@ -234,23 +323,67 @@ func (h *JobHandler) Sync(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("sync job completed",
 		"totalRepos", len(repos),
-		"syncedRepo", target.FullName,
-		"success", result.Success,
+		"targets", len(targets),
+		"syncedRepos", syncedCount,
 		"durationSec", response.DurationSec,
 	)
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// syncConcurrently runs syncRepo for each repository with at most workers in flight.
+// Results are returned in the order of repos.
+func syncConcurrently(ctx context.Context, repos []*model.Repository, workers int, syncRepo func(context.Context, *model.Repository) RepoSyncResult) []RepoSyncResult {
+	results := make([]RepoSyncResult, len(repos))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(repos)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = syncRepo(ctx, repos[i])
+			}
+		}()
+	}
+	for i := range repos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// pickSyncTargets selects up to limit repositories to sync, in the order pickSyncTarget would choose them.
+// A specified repo yields at most one target.
+func (h *JobHandler) pickSyncTargets(repos []*model.Repository, req jobSyncRequest, limit int) []*model.Repository {
+	var targets []*model.Repository
+	remaining := repos
+	for len(targets) < limit {
+		target := h.pickSyncTarget(remaining, req)
+		if target == nil {
+			break
+		}
+		targets = append(targets, target)
+		if req.Repo != "" {
+			break
+		}
+		remaining = slices.DeleteFunc(slices.Clone(remaining), func(r *model.Repository) bool { return r == target })
+	}
+	return targets
+}
+
 // pickSyncTarget selects one repository to sync.
 //
 // When repo is specified:
 //   - Find matching repository by FullName or Name
-//   - Skip ProcessStartAt check if force=true
+//   - Skip ProcessStartAt and archived checks if force=true
 //   - Interval check always applies
 //
 // When repo is not specified:
-//   - From repos where interval has passed AND ProcessStartAt is >10min ago
+//   - From non-archived repos where interval has passed AND ProcessStartAt is >10min ago
 //   - Select the one with the oldest LastSyncedAt
 func (h *JobHandler) pickSyncTarget(repos []*model.Repository, req jobSyncRequest) *model.Repository {
 	// Use request interval if specified, otherwise use config value
@@ -277,8 +410,12 @@ func (h *JobHandler) pickSyncTarget(repos []*model.Repository, req jobSyncReques
 				return nil
 			}
 
-			// Check ProcessStartAt if force=false
+			// Check archived and ProcessStartAt if force=false
 			if !req.Force {
+				if repo.Archived {
+					h.logger.Info("skipping repository: archived", "repository", repo.FullName)
+					return nil
+				}
 				if repo.ProcessStartAt != nil && now.Sub(*repo.ProcessStartAt) < processStartGuard {
 					h.logger.Info("skipping repository: process recently started",
 						"repository", repo.FullName,
@@ -307,6 +444,9 @@ func (h *JobHandler) pickSyncTarget(repos []*model.Repository, req jobSyncReques
 	})
 
 	for _, repo := range repos {
+		if repo.Archived {
+			continue
+		}
 		if repo.LastSyncedAt != nil && now.Sub(*repo.LastSyncedAt) < syncInterval {
 			continue
 		}
@@ -329,15 +469,48 @@ func matchRepoName(repo *model.Repository, name string) bool {
 	return repo.FullName == name || repo.Name == name
 }
 
+// syncCollectOptions resolves collect options for a sync request.
+// A positive maxPages overrides the range's default page limit.
+func syncCollectOptions(syncRange string, maxPages int, skipFileStats bool) *github.CollectOptions {
+	opts := github.CollectOptionsForRange(syncRange)
+	if maxPages > 0 {
+		opts.MaxPages = maxPages
+	}
+	opts.CollectFileStats = !skipFileStats
+	return opts
+}
+
+// parseCollectBackend validates a backend parameter ("rest", "graphql", or "search"). Returns the REST backend when not specified.
+func parseCollectBackend(raw string) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(raw)); backend {
+	case "", github.BackendREST:
+		return github.BackendREST, nil
+	case github.BackendGraphQL, github.BackendSearch:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("invalid backend %q (use rest, graphql, or search)", raw)
+	}
+}
+
+// parseTagPattern validates a tag_pattern glob such as "v*". Returns an empty pattern when not specified.
+func parseTagPattern(raw string) (string, error) {
+	pattern := strings.TrimSpace(raw)
+	if pattern == "" {
+		return "", nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid tag_pattern %q", pattern)
+	}
+	return pattern, nil
+}
+
 // syncSingleRepo executes sync for a single repository.
-func (h *JobHandler) syncSingleRepo(ctx context.Context, repo *model.Repository, syncRange string) RepoSyncResult {
+func (h *JobHandler) syncSingleRepo(ctx context.Context, repo *model.Repository, opts *github.CollectOptions) RepoSyncResult {
 	result := RepoSyncResult{
 		RepositoryID: repo.ID,
 		FullName:     repo.FullName,
 	}
 
-	opts := github.CollectOptionsForRange(syncRange)
-
 	// Collect data from GitHub
 	data, err := h.collector.CollectAll(ctx, repo.Owner, repo.Name, opts)
 	if err != nil {
@@ -346,13 +519,30 @@ func (h *JobHandler) syncSingleRepo(ctx context.Context, repo *model.Repository,
 			"error", err,
 		)
 		result.Error = err.Error()
+		recordSyncError(ctx, h.ds, h.logger, repo, err)
 		return result
 	}
+	if data.Partial {
+		h.logger.Warn("repository sync is partial",
+			"repository", repo.FullName,
+			"errors", data.Errors,
+		)
+		result.Partial = true
+		result.Errors = data.Errors
+		data.Repository.LastSyncError, data.Repository.LastSyncErrorAt = repo.LastSyncError, repo.LastSyncErrorAt
+	}
+	if !data.PullRequestsComplete() {
+		// Keep the previous sync time so the next run retries this repository
+		data.Repository.LastSyncedAt = repo.LastSyncedAt
+	}
 
 	// Save to Datastore
 	if err := h.ds.SaveRepository(ctx, data.Repository); err != nil {
 		h.logger.Error("failed to save repository", "error", err)
 	}
+	if !opts.CollectFileStats {
+		preserveFileExtStats(ctx, h.ds, h.logger, data.PullRequests)
+	}
 	if err := h.ds.SavePullRequests(ctx, data.PullRequests); err != nil {
 		h.logger.Error("failed to save pull requests", "error", err)
 	}
@@ -366,27 +556,20 @@ func (h *JobHandler) syncSingleRepo(ctx context.Context, repo *model.Repository,
 		h.logger.Error("failed to save team members", "error", err)
 	}
 
-	// Aggregate daily metrics
-	aggregator := metrics.NewAggregator()
-	endDate := timeutil.Now()
-	startDate := opts.Since
-	dailyMetrics := aggregator.AggregateRange(
-		repo.ID,
-		startDate,
-		endDate,
-		data.PullRequests,
-		data.Reviews,
-		data.Deployments,
-	)
+	// Recompute daily metrics for the synced window
+	dailyMetrics := recomputeDailyMetrics(ctx, h.ds, h.logger, h.minActivity, repo.ID, opts.Since, timeutil.Now(), data)
 	if err := h.ds.SaveDailyMetricsBatch(ctx, dailyMetrics); err != nil {
 		h.logger.Error("failed to save daily metrics", "error", err)
 	}
 
-	// Update LastSyncedAt
-	now := time.Now()
-	data.Repository.LastSyncedAt = &now
-	if err := h.ds.SaveRepository(ctx, data.Repository); err != nil {
-		h.logger.Error("failed to update last_synced_at", "error", err)
+	// Update LastSyncedAt once pull requests are fully collected; a failing later stage such as
+	// deployments must not keep the repository at the front of the sync queue
+	if data.PullRequestsComplete() {
+		now := time.Now()
+		data.Repository.LastSyncedAt = &now
+		if err := h.ds.SaveRepository(ctx, data.Repository); err != nil {
+			h.logger.Error("failed to update last_synced_at", "error", err)
+		}
 	}
 
 	result.Success = true
@@ -403,3 +586,32 @@ func (h *JobHandler) syncSingleRepo(ctx context.Context, repo *model.Repository,
 
 	return result
 }
+
+// recordSyncError persists a failed sync on the stored repository so it is visible via the API.
+func recordSyncError(ctx context.Context, ds collectedDataStore, logger *slog.Logger, repo *model.Repository, syncErr error) {
+	now := timeutil.Now()
+	repo.LastSyncError = syncErr.Error()
+	repo.LastSyncErrorAt = &now
+	if err := ds.SaveRepository(ctx, repo); err != nil {
+		logger.Error("failed to record sync error", "repository", repo.FullName, "error", err)
+	}
+}
+
+// preserveFileExtStats carries the stored file extension stats over to re-synced PRs collected
+// without file listing, so a skip_file_stats sync does not wipe the stats of an earlier full sync.
+func preserveFileExtStats(ctx context.Context, ds collectedDataStore, logger *slog.Logger, prs []*model.PullRequest) {
+	ids := make([]string, len(prs))
+	for i, pr := range prs {
+		ids[i] = pr.ID
+	}
+	stored, err := ds.GetPullRequests(ctx, ids)
+	if err != nil {
+		logger.Warn("failed to load stored file stats", "error", err)
+		return
+	}
+	for _, pr := range prs {
+		if prev, ok := stored[pr.ID]; ok && len(pr.FileExtStats) == 0 {
+			pr.FileExtStats = prev.FileExtStats
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/compasstechlab/dora-yaki/internal/config"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
 )
 
 // newTestJobHandler creates a JobHandler with the given sync interval for testing.
@@ -108,6 +116,34 @@ func TestPickSyncTarget(t *testing.T) {
 			interval: 30,
 			wantName: "org/repo-a",
 		},
+		{
+			name: "skip archived repository",
+			repos: []*model.Repository{
+				{FullName: "org/archived", Archived: true},
+				{FullName: "org/active", LastSyncedAt: &twoHoursAgo},
+			},
+			req:      jobSyncRequest{Range: "day"},
+			interval: 30,
+			wantName: "org/active",
+		},
+		{
+			name: "skip specified archived repo without force",
+			repos: []*model.Repository{
+				{FullName: "org/repo-a", Name: "repo-a", Archived: true, LastSyncedAt: &twoHoursAgo},
+			},
+			req:      jobSyncRequest{Range: "day", Repo: "org/repo-a"},
+			interval: 30,
+			wantNil:  true,
+		},
+		{
+			name: "force=true picks specified archived repo",
+			repos: []*model.Repository{
+				{FullName: "org/repo-a", Name: "repo-a", Archived: true, LastSyncedAt: &twoHoursAgo},
+			},
+			req:      jobSyncRequest{Range: "day", Repo: "org/repo-a", Force: true},
+			interval: 30,
+			wantName: "org/repo-a",
+		},
 		{
 			name:     "return nil when repos is empty",
 			repos:    []*model.Repository{},
@@ -138,3 +174,362 @@ func TestPickSyncTarget(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncCollectOptions_MaxPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		syncRange string
+		maxPages  int
+		want      int
+	}{
+		{"range default for day", "day", 0, 3},
+		{"range default for full", "full", 0, 10},
+		{"override replaces range default", "full", 200, 200},
+		{"override can lower the limit", "year", 2, 2},
+		{"negative override is ignored", "week", -1, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := syncCollectOptions(tt.syncRange, tt.maxPages, false)
+			if opts.MaxPages != tt.want {
+				t.Errorf("MaxPages = %d, want %d", opts.MaxPages, tt.want)
+			}
+			if !opts.CollectFileStats {
+				t.Error("CollectFileStats = false, want true")
+			}
+		})
+	}
+}
+
+func TestParseSyncRequest_MaxPages(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+	}{
+		{"unset", "/api/job/sync", "", 0},
+		{"query param", "/api/job/sync?max_pages=50", "", 50},
+		{"body overrides query", "/api/job/sync?max_pages=50", `{"max_pages":80}`, 80},
+		{"zero in body keeps query", "/api/job/sync?max_pages=50", `{"max_pages":0}`, 50},
+		{"invalid query ignored", "/api/job/sync?max_pages=abc", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", tt.target, strings.NewReader(tt.body))
+			if got := parseSyncRequest(r).MaxPages; got != tt.want {
+				t.Errorf("MaxPages = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubCollector records how many CollectAll calls run at once.
+type stubCollector struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       []string
+}
+
+func (c *stubCollector) CollectAll(_ context.Context, owner, repo string, _ *github.CollectOptions) (*github.CollectedData, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.calls = append(c.calls, owner+"/"+repo)
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	if repo == "broken" {
+		return nil, fmt.Errorf("rate limited")
+	}
+	return &github.CollectedData{Repository: &model.Repository{Owner: owner, Name: repo}}, nil
+}
+
+func (c *stubCollector) EstimatePullRequests(context.Context, string, string, *github.CollectOptions) (*github.SyncEstimate, error) {
+	return &github.SyncEstimate{}, nil
+}
+
+func (c *stubCollector) EnrichPullRequestTimes(context.Context, string, string, []*model.PullRequest, string) []*model.Review {
+	return nil
+}
+
+func TestSyncConcurrently(t *testing.T) {
+	var repos []*model.Repository
+	for _, name := range []string{"a", "b", "broken", "d", "e"} {
+		repos = append(repos, &model.Repository{ID: name, Owner: "org", Name: name, FullName: "org/" + name})
+	}
+
+	collector := &stubCollector{}
+	h := &JobHandler{collector: collector, logger: slog.Default()}
+	syncRepo := func(ctx context.Context, repo *model.Repository) RepoSyncResult {
+		result := RepoSyncResult{RepositoryID: repo.ID, FullName: repo.FullName}
+		if _, err := h.collector.CollectAll(ctx, repo.Owner, repo.Name, nil); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Success = true
+		return result
+	}
+
+	const workers = 2
+	results := syncConcurrently(context.Background(), repos, workers, syncRepo)
+
+	if len(results) != len(repos) || len(collector.calls) != len(repos) {
+		t.Fatalf("got %d results from %d calls, want %d", len(results), len(collector.calls), len(repos))
+	}
+	for i, result := range results {
+		if result.RepositoryID != repos[i].ID {
+			t.Errorf("results[%d] is for %s, want %s (input order)", i, result.RepositoryID, repos[i].ID)
+		}
+		if wantSuccess := repos[i].Name != "broken"; result.Success != wantSuccess {
+			t.Errorf("results[%d].Success = %v, want %v (error %q)", i, result.Success, wantSuccess, result.Error)
+		}
+	}
+	if collector.maxInFlight > workers {
+		t.Errorf("max concurrent syncs = %d, want at most %d", collector.maxInFlight, workers)
+	}
+	if collector.maxInFlight < 2 {
+		t.Errorf("max concurrent syncs = %d, want repositories synced in parallel", collector.maxInFlight)
+	}
+}
+
+func TestPickSyncTargets(t *testing.T) {
+	now := time.Now()
+	threeHoursAgo := now.Add(-3 * time.Hour)
+	twoHoursAgo := now.Add(-2 * time.Hour)
+
+	repos := func() []*model.Repository {
+		return []*model.Repository{
+			{FullName: "org/recent", Name: "recent", LastSyncedAt: &now},
+			{FullName: "org/older", Name: "older", LastSyncedAt: &twoHoursAgo},
+			{FullName: "org/oldest", Name: "oldest", LastSyncedAt: &threeHoursAgo},
+			{FullName: "org/never", Name: "never"},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		req   jobSyncRequest
+		limit int
+		want  []string
+	}{
+		{"single target", jobSyncRequest{}, 1, []string{"org/never"}},
+		{"oldest first up to limit", jobSyncRequest{}, 3, []string{"org/never", "org/oldest", "org/older"}},
+		{"limit above eligible count", jobSyncRequest{}, 10, []string{"org/never", "org/oldest", "org/older"}},
+		{"specified repo yields one target", jobSyncRequest{Repo: "org/older"}, 5, []string{"org/older"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestJobHandler(60)
+			var got []string
+			for _, repo := range h.pickSyncTargets(repos(), tt.req, tt.limit) {
+				got = append(got, repo.FullName)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyncRequest_Parallel(t *testing.T) {
+	tests := []struct {
+		target string
+		body   string
+		want   int
+	}{
+		{"/api/job/sync", "", 1},
+		{"/api/job/sync?parallel=4", "", 4},
+		{"/api/job/sync?parallel=4", `{"parallel":6}`, 6},
+		{"/api/job/sync?parallel=-3", "", 1},
+		{"/api/job/sync?parallel=100", "", maxSyncParallel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+tt.body, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", tt.target, strings.NewReader(tt.body))
+			if got := parseSyncRequest(r).Parallel; got != tt.want {
+				t.Errorf("Parallel = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeCollector returns fixed data for every repository.
+type fakeCollector struct {
+	data     *github.CollectedData
+	estimate *github.SyncEstimate
+	err      error
+
+	// enrich fills PR times in place for EnrichPullRequestTimes
+	enrich func(prs []*model.PullRequest) []*model.Review
+}
+
+func (c *fakeCollector) CollectAll(context.Context, string, string, *github.CollectOptions) (*github.CollectedData, error) {
+	return c.data, c.err
+}
+
+func (c *fakeCollector) EstimatePullRequests(context.Context, string, string, *github.CollectOptions) (*github.SyncEstimate, error) {
+	return c.estimate, c.err
+}
+
+func (c *fakeCollector) EnrichPullRequestTimes(_ context.Context, _, _ string, prs []*model.PullRequest, _ string) []*model.Review {
+	if c.enrich == nil {
+		return nil
+	}
+	return c.enrich(prs)
+}
+
+func TestSyncSingleRepo(t *testing.T) {
+	now := timeutil.Now()
+	previousSync := now.Add(-3 * time.Hour)
+	opened := now.Add(-2 * time.Hour)
+	merged := now.Add(-30 * time.Minute)
+
+	newData := func(repo *model.Repository) *github.CollectedData {
+		collected := *repo
+		return &github.CollectedData{
+			Repository: &collected,
+			PullRequests: []*model.PullRequest{
+				{ID: "repo-1#2", RepositoryID: repo.ID, State: "closed", CreatedAt: opened, UpdatedAt: merged, MergedAt: &merged, ClosedAt: &merged},
+			},
+			Reviews: []*model.Review{
+				{ID: "rv-1", RepositoryID: repo.ID, PullRequestID: "repo-1#2", Reviewer: "carol", SubmittedAt: merged},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		failedStage  string
+		wantSyncTime bool
+	}{
+		{"complete sync updates last synced time", "", true},
+		{"pull request failure keeps previous sync time", github.StagePullRequests, false},
+		{"deployment failure still updates last synced time", github.StageDeployments, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app", LastSyncedAt: &previousSync}
+			store := newMemStore()
+			// Opened before this sync and still open; only the stored copy knows about it
+			store.pullRequests["repo-1#1"] = &model.PullRequest{ID: "repo-1#1", RepositoryID: repo.ID, State: "open", CreatedAt: opened, UpdatedAt: opened}
+
+			data := newData(repo)
+			partial := tt.failedStage != ""
+			if partial {
+				data.Partial = true
+				data.Errors = []string{tt.failedStage + ": rate limited"}
+				data.FailedStages = []string{tt.failedStage}
+			}
+			h := &JobHandler{ds: store, collector: &fakeCollector{data: data}, logger: slog.Default()}
+
+			result := h.syncSingleRepo(context.Background(), repo, syncCollectOptions("day", 0, false))
+
+			if !result.Success || result.Partial != partial || result.PullRequests != 1 || result.Reviews != 1 {
+				t.Fatalf("result = %+v", result)
+			}
+			if len(store.pullRequests) != 2 || len(store.reviews) != 1 {
+				t.Errorf("stored %d PRs and %d reviews, want 2 and 1", len(store.pullRequests), len(store.reviews))
+			}
+
+			var prsOpened, prsMerged, reviews int
+			for _, m := range store.dailyMetrics {
+				prsOpened += m.PRsOpened
+				prsMerged += m.PRsMerged
+				reviews += m.ReviewsSubmitted
+			}
+			if prsOpened != 2 || prsMerged != 1 || reviews != 1 {
+				t.Errorf("daily metrics = opened %d, merged %d, reviews %d; want 2, 1, 1", prsOpened, prsMerged, reviews)
+			}
+
+			saved := store.repos[repo.ID]
+			if saved == nil || saved.LastSyncedAt == nil {
+				t.Fatalf("saved repository = %+v, want LastSyncedAt set", saved)
+			}
+			if updated := saved.LastSyncedAt.After(previousSync); updated != tt.wantSyncTime {
+				t.Errorf("LastSyncedAt = %v (previous %v), want updated %v", saved.LastSyncedAt, previousSync, tt.wantSyncTime)
+			}
+		})
+	}
+}
+
+func TestSyncSingleRepo_CollectError(t *testing.T) {
+	store := newMemStore()
+	h := &JobHandler{ds: store, collector: &fakeCollector{err: fmt.Errorf("repository not found")}, logger: slog.Default()}
+
+	result := h.syncSingleRepo(context.Background(), &model.Repository{ID: "repo-1", FullName: "org/app"}, syncCollectOptions("day", 0, false))
+
+	if result.Success || result.Error != "repository not found" {
+		t.Errorf("result = %+v, want failure with collector error", result)
+	}
+	if len(store.dailyMetrics) != 0 {
+		t.Errorf("stored %d daily metrics after a failed collect, want none", len(store.dailyMetrics))
+	}
+}
+
+func TestSyncSingleRepo_RecordsAndClearsSyncError(t *testing.T) {
+	store := newMemStore()
+	repo := &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app"}
+	collector := &fakeCollector{err: fmt.Errorf("rate limited")}
+	h := &JobHandler{ds: store, collector: collector, logger: slog.Default()}
+
+	h.syncSingleRepo(context.Background(), repo, syncCollectOptions("day", 0, false))
+
+	saved := store.repos[repo.ID]
+	if saved == nil || saved.LastSyncError != "rate limited" || saved.LastSyncErrorAt == nil {
+		t.Fatalf("saved repository = %+v, want sync error recorded", saved)
+	}
+
+	collected := *saved
+	collected.LastSyncError, collected.LastSyncErrorAt = "", nil
+	collector.data, collector.err = &github.CollectedData{Repository: &collected}, nil
+
+	result := h.syncSingleRepo(context.Background(), saved, syncCollectOptions("day", 0, false))
+	if !result.Success {
+		t.Fatalf("result = %+v, want success", result)
+	}
+	if saved := store.repos[repo.ID]; saved.LastSyncError != "" || saved.LastSyncErrorAt != nil {
+		t.Errorf("sync error = %q at %v, want cleared", saved.LastSyncError, saved.LastSyncErrorAt)
+	}
+}
+
+func TestSyncSingleRepo_SkipFileStatsKeepsStoredStats(t *testing.T) {
+	now := timeutil.Now()
+	repo := &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app"}
+	stats := []model.FileExtStats{{Extension: ".go", Language: "Go", Files: 2, Additions: 40, Deletions: 5}}
+
+	store := newMemStore()
+	store.pullRequests["repo-1#1"] = &model.PullRequest{ID: "repo-1#1", RepositoryID: repo.ID, State: "open", CreatedAt: now, FileExtStats: stats}
+
+	collected := *repo
+	data := &github.CollectedData{
+		Repository: &collected,
+		PullRequests: []*model.PullRequest{
+			{ID: "repo-1#1", RepositoryID: repo.ID, State: "open", CreatedAt: now, Title: "retitled"},
+			{ID: "repo-1#2", RepositoryID: repo.ID, State: "open", CreatedAt: now},
+		},
+	}
+	h := &JobHandler{ds: store, collector: &fakeCollector{data: data}, logger: slog.Default()}
+
+	if result := h.syncSingleRepo(context.Background(), repo, syncCollectOptions("day", 0, true)); !result.Success {
+		t.Fatalf("result = %+v, want success", result)
+	}
+
+	if got := store.pullRequests["repo-1#1"]; got.Title != "retitled" || !slices.Equal(got.FileExtStats, stats) {
+		t.Errorf("repo-1#1 = title %q, file stats %+v; want the new title and the stored stats", got.Title, got.FileExtStats)
+	}
+	if got := store.pullRequests["repo-1#2"]; len(got.FileExtStats) != 0 {
+		t.Errorf("repo-1#2 file stats = %+v, want none", got.FileExtStats)
+	}
+}
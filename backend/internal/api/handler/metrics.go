@@ -2,12 +2,21 @@ package handler
 
 import (
 	"context"
+	"encoding/csv"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"path"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/config"
 	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 	"github.com/compasstechlab/dora-yaki/internal/metrics"
@@ -16,20 +25,90 @@ import (
 
 // MetricsHandler handles metrics-related API requests
 type MetricsHandler struct {
-	ds         *datastore.Client
-	calculator *metrics.Calculator
-	logger     *slog.Logger
+	ds       MetricsStore
+	logger   *slog.Logger
+	settings *SettingsCache
+
+	mu      sync.Mutex
+	derived *metricsConfig
+}
+
+// metricsConfig holds what MetricsHandler derives from the effective config.
+type metricsConfig struct {
+	source            *config.Config
+	calculator        *metrics.Calculator
+	shippableBranches []string
+	reviewSLAHours    float64
 }
 
 // NewMetricsHandler creates a new MetricsHandler
-func NewMetricsHandler(ds *datastore.Client, logger *slog.Logger) *MetricsHandler {
+func NewMetricsHandler(ds MetricsStore, logger *slog.Logger, cfg *config.Config) *MetricsHandler {
 	return &MetricsHandler{
-		ds:         ds,
-		calculator: metrics.NewCalculator(),
-		logger:     logger,
+		ds:       ds,
+		logger:   logger,
+		settings: NewSettingsCache(ds, cfg, logger),
 	}
 }
 
+// WithSettings makes the handler read its configuration from a shared settings cache,
+// so settings updates invalidate it.
+func (h *MetricsHandler) WithSettings(settings *SettingsCache) *MetricsHandler {
+	h.settings = settings
+	return h
+}
+
+// metricsConfig returns the calculator and options for the effective config,
+// rebuilding them when the settings change.
+func (h *MetricsHandler) metricsConfig(ctx context.Context) *metricsConfig {
+	cfg := h.settings.Config(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.derived != nil && h.derived.source == cfg {
+		return h.derived
+	}
+
+	weights := metrics.ScoreWeights{
+		CycleTime:  cfg.ScoreWeightCycleTime,
+		Review:     cfg.ScoreWeightReview,
+		Deployment: cfg.ScoreWeightDeployment,
+		Quality:    cfg.ScoreWeightQuality,
+	}
+	if err := weights.Validate(); err != nil {
+		h.logger.Warn("invalid productivity score weights, using defaults", "error", err)
+	}
+	thresholds := metrics.ScoreThresholds{
+		CycleTimeHours:    cfg.ScoreCycleTimeHours,
+		FirstReviewHours:  cfg.ScoreFirstReviewHours,
+		ReviewsPerPR:      cfg.ScoreReviewsPerPR,
+		ChangeFailureRate: cfg.ScoreChangeFailureRate,
+	}
+	if err := thresholds.Validate(); err != nil {
+		h.logger.Warn("invalid productivity score thresholds, using defaults", "error", err)
+	}
+
+	h.derived = &metricsConfig{
+		source: cfg,
+		calculator: metrics.NewCalculatorWithOptions(metrics.CalculatorOptions{
+			Weights:       weights,
+			Thresholds:    thresholds,
+			FailureLabels: cfg.FailureLabels,
+		}),
+		shippableBranches: cfg.ShippableBranches,
+		reviewSLAHours:    cfg.ReviewSLAHours,
+	}
+	return h.derived
+}
+
+// reviewOptionsFor parses the review options, defaulting the SLA to the configured one.
+func (h *MetricsHandler) reviewOptionsFor(r *http.Request) (metrics.ReviewOptions, error) {
+	opts, err := parseReviewOptions(r)
+	if err == nil && opts.SLAHours == 0 {
+		opts.SLAHours = h.metricsConfig(r.Context()).reviewSLAHours
+	}
+	return opts, err
+}
+
 // botFilter holds bot filtering settings.
 type botFilter struct {
 	excludeBots bool
@@ -49,6 +128,192 @@ func parseBotFilter(r *http.Request) botFilter {
 	return botFilter{excludeBots: excludeBots, botsOnly: false}
 }
 
+// parseReviewOptions parses review calculation options from query parameters.
+// review_sla_hours must be a positive number when specified.
+func parseReviewOptions(r *http.Request) (metrics.ReviewOptions, error) {
+	q := r.URL.Query()
+	opts := metrics.ReviewOptions{
+		IncludeSelfReviews: q.Get("include_self_reviews") == "true",
+	}
+	if raw := q.Get("review_sla_hours"); raw != "" {
+		hours, err := strconv.ParseFloat(raw, 64)
+		if err != nil || hours <= 0 {
+			return opts, fmt.Errorf("invalid review_sla_hours %q", raw)
+		}
+		opts.SLAHours = hours
+	}
+	return opts, nil
+}
+
+// prFilter holds request-scoped pull request filters.
+type prFilter struct {
+	bf           botFilter
+	botUsernames []string
+	// Base branches to restrict to (glob patterns); nil means all branches
+	baseBranches []string
+	labels       labelFilter
+	// include_drafts=false drops PRs that were mostly drafts
+	excludeDrafts bool
+}
+
+// labelFilter holds the label and exclude_label query parameters.
+type labelFilter struct {
+	include  []string
+	exclude  []string
+	matchAll bool // label_match=all requires every include label
+}
+
+// newPRFilter builds the pull request filter for the request.
+func (h *MetricsHandler) newPRFilter(r *http.Request) prFilter {
+	return prFilter{
+		bf:            parseBotFilter(r),
+		botUsernames:  h.getBotUsernames(r.Context()),
+		baseBranches:  parseShippableOnly(r, h.metricsConfig(r.Context()).shippableBranches),
+		labels:        parseLabelFilter(r),
+		excludeDrafts: r.URL.Query().Get("include_drafts") == "false",
+	}
+}
+
+// parseLabelFilter parses the label and exclude_label query parameters.
+// Both may be repeated or comma-separated. Include labels match any by default,
+// or all with label_match=all; a PR with any exclude label is dropped.
+func parseLabelFilter(r *http.Request) labelFilter {
+	q := r.URL.Query()
+	return labelFilter{
+		include:  splitQueryValues(q["label"]),
+		exclude:  splitQueryValues(q["exclude_label"]),
+		matchAll: q.Get("label_match") == "all",
+	}
+}
+
+// splitQueryValues flattens repeated, comma-separated query values, dropping blanks.
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// parseShippableOnly returns the shippable branches when shippable_only=true, or nil otherwise.
+func parseShippableOnly(r *http.Request, shippableBranches []string) []string {
+	if r.URL.Query().Get("shippable_only") != "true" {
+		return nil
+	}
+	return shippableBranches
+}
+
+// apply applies bot, label, draft, and base branch filtering to pull requests.
+func (f prFilter) apply(prs []*model.PullRequest) []*model.PullRequest {
+	prs = model.FilterPullRequestsByBot(prs, f.botUsernames, f.bf.excludeBots, f.bf.botsOnly)
+	prs = model.FilterPullRequestsByLabel(prs, f.labels.include, f.labels.exclude, f.labels.matchAll)
+	prs = model.FilterPullRequestsByDraft(prs, !f.excludeDrafts)
+	if f.baseBranches != nil {
+		prs = filterPullRequestsByBaseRef(prs, f.baseBranches)
+	}
+	return prs
+}
+
+// filterPullRequestsByBaseRef returns PRs whose base branch matches one of the glob patterns.
+// PRs synced before BaseRef was stored have an empty base branch and never match.
+func filterPullRequestsByBaseRef(prs []*model.PullRequest, patterns []string) []*model.PullRequest {
+	result := make([]*model.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		if pr.BaseRef == "" {
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, pr.BaseRef); ok {
+				result = append(result, pr)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// calculatorFor returns the calculator for the request.
+// business_hours=true measures durations in business hours (9:00-18:00, Mon-Fri).
+func (h *MetricsHandler) calculatorFor(r *http.Request) *metrics.Calculator {
+	calc := h.metricsConfig(r.Context()).calculator
+	if r.URL.Query().Get("business_hours") == "true" {
+		return calc.WithBusinessHours(metrics.DefaultBusinessHours())
+	}
+	return calc
+}
+
+// parsePercentiles parses the comma-separated "percentiles" query parameter.
+// Each value must be in the range (0, 100].
+func parsePercentiles(r *http.Request) ([]float64, error) {
+	raw := r.URL.Query().Get("percentiles")
+	if raw == "" {
+		return nil, nil
+	}
+	var result []float64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q", part)
+		}
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("percentile %q out of range (0, 100]", part)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// parseRefPattern parses the "ref_pattern" query parameter, a glob such as "release/api-*".
+// Returns an empty pattern when not specified.
+func parseRefPattern(r *http.Request) (string, error) {
+	pattern := strings.TrimSpace(r.URL.Query().Get("ref_pattern"))
+	if pattern == "" {
+		return "", nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid ref_pattern %q", pattern)
+	}
+	return pattern, nil
+}
+
+// filterDeploymentsByRef returns deployments whose ref matches the glob pattern.
+// The pattern must have been validated with parseRefPattern.
+func filterDeploymentsByRef(deployments []*model.Deployment, pattern string) []*model.Deployment {
+	if pattern == "" {
+		return deployments
+	}
+	result := make([]*model.Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		if ok, _ := path.Match(pattern, d.Ref); ok {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// filterDeploymentsByEnvironment returns deployments to the given environment.
+// Returns all deployments when environment is empty.
+func filterDeploymentsByEnvironment(deployments []*model.Deployment, environment string) []*model.Deployment {
+	if environment == "" {
+		return deployments
+	}
+	result := make([]*model.Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		if d.Environment == environment {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
 // getBotUsernames retrieves custom bot username list from Datastore.
 func (h *MetricsHandler) getBotUsernames(ctx context.Context) []string {
 	usernames, err := h.ds.ListBotUsernames(ctx)
@@ -59,7 +324,8 @@ func (h *MetricsHandler) getBotUsernames(ctx context.Context) []string {
 	return usernames
 }
 
-// parseDateRange parses date range from query params
+// parseDateRange parses date range from query params.
+// start and end take YYYY-MM-DD or a relative date such as "today" or "-30d".
 func parseDateRange(r *http.Request) (time.Time, time.Time) {
 	startStr := r.URL.Query().Get("start")
 	endStr := r.URL.Query().Get("end")
@@ -68,13 +334,13 @@ func parseDateRange(r *http.Request) (time.Time, time.Time) {
 	startDate := endDate.AddDate(0, -1, 0) // Default: last month
 
 	if startStr != "" {
-		if t, err := timeutil.ParseDate(startStr); err == nil {
+		if t, err := parseDateParam(startStr, endDate); err == nil {
 			startDate = t
 		}
 	}
 
 	if endStr != "" {
-		if t, err := timeutil.ParseDate(endStr); err == nil {
+		if t, err := parseDateParam(endStr, endDate); err == nil {
 			// Set to end of day so that records created on endDate are included
 			endDate = t.Add(24*time.Hour - time.Second)
 		}
@@ -83,67 +349,127 @@ func parseDateRange(r *http.Request) (time.Time, time.Time) {
 	return startDate, endDate
 }
 
+// parseDateParam parses a YYYY-MM-DD date or a date relative to now.
+func parseDateParam(s string, now time.Time) (time.Time, error) {
+	if t, err := timeutil.ParseDate(s); err == nil {
+		return t, nil
+	}
+	return timeutil.ParseRelativeDate(s, now)
+}
+
+// setDateRangeHeaders echoes the resolved date range of a list response in headers,
+// as list bodies are bare arrays with no room for the startDate/endDate object responses carry.
+func setDateRangeHeaders(w http.ResponseWriter, startDate, endDate time.Time) {
+	w.Header().Set(middleware.DateRangeStartHeader, startDate.Format(time.RFC3339Nano))
+	w.Header().Set(middleware.DateRangeEndHeader, endDate.Format(time.RFC3339Nano))
+	w.Header().Set(middleware.DateRangeTimezoneHeader, startDate.Location().String())
+}
+
 // getRepositoryIDs retrieves multiple repository IDs. Returns all repositories if empty.
+// One or more ?owner= values restrict the result to repositories of those owners,
+// intersected with any explicit ?repository= values.
 func (h *MetricsHandler) getRepositoryIDs(r *http.Request) ([]string, error) {
 	ids := r.URL.Query()["repository"]
+	if owners := r.URL.Query()["owner"]; len(owners) > 0 {
+		return h.ownerRepositoryIDs(r.Context(), owners, ids)
+	}
 	if len(ids) > 0 {
 		return ids, nil
 	}
 	// Return all registered repositories if not specified
-	repos, err := h.ds.ListRepositories(r.Context())
+	all, err := h.ds.ListRepositoryIDs(r.Context())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
-	all := make([]string, len(repos))
-	for i, repo := range repos {
-		all[i] = repo.ID
-	}
 	return all, nil
 }
 
-// collectPullRequests collects and merges PRs from multiple repositories.
-func (h *MetricsHandler) collectPullRequests(ctx context.Context, repoIDs []string, start, end time.Time) ([]*model.PullRequest, error) {
-	var result []*model.PullRequest
-	for _, id := range repoIDs {
-		prs, err := h.ds.ListPullRequestsByDateRange(ctx, id, start, end)
+// ownerRepositoryIDs returns the IDs of the stored repositories of the owners,
+// limited to only when it is non-empty.
+func (h *MetricsHandler) ownerRepositoryIDs(ctx context.Context, owners, only []string) ([]string, error) {
+	var ids []string
+	for _, owner := range owners {
+		repos, err := h.ds.ListRepositoriesByOwner(ctx, owner)
 		if err != nil {
-			h.logger.Warn("failed to list pull requests for repo", "repository", id, "error", err)
-			continue
+			return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+		}
+		for _, repo := range repos {
+			if len(only) > 0 && !slices.Contains(only, repo.ID) {
+				continue
+			}
+			if !slices.Contains(ids, repo.ID) {
+				ids = append(ids, repo.ID)
+			}
 		}
-		result = append(result, prs...)
 	}
-	return result, nil
+	return ids, nil
+}
+
+// maxCollectWorkers caps the per-repository Datastore queries in flight for one request.
+const maxCollectWorkers = 8
+
+// collectPerRepository runs list for each repository with at most maxCollectWorkers in flight
+// and merges the results in the order of repoIDs. A repository whose query fails is logged
+// and skipped, so the others are still returned.
+func collectPerRepository[T any](ctx context.Context, logger *slog.Logger, repoIDs []string, what string, list func(ctx context.Context, repositoryID string) ([]T, error)) []T {
+	perRepo := make([][]T, len(repoIDs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(maxCollectWorkers, len(repoIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				items, err := list(ctx, repoIDs[i])
+				if err != nil {
+					logger.Warn("failed to list "+what+" for repo", "repository", repoIDs[i], "error", err)
+					continue
+				}
+				perRepo[i] = items
+			}
+		}()
+	}
+	for i := range repoIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var result []T
+	for _, items := range perRepo {
+		result = append(result, items...)
+	}
+	return result
+}
+
+// collectPullRequests collects and merges PRs from multiple repositories.
+func (h *MetricsHandler) collectPullRequests(ctx context.Context, repoIDs []string, start, end time.Time) ([]*model.PullRequest, error) {
+	return collectPerRepository(ctx, h.logger, repoIDs, "pull requests", func(ctx context.Context, id string) ([]*model.PullRequest, error) {
+		return h.ds.ListPullRequestsByDateRange(ctx, id, start, end)
+	}), nil
+}
+
+// collectOpenPullRequests collects currently open PRs from multiple repositories.
+func (h *MetricsHandler) collectOpenPullRequests(ctx context.Context, repoIDs []string) []*model.PullRequest {
+	return collectPerRepository(ctx, h.logger, repoIDs, "open pull requests", h.ds.ListOpenPullRequests)
 }
 
 // collectReviews collects and merges reviews from multiple repositories.
 func (h *MetricsHandler) collectReviews(ctx context.Context, repoIDs []string, start, end time.Time) ([]*model.Review, error) {
-	var result []*model.Review
-	for _, id := range repoIDs {
-		reviews, err := h.ds.ListReviewsByDateRange(ctx, id, start, end)
-		if err != nil {
-			h.logger.Warn("failed to list reviews for repo", "repository", id, "error", err)
-			continue
-		}
-		result = append(result, reviews...)
-	}
-	return result, nil
+	return collectPerRepository(ctx, h.logger, repoIDs, "reviews", func(ctx context.Context, id string) ([]*model.Review, error) {
+		return h.ds.ListReviewsByDateRange(ctx, id, start, end)
+	}), nil
 }
 
 // collectDeployments collects and merges deployments from multiple repositories.
 func (h *MetricsHandler) collectDeployments(ctx context.Context, repoIDs []string, start, end time.Time) ([]*model.Deployment, error) {
-	var result []*model.Deployment
-	for _, id := range repoIDs {
-		deployments, err := h.ds.ListDeployments(ctx, id, &datastore.QueryOptions{
+	return collectPerRepository(ctx, h.logger, repoIDs, "deployments", func(ctx context.Context, id string) ([]*model.Deployment, error) {
+		return h.ds.ListDeployments(ctx, id, &datastore.QueryOptions{
 			Since: start,
 			Until: end,
 		})
-		if err != nil {
-			h.logger.Warn("failed to list deployments for repo", "repository", id, "error", err)
-			continue
-		}
-		result = append(result, deployments...)
-	}
-	return result, nil
+	}), nil
 }
 
 // collectDailyMetrics collects daily metrics from multiple repositories and aggregates by date.
@@ -167,9 +493,9 @@ func (h *MetricsHandler) collectDailyMetrics(ctx context.Context, repoIDs []stri
 				grouped[dateKey] = &copied
 				continue
 			}
-			// Cycle time: weighted average based on PRsMerged
-			prevMerged := agg.PRsMerged
-			newMerged := dm.PRsMerged
+			// Cycle time: weighted average based on the merged PRs each average covers
+			prevMerged := agg.AveragedPRs()
+			newMerged := dm.AveragedPRs()
 			totalMerged := prevMerged + newMerged
 			if totalMerged > 0 {
 				agg.AvgCycleTime = weightedAvg(agg.AvgCycleTime, prevMerged, dm.AvgCycleTime, newMerged)
@@ -182,7 +508,9 @@ func (h *MetricsHandler) collectDailyMetrics(ctx context.Context, repoIDs []stri
 			// Counts: sum up
 			agg.PRsOpened += dm.PRsOpened
 			agg.PRsMerged += dm.PRsMerged
+			agg.BotPRsMerged += dm.BotPRsMerged
 			agg.PRsClosed += dm.PRsClosed
+			agg.OpenPRCount += dm.OpenPRCount
 			agg.ReviewsSubmitted += dm.ReviewsSubmitted
 			agg.TotalAdditions += dm.TotalAdditions
 			agg.TotalDeletions += dm.TotalDeletions
@@ -217,32 +545,87 @@ func weightedAvg(val1 float64, weight1 int, val2 float64, weight2 int) float64 {
 	return (val1*float64(weight1) + val2*float64(weight2)) / float64(total)
 }
 
-// CycleTime returns cycle time metrics
+// CycleTime returns cycle time metrics.
+// ?min_prs=N (default 1) drops authors with fewer merged PRs from byAuthor; their PRs
+// still count in the totals.
 func (h *MetricsHandler) CycleTime(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	startDate, endDate := parseDateRange(r)
-	bf := parseBotFilter(r)
+
+	minPRs := 1
+	if raw := r.URL.Query().Get("min_prs"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "min_prs must be a positive integer")
+			return
+		}
+		minPRs = n
+	}
 
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
-	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	filter := h.newPRFilter(r)
+	calc := h.calculatorFor(r)
+	cycleTimeMetrics, err := h.cycleTimeMetrics(ctx, calc, repoIDs, startDate, endDate, filter)
 	if err != nil {
 		h.logger.Error("failed to collect pull requests", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
+	cycleTimeMetrics.ByAuthor = filterAuthorsByMinPRs(cycleTimeMetrics.ByAuthor, minPRs)
 
-	// Apply bot filtering
-	botUsernames := h.getBotUsernames(ctx)
-	prs = model.FilterPullRequestsByBot(prs, botUsernames, bf.excludeBots, bf.botsOnly)
+	if !compareWithPrevious(r) {
+		respondJSON(w, http.StatusOK, cycleTimeMetrics)
+		return
+	}
+
+	prevStart, prevEnd := previousPeriod(startDate, endDate)
+	previous, err := h.cycleTimeMetrics(ctx, calc, repoIDs, prevStart, prevEnd, filter)
+	if err != nil {
+		h.logger.Error("failed to collect pull requests for previous period", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+	previous.ByAuthor = filterAuthorsByMinPRs(previous.ByAuthor, minPRs)
+
+	respondJSON(w, http.StatusOK, Comparison{
+		Current:  cycleTimeMetrics,
+		Previous: previous,
+		Delta:    cycleTimeDelta(cycleTimeMetrics, previous),
+	})
+}
+
+// filterAuthorsByMinPRs drops authors with fewer than minPRs merged PRs.
+func filterAuthorsByMinPRs(authors []model.AuthorMetrics, minPRs int) []model.AuthorMetrics {
+	if minPRs <= 1 {
+		return authors
+	}
+	result := make([]model.AuthorMetrics, 0, len(authors))
+	for _, a := range authors {
+		if a.PRCount >= minPRs {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// cycleTimeMetrics collects PRs and daily metrics for the period and calculates cycle time metrics.
+func (h *MetricsHandler) cycleTimeMetrics(ctx context.Context, calc *metrics.Calculator, repoIDs []string, startDate, endDate time.Time, filter prFilter) (*model.CycleTimeMetrics, error) {
+	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply bot and base branch filtering
+	prs = filter.apply(prs)
 
 	// Calculate cycle time metrics
-	cycleTimeMetrics := h.calculator.CalculateCycleTime(prs, startDate, endDate)
+	cycleTimeMetrics := calc.CalculateCycleTime(prs, startDate, endDate)
 
 	// Get daily breakdown
 	dailyMetrics, err := h.collectDailyMetrics(ctx, repoIDs, startDate, endDate)
@@ -255,7 +638,7 @@ func (h *MetricsHandler) CycleTime(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	respondJSON(w, http.StatusOK, cycleTimeMetrics)
+	return cycleTimeMetrics, nil
 }
 
 // Reviews returns review analysis metrics
@@ -264,24 +647,30 @@ func (h *MetricsHandler) Reviews(w http.ResponseWriter, r *http.Request) {
 	startDate, endDate := parseDateRange(r)
 	bf := parseBotFilter(r)
 
+	reviewOpts, err := h.reviewOptionsFor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
 	reviews, err := h.collectReviews(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect reviews", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
 	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect pull requests", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
@@ -290,43 +679,170 @@ func (h *MetricsHandler) Reviews(w http.ResponseWriter, r *http.Request) {
 	reviews = model.FilterReviewsByBot(reviews, botUsernames, bf.excludeBots, bf.botsOnly)
 	prs = model.FilterPullRequestsByBot(prs, botUsernames, bf.excludeBots, bf.botsOnly)
 
-	reviewMetrics := h.calculator.CalculateReviewMetrics(reviews, prs, startDate, endDate)
+	reviewMetrics := h.calculatorFor(r).CalculateReviewMetricsWithOptions(reviews, prs, startDate, endDate, reviewOpts)
 	respondJSON(w, http.StatusOK, reviewMetrics)
 }
 
-// DORA returns DORA metrics
-func (h *MetricsHandler) DORA(w http.ResponseWriter, r *http.Request) {
+// ReviewCoverage returns, per author, how many PRs merged in the period got at least one peer review.
+func (h *MetricsHandler) ReviewCoverage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	startDate, endDate := parseDateRange(r)
-	bf := parseBotFilter(r)
+	filter := h.newPRFilter(r)
 
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
 	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect pull requests", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
+	prs = filter.apply(prs)
 
-	deployments, err := h.collectDeployments(ctx, repoIDs, startDate, endDate)
+	// Reviews may predate the period for PRs opened before it
+	reviewsSince := startDate
+	for _, pr := range prs {
+		if pr.CreatedAt.Before(reviewsSince) {
+			reviewsSince = pr.CreatedAt
+		}
+	}
+	reviews, err := h.collectReviews(ctx, repoIDs, reviewsSince, endDate)
 	if err != nil {
-		h.logger.Error("failed to collect deployments", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		h.logger.Error("failed to collect reviews", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
+	// Bot reviews do not count as peer reviews unless bots are included
+	reviews = model.FilterReviewsByBot(reviews, filter.botUsernames, filter.bf.excludeBots, false)
 
-	// Apply bot filtering
+	respondJSON(w, http.StatusOK, h.metricsConfig(ctx).calculator.CalculateReviewCoverage(prs, reviews, startDate, endDate))
+}
+
+// Collaboration returns the review collaboration graph: review counts per (author, reviewer) pair.
+func (h *MetricsHandler) Collaboration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	reviews, err := h.collectReviews(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect reviews", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect pull requests", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	// Bots are always excluded, as authors and as reviewers
 	botUsernames := h.getBotUsernames(ctx)
-	prs = model.FilterPullRequestsByBot(prs, botUsernames, bf.excludeBots, bf.botsOnly)
+	reviews = model.FilterReviewsByBot(reviews, botUsernames, true, false)
+	prs = model.FilterPullRequestsByBot(prs, botUsernames, true, false)
+
+	respondJSON(w, http.StatusOK, h.metricsConfig(ctx).calculator.CalculateCollaboration(prs, reviews, startDate, endDate))
+}
+
+// DORA returns DORA metrics
+func (h *MetricsHandler) DORA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	percentiles, err := parsePercentiles(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	refPattern, err := parseRefPattern(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	q := doraQuery{
+		calc:        h.calculatorFor(r),
+		prFilter:    h.newPRFilter(r),
+		refPattern:  refPattern,
+		environment: r.URL.Query().Get("environment"),
+		opts:        metrics.DORAOptions{Percentiles: percentiles},
+	}
+
+	doraMetrics, err := h.doraMetrics(ctx, repoIDs, startDate, endDate, q)
+	if err != nil {
+		h.logger.Error("failed to collect DORA data", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	if !compareWithPrevious(r) {
+		respondJSON(w, http.StatusOK, doraMetrics)
+		return
+	}
+
+	prevStart, prevEnd := previousPeriod(startDate, endDate)
+	previous, err := h.doraMetrics(ctx, repoIDs, prevStart, prevEnd, q)
+	if err != nil {
+		h.logger.Error("failed to collect DORA data for previous period", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
 
-	doraMetrics := h.calculator.CalculateDORAMetrics(prs, deployments, startDate, endDate)
-	respondJSON(w, http.StatusOK, doraMetrics)
+	respondJSON(w, http.StatusOK, Comparison{
+		Current:  doraMetrics,
+		Previous: previous,
+		Delta:    doraDelta(doraMetrics, previous),
+	})
+}
+
+// doraQuery holds the request-scoped filters and options for DORA calculation.
+type doraQuery struct {
+	calc        *metrics.Calculator
+	prFilter    prFilter
+	refPattern  string
+	environment string
+	opts        metrics.DORAOptions
+}
+
+// doraMetrics collects PRs and deployments for the period and calculates DORA metrics.
+func (h *MetricsHandler) doraMetrics(ctx context.Context, repoIDs []string, startDate, endDate time.Time, q doraQuery) (*model.DORAMetrics, error) {
+	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect pull requests: %w", err)
+	}
+
+	deployments, err := h.collectDeployments(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect deployments: %w", err)
+	}
+	deployments = filterDeploymentsByRef(deployments, q.refPattern)
+	deployments = filterDeploymentsByEnvironment(deployments, q.environment)
+
+	// Apply bot and base branch filtering
+	prs = q.prFilter.apply(prs)
+
+	return q.calc.CalculateDORAMetricsWithOptions(prs, deployments, startDate, endDate, q.opts), nil
 }
 
 // ProductivityScore returns the productivity score
@@ -335,31 +851,37 @@ func (h *MetricsHandler) ProductivityScore(w http.ResponseWriter, r *http.Reques
 	startDate, endDate := parseDateRange(r)
 	bf := parseBotFilter(r)
 
+	reviewOpts, err := h.reviewOptionsFor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
 	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect pull requests", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
 	reviews, err := h.collectReviews(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect reviews", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
 	deployments, err := h.collectDeployments(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect deployments", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
@@ -368,11 +890,12 @@ func (h *MetricsHandler) ProductivityScore(w http.ResponseWriter, r *http.Reques
 	prs = model.FilterPullRequestsByBot(prs, botUsernames, bf.excludeBots, bf.botsOnly)
 	reviews = model.FilterReviewsByBot(reviews, botUsernames, bf.excludeBots, bf.botsOnly)
 
-	cycleTime := h.calculator.CalculateCycleTime(prs, startDate, endDate)
-	reviewMetrics := h.calculator.CalculateReviewMetrics(reviews, prs, startDate, endDate)
-	doraMetrics := h.calculator.CalculateDORAMetrics(prs, deployments, startDate, endDate)
+	calc := h.metricsConfig(ctx).calculator
+	cycleTime := calc.CalculateCycleTime(prs, startDate, endDate)
+	reviewMetrics := calc.CalculateReviewMetricsWithOptions(reviews, prs, startDate, endDate, reviewOpts)
+	doraMetrics := calc.CalculateDORAMetrics(prs, deployments, startDate, endDate)
 
-	score := h.calculator.CalculateProductivityScore(cycleTime, reviewMetrics, doraMetrics)
+	score := calc.CalculateProductivityScore(cycleTime, reviewMetrics, doraMetrics)
 
 	// Set "all" for multiple repositories
 	if len(repoIDs) == 1 {
@@ -393,20 +916,157 @@ func (h *MetricsHandler) DailyMetrics(w http.ResponseWriter, r *http.Request) {
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
 	dailyMetrics, err := h.collectDailyMetrics(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect daily metrics", "error", err)
-		http.Error(w, "failed to get metrics", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
 		return
 	}
 
+	setDateRangeHeaders(w, startDate, endDate)
 	respondJSON(w, http.StatusOK, dailyMetrics)
 }
 
+// Limits on the cycle time trend window, in days
+const (
+	defaultTrendWindow = 7
+	maxTrendWindow     = 90
+)
+
+// CycleTimeTrend returns a rolling ?window=N day (default 7) moving average of daily
+// average cycle time over the period, computed from stored daily metrics.
+func (h *MetricsHandler) CycleTimeTrend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	window := defaultTrendWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxTrendWindow {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("window must be between 1 and %d", maxTrendWindow))
+			return
+		}
+		window = n
+	}
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	dailyMetrics, err := h.collectDailyMetrics(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect daily metrics", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, metrics.CalculateCycleTimeTrend(dailyMetrics, startDate, endDate, window))
+}
+
+// Anomalies returns the days whose average cycle time is more than ?threshold= standard
+// deviations (default 2) above the period's mean, computed from stored daily metrics.
+func (h *MetricsHandler) Anomalies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	threshold := metrics.DefaultAnomalyZThreshold
+	if raw := r.URL.Query().Get("threshold"); raw != "" {
+		z, err := strconv.ParseFloat(raw, 64)
+		if err != nil || z <= 0 || math.IsNaN(z) || math.IsInf(z, 0) {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "threshold must be a positive number")
+			return
+		}
+		threshold = z
+	}
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	dailyMetrics, err := h.collectDailyMetrics(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect daily metrics", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, metrics.CalculateCycleTimeAnomalies(dailyMetrics, startDate, endDate, threshold))
+}
+
+// Churn returns code churn (lines added vs deleted) over time and per author.
+// Daily churn comes from stored daily metrics; per-author churn covers PRs merged in the period.
+func (h *MetricsHandler) Churn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	dailyMetrics, err := h.collectDailyMetrics(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect daily metrics", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+
+	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect pull requests", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get metrics")
+		return
+	}
+	prs = h.newPRFilter(r).apply(prs)
+	byAuthor := h.metricsConfig(ctx).calculator.CalculateCycleTime(prs, startDate, endDate).ByAuthor
+
+	respondJSON(w, http.StatusOK, metrics.CalculateChurn(dailyMetrics, byAuthor, startDate, endDate))
+}
+
+// Deployments returns the raw deployment records for the period, newest first.
+// An optional ?environment= restricts the list to one environment.
+func (h *MetricsHandler) Deployments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startDate, endDate := parseDateRange(r)
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	deployments, err := h.collectDeployments(ctx, repoIDs, startDate, endDate)
+	if err != nil {
+		h.logger.Error("failed to collect deployments", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get deployments")
+		return
+	}
+	deployments = filterDeploymentsByEnvironment(deployments, r.URL.Query().Get("environment"))
+
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].CreatedAt.After(deployments[j].CreatedAt)
+	})
+
+	if deployments == nil {
+		deployments = []*model.Deployment{}
+	}
+	setDateRangeHeaders(w, startDate, endDate)
+	respondJSON(w, http.StatusOK, deployments)
+}
+
 // PullRequests returns a list of pull requests for given repositories.
 func (h *MetricsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -415,7 +1075,7 @@ func (h *MetricsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
@@ -429,9 +1089,12 @@ func (h *MetricsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
 	prs, err := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
 	if err != nil {
 		h.logger.Error("failed to collect pull requests", "error", err)
-		http.Error(w, "failed to get pull requests", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get pull requests")
 		return
 	}
+	prs = h.newPRFilter(r).apply(prs)
+
+	isFirst := firstContributions(ctx, h.ds, h.logger, prs)
 
 	result := make([]MemberPullRequest, 0, len(prs))
 	for _, pr := range prs {
@@ -450,6 +1113,8 @@ func (h *MetricsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
 			ReviewTime: pr.ReviewTimeHours(),
 			MergeTime:  pr.MergeTimeHours(),
 			RepoName:   repoNameMap[pr.RepositoryID],
+
+			IsFirstContribution: isFirst(pr),
 		})
 	}
 
@@ -457,5 +1122,159 @@ func (h *MetricsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
 		return result[i].CreatedAt.After(result[j].CreatedAt)
 	})
 
+	setDateRangeHeaders(w, startDate, endDate)
+	if r.URL.Query().Get("format") == "csv" {
+		respondPullRequestsCSV(w, result)
+		return
+	}
+
 	respondJSON(w, http.StatusOK, result)
 }
+
+// pullRequestCSVHeader is the header row of the pull request CSV export.
+var pullRequestCSVHeader = []string{
+	"number", "title", "author", "state", "createdAt", "mergedAt",
+	"additions", "deletions", "cycleTime", "codingTime", "pickupTime", "reviewTime", "mergeTime", "repoName",
+}
+
+// respondPullRequestsCSV streams pull requests as a CSV attachment.
+func respondPullRequestsCSV(w http.ResponseWriter, prs []MemberPullRequest) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="pull-requests.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(pullRequestCSVHeader)
+
+	formatHours := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, pr := range prs {
+		mergedAt := ""
+		if pr.MergedAt != nil {
+			mergedAt = pr.MergedAt.Format(time.RFC3339)
+		}
+		_ = cw.Write([]string{
+			strconv.Itoa(pr.Number),
+			pr.Title,
+			pr.Author,
+			pr.State,
+			pr.CreatedAt.Format(time.RFC3339),
+			mergedAt,
+			strconv.Itoa(pr.Additions),
+			strconv.Itoa(pr.Deletions),
+			formatHours(pr.CycleTime),
+			formatHours(pr.CodingTime),
+			formatHours(pr.PickupTime),
+			formatHours(pr.ReviewTime),
+			formatHours(pr.MergeTime),
+			pr.RepoName,
+		})
+	}
+	cw.Flush()
+}
+
+// StalePullRequest is the response type for an open PR without recent updates.
+type StalePullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	IdleDays  float64   `json:"idleDays"`
+	RepoName  string    `json:"repoName"`
+}
+
+// StalePRs returns open PRs whose last update is older than ?days= (default: 7), most idle first.
+func (h *MetricsHandler) StalePRs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bf := parseBotFilter(r)
+
+	days := 7
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "days must be a positive integer")
+			return
+		}
+		days = v
+	}
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	// Build repository name map
+	repos, _ := h.ds.ListRepositories(ctx)
+	repoNameMap := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		repoNameMap[repo.ID] = repo.FullName
+	}
+
+	prs := h.collectOpenPullRequests(ctx, repoIDs)
+
+	// Apply bot filtering
+	botUsernames := h.getBotUsernames(ctx)
+	prs = model.FilterPullRequestsByBot(prs, botUsernames, bf.excludeBots, bf.botsOnly)
+
+	respondJSON(w, http.StatusOK, findStalePullRequests(prs, repoNameMap, timeutil.Now(), time.Duration(days)*24*time.Hour))
+}
+
+// findStalePullRequests returns open, unmerged PRs not updated within threshold of now,
+// sorted by staleness (oldest update first).
+func findStalePullRequests(prs []*model.PullRequest, repoNameMap map[string]string, now time.Time, threshold time.Duration) []StalePullRequest {
+	cutoff := now.Add(-threshold)
+	result := make([]StalePullRequest, 0)
+	for _, pr := range prs {
+		if pr.State != "open" || pr.MergedAt != nil || !pr.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		result = append(result, StalePullRequest{
+			Number:    pr.Number,
+			Title:     pr.Title,
+			Author:    pr.Author,
+			CreatedAt: pr.CreatedAt,
+			UpdatedAt: pr.UpdatedAt,
+			IdleDays:  now.Sub(pr.UpdatedAt).Hours() / 24,
+			RepoName:  repoNameMap[pr.RepositoryID],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+	})
+
+	return result
+}
+
+// Authors returns the distinct PR authors across the selected repositories, sorted by name.
+func (h *MetricsHandler) Authors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	seen := make(map[string]bool)
+	authors := make([]string, 0)
+	for _, id := range repoIDs {
+		repoAuthors, err := h.ds.ListAuthors(ctx, id)
+		if err != nil {
+			h.logger.Warn("failed to list authors for repo", "repository", id, "error", err)
+			continue
+		}
+		for _, author := range repoAuthors {
+			if author != "" && !seen[author] {
+				seen[author] = true
+				authors = append(authors, author)
+			}
+		}
+	}
+	sort.Strings(authors)
+
+	respondJSON(w, http.StatusOK, authors)
+}
@@ -0,0 +1,1030 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "not specified", query: "", want: nil},
+		{name: "multiple percentiles", query: "percentiles=50,90,95,99", want: []float64{50, 90, 95, 99}},
+		{name: "spaces and fractional values", query: "percentiles=50,%2099.9", want: []float64{50, 99.9}},
+		{name: "100 is allowed", query: "percentiles=100", want: []float64{100}},
+		{name: "zero is rejected", query: "percentiles=0,50", wantErr: true},
+		{name: "negative is rejected", query: "percentiles=-5", wantErr: true},
+		{name: "over 100 is rejected", query: "percentiles=50,101", wantErr: true},
+		{name: "non-numeric is rejected", query: "percentiles=p90", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/dora?"+tt.query, nil)
+			got, err := parsePercentiles(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondPullRequestsCSV(t *testing.T) {
+	created := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	merged := created.Add(5 * time.Hour)
+	prs := []MemberPullRequest{
+		{
+			Number:    42,
+			Title:     `Fix "login", logout, and session`,
+			Author:    "alice",
+			State:     "closed",
+			CreatedAt: created,
+			MergedAt:  &merged,
+			Additions: 10,
+			Deletions: 3,
+			CycleTime: 5,
+			RepoName:  "org/app",
+		},
+		{Number: 43, Title: "Open PR", Author: "bob", State: "open", CreatedAt: created},
+	}
+
+	w := httptest.NewRecorder()
+	respondPullRequestsCSV(w, prs)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", cd)
+	}
+
+	// Raw output must quote the title and escape embedded quotes
+	if !strings.Contains(w.Body.String(), `"Fix ""login"", logout, and session"`) {
+		t.Errorf("title not escaped in CSV output:\n%s", w.Body.String())
+	}
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3 (header + 2 rows)", len(records))
+	}
+	if !slices.Equal(records[0], pullRequestCSVHeader) {
+		t.Errorf("header = %v, want %v", records[0], pullRequestCSVHeader)
+	}
+
+	row := records[1]
+	if row[0] != "42" || row[1] != prs[0].Title || row[2] != "alice" {
+		t.Errorf("unexpected first row: %v", row)
+	}
+	if row[5] != merged.Format(time.RFC3339) {
+		t.Errorf("mergedAt = %q, want %q", row[5], merged.Format(time.RFC3339))
+	}
+	if row[8] != "5.00" {
+		t.Errorf("cycleTime = %q, want 5.00", row[8])
+	}
+	if records[2][5] != "" {
+		t.Errorf("mergedAt for open PR = %q, want empty", records[2][5])
+	}
+}
+
+func TestParseRefPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "not specified", query: "", want: ""},
+		{name: "glob pattern", query: "ref_pattern=release/api-*", want: "release/api-*"},
+		{name: "character class", query: "ref_pattern=v[0-9]*", want: "v[0-9]*"},
+		{name: "unterminated class", query: "ref_pattern=release/[api", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/dora?"+tt.query, nil)
+			got, err := parseRefPattern(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterDeploymentsByRef(t *testing.T) {
+	deployments := []*model.Deployment{
+		{ID: "1", Ref: "release/api-1.2"},
+		{ID: "2", Ref: "release/web-3.0"},
+		{ID: "3", Ref: "main"},
+		{ID: "4", Ref: "release/api-1.3"},
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		wantIDs []string
+	}{
+		{"empty pattern keeps all", "", []string{"1", "2", "3", "4"}},
+		{"matching refs", "release/api-*", []string{"1", "4"}},
+		{"exact ref", "main", []string{"3"}},
+		{"wildcard does not cross slash", "release*", nil},
+		{"no match", "hotfix/*", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIDs []string
+			for _, d := range filterDeploymentsByRef(deployments, tt.pattern) {
+				gotIDs = append(gotIDs, d.ID)
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("got %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestPRFilter_ShippableBranches(t *testing.T) {
+	merged := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{
+		{ID: "1", Author: "alice", BaseRef: "develop", MergedAt: &merged},
+		{ID: "2", Author: "alice", BaseRef: "main", MergedAt: &merged},
+		{ID: "3", Author: "bob", BaseRef: "release/1.2", MergedAt: &merged},
+		{ID: "4", Author: "bob", BaseRef: "feature/login", MergedAt: &merged},
+		{ID: "5", Author: "bob", MergedAt: &merged}, // synced before BaseRef was stored
+	}
+	shippable := []string{"main", "release/*"}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []string
+	}{
+		{"all branches by default", "", []string{"1", "2", "3", "4", "5"}},
+		{"shippable only excludes develop merges", "shippable_only=true", []string{"2", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/cycle-time?"+tt.query, nil)
+			f := prFilter{
+				bf:           parseBotFilter(r),
+				baseBranches: parseShippableOnly(r, shippable),
+			}
+
+			var gotIDs []string
+			for _, pr := range f.apply(prs) {
+				gotIDs = append(gotIDs, pr.ID)
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("got %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestParseLabelFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/metrics/pull-requests?label=feature,+backend&label=api&exclude_label=dependencies&label_match=all", nil)
+	got := parseLabelFilter(r)
+	if !slices.Equal(got.include, []string{"feature", "backend", "api"}) {
+		t.Errorf("include = %v", got.include)
+	}
+	if !slices.Equal(got.exclude, []string{"dependencies"}) {
+		t.Errorf("exclude = %v", got.exclude)
+	}
+	if !got.matchAll {
+		t.Error("matchAll = false, want true")
+	}
+}
+
+func TestParseReviewOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantSelf bool
+		wantSLA  float64
+		wantErr  bool
+	}{
+		{name: "defaults", query: ""},
+		{name: "include self reviews", query: "include_self_reviews=true", wantSelf: true},
+		{name: "sla hours", query: "review_sla_hours=8", wantSLA: 8},
+		{name: "fractional sla hours", query: "review_sla_hours=0.5", wantSLA: 0.5},
+		{name: "zero sla hours", query: "review_sla_hours=0", wantErr: true},
+		{name: "non-numeric sla hours", query: "review_sla_hours=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/reviews?"+tt.query, nil)
+			got, err := parseReviewOptions(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.IncludeSelfReviews != tt.wantSelf || got.SLAHours != tt.wantSLA {
+				t.Errorf("got %+v, want IncludeSelfReviews=%v SLAHours=%v", got, tt.wantSelf, tt.wantSLA)
+			}
+		})
+	}
+}
+
+func TestParseDateRange_EchoedInResponses(t *testing.T) {
+	jst := time.FixedZone("UTC+09:00", 9*3600)
+	timeutil.Init(jst)
+	defer timeutil.Init(time.UTC)
+
+	now := timeutil.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, jst)
+	endOfDay := func(t time.Time) time.Time { return t.Add(24*time.Hour - time.Second) }
+
+	tests := []struct {
+		name      string
+		query     string
+		wantStart time.Time
+		wantEnd   time.Time
+		slack     time.Duration // allowance for ranges anchored at the current time
+	}{
+		{
+			name:      "default range",
+			query:     "",
+			wantStart: now.AddDate(0, -1, 0),
+			wantEnd:   now,
+			slack:     time.Minute,
+		},
+		{
+			name:      "explicit range",
+			query:     "start=2026-01-01&end=2026-01-31",
+			wantStart: time.Date(2026, 1, 1, 0, 0, 0, 0, jst),
+			wantEnd:   time.Date(2026, 1, 31, 23, 59, 59, 0, jst),
+		},
+		{
+			name:      "relative range",
+			query:     "start=-7d&end=yesterday",
+			wantStart: today.AddDate(0, 0, -7),
+			wantEnd:   endOfDay(today.AddDate(0, 0, -1)),
+		},
+		{
+			name:      "invalid start falls back to the default",
+			query:     "start=01/01/2026",
+			wantStart: now.AddDate(0, -1, 0),
+			wantEnd:   now,
+			slack:     time.Minute,
+		},
+	}
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+	endpoints := map[string]http.HandlerFunc{
+		"cycle-time":         h.CycleTime,
+		"cycle-time-trend":   h.CycleTimeTrend,
+		"anomalies":          h.Anomalies,
+		"reviews":            h.Reviews,
+		"review-coverage":    h.ReviewCoverage,
+		"collaboration":      h.Collaboration,
+		"dora":               h.DORA,
+		"productivity-score": h.ProductivityScore,
+		"daily":              h.DailyMetrics,
+		"pull-requests":      h.PullRequests,
+		"churn":              h.Churn,
+		"deployments":        h.Deployments,
+	}
+
+	listEndpoints := map[string]bool{"daily": true, "pull-requests": true, "deployments": true}
+
+	within := func(got, want time.Time, slack time.Duration) bool {
+		d := got.Sub(want)
+		return d >= 0 && d <= slack
+	}
+	for _, tt := range tests {
+		for path, serve := range endpoints {
+			t.Run(tt.name+"/"+path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				serve(w, httptest.NewRequest("GET", "/api/metrics/"+path+"?"+tt.query, nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+				}
+				var got struct {
+					StartDate time.Time `json:"startDate"`
+					EndDate   time.Time `json:"endDate"`
+					Timezone  string    `json:"timezone"`
+				}
+				if listEndpoints[path] {
+					// Lists keep their array body and echo the range in headers
+					var items []json.RawMessage
+					if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+						t.Fatalf("decode response as an array: %v", err)
+					}
+					var err error
+					if got.StartDate, err = time.Parse(time.RFC3339, w.Header().Get(middleware.DateRangeStartHeader)); err != nil {
+						t.Fatalf("%s: %v", middleware.DateRangeStartHeader, err)
+					}
+					if got.EndDate, err = time.Parse(time.RFC3339, w.Header().Get(middleware.DateRangeEndHeader)); err != nil {
+						t.Fatalf("%s: %v", middleware.DateRangeEndHeader, err)
+					}
+					got.Timezone = w.Header().Get(middleware.DateRangeTimezoneHeader)
+				} else if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if !within(got.StartDate, tt.wantStart, tt.slack) || !within(got.EndDate, tt.wantEnd, tt.slack) {
+					t.Errorf("range = [%v, %v], want [%v, %v]", got.StartDate, got.EndDate, tt.wantStart, tt.wantEnd)
+				}
+				if got.Timezone != "UTC+09:00" {
+					t.Errorf("timezone = %q, want UTC+09:00", got.Timezone)
+				}
+			})
+		}
+	}
+}
+
+func TestFindStalePullRequests(t *testing.T) {
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) time.Time { return now.AddDate(0, 0, -d) }
+	merged := daysAgo(20)
+
+	prs := []*model.PullRequest{
+		{Number: 1, RepositoryID: "100", State: "open", UpdatedAt: daysAgo(10)},
+		{Number: 2, RepositoryID: "100", State: "open", UpdatedAt: daysAgo(3)},    // recently updated
+		{Number: 3, RepositoryID: "200", State: "open", UpdatedAt: daysAgo(30)},   // most idle
+		{Number: 4, RepositoryID: "100", State: "closed", UpdatedAt: daysAgo(40)}, // closed
+		{Number: 5, RepositoryID: "100", State: "open", UpdatedAt: daysAgo(25), MergedAt: &merged},
+		{Number: 6, RepositoryID: "100", State: "open", UpdatedAt: daysAgo(7)}, // exactly at threshold
+	}
+	repoNames := map[string]string{"100": "org/api", "200": "org/web"}
+
+	got := findStalePullRequests(prs, repoNames, now, 7*24*time.Hour)
+
+	var gotNumbers []int
+	for _, pr := range got {
+		gotNumbers = append(gotNumbers, pr.Number)
+	}
+	if want := []int{3, 1}; !slices.Equal(gotNumbers, want) {
+		t.Fatalf("stale PR numbers = %v, want %v", gotNumbers, want)
+	}
+	if got[0].RepoName != "org/web" || got[0].IdleDays != 30 {
+		t.Errorf("got[0] = %+v, want repo org/web idle 30 days", got[0])
+	}
+}
+
+func TestFindStalePullRequests_NoneStale(t *testing.T) {
+	now := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{{Number: 1, State: "open", UpdatedAt: now}}
+
+	got := findStalePullRequests(prs, nil, now, 24*time.Hour)
+	if got == nil || len(got) != 0 {
+		t.Errorf("got %v, want empty non-nil slice", got)
+	}
+}
+
+func TestMetricsHandler_CycleTime(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 0, 0, timeutil.Location()) }
+	merged := func(pr *model.PullRequest, hours int) *model.PullRequest {
+		mergedAt := pr.CreatedAt.Add(time.Duration(hours) * time.Hour)
+		pr.State, pr.MergedAt = "closed", &mergedAt
+		return pr
+	}
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b", FullName: "org/b"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		merged(&model.PullRequest{ID: "repo-a#1", RepositoryID: "repo-a", Author: "alice", CreatedAt: at(10, 9)}, 10),
+		merged(&model.PullRequest{ID: "repo-b#1", RepositoryID: "repo-b", Author: "bob", CreatedAt: at(11, 9)}, 20),
+		// Excluded by default as a bot
+		merged(&model.PullRequest{ID: "repo-a#2", RepositoryID: "repo-a", Author: "dependabot[bot]", CreatedAt: at(12, 9)}, 100),
+		// Merged outside the requested range
+		merged(&model.PullRequest{ID: "repo-a#3", RepositoryID: "repo-a", Author: "alice", CreatedAt: at(29, 9)}, 72),
+	})
+	_ = store.SaveDailyMetricsBatch(t.Context(), []*model.DailyMetrics{
+		{ID: "repo-a:2025-06-10", RepositoryID: "repo-a", Date: at(10, 0), PRsMerged: 1, AvgCycleTime: 10},
+		{ID: "repo-b:2025-06-10", RepositoryID: "repo-b", Date: at(10, 0), PRsMerged: 1, AvgCycleTime: 20},
+	})
+
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	tests := []struct {
+		name      string
+		query     string
+		wantPRs   int
+		wantAvg   float64
+		wantDaily int
+	}{
+		{"all repositories", "", 2, 15, 1},
+		{"single repository", "&repository=repo-b", 1, 20, 1},
+		{"bots included", "&exclude_bots=false", 3, 130.0 / 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/cycle-time?start=2025-06-01&end=2025-06-30"+tt.query, nil)
+			w := httptest.NewRecorder()
+			h.CycleTime(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got model.CycleTimeMetrics
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got.TotalPRs != tt.wantPRs {
+				t.Errorf("TotalPRs = %d, want %d", got.TotalPRs, tt.wantPRs)
+			}
+			if diff := got.AvgCycleTime - tt.wantAvg; diff > 0.01 || diff < -0.01 {
+				t.Errorf("AvgCycleTime = %v, want %v", got.AvgCycleTime, tt.wantAvg)
+			}
+			if len(got.DailyBreakdown) != tt.wantDaily {
+				t.Errorf("DailyBreakdown has %d days, want %d", len(got.DailyBreakdown), tt.wantDaily)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_CycleTime_IncludeDrafts(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 0, 0, timeutil.Location()) }
+	merged := func(pr *model.PullRequest, hours int) *model.PullRequest {
+		mergedAt := pr.CreatedAt.Add(time.Duration(hours) * time.Hour)
+		pr.State, pr.MergedAt = "closed", &mergedAt
+		return pr
+	}
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	readyEarly, readyLate := at(11, 10), at(12, 10)
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		merged(&model.PullRequest{ID: "repo-a#1", RepositoryID: "repo-a", Author: "alice", CreatedAt: at(10, 9)}, 10),
+		// A draft for 1 of its 20 hours
+		merged(&model.PullRequest{ID: "repo-a#2", RepositoryID: "repo-a", Author: "alice", CreatedAt: at(11, 9), ReadyForReviewAt: &readyEarly}, 20),
+		// A draft for 25 of its 30 hours
+		merged(&model.PullRequest{ID: "repo-a#3", RepositoryID: "repo-a", Author: "bob", CreatedAt: at(11, 9), ReadyForReviewAt: &readyLate}, 30),
+		// Merged while still a draft
+		merged(&model.PullRequest{ID: "repo-a#4", RepositoryID: "repo-a", Author: "bob", CreatedAt: at(13, 9), Draft: true}, 60),
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantPRs int
+		wantAvg float64
+	}{
+		{"default includes drafts", "", 4, 30},
+		{"explicitly included", "&include_drafts=true", 4, 30},
+		{"mostly-draft PRs excluded", "&include_drafts=false", 2, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/cycle-time?start=2025-06-01&end=2025-06-30"+tt.query, nil)
+			w := httptest.NewRecorder()
+			h.CycleTime(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got model.CycleTimeMetrics
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got.TotalPRs != tt.wantPRs {
+				t.Errorf("TotalPRs = %d, want %d", got.TotalPRs, tt.wantPRs)
+			}
+			if diff := got.AvgCycleTime - tt.wantAvg; diff > 0.01 || diff < -0.01 {
+				t.Errorf("AvgCycleTime = %v, want %v", got.AvgCycleTime, tt.wantAvg)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_CycleTime_MinPRs(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 9, 0, 0, 0, timeutil.Location()) }
+	merged := func(id, author string, day int) *model.PullRequest {
+		mergedAt := at(day).Add(10 * time.Hour)
+		return &model.PullRequest{ID: id, RepositoryID: "repo-a", Author: author, State: "closed", CreatedAt: at(day), MergedAt: &mergedAt}
+	}
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		merged("repo-a#1", "alice", 10),
+		merged("repo-a#2", "alice", 11),
+		merged("repo-a#3", "bob", 12),
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantAuthors []string
+	}{
+		{"default keeps everyone", "", http.StatusOK, []string{"alice", "bob"}},
+		{"drops low-volume authors", "&min_prs=2", http.StatusOK, []string{"alice"}},
+		{"drops every author", "&min_prs=3", http.StatusOK, nil},
+		{"zero", "&min_prs=0", http.StatusBadRequest, nil},
+		{"not a number", "&min_prs=many", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/metrics/cycle-time?start=2025-06-01&end=2025-06-30"+tt.query, nil)
+			w := httptest.NewRecorder()
+			h.CycleTime(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got model.CycleTimeMetrics
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			// Dropped authors still count in the totals
+			if got.TotalPRs != 3 {
+				t.Errorf("TotalPRs = %d, want 3", got.TotalPRs)
+			}
+			var authors []string
+			for _, a := range got.ByAuthor {
+				authors = append(authors, a.Author)
+			}
+			if !slices.Equal(authors, tt.wantAuthors) {
+				t.Errorf("ByAuthor = %v, want %v", authors, tt.wantAuthors)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_Churn(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 0, 0, 0, 0, timeutil.Location()) }
+	mergedAt := at(10).Add(12 * time.Hour)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	_ = store.SaveDailyMetricsBatch(t.Context(), []*model.DailyMetrics{
+		{ID: "repo-a:2025-06-10", RepositoryID: "repo-a", Date: at(10), TotalAdditions: 120, TotalDeletions: 30},
+		{ID: "repo-a:2025-06-11", RepositoryID: "repo-a", Date: at(11), TotalDeletions: 50},
+	})
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-a#1", RepositoryID: "repo-a", Author: "alice", CreatedAt: at(10), MergedAt: &mergedAt, Additions: 120, Deletions: 30},
+	})
+
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+	r := httptest.NewRequest("GET", "/api/metrics/churn?start=2025-06-01&end=2025-06-30", nil)
+	w := httptest.NewRecorder()
+	h.Churn(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.ChurnMetrics
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.TotalChurn != 200 || len(got.Daily) != 2 {
+		t.Errorf("TotalChurn = %d over %d days, want 200 over 2", got.TotalChurn, len(got.Daily))
+	}
+	if got.Daily[1].DeletionRatio != 0 {
+		t.Errorf("deletion-only day ratio = %v, want 0", got.Daily[1].DeletionRatio)
+	}
+	if len(got.ByAuthor) != 1 || got.ByAuthor[0].Author != "alice" || got.ByAuthor[0].Churn != 150 {
+		t.Errorf("ByAuthor = %+v, want alice with churn 150", got.ByAuthor)
+	}
+}
+
+func TestMetricsHandler_CycleTimeTrend(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 0, 0, 0, 0, timeutil.Location()) }
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	_ = store.SaveDailyMetricsBatch(t.Context(), []*model.DailyMetrics{
+		{ID: "repo-a:2025-06-02", RepositoryID: "repo-a", Date: at(2), PRsMerged: 1, AvgCycleTime: 30},
+		{ID: "repo-a:2025-06-04", RepositoryID: "repo-a", Date: at(4), PRsMerged: 1, AvgCycleTime: 10},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	w := httptest.NewRecorder()
+	h.CycleTimeTrend(w, httptest.NewRequest("GET", "/api/metrics/cycle-time-trend?start=2025-06-01&end=2025-06-05&window=3", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.CycleTimeTrend
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Window != 3 || len(got.Points) != 5 {
+		t.Fatalf("window = %d, points = %d; want 3, 5", got.Window, len(got.Points))
+	}
+	if got.Points[0].MovingAverage != nil {
+		t.Errorf("points[0].MovingAverage = %v, want nil before any merge", *got.Points[0].MovingAverage)
+	}
+	if ma := got.Points[4].MovingAverage; ma == nil || *ma != 10 {
+		t.Errorf("points[4].MovingAverage = %v, want 10 (June 2 has left the window)", ma)
+	}
+
+	w = httptest.NewRecorder()
+	h.CycleTimeTrend(w, httptest.NewRequest("GET", "/api/metrics/cycle-time-trend?window=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("window=0 status = %d, want 400", w.Code)
+	}
+}
+
+func TestMetricsHandler_Anomalies_Threshold(t *testing.T) {
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	tests := []struct {
+		threshold string
+		want      int
+	}{
+		{"2.5", http.StatusOK},
+		{"0", http.StatusBadRequest},
+		{"-1", http.StatusBadRequest},
+		{"abc", http.StatusBadRequest},
+		{"Inf", http.StatusBadRequest},
+		{"NaN", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Anomalies(w, httptest.NewRequest("GET", "/api/metrics/anomalies?threshold="+tt.threshold, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_DailyMetrics_MergesRepositories(t *testing.T) {
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, timeutil.Location())
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b"}
+	_ = store.SaveDailyMetricsBatch(t.Context(), []*model.DailyMetrics{
+		{ID: "repo-a:2025-06-02", RepositoryID: "repo-a", Date: day, PRsOpened: 2, PRsMerged: 1, OpenPRCount: 3, AvgCycleTime: 10},
+		{ID: "repo-b:2025-06-02", RepositoryID: "repo-b", Date: day, PRsOpened: 1, PRsMerged: 3, OpenPRCount: 4, AvgCycleTime: 30},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	w := httptest.NewRecorder()
+	h.DailyMetrics(w, httptest.NewRequest("GET", "/api/metrics/daily?start=2025-06-01&end=2025-06-05", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var days []*model.DailyMetrics
+	if err := json.NewDecoder(w.Body).Decode(&days); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("got %d days, want 1", len(days))
+	}
+	got := days[0]
+	if got.RepositoryID != "" || got.PRsOpened != 3 || got.PRsMerged != 4 || got.OpenPRCount != 7 {
+		t.Errorf("repository %q, opened %d, merged %d, open %d; want \"\", 3, 4, 7",
+			got.RepositoryID, got.PRsOpened, got.PRsMerged, got.OpenPRCount)
+	}
+	if got.AvgCycleTime != 25 {
+		t.Errorf("AvgCycleTime = %v, want 25 (weighted by merged PRs)", got.AvgCycleTime)
+	}
+}
+
+func TestMetricsHandler_Deployments(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 9, 0, 0, 0, timeutil.Location()) }
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b"}
+	_ = store.SaveDeployments(t.Context(), []*model.Deployment{
+		{ID: "1", RepositoryID: "repo-a", Environment: "production", Ref: "v1.0.0", Status: "success", CreatedAt: at(3)},
+		{ID: "2", RepositoryID: "repo-a", Environment: "staging", Ref: "main", Status: "failure", CreatedAt: at(12)},
+		{ID: "3", RepositoryID: "repo-b", Environment: "production", Ref: "v2.1.0", Status: "success", CreatedAt: at(20)},
+		{ID: "4", RepositoryID: "repo-a", Environment: "production", Ref: "v0.9.0", Status: "success", CreatedAt: at(3).AddDate(0, -2, 0)}, // outside the range
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	ids := func(query string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.Deployments(w, httptest.NewRequest("GET", "/api/metrics/deployments?start=2025-06-01&end=2025-06-30"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", query, w.Code, w.Body.String())
+		}
+		var deployments []*model.Deployment
+		if err := json.NewDecoder(w.Body).Decode(&deployments); err != nil {
+			t.Fatalf("%s: decode response: %v", query, err)
+		}
+		got := make([]string, 0, len(deployments))
+		for _, d := range deployments {
+			got = append(got, d.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"3", "2", "1"}},
+		{"&environment=production", []string{"3", "1"}},
+		{"&environment=production&repository=repo-a", []string{"1"}},
+		{"&environment=qa", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsHandler_DORA_Environment(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2025, 6, day, 9, 0, 0, 0, timeutil.Location()) }
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	_ = store.SaveDeployments(t.Context(), []*model.Deployment{
+		{ID: "1", RepositoryID: "repo-a", Environment: "production", Status: "success", CreatedAt: at(3)},
+		{ID: "2", RepositoryID: "repo-a", Environment: "staging", Status: "failure", CreatedAt: at(4)},
+		{ID: "3", RepositoryID: "repo-a", Environment: "staging", Status: "success", CreatedAt: at(5)},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	serve := func(query string) model.DORAMetrics {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.DORA(w, httptest.NewRequest("GET", "/api/metrics/dora?start=2025-06-01&end=2025-06-30"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", query, w.Code, w.Body.String())
+		}
+		var got model.DORAMetrics
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: decode response: %v", query, err)
+		}
+		return got
+	}
+
+	all := serve("")
+	if all.DeploymentCount != 3 || all.ByEnvironment["production"].DeploymentCount != 1 || all.ByEnvironment["staging"].DeploymentCount != 2 {
+		t.Errorf("all environments: %d deploys, byEnvironment %+v", all.DeploymentCount, all.ByEnvironment)
+	}
+
+	prod := serve("&environment=production")
+	if prod.DeploymentCount != 1 || prod.DeploymentSuccessRate != 100 || len(prod.ByEnvironment) != 1 {
+		t.Errorf("production: %d deploys at %v%% success, byEnvironment %+v", prod.DeploymentCount, prod.DeploymentSuccessRate, prod.ByEnvironment)
+	}
+}
+
+func TestMetricsHandler_PullRequests_FirstContribution(t *testing.T) {
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 9, 0, 0, 0, timeutil.Location())
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b", FullName: "org/b"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "a#1", RepositoryID: "repo-a", Number: 1, Author: "newbie", CreatedAt: at(6, 2), MergedAt: ptr(at(6, 4))},
+		{ID: "a#2", RepositoryID: "repo-a", Number: 2, Author: "newbie", CreatedAt: at(6, 10)},
+		// Merged before the requested range; still counts as an earlier contribution
+		{ID: "a#3", RepositoryID: "repo-a", Number: 3, Author: "veteran", CreatedAt: at(1, 5), MergedAt: ptr(at(1, 6))},
+		{ID: "a#4", RepositoryID: "repo-a", Number: 4, Author: "veteran", CreatedAt: at(6, 12)},
+		// First contribution to another repository
+		{ID: "b#1", RepositoryID: "repo-b", Number: 1, Author: "veteran", CreatedAt: at(6, 15)},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	w := httptest.NewRecorder()
+	h.PullRequests(w, httptest.NewRequest("GET", "/api/metrics/pull-requests?start=2025-06-01&end=2025-06-30", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got []MemberPullRequest
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	want := map[string]bool{"org/a#1": true, "org/a#2": false, "org/a#4": false, "org/b#1": true}
+	if len(got) != len(want) {
+		t.Fatalf("got %d PRs, want %d", len(got), len(want))
+	}
+	for _, pr := range got {
+		key := fmt.Sprintf("%s#%d", pr.RepoName, pr.Number)
+		if pr.IsFirstContribution != want[key] {
+			t.Errorf("%s IsFirstContribution = %v, want %v", key, pr.IsFirstContribution, want[key])
+		}
+	}
+}
+
+func TestMetricsHandler_PullRequests_Owner(t *testing.T) {
+	at := time.Date(2025, 6, 2, 9, 0, 0, 0, timeutil.Location())
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", Owner: "acme", FullName: "acme/a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b", Owner: "acme", FullName: "acme/b"}
+	store.repos["repo-c"] = &model.Repository{ID: "repo-c", Owner: "globex", FullName: "globex/c"}
+	store.repos["repo-d"] = &model.Repository{ID: "repo-d", Owner: "initech", FullName: "initech/d"}
+	var prs []*model.PullRequest
+	for _, id := range []string{"repo-a", "repo-b", "repo-c", "repo-d"} {
+		prs = append(prs, &model.PullRequest{ID: id + "#1", RepositoryID: id, Number: 1, CreatedAt: at})
+	}
+	_ = store.SavePullRequests(t.Context(), prs)
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"one owner", "owner=acme", []string{"acme/a", "acme/b"}},
+		{"several owners", "owner=acme&owner=globex", []string{"acme/a", "acme/b", "globex/c"}},
+		{"intersected with repository", "owner=acme&repository=repo-b&repository=repo-c", []string{"acme/b"}},
+		{"unknown owner", "owner=nobody", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.PullRequests(w, httptest.NewRequest("GET", "/api/metrics/pull-requests?start=2025-06-01&end=2025-06-30&"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got []MemberPullRequest
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var names []string
+			for _, pr := range got {
+				names = append(names, pr.RepoName)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("repositories = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_PullRequests_LabelFilter(t *testing.T) {
+	at := time.Date(2025, 6, 2, 9, 0, 0, 0, timeutil.Location())
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/a"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "a#1", RepositoryID: "repo-a", Number: 1, Author: "alice", CreatedAt: at, Labels: []string{"feature"}},
+		{ID: "a#2", RepositoryID: "repo-a", Number: 2, Author: "bob", CreatedAt: at, Labels: []string{"dependencies"}},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+	const query = "/api/metrics/pull-requests?start=2025-06-01&end=2025-06-30&exclude_label=dependencies"
+
+	w := httptest.NewRecorder()
+	h.PullRequests(w, httptest.NewRequest("GET", query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got []MemberPullRequest
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got) != 1 || got[0].Number != 1 {
+		t.Errorf("JSON pull requests = %+v, want only #1", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.PullRequests(w, httptest.NewRequest("GET", query+"&format=csv", nil))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "1" {
+		t.Errorf("CSV records = %v, want the header and #1", records)
+	}
+}
+
+func TestCollectPerRepository(t *testing.T) {
+	repoIDs := make([]string, 2*maxCollectWorkers)
+	for i := range repoIDs {
+		repoIDs[i] = fmt.Sprintf("repo-%02d", i)
+	}
+
+	// The first maxCollectWorkers queries wait until all of them are in flight,
+	// which only happens when they run concurrently.
+	var inFlight, peak atomic.Int32
+	allStarted := make(chan struct{})
+	var started atomic.Int32
+	list := func(ctx context.Context, id string) ([]string, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if started.Add(1) == maxCollectWorkers {
+			close(allStarted)
+		}
+		select {
+		case <-allStarted:
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("queries did not run concurrently")
+		}
+		if id == "repo-03" {
+			return nil, errors.New("datastore unavailable")
+		}
+		return []string{id}, nil
+	}
+
+	got := collectPerRepository(t.Context(), slog.Default(), repoIDs, "items", list)
+
+	var want []string
+	for _, id := range repoIDs {
+		if id != "repo-03" {
+			want = append(want, id)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("collected = %v, want %v (in repository order, failed repository skipped)", got, want)
+	}
+	if p := peak.Load(); p != maxCollectWorkers {
+		t.Errorf("peak concurrency = %d, want %d", p, maxCollectWorkers)
+	}
+
+	if got := collectPerRepository(t.Context(), slog.Default(), nil, "items", list); len(got) != 0 {
+		t.Errorf("collectPerRepository(nil) = %v", got)
+	}
+}
+
+// failingReviewStore fails review queries for one repository.
+type failingReviewStore struct {
+	*memStore
+	failRepo string
+}
+
+func (s *failingReviewStore) ListReviewsByDateRange(ctx context.Context, repositoryID string, start, end time.Time) ([]*model.Review, error) {
+	if repositoryID == s.failRepo {
+		return nil, errors.New("datastore unavailable")
+	}
+	return s.memStore.ListReviewsByDateRange(ctx, repositoryID, start, end)
+}
+
+func TestMetricsHandler_CollectReviews_SkipsFailedRepository(t *testing.T) {
+	at := time.Date(2025, 6, 2, 9, 0, 0, 0, timeutil.Location())
+	store := &failingReviewStore{memStore: newMemStore(), failRepo: "repo-b"}
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		{ID: "a1", RepositoryID: "repo-a", SubmittedAt: at},
+		{ID: "b1", RepositoryID: "repo-b", SubmittedAt: at},
+		{ID: "c1", RepositoryID: "repo-c", SubmittedAt: at},
+	})
+	h := NewMetricsHandler(store, slog.Default(), &config.Config{})
+
+	reviews, err := h.collectReviews(t.Context(), []string{"repo-a", "repo-b", "repo-c"}, at.AddDate(0, 0, -1), at.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("collectReviews: %v", err)
+	}
+	var ids []string
+	for _, rv := range reviews {
+		ids = append(ids, rv.ID)
+	}
+	if want := []string{"a1", "c1"}; !slices.Equal(ids, want) {
+		t.Errorf("reviews = %v, want %v", ids, want)
+	}
+}
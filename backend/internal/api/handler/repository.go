@@ -1,37 +1,47 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/config"
 	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 	"github.com/compasstechlab/dora-yaki/internal/github"
-	"github.com/compasstechlab/dora-yaki/internal/metrics"
 	"github.com/compasstechlab/dora-yaki/internal/timeutil"
 )
 
 // RepositoryHandler handles repository-related API requests
 type RepositoryHandler struct {
-	ds        *datastore.Client
+	ds        RepositoryStore
 	gh        *github.Client
-	collector *github.Collector
+	collector Collector
 	logger    *slog.Logger
 	cache     *middleware.ResponseCache
+
+	minActivity int // activities a day for an active contributor in daily metrics
 }
 
 // NewRepositoryHandler creates a new RepositoryHandler
-func NewRepositoryHandler(ds *datastore.Client, gh *github.Client, logger *slog.Logger, cache *middleware.ResponseCache) *RepositoryHandler {
+func NewRepositoryHandler(ds RepositoryStore, gh *github.Client, logger *slog.Logger, cache *middleware.ResponseCache, cfg *config.Config) *RepositoryHandler {
 	return &RepositoryHandler{
 		ds:        ds,
 		gh:        gh,
-		collector: github.NewCollector(gh, logger),
+		collector: newCollector(gh, logger, cfg),
 		logger:    logger,
 		cache:     cache,
+
+		minActivity: cfg.ActiveContributorMinActivity,
 	}
 }
 
@@ -41,6 +51,9 @@ type AddRepositoryRequest struct {
 	Name  string `json:"name"`
 }
 
+// errArchivedRepository is the error message for adding an archived repository without allow_archived.
+const errArchivedRepository = "repository is archived (set allow_archived=true to add it anyway)"
+
 // BatchAddRepositoryRequest is a batch add request.
 type BatchAddRepositoryRequest struct {
 	Repositories []AddRepositoryRequest `json:"repositories"`
@@ -61,20 +74,21 @@ func (h *RepositoryHandler) BatchAdd(w http.ResponseWriter, r *http.Request) {
 
 	var req BatchAddRepositoryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	const maxBatchSize = 100
 	if len(req.Repositories) == 0 {
-		http.Error(w, "repositories are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "repositories are required")
 		return
 	}
 	if len(req.Repositories) > maxBatchSize {
-		http.Error(w, "too many repositories (max 100)", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "too many repositories (max 100)")
 		return
 	}
 
+	allowArchived, _ := strconv.ParseBool(r.URL.Query().Get("allow_archived"))
 	results := make([]BatchAddResult, 0, len(req.Repositories))
 	for _, repoReq := range req.Repositories {
 		result := BatchAddResult{
@@ -96,6 +110,11 @@ func (h *RepositoryHandler) BatchAdd(w http.ResponseWriter, r *http.Request) {
 			results = append(results, result)
 			continue
 		}
+		if repo.Archived && !allowArchived {
+			result.Error = errArchivedRepository
+			results = append(results, result)
+			continue
+		}
 
 		// Save to datastore
 		if err := h.ds.SaveRepository(ctx, repo); err != nil {
@@ -113,32 +132,91 @@ func (h *RepositoryHandler) BatchAdd(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, results)
 }
 
+// maxRepositoryPageSize caps ?limit= on the repository list.
+const maxRepositoryPageSize = 500
+
+// RepositoryPage is one page of the repository list.
+type RepositoryPage struct {
+	Repositories []*model.Repository `json:"repositories"`
+	NextCursor   string              `json:"nextCursor,omitempty"` // ?cursor= for the next page; empty on the last page
+}
+
 // List returns all repositories
 func (h *RepositoryHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if r.URL.Query().Has("limit") {
+		h.listPage(w, r)
+		return
+	}
+
 	repos, err := h.ds.ListRepositories(ctx)
 	if err != nil {
 		h.logger.Error("failed to list repositories", "error", err)
-		http.Error(w, "failed to list repositories", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
 		return
 	}
 
+	if topic := r.URL.Query().Get("topic"); topic != "" {
+		repos = filterRepositoriesByTopic(repos, topic)
+	}
+
 	respondJSON(w, http.StatusOK, repos)
 }
 
+// listPage returns one page of repositories (?limit=, up to maxRepositoryPageSize, and ?cursor=).
+// The topic filter applies within the page.
+func (h *RepositoryHandler) listPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit < 1 || limit > maxRepositoryPageSize {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxRepositoryPageSize))
+		return
+	}
+
+	repos, next, err := h.ds.ListRepositoriesPage(r.Context(), q.Get("cursor"), limit)
+	if errors.Is(err, datastore.ErrInvalidCursor) {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid cursor")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to list repositories", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
+		return
+	}
+	if topic := q.Get("topic"); topic != "" {
+		repos = filterRepositoriesByTopic(repos, topic)
+	}
+
+	respondJSON(w, http.StatusOK, &RepositoryPage{Repositories: repos, NextCursor: next})
+}
+
+// filterRepositoriesByTopic returns repositories tagged with the given GitHub topic (case-insensitive).
+func filterRepositoriesByTopic(repos []*model.Repository, topic string) []*model.Repository {
+	result := make([]*model.Repository, 0, len(repos))
+	for _, repo := range repos {
+		for _, t := range repo.Topics {
+			if strings.EqualFold(t, topic) {
+				result = append(result, repo)
+				break
+			}
+		}
+	}
+	return result
+}
+
 // Add adds a new repository
 func (h *RepositoryHandler) Add(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req AddRepositoryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	if req.Owner == "" || req.Name == "" {
-		http.Error(w, "owner and name are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "owner and name are required")
 		return
 	}
 
@@ -146,14 +224,23 @@ func (h *RepositoryHandler) Add(w http.ResponseWriter, r *http.Request) {
 	repo, err := h.gh.GetRepository(ctx, req.Owner, req.Name)
 	if err != nil {
 		h.logger.Error("failed to get repository from GitHub", "error", err)
-		http.Error(w, "repository not found on GitHub", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found on GitHub")
 		return
 	}
 
+	// Archived repositories produce no new activity; add them only on explicit request
+	if repo.Archived {
+		if allowArchived, _ := strconv.ParseBool(r.URL.Query().Get("allow_archived")); !allowArchived {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, errArchivedRepository)
+			return
+		}
+		h.logger.Warn("adding archived repository", "repository", repo.FullName)
+	}
+
 	// Save to datastore
 	if err := h.ds.SaveRepository(ctx, repo); err != nil {
 		h.logger.Error("failed to save repository", "error", err)
-		http.Error(w, "failed to save repository", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save repository")
 		return
 	}
 
@@ -167,7 +254,7 @@ func (h *RepositoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	repo, err := h.ds.GetRepository(ctx, id)
 	if err != nil {
-		http.Error(w, "repository not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
 		return
 	}
 
@@ -181,7 +268,7 @@ func (h *RepositoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.ds.DeleteRepository(ctx, id); err != nil {
 		h.logger.Error("failed to delete repository", "error", err)
-		http.Error(w, "failed to delete repository", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete repository")
 		return
 	}
 
@@ -202,6 +289,8 @@ type SyncResponse struct {
 	Deployments  int               `json:"deployments"`
 	TeamMembers  int               `json:"teamMembers"`
 	SyncedAt     time.Time         `json:"syncedAt"`
+	Partial      bool              `json:"partial,omitempty"` // some collection stages failed
+	Errors       []string          `json:"errors,omitempty"`
 }
 
 // Sync triggers a data sync for a repository
@@ -213,7 +302,7 @@ func (h *RepositoryHandler) Sync(w http.ResponseWriter, r *http.Request) {
 	repo, err := h.ds.GetRepository(ctx, id)
 	if err != nil {
 		h.logger.Error("failed to get repository", "error", err, "id", id)
-		http.Error(w, "repository not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
 		return
 	}
 	owner, name := repo.Owner, repo.Name
@@ -223,16 +312,493 @@ func (h *RepositoryHandler) Sync(w http.ResponseWriter, r *http.Request) {
 	if syncRange == "" {
 		syncRange = "full"
 	}
-	opts := github.CollectOptionsForRange(syncRange)
+	maxPages, _ := strconv.Atoi(r.URL.Query().Get("max_pages"))
+	skipFileStats, _ := strconv.ParseBool(r.URL.Query().Get("skip_file_stats"))
+	opts := syncCollectOptions(syncRange, maxPages, skipFileStats)
+	opts.UseReleases, _ = strconv.ParseBool(r.URL.Query().Get("use_releases"))
+	if opts.TagPattern, err = parseTagPattern(r.URL.Query().Get("tag_pattern")); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	if opts.Backend, err = parseCollectBackend(r.URL.Query().Get("backend")); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		h.estimateSync(w, r, repo, opts)
+		return
+	}
 
 	// Collect data from GitHub
 	data, err := h.collector.CollectAll(ctx, owner, name, opts)
 	if err != nil {
 		h.logger.Error("failed to sync repository", "error", err)
-		http.Error(w, "failed to sync repository", http.StatusInternalServerError)
+		recordSyncError(ctx, h.ds, h.logger, repo, err)
+		respondError(w, http.StatusBadGateway, errCodeUpstream, "failed to sync repository")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.saveCollectedData(ctx, repo, data, opts, true))
+}
+
+// BackfillRequest request body for a backfill of a fixed date window
+type BackfillRequest struct {
+	Start         string `json:"start"` // YYYY-MM-DD
+	End           string `json:"end"`   // YYYY-MM-DD (inclusive)
+	MaxPages      int    `json:"max_pages"`
+	SkipFileStats bool   `json:"skip_file_stats"`
+	UseReleases   bool   `json:"use_releases"`
+	TagPattern    string `json:"tag_pattern"`
+	Backend       string `json:"backend"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// defaultBackfillMaxPages bounds how far back a backfill pages through PRs (updated desc).
+const defaultBackfillMaxPages = 30
+
+// Backfill re-collects a repository's data for an explicit [start, end] window.
+// Unlike Sync, it does not update the repository's last sync time.
+func (h *RepositoryHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	var req BackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+		return
+	}
+	opts, err := backfillCollectOptions(req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
+	repo, err := h.ds.GetRepository(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get repository", "error", err, "id", id)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+		return
+	}
+
+	if req.DryRun {
+		h.estimateSync(w, r, repo, opts)
+		return
+	}
+
+	h.logger.Info("starting backfill",
+		"repository", repo.FullName,
+		"since", opts.Since,
+		"until", opts.Until,
+	)
+	data, err := h.collector.CollectAll(ctx, repo.Owner, repo.Name, opts)
+	if err != nil {
+		h.logger.Error("failed to backfill repository", "error", err)
+		respondError(w, http.StatusBadGateway, errCodeUpstream, "failed to backfill repository")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.saveCollectedData(ctx, repo, data, opts, false))
+}
+
+// backfillCollectOptions builds collect options for the exact window of a backfill request.
+func backfillCollectOptions(req BackfillRequest) (*github.CollectOptions, error) {
+	start, end, err := parseDateWindow(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	tagPattern, err := parseTagPattern(req.TagPattern)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := parseCollectBackend(req.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPages := defaultBackfillMaxPages
+	if req.MaxPages > 0 {
+		maxPages = req.MaxPages
+	}
+	return &github.CollectOptions{
+		Since:            start,
+		Until:            end,
+		State:            "all",
+		PerPage:          100,
+		MaxPages:         maxPages,
+		CollectFileStats: !req.SkipFileStats,
+		UseReleases:      req.UseReleases,
+		TagPattern:       tagPattern,
+		Backend:          backend,
+	}, nil
+}
+
+// parseDateWindow parses a required YYYY-MM-DD window; the end extends to the end of its day.
+func parseDateWindow(startRaw, endRaw string) (time.Time, time.Time, error) {
+	if startRaw == "" || endRaw == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("start and end are required")
+	}
+	start, err := timeutil.ParseDate(startRaw)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date: %s", startRaw)
+	}
+	end, err := timeutil.ParseDate(endRaw)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date: %s", endRaw)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must not be before start")
+	}
+	return start, end.Add(24*time.Hour - time.Second), nil // include the whole end day
+}
+
+// ReaggregateResponse response for daily metrics recomputation
+type ReaggregateResponse struct {
+	RepositoryID   string    `json:"repositoryId"`
+	Days           int       `json:"days"` // daily metrics rows saved
+	PullRequests   int       `json:"pullRequests"`
+	Reviews        int       `json:"reviews"`
+	Deployments    int       `json:"deployments"`
+	ReaggregatedAt time.Time `json:"reaggregatedAt"`
+}
+
+// Reaggregate recomputes daily metrics for ?start=&end= from the PRs, reviews, and deployments
+// already in Datastore, without calling GitHub. Used to refresh stored rows after an aggregation fix.
+func (h *RepositoryHandler) Reaggregate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	q := r.URL.Query()
+	start, end, err := parseDateWindow(q.Get("start"), q.Get("end"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	repo, err := h.ds.GetRepository(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get repository", "error", err, "id", id)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+		return
+	}
+
+	// Unlike a sync, a load failure is fatal: aggregating nothing would overwrite the rows with zeros
+	stored, err := loadStoredActivity(ctx, h.ds, repo.ID, start, end)
+	if err != nil {
+		h.logger.Error("failed to load stored data", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to load stored data")
+		return
+	}
+
+	dailyMetrics := dailyAggregator(ctx, h.ds, h.logger, h.minActivity).AggregateRange(repo.ID, start, end, stored.pullRequests, stored.reviews, stored.deployments)
+	if err := h.ds.SaveDailyMetricsBatch(ctx, dailyMetrics); err != nil {
+		h.logger.Error("failed to save daily metrics", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save daily metrics")
+		return
+	}
+	h.logger.Info("reaggregated daily metrics",
+		"repository", repo.FullName,
+		"start", start,
+		"end", end,
+		"days", len(dailyMetrics),
+	)
+
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+
+	respondJSON(w, http.StatusOK, &ReaggregateResponse{
+		RepositoryID:   repo.ID,
+		Days:           len(dailyMetrics),
+		PullRequests:   len(stored.pullRequests),
+		Reviews:        len(stored.reviews),
+		Deployments:    len(stored.deployments),
+		ReaggregatedAt: time.Now(),
+	})
+}
+
+// maxDeploymentImport is the most deployments one import request may carry (the Datastore PutMulti limit).
+const maxDeploymentImport = 500
+
+// DeploymentImport is one externally recorded deployment (CI system, ArgoCD, ...).
+type DeploymentImport struct {
+	ID          string    `json:"id"` // optional external ID; defaults to model.DeploymentID of environment, SHA (or ref), and createdAt
+	Environment string    `json:"environment"`
+	Ref         string    `json:"ref"`
+	SHA         string    `json:"sha"`
+	Status      string    `json:"status"` // success, failure, pending
+	CreatedAt   time.Time `json:"createdAt"`
+	DeployedAt  time.Time `json:"deployedAt"` // defaults to createdAt
+}
+
+// ImportDeploymentsResponse response for a deployment import
+type ImportDeploymentsResponse struct {
+	RepositoryID string `json:"repositoryId"`
+	Imported     int    `json:"imported"`
+}
+
+// ImportDeployments upserts deployments recorded outside GitHub, so external CI can push
+// deploy events for DORA metrics. The body is a JSON array; the whole batch is rejected
+// when any entry is invalid.
+func (h *RepositoryHandler) ImportDeployments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	var req []DeploymentImport
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+		return
+	}
+	if len(req) == 0 {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "deployments are required")
+		return
+	}
+	if len(req) > maxDeploymentImport {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("too many deployments (max %d)", maxDeploymentImport))
+		return
+	}
+
+	repo, err := h.ds.GetRepository(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get repository", "error", err, "id", id)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+		return
+	}
+
+	deployments := make([]*model.Deployment, 0, len(req))
+	for i, d := range req {
+		deployment, err := d.toDeployment(repo.ID)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("deployments[%d]: %v", i, err))
+			return
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	if err := h.ds.SaveDeployments(ctx, deployments); err != nil {
+		h.logger.Error("failed to save deployments", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save deployments")
+		return
+	}
+	h.logger.Info("imported deployments", "repository", repo.FullName, "count", len(deployments))
+
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+
+	respondJSON(w, http.StatusOK, &ImportDeploymentsResponse{
+		RepositoryID: repo.ID,
+		Imported:     len(deployments),
+	})
+}
+
+// toDeployment validates an imported deployment and converts it to the domain model.
+// IDs are prefixed so imports never collide with GitHub deployment, release, or tag IDs.
+func (d DeploymentImport) toDeployment(repositoryID string) (*model.Deployment, error) {
+	switch {
+	case d.Environment == "":
+		return nil, errors.New("environment is required")
+	case d.SHA == "" && d.Ref == "":
+		return nil, errors.New("sha or ref is required")
+	case d.CreatedAt.IsZero():
+		return nil, errors.New("createdAt is required")
+	}
+	switch d.Status {
+	case "success", "failure", "pending":
+	default:
+		return nil, fmt.Errorf("invalid status %q (use success, failure, or pending)", d.Status)
+	}
+
+	deployedAt := d.DeployedAt
+	if deployedAt.IsZero() {
+		deployedAt = d.CreatedAt
+	}
+	// Without an external ID, retries of the same deployment derive the same ID and upsert
+	id := model.DeploymentID(repositoryID, d.Environment, d.SHA, d.Ref, d.CreatedAt)
+	if d.ID != "" {
+		id = "import-" + repositoryID + "-" + d.ID
+	}
+	return &model.Deployment{
+		ID:           id,
+		RepositoryID: repositoryID,
+		Environment:  d.Environment,
+		Ref:          d.Ref,
+		SHA:          d.SHA,
+		Status:       d.Status,
+		CreatedAt:    d.CreatedAt,
+		DeployedAt:   deployedAt,
+	}, nil
+}
+
+// Limits on PRs re-fetched per enrich request
+const (
+	defaultEnrichLimit = 100
+	maxEnrichLimit     = 1000
+)
+
+// EnrichResponse response for PR time re-enrichment
+type EnrichResponse struct {
+	RepositoryID string     `json:"repositoryId"`
+	Candidates   int        `json:"candidates"`       // stored PRs missing a time
+	PullRequests int        `json:"pullRequests"`     // PRs re-fetched in this request
+	FirstCommits int        `json:"firstCommits"`     // FirstCommitAt values filled
+	FirstReviews int        `json:"firstReviews"`     // FirstReviewAt values filled
+	Reviews      int        `json:"reviews"`          // reviews fetched and saved
+	Remaining    int        `json:"remaining"`        // candidates after this batch
+	Next         *time.Time `json:"next,omitempty"`   // ?after= value for the next batch, when candidates remain
+	NextID       string     `json:"nextId,omitempty"` // ?after_id= value for the next batch
+	EnrichedAt   time.Time  `json:"enrichedAt"`
+}
+
+// Enrich re-fetches the first commit and first review times of stored PRs missing them
+// (synced before enrichment existed, or when it failed), oldest first, up to ?limit= PRs per
+// request (default 100, max 1000). Pass the returned next and nextId as ?after= and ?after_id=
+// to continue; PRs created at the same instant are ordered by ID, so none are skipped between
+// batches. PRs that still lack a time afterwards (e.g. merged without review) are not retried
+// by later batches.
+func (h *RepositoryHandler) Enrich(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	limit := defaultEnrichLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxEnrichLimit {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxEnrichLimit))
+			return
+		}
+		limit = n
+	}
+	var after time.Time
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "after must be an RFC 3339 time")
+			return
+		}
+		after = t
+	}
+	afterID := r.URL.Query().Get("after_id")
+
+	repo, err := h.ds.GetRepository(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get repository", "error", err, "id", id)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "repository not found")
+		return
+	}
+
+	var candidates []*model.PullRequest
+	err = h.ds.EachPullRequest(ctx, repo.ID, func(pr *model.PullRequest) error {
+		pastCursor := pr.CreatedAt.After(after) || (pr.CreatedAt.Equal(after) && pr.ID > afterID)
+		if pastCursor && needsTimeEnrichment(pr) {
+			candidates = append(candidates, pr)
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to list pull requests", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list pull requests")
+		return
+	}
+
+	// Oldest first, then by ID, so batches work through the backlog in a stable order
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].CreatedAt.Equal(candidates[j].CreatedAt) {
+			return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	batch := candidates[:min(limit, len(candidates))]
+
+	resp := &EnrichResponse{
+		RepositoryID: repo.ID,
+		Candidates:   len(candidates),
+		PullRequests: len(batch),
+	}
+	missingCommit := make(map[string]bool, len(batch))
+	missingReview := make(map[string]bool, len(batch))
+	for _, pr := range batch {
+		missingCommit[pr.ID] = pr.FirstCommitAt == nil
+		missingReview[pr.ID] = pr.FirstReviewAt == nil
+	}
+
+	reviews := h.collector.EnrichPullRequestTimes(ctx, repo.Owner, repo.Name, batch, repo.ID)
+	for _, pr := range batch {
+		if missingCommit[pr.ID] && pr.FirstCommitAt != nil {
+			resp.FirstCommits++
+		}
+		if missingReview[pr.ID] && pr.FirstReviewAt != nil {
+			resp.FirstReviews++
+		}
+	}
+	resp.Reviews = len(reviews)
+	if resp.Remaining = len(candidates) - len(batch); resp.Remaining > 0 {
+		last := batch[len(batch)-1]
+		next := last.CreatedAt
+		resp.Next, resp.NextID = &next, last.ID
+	}
+
+	if err := h.ds.SavePullRequests(ctx, batch); err != nil {
+		h.logger.Error("failed to save pull requests", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save pull requests")
+		return
+	}
+	if err := h.ds.SaveReviews(ctx, reviews); err != nil {
+		h.logger.Error("failed to save reviews", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save reviews")
+		return
+	}
+	h.logger.Info("enriched pull request times",
+		"repository", repo.FullName,
+		"pull_requests", resp.PullRequests,
+		"first_commits", resp.FirstCommits,
+		"first_reviews", resp.FirstReviews,
+		"remaining", resp.Remaining,
+	)
+
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+
+	resp.EnrichedAt = time.Now()
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// needsTimeEnrichment reports whether a stored PR lacks a time its cycle time breakdown depends on:
+// the first commit time of any PR, or the first review time of a merged PR.
+// Open PRs without reviews are refreshed by the next sync, so they are not candidates.
+func needsTimeEnrichment(pr *model.PullRequest) bool {
+	return pr.FirstCommitAt == nil || (pr.MergedAt != nil && pr.FirstReviewAt == nil)
+}
+
+// estimateSync responds with the PR counts a sync would collect, without enrichment calls or Datastore writes.
+func (h *RepositoryHandler) estimateSync(w http.ResponseWriter, r *http.Request, repo *model.Repository, opts *github.CollectOptions) {
+	estimate, err := h.collector.EstimatePullRequests(r.Context(), repo.Owner, repo.Name, opts)
+	if err != nil {
+		h.logger.Error("failed to estimate sync", "repository", repo.FullName, "error", err)
+		respondError(w, http.StatusBadGateway, errCodeUpstream, "failed to estimate sync")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, estimate)
+}
+
+// saveCollectedData saves collected data and the daily metrics for the collected window.
+// When updateSyncTime is set and pull requests were fully collected, the repository's last sync time
+// is updated, even if a later stage such as deployments failed.
+func (h *RepositoryHandler) saveCollectedData(ctx context.Context, repo *model.Repository, data *github.CollectedData, opts *github.CollectOptions, updateSyncTime bool) *SyncResponse {
+	if data.Partial {
+		h.logger.Warn("repository sync is partial", "repository", repo.FullName, "errors", data.Errors)
+	}
+	if data.Partial || !updateSyncTime {
+		data.Repository.LastSyncError, data.Repository.LastSyncErrorAt = repo.LastSyncError, repo.LastSyncErrorAt
+	}
+	if !data.PullRequestsComplete() || !updateSyncTime {
+		// Keep the previous sync time so the next scheduled run still syncs this repository
+		data.Repository.LastSyncedAt = repo.LastSyncedAt
+	}
+
 	// Save data to datastore
 	h.logger.Info("saving collected data to datastore",
 		"repository", data.Repository.FullName,
@@ -251,37 +817,31 @@ func (h *RepositoryHandler) Sync(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("saved "+entity, "count", count)
 	}
 
+	if !opts.CollectFileStats {
+		preserveFileExtStats(ctx, h.ds, h.logger, data.PullRequests)
+	}
 	saveAndLog(func() error { return h.ds.SaveRepository(ctx, data.Repository) }, "repository", 1)
 	saveAndLog(func() error { return h.ds.SavePullRequests(ctx, data.PullRequests) }, "pull requests", len(data.PullRequests))
 	saveAndLog(func() error { return h.ds.SaveReviews(ctx, data.Reviews) }, "reviews", len(data.Reviews))
 	saveAndLog(func() error { return h.ds.SaveDeployments(ctx, data.Deployments) }, "deployments", len(data.Deployments))
 	saveAndLog(func() error { return h.ds.SaveTeamMembers(ctx, data.TeamMembers) }, "team members", len(data.TeamMembers))
 
-	// Aggregate daily metrics
+	// Recompute daily metrics for the collected window
 	h.logger.Info("aggregating daily metrics")
-	aggregator := metrics.NewAggregator()
-	endDate := timeutil.Now()
-	startDate := opts.Since
-
-	dailyMetrics := aggregator.AggregateRange(
-		id,
-		startDate,
-		endDate,
-		data.PullRequests,
-		data.Reviews,
-		data.Deployments,
-	)
+	dailyMetrics := recomputeDailyMetrics(ctx, h.ds, h.logger, h.minActivity, repo.ID, opts.Since, opts.Until, data)
 
 	if err := h.ds.SaveDailyMetricsBatch(ctx, dailyMetrics); err != nil {
 		h.logger.Error("failed to save daily metrics", "error", err)
 	}
 	h.logger.Info("saved daily metrics", "count", len(dailyMetrics))
 
-	// Update last sync timestamp
-	now := time.Now()
-	data.Repository.LastSyncedAt = &now
-	if err := h.ds.SaveRepository(ctx, data.Repository); err != nil {
-		h.logger.Error("failed to update last_synced_at", "error", err)
+	// Update last sync timestamp once pull requests are fully collected
+	if updateSyncTime && data.PullRequestsComplete() {
+		now := time.Now()
+		data.Repository.LastSyncedAt = &now
+		if err := h.ds.SaveRepository(ctx, data.Repository); err != nil {
+			h.logger.Error("failed to update last_synced_at", "error", err)
+		}
 	}
 
 	// Invalidate cache after sync
@@ -297,9 +857,11 @@ func (h *RepositoryHandler) Sync(w http.ResponseWriter, r *http.Request) {
 		Deployments:  len(data.Deployments),
 		TeamMembers:  len(data.TeamMembers),
 		SyncedAt:     time.Now(),
+		Partial:      data.Partial,
+		Errors:       data.Errors,
 	}
 
-	respondJSON(w, http.StatusOK, response)
+	return response
 }
 
 // DateRanges returns data date ranges for all repositories.
@@ -309,7 +871,7 @@ func (h *RepositoryHandler) DateRanges(w http.ResponseWriter, r *http.Request) {
 	repos, err := h.ds.ListRepositories(ctx)
 	if err != nil {
 		h.logger.Error("failed to list repositories", "error", err)
-		http.Error(w, "failed to list repositories", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list repositories")
 		return
 	}
 
@@ -345,6 +907,19 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
+// Error codes of structured error responses
+const (
+	errCodeNotFound       = "NOT_FOUND"
+	errCodeInvalidRequest = "INVALID_REQUEST"
+	errCodeInternal       = middleware.ErrCodeInternal
+	errCodeUpstream       = "UPSTREAM" // GitHub API failure
+)
+
+// respondError writes the structured error shape shared with the middleware.
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	respondJSON(w, status, middleware.ErrorResponse{Error: middleware.ErrorDetail{Code: code, Message: message}})
+}
+
 func getPathParam(r *http.Request, name string) string {
 	return r.PathValue(name)
 }
@@ -0,0 +1,598 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+func TestFilterRepositoriesByTopic(t *testing.T) {
+	repos := []*model.Repository{
+		{ID: "1", Name: "api", Topics: []string{"backend", "payments"}},
+		{ID: "2", Name: "web", Topics: []string{"frontend"}},
+		{ID: "3", Name: "worker", Topics: []string{"Backend"}},
+		{ID: "4", Name: "legacy"},
+	}
+
+	tests := []struct {
+		name    string
+		topic   string
+		wantIDs []string
+	}{
+		{"matches multiple repositories", "backend", []string{"1", "3"}},
+		{"single match", "frontend", []string{"2"}},
+		{"case-insensitive", "PAYMENTS", []string{"1"}},
+		{"no match", "mobile", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterRepositoriesByTopic(repos, tt.topic)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d repositories, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, repo := range got {
+				if repo.ID != tt.wantIDs[i] {
+					t.Errorf("got[%d].ID = %q, want %q", i, repo.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepositoryHandler_List_Paged(t *testing.T) {
+	store := newMemStore()
+	for _, id := range []string{"repo-1", "repo-2", "repo-3"} {
+		_ = store.SaveRepository(t.Context(), &model.Repository{ID: id, FullName: "org/" + id})
+	}
+	h := NewRepositoryHandler(store, nil, slog.Default(), nil, &config.Config{})
+
+	var ids []string
+	cursor := ""
+	for pages := 1; ; pages++ {
+		w := httptest.NewRecorder()
+		h.List(w, httptest.NewRequest("GET", "/api/repositories?limit=2&cursor="+cursor, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var page RepositoryPage
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		for _, repo := range page.Repositories {
+			ids = append(ids, repo.ID)
+		}
+		if page.NextCursor == "" {
+			if pages != 2 {
+				t.Errorf("got %d pages, want 2", pages)
+			}
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if want := []string{"repo-1", "repo-2", "repo-3"}; !slices.Equal(ids, want) {
+		t.Errorf("paged IDs = %v, want %v", ids, want)
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", fmt.Sprintf("limit=%d", maxRepositoryPageSize+1), "limit=2&cursor=bogus"} {
+		w := httptest.NewRecorder()
+		h.List(w, httptest.NewRequest("GET", "/api/repositories?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestBackfillCollectOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          BackfillRequest
+		wantErr      bool
+		wantSince    time.Time
+		wantUntil    time.Time
+		wantMaxPages int
+	}{
+		{
+			name:         "window covers the whole end day",
+			req:          BackfillRequest{Start: "2024-01-01", End: "2024-03-31"},
+			wantSince:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantUntil:    time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+			wantMaxPages: defaultBackfillMaxPages,
+		},
+		{
+			name:         "single day with max_pages override",
+			req:          BackfillRequest{Start: "2024-02-10", End: "2024-02-10", MaxPages: 80},
+			wantSince:    time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC),
+			wantUntil:    time.Date(2024, 2, 10, 23, 59, 59, 0, time.UTC),
+			wantMaxPages: 80,
+		},
+		{name: "missing end", req: BackfillRequest{Start: "2024-01-01"}, wantErr: true},
+		{name: "invalid start", req: BackfillRequest{Start: "2024/01/01", End: "2024-01-31"}, wantErr: true},
+		{name: "end before start", req: BackfillRequest{Start: "2024-02-01", End: "2024-01-31"}, wantErr: true},
+		{name: "invalid tag_pattern", req: BackfillRequest{Start: "2024-01-01", End: "2024-01-31", TagPattern: "v["}, wantErr: true},
+		{name: "invalid backend", req: BackfillRequest{Start: "2024-01-01", End: "2024-01-31", Backend: "soap"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := backfillCollectOptions(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !opts.Since.Equal(tt.wantSince) || !opts.Until.Equal(tt.wantUntil) {
+				t.Errorf("window = [%v, %v], want [%v, %v]", opts.Since, opts.Until, tt.wantSince, tt.wantUntil)
+			}
+			if opts.MaxPages != tt.wantMaxPages {
+				t.Errorf("MaxPages = %d, want %d", opts.MaxPages, tt.wantMaxPages)
+			}
+			if !opts.CollectFileStats {
+				t.Error("CollectFileStats = false, want true")
+			}
+		})
+	}
+}
+
+func TestRepositoryHandler_Get_NotFoundErrorBody(t *testing.T) {
+	h := NewRepositoryHandler(newMemStore(), nil, slog.Default(), nil, &config.Config{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/repositories/{id}", h.Get)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/repositories/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var body middleware.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Error.Code != "NOT_FOUND" {
+		t.Errorf("code = %q, want NOT_FOUND", body.Error.Code)
+	}
+	if body.Error.Message != "repository not found" {
+		t.Errorf("message = %q, want %q", body.Error.Message, "repository not found")
+	}
+}
+
+// githubStub serves GitHub API requests from an in-process handler.
+type githubStub struct{ handler http.Handler }
+
+func (s githubStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+func TestRepositoryHandler_Add_RejectsArchived(t *testing.T) {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /repos/acme/legacy", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 7, "name": "legacy", "full_name": "acme/legacy", "owner": {"login": "acme"}, "archived": true}`))
+	})
+	gh := github.NewClientWithHTTPClient(&http.Client{Transport: githubStub{handler: api}})
+	store := newMemStore()
+	h := NewRepositoryHandler(store, gh, slog.Default(), nil, &config.Config{})
+
+	w := httptest.NewRecorder()
+	h.Add(w, httptest.NewRequest("POST", "/api/repositories", strings.NewReader(`{"owner": "acme", "name": "legacy"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if repos, _ := store.ListRepositories(t.Context()); len(repos) != 0 {
+		t.Errorf("saved %d repositories, want 0", len(repos))
+	}
+
+	w = httptest.NewRecorder()
+	h.Add(w, httptest.NewRequest("POST", "/api/repositories?allow_archived=true", strings.NewReader(`{"owner": "acme", "name": "legacy"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("allow_archived status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.Repository
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !got.Archived {
+		t.Error("Archived = false, want true")
+	}
+}
+
+func TestRepositoryHandler_Sync_DryRun(t *testing.T) {
+	store := newMemStore()
+	repo := &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app"}
+	_ = store.SaveRepository(t.Context(), repo)
+	collector := &fakeCollector{
+		// Saved by a real sync; a dry run must not touch it
+		data: &github.CollectedData{
+			Repository:   repo,
+			PullRequests: []*model.PullRequest{{ID: "repo-1#1", RepositoryID: repo.ID}},
+		},
+		estimate: &github.SyncEstimate{Pages: 2, PullRequests: 150, APICalls: 452},
+	}
+	h := &RepositoryHandler{ds: store, collector: collector, logger: slog.Default()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/repositories/{id}/sync", h.Sync)
+	mux.HandleFunc("POST /api/repositories/{id}/backfill", h.Backfill)
+
+	requests := []*http.Request{
+		httptest.NewRequest("POST", "/api/repositories/repo-1/sync?dry_run=true", nil),
+		httptest.NewRequest("POST", "/api/repositories/repo-1/backfill", strings.NewReader(`{"start": "2024-01-01", "end": "2024-03-31", "dry_run": true}`)),
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", req.URL.Path, w.Code, w.Body.String())
+		}
+		var got github.SyncEstimate
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got != *collector.estimate {
+			t.Errorf("%s: estimate = %+v, want %+v", req.URL.Path, got, *collector.estimate)
+		}
+	}
+
+	if len(store.pullRequests) != 0 || len(store.dailyMetrics) != 0 {
+		t.Errorf("stored %d PRs and %d daily metrics in dry run, want none", len(store.pullRequests), len(store.dailyMetrics))
+	}
+	if saved := store.repos[repo.ID]; saved.LastSyncedAt != nil {
+		t.Errorf("LastSyncedAt = %v after dry run, want unchanged", saved.LastSyncedAt)
+	}
+}
+
+func TestRepositoryHandler_Reaggregate(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2024, 6, day, hour, 0, 0, 0, timeutil.Location()) }
+
+	store := newMemStore()
+	_ = store.SaveRepository(t.Context(), &model.Repository{ID: "repo-1", FullName: "org/app"})
+	merged := at(4, 15)
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-1#1", RepositoryID: "repo-1", Number: 1, State: "closed", CreatedAt: at(3, 10), UpdatedAt: merged, MergedAt: &merged},
+	})
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		{ID: "r1", RepositoryID: "repo-1", PullRequestID: "repo-1#1", Reviewer: "bob", State: "APPROVED", SubmittedAt: at(4, 11)},
+	})
+	// Stale row written by an older aggregation
+	_ = store.SaveDailyMetricsBatch(t.Context(), []*model.DailyMetrics{
+		{ID: "stale", RepositoryID: "repo-1", Date: at(4, 0), PRsMerged: 7},
+	})
+
+	// No GitHub client or collector: any GitHub access would panic
+	h := &RepositoryHandler{ds: store, logger: slog.Default()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/repositories/{id}/reaggregate", h.Reaggregate)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/repositories/repo-1/reaggregate?start=2024-06-03&end=2024-06-05", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got ReaggregateResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Days != 3 || got.PullRequests != 1 || got.Reviews != 1 {
+		t.Errorf("response = %+v, want 3 days from 1 PR and 1 review", got)
+	}
+
+	var day *model.DailyMetrics
+	for _, m := range store.dailyMetrics {
+		if m.ID != "stale" && m.Date.Equal(at(4, 0)) {
+			day = m
+		}
+	}
+	if day == nil {
+		t.Fatal("no daily metrics saved for 2024-06-04")
+	}
+	if day.PRsMerged != 1 || day.ReviewsSubmitted != 1 {
+		t.Errorf("2024-06-04 = %d merged, %d reviews; want 1 and 1", day.PRsMerged, day.ReviewsSubmitted)
+	}
+
+	for _, target := range []string{
+		"/api/repositories/repo-1/reaggregate?start=2024-06-05&end=2024-06-03",
+		"/api/repositories/repo-1/reaggregate",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestRepositoryHandler_SaveCollectedData_SyncTime(t *testing.T) {
+	previousSync := timeutil.Now().Add(-3 * time.Hour)
+
+	tests := []struct {
+		name           string
+		failedStage    string
+		updateSyncTime bool
+		wantSyncTime   bool
+	}{
+		{"complete sync", "", true, true},
+		{"pull request failure", github.StagePullRequests, true, false},
+		{"deployment failure only", github.StageDeployments, true, true},
+		{"backfill", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &model.Repository{ID: "repo-1", FullName: "org/app", LastSyncedAt: &previousSync}
+			collected := *repo
+			collected.LastSyncedAt = nil
+			data := &github.CollectedData{Repository: &collected}
+			if tt.failedStage != "" {
+				data.Partial = true
+				data.Errors = []string{tt.failedStage + ": 502 bad gateway"}
+				data.FailedStages = []string{tt.failedStage}
+			}
+			store := newMemStore()
+			h := &RepositoryHandler{ds: store, logger: slog.Default()}
+
+			opts := &github.CollectOptions{Since: previousSync, Until: timeutil.Now(), CollectFileStats: true}
+			h.saveCollectedData(t.Context(), repo, data, opts, tt.updateSyncTime)
+
+			saved := store.repos[repo.ID]
+			if saved == nil || saved.LastSyncedAt == nil {
+				t.Fatalf("saved repository = %+v, want LastSyncedAt set", saved)
+			}
+			if updated := saved.LastSyncedAt.After(previousSync); updated != tt.wantSyncTime {
+				t.Errorf("LastSyncedAt = %v (previous %v), want updated %v", saved.LastSyncedAt, previousSync, tt.wantSyncTime)
+			}
+		})
+	}
+}
+
+func TestNeedsTimeEnrichment(t *testing.T) {
+	at := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		pr   model.PullRequest
+		want bool
+	}{
+		{"complete merged PR", model.PullRequest{FirstCommitAt: &at, FirstReviewAt: &at, MergedAt: &at}, false},
+		{"missing first commit", model.PullRequest{FirstReviewAt: &at, MergedAt: &at}, true},
+		{"merged without first review", model.PullRequest{FirstCommitAt: &at, MergedAt: &at}, true},
+		{"open PR awaiting review", model.PullRequest{FirstCommitAt: &at, State: "open"}, false},
+		{"open PR missing first commit", model.PullRequest{State: "open"}, true},
+		{"closed unmerged PR without review", model.PullRequest{FirstCommitAt: &at, State: "closed", ClosedAt: &at}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsTimeEnrichment(&tt.pr); got != tt.want {
+				t.Errorf("needsTimeEnrichment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryHandler_Enrich(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2024, 6, day, 10, 0, 0, 0, time.UTC) }
+	committed, reviewed, merged := at(1), at(2), at(3)
+
+	store := newMemStore()
+	_ = store.SaveRepository(t.Context(), &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app"})
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-1#1", RepositoryID: "repo-1", Number: 1, CreatedAt: at(1), MergedAt: &merged, FirstReviewAt: &reviewed},
+		{ID: "repo-1#2", RepositoryID: "repo-1", Number: 2, CreatedAt: at(2), MergedAt: &merged, FirstCommitAt: &committed},
+		{ID: "repo-1#3", RepositoryID: "repo-1", Number: 3, CreatedAt: at(3), MergedAt: &merged},
+		{ID: "repo-1#4", RepositoryID: "repo-1", Number: 4, CreatedAt: at(4), MergedAt: &merged, FirstCommitAt: &committed, FirstReviewAt: &reviewed},
+	})
+
+	var enriched []int
+	collector := &fakeCollector{enrich: func(prs []*model.PullRequest) []*model.Review {
+		var reviews []*model.Review
+		for _, pr := range prs {
+			enriched = append(enriched, pr.Number)
+			if pr.FirstCommitAt == nil {
+				pr.FirstCommitAt = &committed
+			}
+			if pr.FirstReviewAt == nil {
+				pr.FirstReviewAt = &reviewed
+				reviews = append(reviews, &model.Review{ID: fmt.Sprintf("rv-%d", pr.Number), RepositoryID: "repo-1", PullRequestID: pr.ReviewKey(), SubmittedAt: reviewed})
+			}
+		}
+		return reviews
+	}}
+	h := &RepositoryHandler{ds: store, collector: collector, logger: slog.Default()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/repositories/{id}/enrich", h.Enrich)
+
+	enrich := func(query string) EnrichResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/repositories/repo-1/enrich?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var got EnrichResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return got
+	}
+
+	// First batch: the oldest candidate only
+	first := enrich("limit=1")
+	if first.Candidates != 3 || first.PullRequests != 1 || first.FirstCommits != 1 || first.Remaining != 2 || first.Next == nil {
+		t.Fatalf("first batch = %+v, want 3 candidates, 1 PR, 1 first commit, 2 remaining, next set", first)
+	}
+
+	second := enrich("after=" + first.Next.Format(time.RFC3339) + "&after_id=" + url.QueryEscape(first.NextID))
+	if second.PullRequests != 2 || second.FirstCommits != 1 || second.FirstReviews != 2 || second.Reviews != 2 || second.Remaining != 0 || second.Next != nil {
+		t.Errorf("second batch = %+v, want 2 PRs, 1 first commit, 2 first reviews, 2 reviews, none remaining", second)
+	}
+	if !slices.Equal(enriched, []int{1, 2, 3}) {
+		t.Errorf("enriched PRs = %v, want [1 2 3]", enriched)
+	}
+	for _, id := range []string{"repo-1#1", "repo-1#2", "repo-1#3"} {
+		if pr := store.pullRequests[id]; needsTimeEnrichment(pr) {
+			t.Errorf("%s still missing times after enrichment", id)
+		}
+	}
+	if len(store.reviews) != 2 {
+		t.Errorf("stored %d reviews, want 2", len(store.reviews))
+	}
+}
+
+func TestRepositoryHandler_Enrich_SameCreatedAt(t *testing.T) {
+	created := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	merged := created.Add(time.Hour)
+
+	// Imported in bulk: every PR has the same creation time
+	store := newMemStore()
+	_ = store.SaveRepository(t.Context(), &model.Repository{ID: "repo-1", Owner: "org", Name: "app", FullName: "org/app"})
+	for n := 1; n <= 5; n++ {
+		_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+			{ID: fmt.Sprintf("repo-1#%d", n), RepositoryID: "repo-1", Number: n, CreatedAt: created, MergedAt: &merged},
+		})
+	}
+
+	var enriched []int
+	collector := &fakeCollector{enrich: func(prs []*model.PullRequest) []*model.Review {
+		for _, pr := range prs {
+			enriched = append(enriched, pr.Number) // left without times, so still candidates
+		}
+		return nil
+	}}
+	h := &RepositoryHandler{ds: store, collector: collector, logger: slog.Default()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/repositories/{id}/enrich", h.Enrich)
+
+	query := "limit=2"
+	for range 5 {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/repositories/repo-1/enrich?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var got EnrichResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Next == nil {
+			break
+		}
+		query = "limit=2&after=" + got.Next.Format(time.RFC3339) + "&after_id=" + url.QueryEscape(got.NextID)
+	}
+
+	slices.Sort(enriched)
+	if !slices.Equal(enriched, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("enriched PRs = %v, want each of [1 2 3 4 5] once", enriched)
+	}
+}
+
+func TestRepositoryHandler_Enrich_InvalidLimit(t *testing.T) {
+	h := &RepositoryHandler{ds: newMemStore(), logger: slog.Default()}
+
+	w := httptest.NewRecorder()
+	h.Enrich(w, httptest.NewRequest("POST", "/api/repositories/repo-1/enrich?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestRepositoryHandler_ImportDeployments(t *testing.T) {
+	store := newMemStore()
+	_ = store.SaveRepository(t.Context(), &model.Repository{ID: "repo-1", FullName: "org/app"})
+	h := &RepositoryHandler{ds: store, logger: slog.Default()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/repositories/{id}/deployments", h.ImportDeployments)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/repositories/repo-1/deployments", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("valid batch", func(t *testing.T) {
+		w := post(`[
+			{"environment": "production", "ref": "main", "sha": "abc123", "status": "success", "createdAt": "2025-06-02T10:00:00Z", "deployedAt": "2025-06-02T10:05:00Z"},
+			{"environment": "staging", "sha": "def456", "status": "failure", "createdAt": "2025-06-03T09:00:00Z"},
+			{"id": "argo-42", "environment": "production", "ref": "v1.2.0", "status": "pending", "createdAt": "2025-06-04T09:00:00Z"}
+		]`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var got ImportDeploymentsResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Imported != 3 || len(store.deployments) != 3 {
+			t.Fatalf("imported %d, stored %d; want 3, 3", got.Imported, len(store.deployments))
+		}
+
+		staging := store.deployments[model.DeploymentID("repo-1", "staging", "def456", "", time.Date(2025, 6, 3, 9, 0, 0, 0, time.UTC))]
+		if staging == nil {
+			t.Fatalf("staging deployment missing; stored %v", slices.Collect(maps.Keys(store.deployments)))
+		}
+		if !staging.DeployedAt.Equal(staging.CreatedAt) {
+			t.Errorf("DeployedAt = %v, want createdAt when omitted", staging.DeployedAt)
+		}
+		if d := store.deployments["import-repo-1-argo-42"]; d == nil || d.Status != "pending" {
+			t.Errorf("externally identified deployment = %+v, want pending", d)
+		}
+
+		// Re-posting a status change updates the same record
+		w = post(`[{"id": "argo-42", "environment": "production", "ref": "v1.2.0", "status": "success", "createdAt": "2025-06-04T09:00:00Z"}]`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		if len(store.deployments) != 3 || store.deployments["import-repo-1-argo-42"].Status != "success" {
+			t.Errorf("upsert stored %d deployments with argo-42 %+v; want 3 with success", len(store.deployments), store.deployments["import-repo-1-argo-42"])
+		}
+	})
+
+	t.Run("retried import upserts", func(t *testing.T) {
+		before := len(store.deployments)
+		body := `[{"environment": "production", "sha": "retry1", "status": "success", "createdAt": "2025-06-06T10:00:00Z"}]`
+		for range 2 {
+			if w := post(body); w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+		}
+		if len(store.deployments) != before+1 {
+			t.Errorf("stored %d deployments after two identical imports, want %d", len(store.deployments), before+1)
+		}
+	})
+
+	t.Run("invalid status rejects the batch", func(t *testing.T) {
+		before := len(store.deployments)
+		w := post(`[
+			{"environment": "production", "sha": "aaa", "status": "success", "createdAt": "2025-06-05T10:00:00Z"},
+			{"environment": "production", "sha": "bbb", "status": "done", "createdAt": "2025-06-05T11:00:00Z"}
+		]`)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "deployments[1]") {
+			t.Errorf("body %s, want the invalid entry identified", w.Body.String())
+		}
+		if len(store.deployments) != before {
+			t.Errorf("stored %d deployments, want %d (nothing saved)", len(store.deployments), before)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/api/middleware"
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/metrics"
+)
+
+// settingsTTL is how long loaded runtime settings are reused before re-reading Datastore.
+const settingsTTL = time.Minute
+
+// settingsReader loads the stored runtime settings.
+type settingsReader interface {
+	GetSettings(ctx context.Context) (*model.Settings, error)
+}
+
+// SettingsCache holds the effective configuration: the environment config
+// overridden by the runtime settings stored in Datastore.
+type SettingsCache struct {
+	ds     settingsReader
+	env    *config.Config
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	cfg      *config.Config
+	loadedAt time.Time
+}
+
+// NewSettingsCache creates a new SettingsCache
+func NewSettingsCache(ds settingsReader, env *config.Config, logger *slog.Logger) *SettingsCache {
+	return &SettingsCache{
+		ds:     ds,
+		env:    env,
+		logger: logger,
+	}
+}
+
+// Config returns the effective configuration, re-reading the settings after settingsTTL.
+// When the settings cannot be read, the environment config is used.
+func (c *SettingsCache) Config(ctx context.Context) *config.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg != nil && time.Since(c.loadedAt) < settingsTTL {
+		return c.cfg
+	}
+	settings, err := c.ds.GetSettings(ctx)
+	if err != nil {
+		c.logger.Warn("failed to load settings, using environment config", "error", err)
+		return c.env
+	}
+	c.cfg = c.env.WithSettings(settings)
+	c.loadedAt = time.Now()
+	return c.cfg
+}
+
+// Invalidate drops the loaded settings so the next Config call re-reads them.
+func (c *SettingsCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = nil
+}
+
+// SettingsHandler handles runtime settings.
+type SettingsHandler struct {
+	ds       SettingsStore
+	settings *SettingsCache
+	logger   *slog.Logger
+	cache    *middleware.ResponseCache
+}
+
+// NewSettingsHandler creates a new SettingsHandler
+func NewSettingsHandler(ds SettingsStore, settings *SettingsCache, logger *slog.Logger, cache *middleware.ResponseCache) *SettingsHandler {
+	return &SettingsHandler{
+		ds:       ds,
+		settings: settings,
+		logger:   logger,
+		cache:    cache,
+	}
+}
+
+// Get returns the stored runtime settings (unset fields fall back to the environment).
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.ds.GetSettings(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get settings", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// Put replaces the runtime settings and invalidates cached metrics computed with the old ones.
+func (h *SettingsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var settings model.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+		return
+	}
+	if err := validateSettings(&settings); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	settings.UpdatedAt = time.Now()
+	if err := h.ds.SaveSettings(ctx, &settings); err != nil {
+		h.logger.Error("failed to save settings", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to save settings")
+		return
+	}
+
+	if h.settings != nil {
+		h.settings.Invalidate()
+	}
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+
+	respondJSON(w, http.StatusOK, &settings)
+}
+
+// validateSettings checks the score weights and thresholds the same way the environment config is checked.
+func validateSettings(s *model.Settings) error {
+	if s.HasScoreWeights() {
+		weights := metrics.ScoreWeights{
+			CycleTime:  s.ScoreWeightCycleTime,
+			Review:     s.ScoreWeightReview,
+			Deployment: s.ScoreWeightDeployment,
+			Quality:    s.ScoreWeightQuality,
+		}
+		if err := weights.Validate(); err != nil {
+			return err
+		}
+	}
+	thresholds := metrics.ScoreThresholds{
+		CycleTimeHours:    s.ScoreCycleTimeHours,
+		FirstReviewHours:  s.ScoreFirstReviewHours,
+		ReviewsPerPR:      s.ScoreReviewsPerPR,
+		ChangeFailureRate: s.ScoreChangeFailureRate,
+	}
+	if err := thresholds.Validate(); err != nil {
+		return err
+	}
+	if s.ReviewSLAHours < 0 {
+		return errors.New("review SLA hours must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestSettingsHandler_GetPut(t *testing.T) {
+	store := newMemStore()
+	env := &config.Config{}
+	h := NewSettingsHandler(store, NewSettingsCache(store, env, slog.Default()), slog.Default(), nil)
+
+	w := httptest.NewRecorder()
+	h.Get(w, httptest.NewRequest("GET", "/api/settings", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.Settings
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got.ShippableBranches) != 0 || got.HasScoreWeights() {
+		t.Errorf("settings before PUT = %+v, want empty", got)
+	}
+
+	body := `{"shippableBranches":["main","release/*"],"reviewSlaHours":8,
+		"scoreWeightCycleTime":0.4,"scoreWeightReview":0.2,"scoreWeightDeployment":0.2,"scoreWeightQuality":0.2}`
+	w = httptest.NewRecorder()
+	h.Put(w, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Get(w, httptest.NewRequest("GET", "/api/settings", nil))
+	got = model.Settings{}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !slices.Equal(got.ShippableBranches, []string{"main", "release/*"}) {
+		t.Errorf("ShippableBranches = %v", got.ShippableBranches)
+	}
+	if got.ReviewSLAHours != 8 || got.ScoreWeightCycleTime != 0.4 {
+		t.Errorf("settings = %+v", got)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt not set")
+	}
+}
+
+func TestSettingsHandler_Put_RejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", `{"shippableBranches":`},
+		{"weights not summing to one", `{"scoreWeightCycleTime":0.5,"scoreWeightReview":0.5,"scoreWeightDeployment":0.5}`},
+		{"wrong threshold count", `{"scoreCycleTimeHours":[24,72]}`},
+		{"negative SLA", `{"reviewSlaHours":-1}`},
+	}
+
+	store := newMemStore()
+	h := NewSettingsHandler(store, nil, slog.Default(), nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Put(w, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+	if store.settings != nil {
+		t.Errorf("invalid settings were saved: %+v", store.settings)
+	}
+}
+
+func TestSettingsCache_OverridesEnvironment(t *testing.T) {
+	store := newMemStore()
+	env := &config.Config{
+		ShippableBranches: []string{"main"},
+		FailureLabels:     []string{"incident"},
+		ReviewSLAHours:    24,
+	}
+	settings := NewSettingsCache(store, env, slog.Default())
+	mh := NewMetricsHandler(store, slog.Default(), env).WithSettings(settings)
+	sh := NewSettingsHandler(store, settings, slog.Default(), nil)
+
+	r := httptest.NewRequest("GET", "/api/metrics/reviews?shippable_only=true", nil)
+	if got := mh.newPRFilter(r).baseBranches; !slices.Equal(got, []string{"main"}) {
+		t.Fatalf("baseBranches before PUT = %v, want environment value", got)
+	}
+
+	w := httptest.NewRecorder()
+	sh.Put(w, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"shippableBranches":["trunk"],"reviewSlaHours":4}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	// The PUT invalidates the shared cache, so the new settings apply immediately
+	if got := mh.newPRFilter(r).baseBranches; !slices.Equal(got, []string{"trunk"}) {
+		t.Errorf("baseBranches after PUT = %v, want settings value", got)
+	}
+	opts, err := mh.reviewOptionsFor(r)
+	if err != nil {
+		t.Fatalf("reviewOptionsFor: %v", err)
+	}
+	if opts.SLAHours != 4 {
+		t.Errorf("SLAHours = %v, want 4", opts.SLAHours)
+	}
+	// Fields left unset in the settings keep the environment value
+	if got := settings.Config(t.Context()).FailureLabels; !slices.Equal(got, []string{"incident"}) {
+		t.Errorf("FailureLabels = %v, want environment value", got)
+	}
+	if env.ShippableBranches[0] != "main" {
+		t.Error("environment config was modified")
+	}
+}
@@ -1,26 +1,28 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 	"github.com/compasstechlab/dora-yaki/internal/metrics"
 )
 
 // SprintHandler handles sprint-related API requests
 type SprintHandler struct {
-	ds         *datastore.Client
+	ds         SprintStore
 	aggregator *metrics.Aggregator
 	logger     *slog.Logger
 }
 
 // NewSprintHandler creates a new SprintHandler
-func NewSprintHandler(ds *datastore.Client, logger *slog.Logger) *SprintHandler {
+func NewSprintHandler(ds SprintStore, logger *slog.Logger) *SprintHandler {
 	return &SprintHandler{
 		ds:         ds,
 		aggregator: metrics.NewAggregator(),
@@ -37,20 +39,29 @@ type CreateSprintRequest struct {
 	Goals        string `json:"goals"`
 }
 
+// UpdateSprintRequest request body for updating a sprint.
+// All fields replace the stored values; the sprint keeps its ID and repository.
+type UpdateSprintRequest struct {
+	Name      string `json:"name"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Goals     string `json:"goals"`
+}
+
 // List lists all sprints for a repository
 func (h *SprintHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	repoID := r.URL.Query().Get("repository")
 
 	if repoID == "" {
-		http.Error(w, "repository parameter is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "repository parameter is required")
 		return
 	}
 
 	sprints, err := h.ds.ListSprints(ctx, repoID)
 	if err != nil {
 		h.logger.Error("failed to list sprints", "error", err)
-		http.Error(w, "failed to list sprints", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list sprints")
 		return
 	}
 
@@ -63,24 +74,18 @@ func (h *SprintHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	var req CreateSprintRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	if req.RepositoryID == "" || req.Name == "" || req.StartDate == "" || req.EndDate == "" {
-		http.Error(w, "repositoryId, name, startDate, and endDate are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "repositoryId, name, startDate, and endDate are required")
 		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	startDate, endDate, err := parseSprintDates(req.StartDate, req.EndDate)
 	if err != nil {
-		http.Error(w, "invalid startDate format (use YYYY-MM-DD)", http.StatusBadRequest)
-		return
-	}
-
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
-	if err != nil {
-		http.Error(w, "invalid endDate format (use YYYY-MM-DD)", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -95,7 +100,7 @@ func (h *SprintHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.ds.SaveSprint(ctx, sprint); err != nil {
 		h.logger.Error("failed to save sprint", "error", err)
-		http.Error(w, "failed to create sprint", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to create sprint")
 		return
 	}
 
@@ -105,65 +110,143 @@ func (h *SprintHandler) Create(w http.ResponseWriter, r *http.Request) {
 // Get returns a specific sprint
 func (h *SprintHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	id := getSprintID(r)
+	id := getPathParam(r, "id")
 
 	sprint, err := h.ds.GetSprint(ctx, id)
 	if err != nil {
-		http.Error(w, "sprint not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "sprint not found")
 		return
 	}
 
 	respondJSON(w, http.StatusOK, sprint)
 }
 
-// GetPerformance returns performance metrics for a sprint
-func (h *SprintHandler) GetPerformance(w http.ResponseWriter, r *http.Request) {
+// Update replaces the name, dates, and goals of a sprint
+func (h *SprintHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	id := getSprintID(r)
+	id := getPathParam(r, "id")
+
+	var req UpdateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	if req.Name == "" || req.StartDate == "" || req.EndDate == "" {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "name, startDate, and endDate are required")
+		return
+	}
+
+	startDate, endDate, err := parseSprintDates(req.StartDate, req.EndDate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
 
 	sprint, err := h.ds.GetSprint(ctx, id)
 	if err != nil {
-		http.Error(w, "sprint not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "sprint not found")
 		return
 	}
 
-	// Get PRs and reviews for the sprint period
-	prs, err := h.ds.ListPullRequestsByDateRange(ctx, sprint.RepositoryID, sprint.StartDate, sprint.EndDate)
+	sprint.Name = req.Name
+	sprint.StartDate = startDate
+	sprint.EndDate = endDate
+	sprint.Goals = req.Goals
+
+	if err := h.ds.SaveSprint(ctx, sprint); err != nil {
+		h.logger.Error("failed to save sprint", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to update sprint")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, sprint)
+}
+
+// Delete deletes a sprint
+func (h *SprintHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	if _, err := h.ds.GetSprint(ctx, id); err != nil {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "sprint not found")
+		return
+	}
+
+	if err := h.ds.DeleteSprint(ctx, id); err != nil {
+		h.logger.Error("failed to delete sprint", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete sprint")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPerformance returns performance metrics for a sprint
+func (h *SprintHandler) GetPerformance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getPathParam(r, "id")
+
+	sprint, err := h.ds.GetSprint(ctx, id)
 	if err != nil {
-		h.logger.Error("failed to list pull requests", "error", err)
-		http.Error(w, "failed to get sprint performance", http.StatusInternalServerError)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "sprint not found")
 		return
 	}
 
-	reviews, err := h.ds.ListReviewsByDateRange(ctx, sprint.RepositoryID, sprint.StartDate, sprint.EndDate)
+	performance, err := h.sprintPerformance(ctx, sprint)
 	if err != nil {
-		h.logger.Error("failed to list reviews", "error", err)
-		http.Error(w, "failed to get sprint performance", http.StatusInternalServerError)
+		h.logger.Error("failed to calculate sprint performance", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get sprint performance")
 		return
 	}
 
-	// Calculate sprint performance
-	performance := h.aggregator.CalculateSprintMetrics(sprint, prs, reviews)
+	// Compare with the immediately preceding sprint of the same repository, if any
+	sprints, err := h.ds.ListSprints(ctx, sprint.RepositoryID)
+	if err != nil {
+		h.logger.Warn("failed to list sprints for comparison", "error", err)
+	} else if previous := metrics.PreviousSprint(sprints, sprint); previous != nil {
+		previousPerformance, err := h.sprintPerformance(ctx, previous)
+		if err != nil {
+			h.logger.Warn("failed to calculate previous sprint performance", "sprint", previous.ID, "error", err)
+		} else {
+			metrics.CompareWithPreviousSprint(performance, previousPerformance)
+		}
+	}
 
 	respondJSON(w, http.StatusOK, performance)
 }
 
-func generateSprintID(repoID, name string) string {
-	safeName := strings.ReplaceAll(name, " ", "-")
-	return repoID + ":" + safeName
+// sprintPerformance loads the PRs and reviews of the sprint period and calculates its performance.
+func (h *SprintHandler) sprintPerformance(ctx context.Context, sprint *model.Sprint) (*model.SprintPerformance, error) {
+	prs, err := h.ds.ListPullRequestsByDateRange(ctx, sprint.RepositoryID, sprint.StartDate, sprint.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	reviews, err := h.ds.ListReviewsByDateRange(ctx, sprint.RepositoryID, sprint.StartDate, sprint.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	return h.aggregator.CalculateSprintMetrics(sprint, prs, reviews), nil
 }
 
-func getSprintID(r *http.Request) string {
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
+// parseSprintDates parses sprint start and end dates in YYYY-MM-DD format.
+func parseSprintDates(start, end string) (time.Time, time.Time, error) {
+	startDate, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid startDate format (use YYYY-MM-DD)")
+	}
 
-	// /api/sprints/{id} or /api/sprints/{id}/performance
-	if len(parts) >= 4 {
-		if parts[len(parts)-1] == "performance" {
-			return parts[len(parts)-2]
-		}
-		return parts[len(parts)-1]
+	endDate, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid endDate format (use YYYY-MM-DD)")
 	}
 
-	return ""
+	return startDate, endDate, nil
+}
+
+func generateSprintID(repoID, name string) string {
+	safeName := strings.ReplaceAll(name, " ", "-")
+	return repoID + ":" + safeName
 }
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestSprintHandler_Get_EncodedID(t *testing.T) {
+	store := newMemStore()
+	// Sprint IDs embed the sprint name, which may contain a slash
+	_ = store.SaveSprint(t.Context(), &model.Sprint{ID: "repo-a:Q1/W2", RepositoryID: "repo-a", Name: "Q1/W2"})
+	h := NewSprintHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/sprints/{id}", h.Get)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/sprints/repo-a:Q1%2FW2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.Sprint
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.ID != "repo-a:Q1/W2" {
+		t.Errorf("ID = %q, want %q", got.ID, "repo-a:Q1/W2")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/sprints/repo-a:Q1%2FW3", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown sprint status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSprintHandler_Update(t *testing.T) {
+	store := newMemStore()
+	_ = store.SaveSprint(t.Context(), &model.Sprint{ID: "repo-a:Sprint-1", RepositoryID: "repo-a", Name: "Sprint 1", Goals: "ship v1"})
+	h := NewSprintHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/sprints/{id}", h.Update)
+
+	serve := func(id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("PUT", "/api/sprints/"+id, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve("repo-a:Sprint-1", `{"name":"Sprint 1b","startDate":"2026-03-02","endDate":"2026-03-13","goals":""}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	got := store.sprints["repo-a:Sprint-1"]
+	if got.Name != "Sprint 1b" || got.Goals != "" || got.RepositoryID != "repo-a" {
+		t.Errorf("stored sprint = %+v, want renamed with goals cleared", got)
+	}
+	if !got.StartDate.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) || !got.EndDate.Equal(time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("dates = %v - %v, want 2026-03-02 - 2026-03-13", got.StartDate, got.EndDate)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"invalid endDate", "repo-a:Sprint-1", `{"name":"x","startDate":"2026-03-02","endDate":"03/13/2026"}`, http.StatusBadRequest},
+		{"missing name", "repo-a:Sprint-1", `{"startDate":"2026-03-02","endDate":"2026-03-13"}`, http.StatusBadRequest},
+		{"invalid body", "repo-a:Sprint-1", `{`, http.StatusBadRequest},
+		{"unknown sprint", "repo-a:Sprint-9", `{"name":"x","startDate":"2026-03-02","endDate":"2026-03-13"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(tt.id, tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+	if _, ok := store.sprints["repo-a:Sprint-9"]; ok {
+		t.Error("update of an unknown sprint created it")
+	}
+}
+
+func TestSprintHandler_Delete(t *testing.T) {
+	store := newMemStore()
+	_ = store.SaveSprint(t.Context(), &model.Sprint{ID: "repo-a:Sprint-1", RepositoryID: "repo-a", Name: "Sprint 1"})
+	h := NewSprintHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/sprints/{id}", h.Delete)
+
+	serve := func(id string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/sprints/"+id, nil))
+		return w.Code
+	}
+
+	if code := serve("repo-a:Sprint-1"); code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", code, http.StatusNoContent)
+	}
+	if _, ok := store.sprints["repo-a:Sprint-1"]; ok {
+		t.Error("sprint still stored after delete")
+	}
+	if code := serve("repo-a:Sprint-1"); code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// Each handler depends on the subset of the Datastore client it uses,
+// so handlers can be tested against an in-memory store.
+var (
+	_ RepositoryStore = (*datastore.Client)(nil)
+	_ MetricsStore    = (*datastore.Client)(nil)
+	_ TeamStore       = (*datastore.Client)(nil)
+	_ SprintStore     = (*datastore.Client)(nil)
+	_ BotUserStore    = (*datastore.Client)(nil)
+	_ JobStore        = (*datastore.Client)(nil)
+	_ SchemaStore     = (*datastore.Client)(nil)
+	_ SettingsStore   = (*datastore.Client)(nil)
+)
+
+// activityStore loads the stored data daily metrics are recomputed from.
+type activityStore interface {
+	ListPullRequestsUpdatedSince(ctx context.Context, repositoryID string, since time.Time) ([]*model.PullRequest, error)
+	ListOpenPullRequests(ctx context.Context, repositoryID string) ([]*model.PullRequest, error)
+	ListReviewsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error)
+	ListDeployments(ctx context.Context, repositoryID string, opts *datastore.QueryOptions) ([]*model.Deployment, error)
+	ListBotUsernames(ctx context.Context) ([]string, error)
+}
+
+// collectedDataStore saves the data collected by a repository sync.
+type collectedDataStore interface {
+	activityStore
+	SaveRepository(ctx context.Context, repo *model.Repository) error
+	GetPullRequests(ctx context.Context, ids []string) (map[string]*model.PullRequest, error)
+	SavePullRequests(ctx context.Context, prs []*model.PullRequest) error
+	SaveReviews(ctx context.Context, reviews []*model.Review) error
+	SaveDeployments(ctx context.Context, deployments []*model.Deployment) error
+	SaveTeamMembers(ctx context.Context, members []*model.TeamMember) error
+	SaveDailyMetricsBatch(ctx context.Context, metricsList []*model.DailyMetrics) error
+}
+
+// RepositoryStore is the Datastore subset used by RepositoryHandler.
+type RepositoryStore interface {
+	collectedDataStore
+	GetRepository(ctx context.Context, id string) (*model.Repository, error)
+	ListRepositories(ctx context.Context) ([]*model.Repository, error)
+	ListRepositoriesPage(ctx context.Context, cursor string, limit int) ([]*model.Repository, string, error)
+	DeleteRepository(ctx context.Context, id string) error
+	GetDataDateRange(ctx context.Context, repositoryID string) (*datastore.DataDateRange, error)
+	EachPullRequest(ctx context.Context, repositoryID string, fn func(*model.PullRequest) error) error
+}
+
+// MetricsStore is the Datastore subset used by MetricsHandler.
+type MetricsStore interface {
+	ListRepositories(ctx context.Context) ([]*model.Repository, error)
+	ListRepositoryIDs(ctx context.Context) ([]string, error)
+	ListRepositoriesByOwner(ctx context.Context, owner string) ([]*model.Repository, error)
+	ListPullRequestsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.PullRequest, error)
+	ListOpenPullRequests(ctx context.Context, repositoryID string) ([]*model.PullRequest, error)
+	ListReviewsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error)
+	ListDeployments(ctx context.Context, repositoryID string, opts *datastore.QueryOptions) ([]*model.Deployment, error)
+	ListDailyMetrics(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.DailyMetrics, error)
+	ListAuthors(ctx context.Context, repositoryID string) ([]string, error)
+	ListPullRequestsByAuthor(ctx context.Context, repositoryID, author string) ([]*model.PullRequest, error)
+	ListBotUsernames(ctx context.Context) ([]string, error)
+	GetSettings(ctx context.Context) (*model.Settings, error)
+	EachPullRequestByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.PullRequest) error) error
+	EachReviewByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Review) error) error
+	EachDeploymentByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Deployment) error) error
+}
+
+// TeamStore is the Datastore subset used by TeamHandler.
+type TeamStore interface {
+	ListRepositories(ctx context.Context) ([]*model.Repository, error)
+	ListRepositoryIDs(ctx context.Context) ([]string, error)
+	ListTeamMembers(ctx context.Context) ([]*model.TeamMember, error)
+	ListPullRequestsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.PullRequest, error)
+	ListPullRequestsByAuthor(ctx context.Context, repositoryID, author string) ([]*model.PullRequest, error)
+	ListReviewsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error)
+	ListBotUsernames(ctx context.Context) ([]string, error)
+}
+
+// SprintStore is the Datastore subset used by SprintHandler.
+type SprintStore interface {
+	SaveSprint(ctx context.Context, sprint *model.Sprint) error
+	GetSprint(ctx context.Context, id string) (*model.Sprint, error)
+	DeleteSprint(ctx context.Context, id string) error
+	ListSprints(ctx context.Context, repositoryID string) ([]*model.Sprint, error)
+	ListPullRequestsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.PullRequest, error)
+	ListReviewsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error)
+}
+
+// BotUserStore is the Datastore subset used by BotUserHandler.
+type BotUserStore interface {
+	SaveBotUser(ctx context.Context, botUser *model.BotUser) error
+	ListBotUsers(ctx context.Context) ([]*model.BotUser, error)
+	DeleteBotUser(ctx context.Context, username string) error
+}
+
+// JobStore is the Datastore subset used by JobHandler.
+type JobStore interface {
+	collectedDataStore
+	AcquireSyncLock(ctx context.Context, lockID, lockedBy string, ttl time.Duration) error
+	ReleaseSyncLock(ctx context.Context, lockID, lockedBy string) error
+	ListRepositories(ctx context.Context) ([]*model.Repository, error)
+	DeleteOlderThan(ctx context.Context, repositoryID, kind string, cutoff time.Time) (int, error)
+}
+
+// SchemaStore is the Datastore subset used by AdminHandler.
+type SchemaStore interface {
+	ListRepositories(ctx context.Context) ([]*model.Repository, error)
+	SaveRepository(ctx context.Context, repo *model.Repository) error
+	SavePullRequests(ctx context.Context, prs []*model.PullRequest) error
+	SaveReviews(ctx context.Context, reviews []*model.Review) error
+	SaveDeployments(ctx context.Context, deployments []*model.Deployment) error
+	CountBySchemaVersion(ctx context.Context, kind string) (map[int]int64, error)
+	CountKind(ctx context.Context, kind, repositoryID string) (int64, error)
+	EachPullRequest(ctx context.Context, repositoryID string, fn func(*model.PullRequest) error) error
+	EachReview(ctx context.Context, repositoryID string, fn func(*model.Review) error) error
+	EachDeployment(ctx context.Context, repositoryID string, fn func(*model.Deployment) error) error
+}
+
+// SettingsStore is the Datastore subset used by SettingsHandler.
+type SettingsStore interface {
+	GetSettings(ctx context.Context) (*model.Settings, error)
+	SaveSettings(ctx context.Context, settings *model.Settings) error
+}
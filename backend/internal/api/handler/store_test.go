@@ -0,0 +1,542 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// memStore is an in-memory stand-in for the Datastore client in handler tests.
+type memStore struct {
+	mu           sync.Mutex
+	repos        map[string]*model.Repository
+	pullRequests map[string]*model.PullRequest
+	reviews      map[string]*model.Review
+	deployments  map[string]*model.Deployment
+	members      map[string]*model.TeamMember
+	dailyMetrics map[string]*model.DailyMetrics
+	sprints      map[string]*model.Sprint
+	botUsers     map[string]*model.BotUser
+	locks        map[string]string
+	settings     *model.Settings
+}
+
+// errNotFound mirrors datastore.ErrNoSuchEntity for lookups of missing keys.
+var errNotFound = errors.New("datastore: no such entity")
+
+func newMemStore() *memStore {
+	return &memStore{
+		repos:        make(map[string]*model.Repository),
+		pullRequests: make(map[string]*model.PullRequest),
+		reviews:      make(map[string]*model.Review),
+		deployments:  make(map[string]*model.Deployment),
+		members:      make(map[string]*model.TeamMember),
+		dailyMetrics: make(map[string]*model.DailyMetrics),
+		sprints:      make(map[string]*model.Sprint),
+		botUsers:     make(map[string]*model.BotUser),
+		locks:        make(map[string]string),
+	}
+}
+
+var (
+	_ RepositoryStore = (*memStore)(nil)
+	_ MetricsStore    = (*memStore)(nil)
+	_ TeamStore       = (*memStore)(nil)
+	_ SprintStore     = (*memStore)(nil)
+	_ BotUserStore    = (*memStore)(nil)
+	_ JobStore        = (*memStore)(nil)
+	_ SchemaStore     = (*memStore)(nil)
+	_ SettingsStore   = (*memStore)(nil)
+)
+
+func (s *memStore) AcquireSyncLock(_ context.Context, lockID, lockedBy string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holder, ok := s.locks[lockID]; ok && holder != lockedBy {
+		return fmt.Errorf("lock held by %s", holder)
+	}
+	s.locks[lockID] = lockedBy
+	return nil
+}
+
+func (s *memStore) ReleaseSyncLock(_ context.Context, lockID, lockedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks[lockID] == lockedBy {
+		delete(s.locks, lockID)
+	}
+	return nil
+}
+
+func (s *memStore) SaveRepository(_ context.Context, repo *model.Repository) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo.SchemaVersion = model.CurrentSchemaVersion
+	saved := *repo
+	s.repos[repo.ID] = &saved
+	return nil
+}
+
+func (s *memStore) ListRepositories(_ context.Context) ([]*model.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return values(s.repos), nil
+}
+
+// ListRepositoriesPage pages repositories ordered by ID; the cursor is the offset.
+func (s *memStore) ListRepositoriesPage(ctx context.Context, cursor string, limit int) ([]*model.Repository, string, error) {
+	repos, _ := s.ListRepositories(ctx)
+	slices.SortFunc(repos, func(a, b *model.Repository) int { return strings.Compare(a.ID, b.ID) })
+	offset := 0
+	if cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", datastore.ErrInvalidCursor, err)
+		}
+		offset = n
+	}
+	end := min(offset+limit, len(repos))
+	if offset >= end {
+		return []*model.Repository{}, "", nil
+	}
+	next := ""
+	if end < len(repos) {
+		next = strconv.Itoa(end)
+	}
+	return repos[offset:end], next, nil
+}
+
+func (s *memStore) ListRepositoryIDs(ctx context.Context) ([]string, error) {
+	repos, _ := s.ListRepositories(ctx)
+	ids := make([]string, len(repos))
+	for i, repo := range repos {
+		ids[i] = repo.ID
+	}
+	return ids, nil
+}
+
+func (s *memStore) ListRepositoriesByOwner(_ context.Context, owner string) ([]*model.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var repos []*model.Repository
+	for _, repo := range values(s.repos) {
+		if repo.Owner == owner {
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+func (s *memStore) GetRepository(_ context.Context, id string) (*model.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.repos[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *repo
+	return &copied, nil
+}
+
+func (s *memStore) DeleteRepository(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.repos, id)
+	return nil
+}
+
+func (s *memStore) GetDataDateRange(_ context.Context, repositoryID string) (*datastore.DataDateRange, error) {
+	result := &datastore.DataDateRange{RepositoryID: repositoryID}
+	for _, pr := range s.filterPullRequests(func(pr *model.PullRequest) bool { return pr.RepositoryID == repositoryID }) {
+		createdAt := pr.CreatedAt
+		if result.OldestDate == nil || createdAt.Before(*result.OldestDate) {
+			result.OldestDate = &createdAt
+		}
+		if result.NewestDate == nil || createdAt.After(*result.NewestDate) {
+			result.NewestDate = &createdAt
+		}
+		result.PRCount++
+	}
+	return result, nil
+}
+
+func (s *memStore) GetPullRequests(_ context.Context, ids []string) (map[string]*model.PullRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := make(map[string]*model.PullRequest, len(ids))
+	for _, id := range ids {
+		if pr, ok := s.pullRequests[id]; ok {
+			copied := *pr
+			found[id] = &copied
+		}
+	}
+	return found, nil
+}
+
+func (s *memStore) SavePullRequests(_ context.Context, prs []*model.PullRequest) error {
+	for _, pr := range prs {
+		pr.SchemaVersion = model.CurrentSchemaVersion
+	}
+	return saveAll(&s.mu, s.pullRequests, prs, func(pr *model.PullRequest) string { return pr.ID })
+}
+
+func (s *memStore) SaveReviews(_ context.Context, reviews []*model.Review) error {
+	for _, r := range reviews {
+		r.SchemaVersion = model.CurrentSchemaVersion
+	}
+	return saveAll(&s.mu, s.reviews, reviews, func(r *model.Review) string { return r.ID })
+}
+
+func (s *memStore) SaveDeployments(_ context.Context, deployments []*model.Deployment) error {
+	for _, d := range deployments {
+		if d.ID == "" {
+			d.ID = model.DeploymentID(d.RepositoryID, d.Environment, d.SHA, d.Ref, d.CreatedAt)
+		}
+		d.SchemaVersion = model.CurrentSchemaVersion
+	}
+	return saveAll(&s.mu, s.deployments, deployments, func(d *model.Deployment) string { return d.ID })
+}
+
+func (s *memStore) SaveTeamMembers(_ context.Context, members []*model.TeamMember) error {
+	return saveAll(&s.mu, s.members, members, func(m *model.TeamMember) string { return m.ID })
+}
+
+func (s *memStore) SaveDailyMetricsBatch(_ context.Context, metricsList []*model.DailyMetrics) error {
+	return saveAll(&s.mu, s.dailyMetrics, metricsList, func(m *model.DailyMetrics) string { return m.ID })
+}
+
+func (s *memStore) ListPullRequestsUpdatedSince(_ context.Context, repositoryID string, since time.Time) ([]*model.PullRequest, error) {
+	return s.filterPullRequests(func(pr *model.PullRequest) bool {
+		return pr.RepositoryID == repositoryID && !pr.UpdatedAt.Before(since)
+	}), nil
+}
+
+func (s *memStore) ListOpenPullRequests(_ context.Context, repositoryID string) ([]*model.PullRequest, error) {
+	return s.filterPullRequests(func(pr *model.PullRequest) bool {
+		return pr.RepositoryID == repositoryID && pr.State == "open"
+	}), nil
+}
+
+func (s *memStore) ListPullRequestsByDateRange(_ context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.PullRequest, error) {
+	return s.filterPullRequests(func(pr *model.PullRequest) bool {
+		return pr.RepositoryID == repositoryID && !pr.CreatedAt.Before(startDate) && !pr.CreatedAt.After(endDate)
+	}), nil
+}
+
+func (s *memStore) ListPullRequestsByAuthor(_ context.Context, repositoryID, author string) ([]*model.PullRequest, error) {
+	return s.filterPullRequests(func(pr *model.PullRequest) bool {
+		return pr.RepositoryID == repositoryID && pr.Author == author
+	}), nil
+}
+
+func (s *memStore) ListAuthors(_ context.Context, repositoryID string) ([]string, error) {
+	var authors []string
+	for _, pr := range s.filterPullRequests(func(pr *model.PullRequest) bool { return pr.RepositoryID == repositoryID }) {
+		authors = append(authors, pr.Author)
+	}
+	slices.Sort(authors)
+	return slices.Compact(authors), nil
+}
+
+func (s *memStore) ListReviewsByDateRange(_ context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reviews []*model.Review
+	for _, r := range values(s.reviews) {
+		if r.RepositoryID == repositoryID && !r.SubmittedAt.Before(startDate) && !r.SubmittedAt.After(endDate) {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews, nil
+}
+
+func (s *memStore) ListDeployments(_ context.Context, repositoryID string, opts *datastore.QueryOptions) ([]*model.Deployment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deployments []*model.Deployment
+	for _, d := range values(s.deployments) {
+		if d.RepositoryID != repositoryID {
+			continue
+		}
+		if opts != nil && !opts.Since.IsZero() && d.CreatedAt.Before(opts.Since) {
+			continue
+		}
+		if opts != nil && !opts.Until.IsZero() && d.CreatedAt.After(opts.Until) {
+			continue
+		}
+		deployments = append(deployments, d)
+	}
+	return deployments, nil
+}
+
+func (s *memStore) EachPullRequestByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.PullRequest) error) error {
+	prs, _ := s.ListPullRequestsByDateRange(ctx, repositoryID, startDate, endDate)
+	return eachOf(prs, fn)
+}
+
+func (s *memStore) EachReviewByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Review) error) error {
+	reviews, _ := s.ListReviewsByDateRange(ctx, repositoryID, startDate, endDate)
+	return eachOf(reviews, fn)
+}
+
+func (s *memStore) EachDeploymentByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Deployment) error) error {
+	deployments, _ := s.ListDeployments(ctx, repositoryID, &datastore.QueryOptions{Since: startDate, Until: endDate})
+	return eachOf(deployments, fn)
+}
+
+func (s *memStore) EachPullRequest(_ context.Context, repositoryID string, fn func(*model.PullRequest) error) error {
+	return eachOf(s.filterPullRequests(func(pr *model.PullRequest) bool { return pr.RepositoryID == repositoryID }), fn)
+}
+
+func (s *memStore) EachReview(_ context.Context, repositoryID string, fn func(*model.Review) error) error {
+	s.mu.Lock()
+	var reviews []*model.Review
+	for _, r := range values(s.reviews) {
+		if r.RepositoryID == repositoryID {
+			reviews = append(reviews, r)
+		}
+	}
+	s.mu.Unlock()
+	return eachOf(reviews, fn)
+}
+
+func (s *memStore) EachDeployment(ctx context.Context, repositoryID string, fn func(*model.Deployment) error) error {
+	deployments, _ := s.ListDeployments(ctx, repositoryID, nil)
+	return eachOf(deployments, fn)
+}
+
+func (s *memStore) CountBySchemaVersion(_ context.Context, kind string) (map[int]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[int]int64)
+	switch kind {
+	case datastore.KindRepository:
+		for _, r := range s.repos {
+			counts[r.SchemaVersion]++
+		}
+	case datastore.KindPullRequest:
+		for _, pr := range s.pullRequests {
+			counts[pr.SchemaVersion]++
+		}
+	case datastore.KindReview:
+		for _, r := range s.reviews {
+			counts[r.SchemaVersion]++
+		}
+	case datastore.KindDeployment:
+		for _, d := range s.deployments {
+			counts[d.SchemaVersion]++
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+	return counts, nil
+}
+
+func (s *memStore) CountKind(_ context.Context, kind, repositoryID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch kind {
+	case datastore.KindRepository:
+		return countWhere(s.repos, func(r *model.Repository) bool { return repositoryID == "" || r.ID == repositoryID }), nil
+	case datastore.KindPullRequest:
+		return countWhere(s.pullRequests, func(pr *model.PullRequest) bool { return repositoryID == "" || pr.RepositoryID == repositoryID }), nil
+	case datastore.KindReview:
+		return countWhere(s.reviews, func(r *model.Review) bool { return repositoryID == "" || r.RepositoryID == repositoryID }), nil
+	case datastore.KindDeployment:
+		return countWhere(s.deployments, func(d *model.Deployment) bool { return repositoryID == "" || d.RepositoryID == repositoryID }), nil
+	case datastore.KindDailyMetrics:
+		return countWhere(s.dailyMetrics, func(m *model.DailyMetrics) bool { return repositoryID == "" || m.RepositoryID == repositoryID }), nil
+	default:
+		return 0, fmt.Errorf("unknown kind %q", kind)
+	}
+}
+
+func (s *memStore) DeleteOlderThan(_ context.Context, repositoryID, kind string, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch kind {
+	case datastore.KindPullRequest:
+		return deleteWhere(s.pullRequests, func(pr *model.PullRequest) bool {
+			return pr.RepositoryID == repositoryID && pr.UpdatedAt.Before(cutoff)
+		}), nil
+	case datastore.KindReview:
+		return deleteWhere(s.reviews, func(r *model.Review) bool {
+			return r.RepositoryID == repositoryID && r.SubmittedAt.Before(cutoff)
+		}), nil
+	case datastore.KindDeployment:
+		return deleteWhere(s.deployments, func(d *model.Deployment) bool {
+			return d.RepositoryID == repositoryID && d.CreatedAt.Before(cutoff)
+		}), nil
+	default:
+		return 0, fmt.Errorf("kind %q is not subject to retention", kind)
+	}
+}
+
+// deleteWhere removes the map values matching drop, returning how many were removed.
+func deleteWhere[T any](m map[string]T, drop func(T) bool) int {
+	n := 0
+	for k, v := range m {
+		if drop(v) {
+			delete(m, k)
+			n++
+		}
+	}
+	return n
+}
+
+// countWhere counts the map values matching keep.
+func countWhere[T any](m map[string]T, keep func(T) bool) int64 {
+	var n int64
+	for _, v := range m {
+		if keep(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// eachOf calls fn for each item in order, stopping at the first error.
+func eachOf[T any](items []*T, fn func(*T) error) error {
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ListDailyMetrics(_ context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.DailyMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*model.DailyMetrics
+	for _, m := range values(s.dailyMetrics) {
+		if m.RepositoryID == repositoryID && !m.Date.Before(startDate) && !m.Date.After(endDate) {
+			result = append(result, m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result, nil
+}
+
+func (s *memStore) ListTeamMembers(_ context.Context) ([]*model.TeamMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return values(s.members), nil
+}
+
+func (s *memStore) SaveSprint(_ context.Context, sprint *model.Sprint) error {
+	return saveAll(&s.mu, s.sprints, []*model.Sprint{sprint}, func(sp *model.Sprint) string { return sp.ID })
+}
+
+func (s *memStore) GetSprint(_ context.Context, id string) (*model.Sprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sprint, ok := s.sprints[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return sprint, nil
+}
+
+func (s *memStore) DeleteSprint(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sprints, id)
+	return nil
+}
+
+func (s *memStore) ListSprints(_ context.Context, repositoryID string) ([]*model.Sprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sprints []*model.Sprint
+	for _, sprint := range values(s.sprints) {
+		if sprint.RepositoryID == repositoryID {
+			sprints = append(sprints, sprint)
+		}
+	}
+	return sprints, nil
+}
+
+func (s *memStore) SaveBotUser(_ context.Context, botUser *model.BotUser) error {
+	return saveAll(&s.mu, s.botUsers, []*model.BotUser{botUser}, func(b *model.BotUser) string { return b.Username })
+}
+
+func (s *memStore) ListBotUsers(_ context.Context) ([]*model.BotUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	botUsers := values(s.botUsers)
+	sort.Slice(botUsers, func(i, j int) bool { return botUsers[i].Username < botUsers[j].Username })
+	return botUsers, nil
+}
+
+func (s *memStore) DeleteBotUser(_ context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.botUsers, username)
+	return nil
+}
+
+func (s *memStore) ListBotUsernames(ctx context.Context) ([]string, error) {
+	botUsers, _ := s.ListBotUsers(ctx)
+	usernames := make([]string, len(botUsers))
+	for i, bu := range botUsers {
+		usernames[i] = bu.Username
+	}
+	return usernames, nil
+}
+
+func (s *memStore) GetSettings(_ context.Context) (*model.Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settings == nil {
+		return &model.Settings{}, nil
+	}
+	settings := *s.settings
+	return &settings, nil
+}
+
+func (s *memStore) SaveSettings(_ context.Context, settings *model.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *settings
+	s.settings = &saved
+	return nil
+}
+
+func (s *memStore) filterPullRequests(keep func(*model.PullRequest) bool) []*model.PullRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var prs []*model.PullRequest
+	for _, pr := range values(s.pullRequests) {
+		if keep(pr) {
+			prs = append(prs, pr)
+		}
+	}
+	return prs
+}
+
+func saveAll[T any](mu *sync.Mutex, m map[string]T, items []T, id func(T) string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, item := range items {
+		m[id(item)] = item
+	}
+	return nil
+}
+
+func values[T any](m map[string]T) []T {
+	result := make([]T, 0, len(m))
+	for _, v := range m {
+		result = append(result, v)
+	}
+	return result
+}
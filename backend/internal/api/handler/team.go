@@ -6,21 +6,21 @@ import (
 	"log/slog"
 	"net/http"
 	"sort"
-	"strings"
+	"strconv"
 	"time"
 
-	"github.com/compasstechlab/dora-yaki/internal/datastore"
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/metrics"
 )
 
 // TeamHandler handles team-related API requests
 type TeamHandler struct {
-	ds     *datastore.Client
+	ds     TeamStore
 	logger *slog.Logger
 }
 
 // NewTeamHandler creates a new TeamHandler
-func NewTeamHandler(ds *datastore.Client, logger *slog.Logger) *TeamHandler {
+func NewTeamHandler(ds TeamStore, logger *slog.Logger) *TeamHandler {
 	return &TeamHandler{
 		ds:     ds,
 		logger: logger,
@@ -45,6 +45,8 @@ type MemberStats struct {
 	ReviewsGiven            int                          `json:"reviewsGiven"`
 	CommentsGiven           int                          `json:"commentsGiven"`
 	AvgCycleTime            float64                      `json:"avgCycleTime"`
+	MedianCycleTime         float64                      `json:"medianCycleTime"`
+	P90CycleTime            float64                      `json:"p90CycleTime"`
 	AvgCodingTime           float64                      `json:"avgCodingTime"`
 	AvgPickupTime           float64                      `json:"avgPickupTime"`
 	AvgReviewTime           float64                      `json:"avgReviewTime"`
@@ -81,17 +83,111 @@ type MemberPullRequest struct {
 	ReviewTime float64    `json:"reviewTime"`
 	MergeTime  float64    `json:"mergeTime"`
 	RepoName   string     `json:"repoName"`
+
+	// IsFirstContribution is set when the author had no merged PR in the repository before this PR was opened.
+	IsFirstContribution bool `json:"isFirstContribution"`
+}
+
+// authorPullRequestStore lists an author's stored PRs in a repository.
+type authorPullRequestStore interface {
+	ListPullRequestsByAuthor(ctx context.Context, repositoryID, author string) ([]*model.PullRequest, error)
+}
+
+// repoAuthor identifies an author within a repository.
+type repoAuthor struct {
+	repositoryID string
+	author       string
+}
+
+// firstContributions pre-scans the stored PRs of each author in prs for their earliest merge per repository,
+// and returns a function reporting whether a PR is its author's first contribution.
+// PRs whose author history cannot be loaded are not flagged.
+func firstContributions(ctx context.Context, ds authorPullRequestStore, logger *slog.Logger, prs []*model.PullRequest) func(*model.PullRequest) bool {
+	firstMerges := make(map[repoAuthor]*time.Time)
+	for _, pr := range prs {
+		key := repoAuthor{pr.RepositoryID, pr.Author}
+		if _, ok := firstMerges[key]; ok {
+			continue
+		}
+		history, err := ds.ListPullRequestsByAuthor(ctx, pr.RepositoryID, pr.Author)
+		if err != nil {
+			logger.Warn("failed to list pull requests by author", "repository", pr.RepositoryID, "author", pr.Author, "error", err)
+			continue
+		}
+		var first *time.Time
+		for _, h := range history {
+			if h.MergedAt != nil && (first == nil || h.MergedAt.Before(*first)) {
+				first = h.MergedAt
+			}
+		}
+		firstMerges[key] = first
+	}
+
+	return func(pr *model.PullRequest) bool {
+		first, ok := firstMerges[repoAuthor{pr.RepositoryID, pr.Author}]
+		return ok && (first == nil || !first.Before(pr.CreatedAt))
+	}
+}
+
+// Member list sort keys
+const (
+	memberSortLogin        = "login"
+	memberSortPRsAuthored  = "prsAuthored"
+	memberSortReviewsGiven = "reviewsGiven"
+)
+
+// memberListQuery holds the paging and sort parameters of a member list request.
+type memberListQuery struct {
+	sort   string
+	limit  int // 0 = no limit
+	offset int
 }
 
-// ListMembers lists all team members
+// parseMemberListQuery parses ?sort=, ?limit= and ?offset=.
+func parseMemberListQuery(r *http.Request) (memberListQuery, error) {
+	q := r.URL.Query()
+	query := memberListQuery{sort: memberSortLogin}
+
+	switch s := q.Get("sort"); s {
+	case "", memberSortLogin:
+	case memberSortPRsAuthored, memberSortReviewsGiven:
+		query.sort = s
+	default:
+		return query, fmt.Errorf("invalid sort %q (use login, prsAuthored or reviewsGiven)", s)
+	}
+
+	for name, dst := range map[string]*int{"limit": &query.limit, "offset": &query.offset} {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return query, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		*dst = v
+	}
+	return query, nil
+}
+
+// ListMembers lists team members, sorted by ?sort= (login by default) and paged by ?limit= / ?offset=.
+// Activity sorts (prsAuthored, reviewsGiven) count PRs and reviews in the date range and
+// repositories of the request, so they cost a PR and review query per repository.
+// The response stays a plain array; a page shorter than limit is the last one.
 func (h *TeamHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	bf := parseBotFilter(r)
 
+	query, err := parseMemberListQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
 	members, err := h.ds.ListTeamMembers(ctx)
 	if err != nil {
 		h.logger.Error("failed to list team members", "error", err)
-		http.Error(w, "failed to list team members", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to list team members")
 		return
 	}
 
@@ -99,7 +195,52 @@ func (h *TeamHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 	botUsernames := h.getBotUsernames(ctx)
 	members = model.FilterTeamMembersByBot(members, botUsernames, bf.excludeBots, bf.botsOnly)
 
-	respondJSON(w, http.StatusOK, members)
+	var counts map[string]int
+	if query.sort != memberSortLogin {
+		repoIDs, err := h.getRepositoryIDs(r)
+		if err != nil {
+			h.logger.Error("failed to get repository IDs", "error", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+			return
+		}
+		startDate, endDate := parseDateRange(r)
+		counts = make(map[string]int)
+		if query.sort == memberSortPRsAuthored {
+			for _, pr := range h.collectPullRequests(ctx, repoIDs, startDate, endDate) {
+				counts[pr.Author]++
+			}
+		} else {
+			for _, review := range h.collectReviews(ctx, repoIDs, startDate, endDate) {
+				counts[review.Reviewer]++
+			}
+		}
+	}
+
+	sortMembers(members, counts)
+	respondJSON(w, http.StatusOK, pageMembers(members, query.offset, query.limit))
+}
+
+// sortMembers sorts by count per login descending when counts is non-nil, otherwise by login.
+// Ties are ordered by login.
+func sortMembers(members []*model.TeamMember, counts map[string]int) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if ci, cj := counts[members[i].Login], counts[members[j].Login]; ci != cj {
+			return ci > cj
+		}
+		return members[i].Login < members[j].Login
+	})
+}
+
+// pageMembers returns the page of members starting at offset; limit 0 returns the rest.
+func pageMembers(members []*model.TeamMember, offset, limit int) []*model.TeamMember {
+	if offset >= len(members) {
+		return []*model.TeamMember{}
+	}
+	members = members[offset:]
+	if limit > 0 && limit < len(members) {
+		members = members[:limit]
+	}
+	return members
 }
 
 // getRepositoryIDs retrieves multiple repository IDs. Returns all repositories if empty.
@@ -109,56 +250,38 @@ func (h *TeamHandler) getRepositoryIDs(r *http.Request) ([]string, error) {
 		return ids, nil
 	}
 	// Return all registered repositories if not specified
-	repos, err := h.ds.ListRepositories(r.Context())
+	all, err := h.ds.ListRepositoryIDs(r.Context())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
-	all := make([]string, len(repos))
-	for i, repo := range repos {
-		all[i] = repo.ID
-	}
 	return all, nil
 }
 
 // collectPullRequests collects PRs from multiple repositories.
 func (h *TeamHandler) collectPullRequests(ctx context.Context, repoIDs []string, start, end time.Time) []*model.PullRequest {
-	var result []*model.PullRequest
-	for _, id := range repoIDs {
-		prs, err := h.ds.ListPullRequestsByDateRange(ctx, id, start, end)
-		if err != nil {
-			h.logger.Warn("failed to list pull requests for repo", "repository", id, "error", err)
-			continue
-		}
-		result = append(result, prs...)
-	}
-	return result
+	return collectPerRepository(ctx, h.logger, repoIDs, "pull requests", func(ctx context.Context, id string) ([]*model.PullRequest, error) {
+		return h.ds.ListPullRequestsByDateRange(ctx, id, start, end)
+	})
 }
 
 // collectReviews collects reviews from multiple repositories.
 func (h *TeamHandler) collectReviews(ctx context.Context, repoIDs []string, start, end time.Time) []*model.Review {
-	var result []*model.Review
-	for _, id := range repoIDs {
-		reviews, err := h.ds.ListReviewsByDateRange(ctx, id, start, end)
-		if err != nil {
-			h.logger.Warn("failed to list reviews for repo", "repository", id, "error", err)
-			continue
-		}
-		result = append(result, reviews...)
-	}
-	return result
+	return collectPerRepository(ctx, h.logger, repoIDs, "reviews", func(ctx context.Context, id string) ([]*model.Review, error) {
+		return h.ds.ListReviewsByDateRange(ctx, id, start, end)
+	})
 }
 
 // GetMemberStats returns statistics for a specific team member
 func (h *TeamHandler) GetMemberStats(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	memberID := getMemberID(r)
+	memberID := getPathParam(r, "id")
 	startDate, endDate := parseDateRange(r)
 
 	// Get multiple repository IDs
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
@@ -166,20 +289,14 @@ func (h *TeamHandler) GetMemberStats(w http.ResponseWriter, r *http.Request) {
 	members, err := h.ds.ListTeamMembers(ctx)
 	if err != nil {
 		h.logger.Error("failed to get team members", "error", err)
-		http.Error(w, "failed to get member stats", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get member stats")
 		return
 	}
 
-	var member *model.TeamMember
-	for _, m := range members {
-		if m.ID == memberID || m.Login == memberID {
-			member = m
-			break
-		}
-	}
+	member := findMember(members, memberID)
 
 	if member == nil {
-		http.Error(w, "member not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "member not found")
 		return
 	}
 
@@ -191,20 +308,64 @@ func (h *TeamHandler) GetMemberStats(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, stats)
 }
 
-func getMemberID(r *http.Request) string {
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
+// CompareMembers returns stats for each ?member= (ID or login, two or more) over the same
+// date range and repositories, in request order. PRs and reviews are collected once and
+// grouped by author/reviewer, so the cost does not grow with the number of members.
+func (h *TeamHandler) CompareMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ids := r.URL.Query()["member"]
+	if len(ids) < 2 {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "at least two member parameters are required")
+		return
+	}
+	startDate, endDate := parseDateRange(r)
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
 
-	// /api/team/members/{id}/stats or /api/team/members/{id}/pull-requests or /api/team/members/{id}/reviews
-	if len(parts) >= 5 {
-		suffix := parts[len(parts)-1]
-		if suffix == "stats" || suffix == "pull-requests" || suffix == "reviews" {
-			return parts[len(parts)-2]
+	members, err := h.ds.ListTeamMembers(ctx)
+	if err != nil {
+		h.logger.Error("failed to get team members", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to compare members")
+		return
+	}
+
+	selected := make([]*model.TeamMember, len(ids))
+	for i, id := range ids {
+		if selected[i] = findMember(members, id); selected[i] == nil {
+			respondError(w, http.StatusNotFound, errCodeNotFound, fmt.Sprintf("member %q not found", id))
+			return
 		}
-		return parts[len(parts)-1]
 	}
 
-	return ""
+	prsByAuthor := make(map[string][]*model.PullRequest)
+	for _, pr := range h.collectPullRequests(ctx, repoIDs, startDate, endDate) {
+		prsByAuthor[pr.Author] = append(prsByAuthor[pr.Author], pr)
+	}
+	reviewsByReviewer := make(map[string][]*model.Review)
+	for _, review := range h.collectReviews(ctx, repoIDs, startDate, endDate) {
+		reviewsByReviewer[review.Reviewer] = append(reviewsByReviewer[review.Reviewer], review)
+	}
+
+	result := make([]*MemberStats, len(selected))
+	for i, member := range selected {
+		result[i] = calculateMemberStats(member, prsByAuthor[member.Login], reviewsByReviewer[member.Login])
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// findMember returns the member whose ID or login matches id, or nil.
+func findMember(members []*model.TeamMember, id string) *model.TeamMember {
+	for _, m := range members {
+		if m.ID == id || m.Login == id {
+			return m
+		}
+	}
+	return nil
 }
 
 func calculateMemberStats(member *model.TeamMember, prs []*model.PullRequest, reviews []*model.Review) *MemberStats {
@@ -213,9 +374,7 @@ func calculateMemberStats(member *model.TeamMember, prs []*model.PullRequest, re
 	}
 
 	// For cycle time breakdown aggregation
-	var codingTimes, pickupTimes, reviewTimes, mergeTimes []float64
-	var cycleTimesSum float64
-	var cycleTimeCount int
+	var cycleTimes, codingTimes, pickupTimes, reviewTimes, mergeTimes []float64
 
 	// For file extension aggregation
 	type extAgg struct {
@@ -237,9 +396,7 @@ func calculateMemberStats(member *model.TeamMember, prs []*model.PullRequest, re
 
 				// Calculate cycle time
 				if pr.FirstCommitAt != nil {
-					cycleTime := pr.MergedAt.Sub(*pr.FirstCommitAt).Hours()
-					cycleTimesSum += cycleTime
-					cycleTimeCount++
+					cycleTimes = append(cycleTimes, pr.MergedAt.Sub(*pr.FirstCommitAt).Hours())
 				}
 
 				// Cycle time breakdown
@@ -276,9 +433,9 @@ func calculateMemberStats(member *model.TeamMember, prs []*model.PullRequest, re
 		}
 	}
 
-	if cycleTimeCount > 0 {
-		stats.AvgCycleTime = cycleTimesSum / float64(cycleTimeCount)
-	}
+	stats.AvgCycleTime = avgFloat(cycleTimes)
+	stats.MedianCycleTime = metrics.Median(cycleTimes)
+	stats.P90CycleTime = metrics.Percentile(cycleTimes, 90)
 	stats.AvgCodingTime = avgFloat(codingTimes)
 	stats.AvgPickupTime = avgFloat(pickupTimes)
 	stats.AvgReviewTime = avgFloat(reviewTimes)
@@ -329,13 +486,13 @@ func calculateMemberStats(member *model.TeamMember, prs []*model.PullRequest, re
 // GetMemberPullRequests returns a list of pull requests for a member.
 func (h *TeamHandler) GetMemberPullRequests(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	memberID := getMemberID(r)
+	memberID := getPathParam(r, "id")
 	startDate, endDate := parseDateRange(r)
 
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
@@ -343,19 +500,13 @@ func (h *TeamHandler) GetMemberPullRequests(w http.ResponseWriter, r *http.Reque
 	members, err := h.ds.ListTeamMembers(ctx)
 	if err != nil {
 		h.logger.Error("failed to get team members", "error", err)
-		http.Error(w, "failed to get member", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get member")
 		return
 	}
 
-	var member *model.TeamMember
-	for _, m := range members {
-		if m.ID == memberID || m.Login == memberID {
-			member = m
-			break
-		}
-	}
+	member := findMember(members, memberID)
 	if member == nil {
-		http.Error(w, "member not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "member not found")
 		return
 	}
 
@@ -367,12 +518,16 @@ func (h *TeamHandler) GetMemberPullRequests(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Collect and filter PRs
-	prs := h.collectPullRequests(ctx, repoIDs, startDate, endDate)
+	var prs []*model.PullRequest
+	for _, pr := range h.collectPullRequests(ctx, repoIDs, startDate, endDate) {
+		if pr.Author == member.Login {
+			prs = append(prs, pr)
+		}
+	}
+	isFirst := firstContributions(ctx, h.ds, h.logger, prs)
+
 	result := make([]MemberPullRequest, 0, len(prs))
 	for _, pr := range prs {
-		if pr.Author != member.Login {
-			continue
-		}
 		result = append(result, MemberPullRequest{
 			Number:     pr.Number,
 			Title:      pr.Title,
@@ -387,6 +542,8 @@ func (h *TeamHandler) GetMemberPullRequests(w http.ResponseWriter, r *http.Reque
 			ReviewTime: pr.ReviewTimeHours(),
 			MergeTime:  pr.MergeTimeHours(),
 			RepoName:   repoNameMap[pr.RepositoryID],
+
+			IsFirstContribution: isFirst(pr),
 		})
 	}
 
@@ -398,16 +555,59 @@ func (h *TeamHandler) GetMemberPullRequests(w http.ResponseWriter, r *http.Reque
 	respondJSON(w, http.StatusOK, result)
 }
 
+// GetMemberOnboarding returns ramp-up metrics for a member: first PR, time to first merge,
+// and activity in the 30 days after their first PR. All of the member's PRs are considered,
+// regardless of the date range.
+func (h *TeamHandler) GetMemberOnboarding(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	memberID := getPathParam(r, "id")
+
+	repoIDs, err := h.getRepositoryIDs(r)
+	if err != nil {
+		h.logger.Error("failed to get repository IDs", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
+		return
+	}
+
+	members, err := h.ds.ListTeamMembers(ctx)
+	if err != nil {
+		h.logger.Error("failed to get team members", "error", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get member")
+		return
+	}
+	member := findMember(members, memberID)
+	if member == nil {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "member not found")
+		return
+	}
+
+	var prs []*model.PullRequest
+	for _, id := range repoIDs {
+		repoPRs, err := h.ds.ListPullRequestsByAuthor(ctx, id, member.Login)
+		if err != nil {
+			h.logger.Warn("failed to list member pull requests for repo", "repository", id, "error", err)
+			continue
+		}
+		prs = append(prs, repoPRs...)
+	}
+
+	calc := metrics.NewCalculator()
+	if r.URL.Query().Get("business_hours") == "true" {
+		calc = calc.WithBusinessHours(metrics.DefaultBusinessHours())
+	}
+	respondJSON(w, http.StatusOK, calc.CalculateOnboarding(prs))
+}
+
 // GetMemberReviews returns a list of reviews for a member.
 func (h *TeamHandler) GetMemberReviews(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	memberID := getMemberID(r)
+	memberID := getPathParam(r, "id")
 	startDate, endDate := parseDateRange(r)
 
 	repoIDs, err := h.getRepositoryIDs(r)
 	if err != nil {
 		h.logger.Error("failed to get repository IDs", "error", err)
-		http.Error(w, "failed to get repository IDs", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get repository IDs")
 		return
 	}
 
@@ -415,19 +615,13 @@ func (h *TeamHandler) GetMemberReviews(w http.ResponseWriter, r *http.Request) {
 	members, err := h.ds.ListTeamMembers(ctx)
 	if err != nil {
 		h.logger.Error("failed to get team members", "error", err)
-		http.Error(w, "failed to get member", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "failed to get member")
 		return
 	}
 
-	var member *model.TeamMember
-	for _, m := range members {
-		if m.ID == memberID || m.Login == memberID {
-			member = m
-			break
-		}
-	}
+	member := findMember(members, memberID)
 	if member == nil {
-		http.Error(w, "member not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, errCodeNotFound, "member not found")
 		return
 	}
 
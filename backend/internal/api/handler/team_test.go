@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// mergedPR builds a merged PR whose cycle time (first commit to merge) is the given hours.
+func mergedPR(author string, created time.Time, cycleHours float64) *model.PullRequest {
+	firstCommit := created
+	merged := created.Add(time.Duration(cycleHours * float64(time.Hour)))
+	return &model.PullRequest{
+		Author:        author,
+		CreatedAt:     created,
+		FirstCommitAt: &firstCommit,
+		MergedAt:      &merged,
+	}
+}
+
+func TestCalculateMemberStats_CycleTimePercentiles(t *testing.T) {
+	member := &model.TeamMember{ID: "1", Login: "alice"}
+	created := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	// Skewed set: four quick PRs and one outlier
+	prs := []*model.PullRequest{
+		mergedPR("alice", created, 2),
+		mergedPR("alice", created, 4),
+		mergedPR("alice", created, 4),
+		mergedPR("alice", created, 6),
+		mergedPR("alice", created, 200),
+		mergedPR("bob", created, 1000), // other member is ignored
+	}
+
+	stats := calculateMemberStats(member, prs, nil)
+
+	if stats.PRsMerged != 5 {
+		t.Fatalf("PRsMerged = %d, want 5", stats.PRsMerged)
+	}
+	if math.Abs(stats.AvgCycleTime-43.2) > 1e-9 {
+		t.Errorf("AvgCycleTime = %v, want 43.2", stats.AvgCycleTime)
+	}
+	if math.Abs(stats.MedianCycleTime-4) > 1e-9 {
+		t.Errorf("MedianCycleTime = %v, want 4 (unaffected by the outlier)", stats.MedianCycleTime)
+	}
+	// p90 over [2,4,4,6,200]: index 3.6 -> 6 + 0.6*(200-6)
+	if math.Abs(stats.P90CycleTime-122.4) > 1e-9 {
+		t.Errorf("P90CycleTime = %v, want 122.4", stats.P90CycleTime)
+	}
+}
+
+func TestCalculateMemberStats_NoMergedPRs(t *testing.T) {
+	member := &model.TeamMember{ID: "1", Login: "alice"}
+	prs := []*model.PullRequest{{Author: "alice", CreatedAt: time.Now()}}
+
+	stats := calculateMemberStats(member, prs, nil)
+	if stats.MedianCycleTime != 0 || stats.P90CycleTime != 0 {
+		t.Errorf("got median=%v p90=%v, want 0 for no merged PRs", stats.MedianCycleTime, stats.P90CycleTime)
+	}
+}
+
+func TestTeamHandler_GetMemberOnboarding(t *testing.T) {
+	first := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+	mergedAt := first.AddDate(0, 0, 2)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b"}
+	store.members["1"] = &model.TeamMember{ID: "1", Login: "newbie"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		// Older than any default date range; onboarding uses the member's full history
+		{ID: "repo-a#1", RepositoryID: "repo-a", Author: "newbie", CreatedAt: first},
+		{ID: "repo-b#7", RepositoryID: "repo-b", Author: "newbie", CreatedAt: first.AddDate(0, 0, 1), MergedAt: &mergedAt},
+		{ID: "repo-a#2", RepositoryID: "repo-a", Author: "veteran", CreatedAt: first.AddDate(-1, 0, 0)},
+	})
+	h := NewTeamHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/team/members/{id}/onboarding", h.GetMemberOnboarding)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	w := serve("/api/team/members/newbie/onboarding")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got model.OnboardingMetrics
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.FirstPRAt == nil || !got.FirstPRAt.Equal(first) {
+		t.Errorf("FirstPRAt = %v, want %v", got.FirstPRAt, first)
+	}
+	if got.TotalPRs != 2 || got.TotalMerged != 1 || got.TimeToFirstMerge != 48 {
+		t.Errorf("got %d PRs / %d merged / %vh to first merge, want 2 / 1 / 48h", got.TotalPRs, got.TotalMerged, got.TimeToFirstMerge)
+	}
+
+	if w := serve("/api/team/members/ghost/onboarding"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown member status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTeamHandler_ListMembers_SortAndPaging(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	for _, login := range []string{"carol", "alice", "bob", "dave"} {
+		store.members[login] = &model.TeamMember{ID: login, Login: login}
+	}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-a#1", RepositoryID: "repo-a", Author: "bob", CreatedAt: day},
+		{ID: "repo-a#2", RepositoryID: "repo-a", Author: "bob", CreatedAt: day},
+		{ID: "repo-a#3", RepositoryID: "repo-a", Author: "carol", CreatedAt: day},
+		{ID: "repo-a#4", RepositoryID: "repo-a", Author: "dave", CreatedAt: day.AddDate(-1, 0, 0)}, // outside the range
+	})
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		{ID: "r1", RepositoryID: "repo-a", Reviewer: "dave", SubmittedAt: day},
+		{ID: "r2", RepositoryID: "repo-a", Reviewer: "dave", SubmittedAt: day},
+		{ID: "r3", RepositoryID: "repo-a", Reviewer: "alice", SubmittedAt: day},
+	})
+	h := NewTeamHandler(store, slog.Default())
+
+	logins := func(query string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ListMembers(w, httptest.NewRequest("GET", "/api/team/members?start=2026-03-01&end=2026-03-31"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", query, w.Code, w.Body.String())
+		}
+		var members []*model.TeamMember
+		if err := json.NewDecoder(w.Body).Decode(&members); err != nil {
+			t.Fatalf("%s: decode response: %v", query, err)
+		}
+		got := make([]string, 0, len(members))
+		for _, m := range members {
+			got = append(got, m.Login)
+		}
+		return got
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"alice", "bob", "carol", "dave"}},
+		{"&sort=login", []string{"alice", "bob", "carol", "dave"}},
+		{"&sort=prsAuthored", []string{"bob", "carol", "alice", "dave"}},
+		{"&sort=reviewsGiven", []string{"dave", "alice", "bob", "carol"}},
+		{"&limit=2", []string{"alice", "bob"}},
+		{"&limit=2&offset=2", []string{"carol", "dave"}},
+		{"&offset=3", []string{"dave"}},
+		{"&offset=10", []string{}},
+		{"&sort=prsAuthored&limit=1&offset=1", []string{"carol"}},
+	}
+	for _, tt := range tests {
+		if got := logins(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?sort=stars", "?limit=-1", "?offset=x"} {
+		w := httptest.NewRecorder()
+		h.ListMembers(w, httptest.NewRequest("GET", "/api/team/members"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestTeamHandler_CompareMembers(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	store.repos["repo-b"] = &model.Repository{ID: "repo-b"}
+	store.members["1"] = &model.TeamMember{ID: "1", Login: "alice"}
+	store.members["2"] = &model.TeamMember{ID: "2", Login: "bob"}
+	merged := mergedPR("alice", day, 10)
+	merged.ID, merged.RepositoryID = "repo-a#1", "repo-a"
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		merged,
+		{ID: "repo-b#1", RepositoryID: "repo-b", Author: "alice", CreatedAt: day},
+		{ID: "repo-a#2", RepositoryID: "repo-a", Author: "bob", CreatedAt: day},
+	})
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		// Each member reviews the other's PR
+		{ID: "r1", RepositoryID: "repo-a", PullRequestID: "repo-a#1", Reviewer: "bob", State: "APPROVED", SubmittedAt: day},
+		{ID: "r2", RepositoryID: "repo-a", PullRequestID: "repo-a#2", Reviewer: "alice", State: "COMMENTED", SubmittedAt: day},
+		{ID: "r3", RepositoryID: "repo-b", PullRequestID: "repo-b#1", Reviewer: "bob", State: "CHANGES_REQUESTED", SubmittedAt: day},
+	})
+	h := NewTeamHandler(store, slog.Default())
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.CompareMembers(w, httptest.NewRequest("GET", "/api/team/compare?start=2026-03-01&end=2026-03-31"+query, nil))
+		return w
+	}
+
+	w := serve("&member=bob&member=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got []*MemberStats
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got) != 2 || got[0].Member.Login != "bob" || got[1].Member.Login != "alice" {
+		t.Fatalf("got %d entries, want bob then alice in request order", len(got))
+	}
+	bob, alice := got[0], got[1]
+	if bob.PRsAuthored != 1 || bob.PRsMerged != 0 || bob.ReviewsGiven != 2 || bob.ReviewsApproved != 1 {
+		t.Errorf("bob = %d PRs / %d merged / %d reviews / %d approved, want 1 / 0 / 2 / 1",
+			bob.PRsAuthored, bob.PRsMerged, bob.ReviewsGiven, bob.ReviewsApproved)
+	}
+	if alice.PRsAuthored != 2 || alice.PRsMerged != 1 || alice.AvgCycleTime != 10 || alice.ReviewsGiven != 1 {
+		t.Errorf("alice = %d PRs / %d merged / %vh cycle / %d reviews, want 2 / 1 / 10h / 1",
+			alice.PRsAuthored, alice.PRsMerged, alice.AvgCycleTime, alice.ReviewsGiven)
+	}
+
+	// Repository filter applies to every member
+	w = serve("&member=alice&member=bob&repository=repo-b")
+	got = nil
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got) != 2 || got[0].PRsAuthored != 1 || got[1].ReviewsGiven != 1 {
+		t.Errorf("repo-b only: want alice with 1 PR and bob with 1 review")
+	}
+
+	if w := serve("&member=alice"); w.Code != http.StatusBadRequest {
+		t.Errorf("single member status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := serve("&member=alice&member=ghost"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown member status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTeamHandler_MemberRoutes_EncodedID(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a"}
+	store.members["1"] = &model.TeamMember{ID: "1", Login: "renovate[bot]"}
+	store.members["2"] = &model.TeamMember{ID: "2", Login: "stats"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-a#1", RepositoryID: "repo-a", Author: "renovate[bot]", CreatedAt: day},
+	})
+	h := NewTeamHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/team/members/{id}/stats", h.GetMemberStats)
+	mux.HandleFunc("GET /api/team/members/{id}/pull-requests", h.GetMemberPullRequests)
+
+	tests := []struct {
+		target string
+		login  string
+	}{
+		{"/api/team/members/renovate%5Bbot%5D/stats?start=2026-03-01&end=2026-03-31", "renovate[bot]"},
+		{"/api/team/members/1/stats?start=2026-03-01&end=2026-03-31", "renovate[bot]"},
+		// A login equal to a route suffix must not be confused with the suffix
+		{"/api/team/members/stats/stats", "stats"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", tt.target, w.Code, w.Body.String())
+		}
+		var got MemberStats
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: decode response: %v", tt.target, err)
+		}
+		if got.Member.Login != tt.login {
+			t.Errorf("%s: member = %q, want %q", tt.target, got.Member.Login, tt.login)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/team/members/renovate%5Bbot%5D/pull-requests?start=2026-03-01&end=2026-03-31", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("pull-requests status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTeamHandler_MemberRoutes_LoginMatchesSuffix(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	store := newMemStore()
+	store.repos["repo-a"] = &model.Repository{ID: "repo-a", FullName: "org/repo-a"}
+	store.members["1"] = &model.TeamMember{ID: "1", Login: "reviews"}
+	store.members["2"] = &model.TeamMember{ID: "2", Login: "alice"}
+	_ = store.SavePullRequests(t.Context(), []*model.PullRequest{
+		{ID: "repo-a#1", RepositoryID: "repo-a", Number: 1, Author: "reviews", CreatedAt: day},
+		{ID: "repo-a#2", RepositoryID: "repo-a", Number: 2, Author: "alice", CreatedAt: day},
+	})
+	_ = store.SaveReviews(t.Context(), []*model.Review{
+		{ID: "r1", RepositoryID: "repo-a", Reviewer: "reviews", State: "APPROVED", SubmittedAt: day},
+		{ID: "r2", RepositoryID: "repo-a", Reviewer: "alice", State: "COMMENTED", SubmittedAt: day},
+		{ID: "r3", RepositoryID: "repo-a", Reviewer: "alice", State: "COMMENTED", SubmittedAt: day},
+	})
+	h := NewTeamHandler(store, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/team/members/{id}/stats", h.GetMemberStats)
+	mux.HandleFunc("GET /api/team/members/{id}/pull-requests", h.GetMemberPullRequests)
+	mux.HandleFunc("GET /api/team/members/{id}/reviews", h.GetMemberReviews)
+	mux.HandleFunc("GET /api/team/members/{id}/onboarding", h.GetMemberOnboarding)
+
+	serve := func(sub string, v any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/team/members/reviews/"+sub+"?start=2026-03-01&end=2026-03-31", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", sub, w.Code, w.Body.String())
+		}
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("%s: decode response: %v", sub, err)
+		}
+	}
+
+	var stats MemberStats
+	serve("stats", &stats)
+	if stats.Member.Login != "reviews" || stats.PRsAuthored != 1 || stats.ReviewsGiven != 1 {
+		t.Errorf("stats = %q with %d PRs / %d reviews, want reviews with 1 / 1", stats.Member.Login, stats.PRsAuthored, stats.ReviewsGiven)
+	}
+
+	var prs []MemberPullRequest
+	serve("pull-requests", &prs)
+	if len(prs) != 1 || prs[0].Number != 1 {
+		t.Errorf("pull-requests = %+v, want only #1", prs)
+	}
+
+	var reviews []MemberReview
+	serve("reviews", &reviews)
+	if len(reviews) != 1 || reviews[0].State != "APPROVED" {
+		t.Errorf("reviews = %+v, want the single APPROVED review", reviews)
+	}
+
+	var onboarding model.OnboardingMetrics
+	serve("onboarding", &onboarding)
+	if onboarding.TotalPRs != 1 {
+		t.Errorf("onboarding TotalPRs = %d, want 1", onboarding.TotalPRs)
+	}
+}
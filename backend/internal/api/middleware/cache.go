@@ -3,8 +3,12 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,26 +21,81 @@ type CacheEntry struct {
 	contentType string
 	statusCode  int
 	createdAt   time.Time
+	ttl         time.Duration
+	headers     map[string]string // values of cachedHeaders set by the handler
+}
+
+// cachedHeaders are the response headers kept with a cached body in both tiers.
+var cachedHeaders = []string{DateRangeStartHeader, DateRangeEndHeader, DateRangeTimezoneHeader}
+
+// cacheStore is the Datastore tier of the response cache.
+type cacheStore interface {
+	GetMetricsCache(ctx context.Context, cacheKey string) (*datastore.MetricsCacheEntry, error)
+	PutMetricsCache(ctx context.Context, cacheKey string, body []byte, headers map[string]string, ttlSec int) error
+	DeleteAllMetricsCache(ctx context.Context) error
 }
 
 // ResponseCache is a 3-tier cache: in-memory → Datastore → handler (live query).
+// Without a Datastore client only the in-memory tier is used.
 type ResponseCache struct {
 	mu      sync.RWMutex
 	entries map[string]*CacheEntry
 	ttl     time.Duration
 	ttlSec  int
-	ds      *datastore.Client
+	ds      cacheStore
 	logger  *slog.Logger
+
+	statsMu sync.Mutex
+	stats   cacheCounters
+	costs   map[string]time.Duration // handler duration of the last miss per key
+}
+
+// cacheCounters holds cumulative cache accounting since startup.
+type cacheCounters struct {
+	memoryHits     int64
+	datastoreHits  int64
+	misses         int64
+	bypasses       int64
+	unmeasuredHits int64
+	timeSaved      time.Duration
 }
 
+// CacheStats reports cache efficiency since startup.
+type CacheStats struct {
+	Entries       int     `json:"entries"`
+	MemoryHits    int64   `json:"memoryHits"`
+	DatastoreHits int64   `json:"datastoreHits"`
+	Misses        int64   `json:"misses"`
+	Bypasses      int64   `json:"bypasses"`
+	HitRate       float64 `json:"hitRate"` // percentage of hits among hits and misses
+
+	// Estimated savings: each hit avoids re-running the handler's live Datastore queries.
+	// TimeSavedMs sums the measured duration of the last miss for each hit key;
+	// hits with no measured miss (e.g. Datastore hits after a restart) are counted as unmeasured.
+	HandlerCallsSaved   int64   `json:"handlerCallsSaved"`
+	DatastoreReadsSaved int64   `json:"datastoreReadsSaved"` // memory hits also skip the Datastore cache read
+	TimeSavedMs         float64 `json:"timeSavedMs"`
+	UnmeasuredHits      int64   `json:"unmeasuredHits"`
+}
+
+// Cache hit tiers
+const (
+	tierMemory    = "memory"
+	tierDatastore = "datastore"
+)
+
 // NewResponseCache creates a new 3-tier response cache.
 func NewResponseCache(ttl time.Duration, ds *datastore.Client, logger *slog.Logger) *ResponseCache {
 	rc := &ResponseCache{
 		entries: make(map[string]*CacheEntry),
 		ttl:     ttl,
 		ttlSec:  int(ttl.Seconds()),
-		ds:      ds,
 		logger:  logger,
+		costs:   make(map[string]time.Duration),
+	}
+	// Leave the interface nil (memory-only) rather than wrapping a nil client
+	if ds != nil {
+		rc.ds = ds
 	}
 	go rc.cleanup()
 	return rc
@@ -50,20 +109,89 @@ func (rc *ResponseCache) cleanup() {
 		rc.mu.Lock()
 		now := time.Now()
 		for key, entry := range rc.entries {
-			if now.Sub(entry.createdAt) > rc.ttl {
+			if now.Sub(entry.createdAt) > entry.ttl {
 				delete(rc.entries, key)
+				rc.forgetCost(key)
 			}
 		}
 		rc.mu.Unlock()
 	}
 }
 
+// recordMiss records a handler execution and its duration for the key.
+func (rc *ResponseCache) recordMiss(key string, d time.Duration, bypass bool) {
+	rc.statsMu.Lock()
+	defer rc.statsMu.Unlock()
+	if bypass {
+		rc.stats.bypasses++
+	} else {
+		rc.stats.misses++
+	}
+	rc.costs[key] = d
+}
+
+// recordHit records a cache hit and credits the last measured miss cost for the key.
+func (rc *ResponseCache) recordHit(key, tier string) {
+	rc.statsMu.Lock()
+	defer rc.statsMu.Unlock()
+	switch tier {
+	case tierMemory:
+		rc.stats.memoryHits++
+	case tierDatastore:
+		rc.stats.datastoreHits++
+	}
+	if cost, ok := rc.costs[key]; ok {
+		rc.stats.timeSaved += cost
+	} else {
+		rc.stats.unmeasuredHits++
+	}
+}
+
+// forgetCost drops the measured cost for an expired key.
+func (rc *ResponseCache) forgetCost(key string) {
+	rc.statsMu.Lock()
+	delete(rc.costs, key)
+	rc.statsMu.Unlock()
+}
+
+// Stats returns cache efficiency statistics since startup.
+func (rc *ResponseCache) Stats() CacheStats {
+	rc.mu.RLock()
+	entries := len(rc.entries)
+	rc.mu.RUnlock()
+
+	rc.statsMu.Lock()
+	c := rc.stats
+	rc.statsMu.Unlock()
+
+	hits := c.memoryHits + c.datastoreHits
+	stats := CacheStats{
+		Entries:             entries,
+		MemoryHits:          c.memoryHits,
+		DatastoreHits:       c.datastoreHits,
+		Misses:              c.misses,
+		Bypasses:            c.bypasses,
+		HandlerCallsSaved:   hits,
+		DatastoreReadsSaved: c.memoryHits,
+		TimeSavedMs:         float64(c.timeSaved) / float64(time.Millisecond),
+		UnmeasuredHits:      c.unmeasuredHits,
+	}
+	if total := hits + c.misses; total > 0 {
+		stats.HitRate = float64(hits) / float64(total) * 100
+	}
+	return stats
+}
+
 // Invalidate clears both in-memory and Datastore caches.
 func (rc *ResponseCache) Invalidate() {
 	rc.mu.Lock()
 	rc.entries = make(map[string]*CacheEntry)
 	rc.mu.Unlock()
 
+	if rc.ds == nil {
+		return
+	}
+
 	// Delete Datastore cache asynchronously
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -81,7 +209,7 @@ func (rc *ResponseCache) getFromMemory(key string) (*CacheEntry, bool) {
 	rc.mu.RLock()
 	defer rc.mu.RUnlock()
 	entry, ok := rc.entries[key]
-	if !ok || time.Since(entry.createdAt) > rc.ttl {
+	if !ok || time.Since(entry.createdAt) > entry.ttl {
 		return nil, false
 	}
 	return entry, true
@@ -89,17 +217,23 @@ func (rc *ResponseCache) getFromMemory(key string) (*CacheEntry, bool) {
 
 // getFromDatastore retrieves from Datastore cache and promotes to in-memory on hit.
 func (rc *ResponseCache) getFromDatastore(ctx context.Context, key string) (*CacheEntry, bool) {
-	body, err := rc.ds.GetMetricsCache(ctx, key)
+	if rc.ds == nil {
+		return nil, false
+	}
+	stored, err := rc.ds.GetMetricsCache(ctx, key)
 	if err != nil {
 		return nil, false
 	}
 
-	// Restore from Datastore and promote to in-memory
+	// Restore from Datastore and promote to in-memory, keeping the stored creation time
+	// and TTL so the entry expires when the Datastore copy does
 	entry := &CacheEntry{
-		body:        body,
+		body:        stored.Body,
 		contentType: "application/json",
 		statusCode:  http.StatusOK,
-		createdAt:   time.Now(),
+		createdAt:   stored.CreatedAt,
+		ttl:         time.Duration(stored.TTLSec) * time.Second,
+		headers:     stored.HeaderMap(),
 	}
 
 	rc.mu.Lock()
@@ -110,12 +244,29 @@ func (rc *ResponseCache) getFromDatastore(ctx context.Context, key string) (*Cac
 }
 
 // storeAll stores in both in-memory and Datastore caches.
+// Datastore stores the body compressed and rejects it when it is still too large after
+// compression; such responses stay cached in memory only.
 func (rc *ResponseCache) storeAll(ctx context.Context, key string, cw *cacheWriter) {
 	bodyBytes := cw.body.Bytes()
 	contentType := cw.Header().Get("Content-Type")
 	if contentType == "" {
 		contentType = "application/json"
 	}
+	// Only JSON responses are cached (the Datastore tier restores entries as JSON
+	// and other headers such as Content-Disposition are not preserved)
+	if !strings.HasPrefix(contentType, "application/json") {
+		return
+	}
+
+	var headers map[string]string
+	for _, name := range cachedHeaders {
+		if v := cw.Header().Get(name); v != "" {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[name] = v
+		}
+	}
 
 	// Store in memory
 	rc.mu.Lock()
@@ -124,19 +275,45 @@ func (rc *ResponseCache) storeAll(ctx context.Context, key string, cw *cacheWrit
 		contentType: contentType,
 		statusCode:  cw.statusCode,
 		createdAt:   time.Now(),
+		ttl:         rc.ttl,
+		headers:     headers,
 	}
 	rc.mu.Unlock()
 
+	if rc.ds == nil {
+		return
+	}
+
 	// Store in Datastore asynchronously
 	go func() {
 		dsCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		if err := rc.ds.PutMetricsCache(dsCtx, key, bodyBytes, rc.ttlSec); err != nil {
+		err := rc.ds.PutMetricsCache(dsCtx, key, bodyBytes, headers, rc.ttlSec)
+		switch {
+		case errors.Is(err, datastore.ErrCacheBodyTooLarge):
+			rc.logger.Info("response too large for datastore cache, caching in memory only",
+				"key", key,
+				"bytes", len(bodyBytes),
+			)
+		case err != nil:
 			rc.logger.Warn("failed to store datastore cache", "key", key, "error", err)
 		}
 	}()
 }
 
+// writeHeaders restores the headers of a cached response and sets Cache-Control and Age so
+// browsers and shared caches can reuse it for the rest of the entry's TTL.
+func (rc *ResponseCache) writeHeaders(h http.Header, entry *CacheEntry) {
+	h.Set("Content-Type", entry.contentType)
+	for name, v := range entry.headers {
+		h.Set(name, v)
+	}
+	age := int(time.Since(entry.createdAt).Seconds())
+	remaining := max(int(entry.ttl.Seconds())-age, 0)
+	h.Set("Cache-Control", fmt.Sprintf("max-age=%d", remaining))
+	h.Set("Age", strconv.Itoa(age))
+}
+
 // Middleware returns a 3-tier cache middleware.
 func (rc *ResponseCache) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -154,8 +331,10 @@ func (rc *ResponseCache) Middleware() func(http.Handler) http.Handler {
 				r.URL.RawQuery = q.Encode()
 
 				cw := &cacheWriter{ResponseWriter: w, body: &bytes.Buffer{}}
+				started := time.Now()
 				next.ServeHTTP(cw, r)
 				if cw.statusCode >= 200 && cw.statusCode < 300 {
+					rc.recordMiss(r.URL.RequestURI(), time.Since(started), true)
 					rc.storeAll(r.Context(), r.URL.RequestURI(), cw)
 				}
 				w.Header().Set("X-Cache", "BYPASS")
@@ -166,7 +345,8 @@ func (rc *ResponseCache) Middleware() func(http.Handler) http.Handler {
 
 			// Stage 1: in-memory cache
 			if entry, ok := rc.getFromMemory(key); ok {
-				w.Header().Set("Content-Type", entry.contentType)
+				rc.recordHit(key, tierMemory)
+				rc.writeHeaders(w.Header(), entry)
 				w.Header().Set("X-Cache", "HIT-MEMORY")
 				w.WriteHeader(entry.statusCode)
 				_, _ = w.Write(entry.body)
@@ -175,7 +355,8 @@ func (rc *ResponseCache) Middleware() func(http.Handler) http.Handler {
 
 			// Stage 2: Datastore cache
 			if entry, ok := rc.getFromDatastore(r.Context(), key); ok {
-				w.Header().Set("Content-Type", entry.contentType)
+				rc.recordHit(key, tierDatastore)
+				rc.writeHeaders(w.Header(), entry)
 				w.Header().Set("X-Cache", "HIT-DATASTORE")
 				w.WriteHeader(entry.statusCode)
 				_, _ = w.Write(entry.body)
@@ -184,10 +365,12 @@ func (rc *ResponseCache) Middleware() func(http.Handler) http.Handler {
 
 			// Stage 3: handler (live Datastore query)
 			cw := &cacheWriter{ResponseWriter: w, body: &bytes.Buffer{}}
+			started := time.Now()
 			next.ServeHTTP(cw, r)
 
 			// Only cache 2xx responses in both tiers
 			if cw.statusCode >= 200 && cw.statusCode < 300 {
+				rc.recordMiss(key, time.Since(started), false)
 				rc.storeAll(r.Context(), key, cw)
 			}
 
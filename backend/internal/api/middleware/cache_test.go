@@ -0,0 +1,330 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/datastore"
+)
+
+func newTestCache() *ResponseCache {
+	return &ResponseCache{
+		entries: make(map[string]*CacheEntry),
+		ttl:     time.Minute,
+		costs:   make(map[string]time.Duration),
+	}
+}
+
+func TestResponseCache_SavingsAccounting(t *testing.T) {
+	rc := newTestCache()
+
+	// /a: miss (200ms) then three memory hits and one Datastore hit
+	rc.recordMiss("/a", 200*time.Millisecond, false)
+	rc.recordHit("/a", tierMemory)
+	rc.recordHit("/a", tierMemory)
+	rc.recordHit("/a", tierMemory)
+	rc.recordHit("/a", tierDatastore)
+
+	// /b: refreshed with a bypass (50ms), then re-measured by a later miss (80ms) and hit once
+	rc.recordMiss("/b", 50*time.Millisecond, true)
+	rc.recordMiss("/b", 80*time.Millisecond, false)
+	rc.recordHit("/b", tierMemory)
+
+	// /c: Datastore hit with no measured miss (e.g. after a restart)
+	rc.recordHit("/c", tierDatastore)
+
+	got := rc.Stats()
+	if got.MemoryHits != 4 || got.DatastoreHits != 2 {
+		t.Errorf("hits = memory %d / datastore %d, want 4 / 2", got.MemoryHits, got.DatastoreHits)
+	}
+	if got.Misses != 2 || got.Bypasses != 1 {
+		t.Errorf("misses = %d, bypasses = %d, want 2 and 1", got.Misses, got.Bypasses)
+	}
+	if got.HandlerCallsSaved != 6 {
+		t.Errorf("HandlerCallsSaved = %d, want 6", got.HandlerCallsSaved)
+	}
+	if got.DatastoreReadsSaved != 4 {
+		t.Errorf("DatastoreReadsSaved = %d, want 4", got.DatastoreReadsSaved)
+	}
+	// 4 × 200ms + 1 × 80ms (latest miss cost for /b)
+	if math.Abs(got.TimeSavedMs-880) > 1e-9 {
+		t.Errorf("TimeSavedMs = %v, want 880", got.TimeSavedMs)
+	}
+	if got.UnmeasuredHits != 1 {
+		t.Errorf("UnmeasuredHits = %d, want 1", got.UnmeasuredHits)
+	}
+	// 6 hits out of 8 lookups
+	if math.Abs(got.HitRate-75) > 1e-9 {
+		t.Errorf("HitRate = %v, want 75", got.HitRate)
+	}
+}
+
+func TestResponseCache_StatsEmpty(t *testing.T) {
+	got := newTestCache().Stats()
+	if got != (CacheStats{}) {
+		t.Errorf("Stats() = %+v, want zero value", got)
+	}
+}
+
+// fakeCacheStore serves stored entries, records Datastore tier writes and rejects bodies
+// larger than maxBody, as PutMetricsCache does with bodies still too large after compression.
+type fakeCacheStore struct {
+	entries map[string]*datastore.MetricsCacheEntry
+	puts    chan string
+	maxBody int
+}
+
+func (s *fakeCacheStore) GetMetricsCache(_ context.Context, key string) (*datastore.MetricsCacheEntry, error) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return entry, nil
+}
+
+func (s *fakeCacheStore) PutMetricsCache(_ context.Context, key string, body []byte, _ map[string]string, _ int) error {
+	s.puts <- key
+	if len(body) > s.maxBody {
+		return fmt.Errorf("%w: %d bytes compressed", datastore.ErrCacheBodyTooLarge, len(body))
+	}
+	return nil
+}
+
+func (s *fakeCacheStore) DeleteAllMetricsCache(context.Context) error { return nil }
+
+func TestResponseCache_DatastoreDecidesBodySize(t *testing.T) {
+	store := &fakeCacheStore{puts: make(chan string, 2), maxBody: 1024}
+	rc := newTestCache()
+	rc.ds = store
+	rc.logger = slog.Default()
+
+	handler := rc.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := 16
+		if r.URL.Path == "/large" {
+			// Over the limit uncompressed; only the store knows whether it fits once compressed
+			size = datastore.MaxMetricsCacheBodySize + 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`"` + strings.Repeat("x", size-2) + `"`))
+	}))
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+	waitPut := func(want string) {
+		t.Helper()
+		select {
+		case key := <-store.puts:
+			if key != want {
+				t.Errorf("datastore write for %q, want %q", key, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not handed to the datastore cache", want)
+		}
+	}
+
+	serve("/small")
+	waitPut("/small")
+
+	// A large body is still offered to the datastore; when it is rejected, it stays in memory
+	if w := serve("/large"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request X-Cache = %q, want MISS", w.Header().Get("X-Cache"))
+	}
+	waitPut("/large")
+	if w := serve("/large"); w.Header().Get("X-Cache") != "HIT-MEMORY" {
+		t.Errorf("second request X-Cache = %q, want HIT-MEMORY", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCache_FreshnessHeaders(t *testing.T) {
+	rc := newTestCache()
+	handler := rc.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w
+	}
+
+	// A miss is not marked cacheable
+	if w := serve(); w.Header().Get("Cache-Control") != "" || w.Header().Get("Age") != "" {
+		t.Errorf("miss headers = Cache-Control %q, Age %q, want none", w.Header().Get("Cache-Control"), w.Header().Get("Age"))
+	}
+
+	// Age the entry 10s, then 25s, and check the remaining TTL shrinks accordingly
+	for _, tt := range []struct {
+		age          time.Duration
+		wantCC, want string
+	}{
+		{10 * time.Second, "max-age=50", "10"},
+		{25 * time.Second, "max-age=35", "25"},
+	} {
+		rc.mu.Lock()
+		rc.entries["/metrics"].createdAt = time.Now().Add(-tt.age)
+		rc.mu.Unlock()
+
+		w := serve()
+		if w.Header().Get("X-Cache") != "HIT-MEMORY" {
+			t.Fatalf("X-Cache = %q, want HIT-MEMORY", w.Header().Get("X-Cache"))
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.wantCC {
+			t.Errorf("after %v: Cache-Control = %q, want %q", tt.age, got, tt.wantCC)
+		}
+		if got := w.Header().Get("Age"); got != tt.want {
+			t.Errorf("after %v: Age = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestResponseCache_FreshnessHeadersFromDatastore(t *testing.T) {
+	// Stored 40s ago with a 60s TTL, by this or another instance
+	store := &fakeCacheStore{entries: map[string]*datastore.MetricsCacheEntry{
+		"/metrics": {Body: []byte(`{}`), CreatedAt: time.Now().Add(-40 * time.Second), TTLSec: 60},
+	}}
+	rc := newTestCache()
+	rc.ttl = 10 * time.Minute
+	rc.ds = store
+	handler := rc.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for a cached response")
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w
+	}
+
+	// The age counts from the original write and only the rest of the stored TTL is left
+	w := serve()
+	if w.Header().Get("X-Cache") != "HIT-DATASTORE" {
+		t.Fatalf("X-Cache = %q, want HIT-DATASTORE", w.Header().Get("X-Cache"))
+	}
+	if got := w.Header().Get("Age"); got != "40" {
+		t.Errorf("Age = %q, want 40", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "max-age=20" {
+		t.Errorf("Cache-Control = %q, want max-age=20", got)
+	}
+
+	// The promoted memory entry keeps the stored TTL rather than starting a fresh one
+	w = serve()
+	if w.Header().Get("X-Cache") != "HIT-MEMORY" {
+		t.Fatalf("X-Cache = %q, want HIT-MEMORY", w.Header().Get("X-Cache"))
+	}
+	if got := w.Header().Get("Cache-Control"); got != "max-age=20" {
+		t.Errorf("memory hit Cache-Control = %q, want max-age=20", got)
+	}
+	rc.mu.Lock()
+	rc.entries["/metrics"].createdAt = time.Now().Add(-61 * time.Second)
+	rc.mu.Unlock()
+	if _, ok := rc.getFromMemory("/metrics"); ok {
+		t.Error("promoted entry outlived the stored TTL")
+	}
+}
+
+func TestResponseCache_KeepsDateRangeHeaders(t *testing.T) {
+	store := &fakeCacheStore{entries: map[string]*datastore.MetricsCacheEntry{
+		"/stored": {
+			Body:      []byte(`[]`),
+			CreatedAt: time.Now(),
+			TTLSec:    60,
+			Headers:   []datastore.MetricsCacheHeader{{Name: DateRangeStartHeader, Value: "2026-01-01T00:00:00Z"}},
+		},
+	}}
+	rc := newTestCache()
+	rc.ds = store
+	rc.logger = slog.Default()
+	handler := rc.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(DateRangeStartHeader, "2026-02-01T00:00:00+09:00")
+		w.Header().Set("X-Other", "dropped")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Memory tier: only the listed headers are kept
+	store.puts = make(chan string, 1)
+	store.maxBody = 1024
+	serve("/fresh")
+	<-store.puts
+	w := serve("/fresh")
+	if w.Header().Get("X-Cache") != "HIT-MEMORY" {
+		t.Fatalf("X-Cache = %q, want HIT-MEMORY", w.Header().Get("X-Cache"))
+	}
+	if got := w.Header().Get(DateRangeStartHeader); got != "2026-02-01T00:00:00+09:00" {
+		t.Errorf("memory hit %s = %q, want the handler's value", DateRangeStartHeader, got)
+	}
+	if got := w.Header().Get("X-Other"); got != "" {
+		t.Errorf("memory hit X-Other = %q, want not cached", got)
+	}
+
+	// Datastore tier: stored headers are restored
+	w = serve("/stored")
+	if w.Header().Get("X-Cache") != "HIT-DATASTORE" {
+		t.Fatalf("X-Cache = %q, want HIT-DATASTORE", w.Header().Get("X-Cache"))
+	}
+	if got := w.Header().Get(DateRangeStartHeader); got != "2026-01-01T00:00:00Z" {
+		t.Errorf("datastore hit %s = %q, want the stored value", DateRangeStartHeader, got)
+	}
+}
+
+// recordHandler is a slog.Handler sending each record to a channel.
+type recordHandler struct {
+	records chan slog.Record
+}
+
+func (h recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records <- r
+	return nil
+}
+func (h recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h recordHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestResponseCache_OversizedBodyLoggedAtInfo(t *testing.T) {
+	records := make(chan slog.Record, 4)
+	store := &fakeCacheStore{puts: make(chan string, 2), maxBody: 16}
+	rc := newTestCache()
+	rc.ds = store
+	rc.logger = slog.New(recordHandler{records: records})
+
+	handler := rc.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`"` + strings.Repeat("x", 64) + `"`))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/large", nil))
+
+	// Rejection for size is expected and logged at Info, not as a failed write
+	select {
+	case r := <-records:
+		if r.Level != slog.LevelInfo || !strings.Contains(r.Message, "too large") {
+			t.Errorf("logged %s %q, want an Info about the body size", r.Level, r.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("rejected body was not logged")
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/large", nil))
+	if w.Header().Get("X-Cache") != "HIT-MEMORY" {
+		t.Errorf("second request X-Cache = %q, want HIT-MEMORY", w.Header().Get("X-Cache"))
+	}
+}
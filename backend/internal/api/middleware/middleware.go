@@ -1,12 +1,60 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
 )
 
+// RequestIDHeader is the header used to propagate request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// Headers echoing the resolved date range of list responses, whose bodies are bare arrays.
+// The response cache keeps them with the body.
+const (
+	DateRangeStartHeader    = "X-Date-Range-Start"
+	DateRangeEndHeader      = "X-Date-Range-End"
+	DateRangeTimezoneHeader = "X-Date-Range-Timezone"
+)
+
+// maxRequestIDLength is the maximum accepted length of an incoming request ID.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key for the request ID.
+type requestIDKey struct{}
+
+// Error codes written by the middleware; handlers add their own codes to the same response shape.
+const (
+	ErrCodeInternal    = "INTERNAL"
+	ErrCodeUnavailable = "UNAVAILABLE" // a backing service (Datastore) is not configured
+)
+
+// ErrorResponse is the body of an error response.
+// RequestID and Detail are only set on responses to recovered panics.
+type ErrorResponse struct {
+	Error     ErrorDetail `json:"error"`
+	RequestID string      `json:"requestId,omitempty"`
+	Detail    string      `json:"detail,omitempty"` // development only
+}
+
+// ErrorDetail is a machine-readable error code with a human-readable message.
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeError writes an error response as JSON.
+func writeError(w http.ResponseWriter, status int, body ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
 // Logger returns a middleware that logs HTTP requests
 func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -19,6 +67,7 @@ func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 			next.ServeHTTP(wrapped, r)
 
 			logger.Info("http request",
+				"request_id", RequestIDFromContext(r.Context()),
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", wrapped.statusCode,
@@ -47,7 +96,8 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{RequestIDHeader, DateRangeStartHeader, DateRangeEndHeader, DateRangeTimezoneHeader}, ", "))
 				w.Header().Set("Access-Control-Max-Age", "86400")
 			}
 
@@ -62,18 +112,41 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 	}
 }
 
-// Recovery returns a middleware that recovers from panics
-func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
+// maxPanicDetailLength bounds the panic message echoed in development responses.
+const maxPanicDetailLength = 200
+
+// Recovery returns a middleware that recovers from panics.
+// The panic value and stack are logged with the request ID, and the client gets a JSON 500 carrying the
+// request ID for correlation. Only when exposeDetail is set (development) does the body include the panic
+// message, flattened to one line and truncated; the stack is never sent to the client.
+func Recovery(logger *slog.Logger, exposeDetail bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
-				if err := recover(); err != nil {
-					logger.Error("panic recovered",
-						"error", err,
-						"path", r.URL.Path,
-					)
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err) // deliberate abort; let net/http handle it
+				}
+
+				requestID := RequestIDFromContext(r.Context())
+				logger.Error("panic recovered",
+					"request_id", requestID,
+					"error", err,
+					"path", r.URL.Path,
+					"stack", string(debug.Stack()),
+				)
+
+				body := ErrorResponse{
+					Error:     ErrorDetail{Code: ErrCodeInternal, Message: "internal server error"},
+					RequestID: requestID,
+				}
+				if exposeDetail {
+					body.Detail = sanitizePanicDetail(err)
 				}
+				writeError(w, http.StatusInternalServerError, body)
 			}()
 
 			next.ServeHTTP(w, r)
@@ -81,21 +154,71 @@ func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
-// RequestID returns a middleware that adds a request ID to the context
+// sanitizePanicDetail renders a panic value as a single line of at most maxPanicDetailLength bytes.
+func sanitizePanicDetail(v any) string {
+	detail := strings.Join(strings.Fields(fmt.Sprint(v)), " ")
+	if len(detail) > maxPanicDetailLength {
+		detail = strings.ToValidUTF8(detail[:maxPanicDetailLength], "") + "..."
+	}
+	return detail
+}
+
+// RequireDatastore returns a middleware that responds 503 when no Datastore client is configured.
+// Routes that read or write Datastore are wrapped so they fail cleanly instead of panicking.
+func RequireDatastore(configured bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if configured {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusServiceUnavailable, ErrorResponse{
+				Error: ErrorDetail{Code: ErrCodeUnavailable, Message: "datastore not configured"},
+			})
+		})
+	}
+}
+
+// RequestID returns a middleware that adds a request ID to the context.
+// A valid incoming X-Request-ID is propagated; otherwise a new ID is generated.
+// The ID is echoed in the response header so clients can correlate logs.
 func RequestID() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			requestID := r.Header.Get("X-Request-ID")
-			if requestID == "" {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !isValidRequestID(requestID) {
 				requestID = generateRequestID()
 			}
 
-			w.Header().Set("X-Request-ID", requestID)
-			next.ServeHTTP(w, r)
+			w.Header().Set(RequestIDHeader, requestID)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// RequestIDFromContext returns the request ID stored by RequestID, or "" if absent.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// isValidRequestID reports whether an incoming request ID is safe to propagate.
+// Only alphanumerics and "-", "_", ".", ":" are accepted to prevent header/log injection.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, ch := range id {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Chain chains multiple middlewares
 func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
 	return func(final http.Handler) http.Handler {
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name           string
+		incoming       string
+		wantPropagated bool
+	}{
+		{"generated when header is absent", "", false},
+		{"propagated when header is valid", "frontend-abc_123.4:5", true},
+		{"regenerated when header has invalid characters", "bad id\r\nX-Injected: 1", false},
+		{"regenerated when header is too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics/dora", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			echoed := w.Header().Get(RequestIDHeader)
+			if echoed == "" {
+				t.Fatal("response header X-Request-ID is empty")
+			}
+			if echoed != ctxID {
+				t.Errorf("context ID %q does not match echoed ID %q", ctxID, echoed)
+			}
+			if tt.wantPropagated && echoed != tt.incoming {
+				t.Errorf("echoed ID = %q, want propagated %q", echoed, tt.incoming)
+			}
+			if !tt.wantPropagated && echoed == tt.incoming {
+				t.Errorf("echoed ID = %q, want a newly generated ID", echoed)
+			}
+		})
+	}
+}
+
+func TestLogger_IncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := Chain(RequestID(), Logger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(RequestIDHeader, "trace-42")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse log line %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "trace-42" {
+		t.Errorf("request_id = %v, want trace-42", entry["request_id"])
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom: secret=hunter2\n\tat somewhere")
+	})
+
+	for _, tt := range []struct {
+		name       string
+		dev        bool
+		wantDetail string
+	}{
+		{"production hides the panic", false, ""},
+		{"development includes a sanitized detail", true, "boom: secret=hunter2 at somewhere"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			h := Chain(RequestID(), Recovery(logger, tt.dev))(panicking)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics/dora", nil)
+			req.Header.Set(RequestIDHeader, "trace-500")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+			raw := w.Body.String()
+			var body ErrorResponse
+			if err := json.Unmarshal([]byte(raw), &body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if body.Error.Code != ErrCodeInternal || body.Error.Message != "internal server error" {
+				t.Errorf("error = %+v, want code %s", body.Error, ErrCodeInternal)
+			}
+			if body.RequestID != "trace-500" || body.Detail != tt.wantDetail {
+				t.Errorf("body = %+v, want requestId trace-500 and detail %q", body, tt.wantDetail)
+			}
+			if strings.Contains(raw, "goroutine") || strings.Contains(raw, ".go:") {
+				t.Errorf("response leaks a stack trace: %s", raw)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to parse log line %q: %v", buf.String(), err)
+			}
+			if entry["request_id"] != "trace-500" {
+				t.Errorf("logged request_id = %v, want trace-500", entry["request_id"])
+			}
+			if stack, _ := entry["stack"].(string); !strings.Contains(stack, "goroutine") {
+				t.Errorf("logged stack = %q, want a goroutine stack trace", stack)
+			}
+		})
+	}
+}
+
+func TestRequireDatastore(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	RequireDatastore(true)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/repositories", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("configured: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	w = httptest.NewRecorder()
+	RequireDatastore(false)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/repositories", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("not configured: status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := ErrorResponse{Error: ErrorDetail{Code: ErrCodeUnavailable, Message: "datastore not configured"}}
+	if body != want {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+}
+
+func TestSanitizePanicDetail(t *testing.T) {
+	got := sanitizePanicDetail(strings.Repeat("x", maxPanicDetailLength+50))
+	if len(got) != maxPanicDetailLength+len("...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("long detail = %d bytes, want truncated to %d plus ellipsis", len(got), maxPanicDetailLength)
+	}
+}
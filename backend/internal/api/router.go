@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"time"
@@ -18,6 +19,8 @@ type Router struct {
 	logger     *slog.Logger
 	middleware func(http.Handler) http.Handler
 	cache      *middleware.ResponseCache
+	// requireDS guards routes that need Datastore (503 when running without it)
+	requireDS func(http.Handler) http.Handler
 }
 
 // NewRouter creates a new Router
@@ -26,30 +29,34 @@ func NewRouter(ds *datastore.Client, gh *github.Client, logger *slog.Logger, cfg
 	cache := middleware.NewResponseCache(50*time.Minute, ds, logger)
 
 	r := &Router{
-		mux:    http.NewServeMux(),
-		logger: logger,
-		cache:  cache,
+		mux:       http.NewServeMux(),
+		logger:    logger,
+		cache:     cache,
+		requireDS: middleware.RequireDatastore(ds != nil),
 	}
 
-	// Setup middleware chain
+	// Setup middleware chain (RequestID first so that log lines carry the ID)
 	r.middleware = middleware.Chain(
-		middleware.Recovery(logger),
+		middleware.RequestID(),
+		middleware.Recovery(logger, cfg.IsDevelopment()),
 		middleware.Logger(logger),
 		middleware.CORS([]string{"*"}),
-		middleware.RequestID(),
 	)
 
 	// Initialize handlers
-	repoHandler := handler.NewRepositoryHandler(ds, gh, logger, cache)
-	metricsHandler := handler.NewMetricsHandler(ds, logger)
+	repoHandler := handler.NewRepositoryHandler(ds, gh, logger, cache, cfg)
+	settings := handler.NewSettingsCache(ds, cfg, logger)
+	metricsHandler := handler.NewMetricsHandler(ds, logger, cfg).WithSettings(settings)
 	sprintHandler := handler.NewSprintHandler(ds, logger)
 	teamHandler := handler.NewTeamHandler(ds, logger)
 	githubHandler := handler.NewGitHubHandler(gh, logger)
 	botUserHandler := handler.NewBotUserHandler(ds, logger)
 	jobHandler := handler.NewJobHandler(ds, gh, logger, cache, cfg)
+	adminHandler := handler.NewAdminHandler(ds, gh, logger, cache)
+	settingsHandler := handler.NewSettingsHandler(ds, settings, logger, cache)
 
 	// Register routes
-	r.registerRoutes(repoHandler, metricsHandler, sprintHandler, teamHandler, githubHandler, botUserHandler, jobHandler)
+	r.registerRoutes(repoHandler, metricsHandler, sprintHandler, teamHandler, githubHandler, botUserHandler, jobHandler, adminHandler, settingsHandler)
 
 	return r
 }
@@ -62,9 +69,13 @@ func (r *Router) registerRoutes(
 	githubHandler *handler.GitHubHandler,
 	botUserHandler *handler.BotUserHandler,
 	jobHandler *handler.JobHandler,
+	adminHandler *handler.AdminHandler,
+	settingsHandler *handler.SettingsHandler,
 ) {
-	// Cache middleware
-	cached := r.cache.Middleware()
+	// Cache middleware (behind the Datastore guard, since the cache's second tier is Datastore)
+	cache := r.cache.Middleware()
+	cached := func(h http.Handler) http.Handler { return r.requireDS(cache(h)) }
+	store := func(h http.HandlerFunc) http.Handler { return r.requireDS(h) }
 
 	// Health check
 	r.mux.HandleFunc("GET /health", func(w http.ResponseWriter, req *http.Request) {
@@ -79,13 +90,23 @@ func (r *Router) registerRoutes(
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// Cache statistics endpoint
+	r.mux.HandleFunc("GET /api/cache/stats", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.cache.Stats())
+	})
+
 	// Repository endpoints (list is cached)
 	r.mux.Handle("GET /api/repositories", cached(http.HandlerFunc(repoHandler.List)))
-	r.mux.HandleFunc("POST /api/repositories", repoHandler.Add)
-	r.mux.HandleFunc("GET /api/repositories/{id}", repoHandler.Get)
-	r.mux.HandleFunc("DELETE /api/repositories/{id}", repoHandler.Delete)
-	r.mux.HandleFunc("POST /api/repositories/batch", repoHandler.BatchAdd)
-	r.mux.HandleFunc("POST /api/repositories/{id}/sync", repoHandler.Sync)
+	r.mux.Handle("POST /api/repositories", store(repoHandler.Add))
+	r.mux.Handle("GET /api/repositories/{id}", store(repoHandler.Get))
+	r.mux.Handle("DELETE /api/repositories/{id}", store(repoHandler.Delete))
+	r.mux.Handle("POST /api/repositories/batch", store(repoHandler.BatchAdd))
+	r.mux.Handle("POST /api/repositories/{id}/sync", store(repoHandler.Sync))
+	r.mux.Handle("POST /api/repositories/{id}/backfill", store(repoHandler.Backfill))
+	r.mux.Handle("POST /api/repositories/{id}/reaggregate", store(repoHandler.Reaggregate))
+	r.mux.Handle("POST /api/repositories/{id}/enrich", store(repoHandler.Enrich))
+	r.mux.Handle("POST /api/repositories/{id}/deployments", store(repoHandler.ImportDeployments))
 	r.mux.Handle("GET /api/repositories/date-ranges", cached(http.HandlerFunc(repoHandler.DateRanges)))
 
 	// GitHub proxy endpoints
@@ -94,31 +115,56 @@ func (r *Router) registerRoutes(
 
 	// Metrics endpoints (cached)
 	r.mux.Handle("GET /api/metrics/cycle-time", cached(http.HandlerFunc(metricsHandler.CycleTime)))
+	r.mux.Handle("GET /api/metrics/cycle-time-trend", cached(http.HandlerFunc(metricsHandler.CycleTimeTrend)))
+	r.mux.Handle("GET /api/metrics/anomalies", cached(http.HandlerFunc(metricsHandler.Anomalies)))
 	r.mux.Handle("GET /api/metrics/reviews", cached(http.HandlerFunc(metricsHandler.Reviews)))
+	r.mux.Handle("GET /api/metrics/review-coverage", cached(http.HandlerFunc(metricsHandler.ReviewCoverage)))
+	r.mux.Handle("GET /api/metrics/collaboration", cached(http.HandlerFunc(metricsHandler.Collaboration)))
 	r.mux.Handle("GET /api/metrics/dora", cached(http.HandlerFunc(metricsHandler.DORA)))
 	r.mux.Handle("GET /api/metrics/productivity-score", cached(http.HandlerFunc(metricsHandler.ProductivityScore)))
 	r.mux.Handle("GET /api/metrics/daily", cached(http.HandlerFunc(metricsHandler.DailyMetrics)))
 	r.mux.Handle("GET /api/metrics/pull-requests", cached(http.HandlerFunc(metricsHandler.PullRequests)))
+	r.mux.Handle("GET /api/metrics/stale-prs", cached(http.HandlerFunc(metricsHandler.StalePRs)))
+	r.mux.Handle("GET /api/metrics/authors", cached(http.HandlerFunc(metricsHandler.Authors)))
+	r.mux.Handle("GET /api/metrics/churn", cached(http.HandlerFunc(metricsHandler.Churn)))
+	r.mux.Handle("GET /api/metrics/deployments", cached(http.HandlerFunc(metricsHandler.Deployments)))
+	// Streamed data dump (not cached: the cache buffers the whole body)
+	r.mux.Handle("GET /api/metrics/export", store(metricsHandler.Export))
 
 	// Sprint endpoints
-	r.mux.HandleFunc("GET /api/sprints", sprintHandler.List)
-	r.mux.HandleFunc("POST /api/sprints", sprintHandler.Create)
-	r.mux.HandleFunc("GET /api/sprints/{id}", sprintHandler.Get)
-	r.mux.HandleFunc("GET /api/sprints/{id}/performance", sprintHandler.GetPerformance)
+	r.mux.Handle("GET /api/sprints", store(sprintHandler.List))
+	r.mux.Handle("POST /api/sprints", store(sprintHandler.Create))
+	r.mux.Handle("GET /api/sprints/{id}", store(sprintHandler.Get))
+	r.mux.Handle("PUT /api/sprints/{id}", store(sprintHandler.Update))
+	r.mux.Handle("DELETE /api/sprints/{id}", store(sprintHandler.Delete))
+	r.mux.Handle("GET /api/sprints/{id}/performance", store(sprintHandler.GetPerformance))
 
 	// Bot user endpoints
-	r.mux.HandleFunc("GET /api/bot-users", botUserHandler.List)
-	r.mux.HandleFunc("POST /api/bot-users", botUserHandler.Add)
-	r.mux.HandleFunc("DELETE /api/bot-users", botUserHandler.Delete)
+	r.mux.Handle("GET /api/bot-users", store(botUserHandler.List))
+	r.mux.Handle("POST /api/bot-users", store(botUserHandler.Add))
+	r.mux.Handle("POST /api/bot-users/batch", store(botUserHandler.BatchAdd))
+	r.mux.Handle("DELETE /api/bot-users", store(botUserHandler.Delete))
 
 	// Job endpoints
-	r.mux.HandleFunc("PUT /api/job/sync", jobHandler.Sync)
+	r.mux.Handle("PUT /api/job/sync", store(jobHandler.Sync))
+	r.mux.Handle("PUT /api/job/cleanup", store(jobHandler.Cleanup))
+
+	// Settings endpoints
+	r.mux.Handle("GET /api/settings", store(settingsHandler.Get))
+	r.mux.Handle("PUT /api/settings", store(settingsHandler.Put))
+
+	// Admin endpoints
+	r.mux.Handle("GET /api/admin/stats", store(adminHandler.Stats))
+	r.mux.Handle("GET /api/admin/schema-status", store(adminHandler.SchemaStatus))
+	r.mux.Handle("POST /api/admin/migrate", store(adminHandler.Migrate))
 
 	// Team endpoints (cached)
 	r.mux.Handle("GET /api/team/members", cached(http.HandlerFunc(teamHandler.ListMembers)))
 	r.mux.Handle("GET /api/team/members/{id}/stats", cached(http.HandlerFunc(teamHandler.GetMemberStats)))
 	r.mux.Handle("GET /api/team/members/{id}/pull-requests", cached(http.HandlerFunc(teamHandler.GetMemberPullRequests)))
 	r.mux.Handle("GET /api/team/members/{id}/reviews", cached(http.HandlerFunc(teamHandler.GetMemberReviews)))
+	r.mux.Handle("GET /api/team/members/{id}/onboarding", cached(http.HandlerFunc(teamHandler.GetMemberOnboarding)))
+	r.mux.Handle("GET /api/team/compare", cached(http.HandlerFunc(teamHandler.CompareMembers)))
 }
 
 // ServeHTTP implements http.Handler
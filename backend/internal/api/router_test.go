@@ -0,0 +1,47 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/compasstechlab/dora-yaki/internal/config"
+	"github.com/compasstechlab/dora-yaki/internal/github"
+)
+
+func TestRouter_WithoutDatastore(t *testing.T) {
+	// Mirrors app.Init when no GCP project ID is resolved
+	h := NewRouter(nil, github.NewClient(""), slog.Default(), &config.Config{}).Handler()
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"GET", "/health", "", http.StatusOK},
+		{"GET", "/api/repositories", "", http.StatusServiceUnavailable},
+		{"GET", "/api/metrics/cycle-time?start=2025-06-01&end=2025-06-30", "", http.StatusServiceUnavailable},
+		{"GET", "/api/team/members", "", http.StatusServiceUnavailable},
+		{"POST", "/api/bot-users", `{"username":"renovate"}`, http.StatusServiceUnavailable},
+		{"PUT", "/api/job/sync", "", http.StatusServiceUnavailable},
+		{"POST", "/api/cache/invalidate", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusServiceUnavailable && !strings.Contains(w.Body.String(), "datastore not configured") {
+				t.Errorf("body = %q, want datastore not configured", w.Body.String())
+			}
+		})
+	}
+}
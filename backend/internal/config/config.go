@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"cloud.google.com/go/compute/metadata"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 )
 
 // Config holds the application configuration
@@ -17,9 +19,38 @@ type Config struct {
 	Environment         string
 	GCPProjectID        string
 	GitHubToken         string
-	TZOffset            string // Timezone offset (e.g. "+09:00", "-05:30")
-	SyncIntervalMinutes int    // Sync interval in minutes (default: 60)
-	SyncLockTTLMinutes  int    // Lock TTL in minutes (default: 10)
+	GitHubBaseURL       string   // GitHub Enterprise Server API URL (empty for github.com)
+	TZOffset            string   // Timezone offset (e.g. "+09:00", "-05:30")
+	SyncIntervalMinutes int      // Sync interval in minutes (default: 60)
+	SyncLockTTLMinutes  int      // Lock TTL in minutes (default: 10)
+	ShippableBranches   []string // Base branches (glob patterns) whose merges count as shippable (default: main, master)
+	FailureLabels       []string // PR labels marking a merged PR as a failed change (default: incident, rollback, hotfix)
+	ReviewSLAHours      float64  // Default first-review SLA in hours (0: 24)
+
+	// Activities (PRs opened or merged, reviews) a day needs to count as an active contributor (default: 1)
+	ActiveContributorMinActivity int
+	// Record the last approval before the merge at sync, so merge time counts from a re-approval
+	MergeTimeFromLastApproval bool
+
+	// Productivity score weights (must sum to 1.0; defaults: 0.30, 0.25, 0.25, 0.20)
+	ScoreWeightCycleTime  float64
+	ScoreWeightReview     float64
+	ScoreWeightDeployment float64
+	ScoreWeightQuality    float64
+
+	// Productivity score benchmarks, as comma-separated ascending bounds (defaults in metrics.DefaultScoreThresholds)
+	ScoreCycleTimeHours    []float64 // 4 bounds (default: 24,72,168,336)
+	ScoreFirstReviewHours  []float64 // 3 bounds (default: 4,8,24)
+	ScoreReviewsPerPR      []float64 // target range min,max (default: 1,3)
+	ScoreChangeFailureRate []float64 // 4 bounds in % (default: 5,10,15,30)
+
+	// Extension (".tsx") or filename ("Dockerfile") -> language overrides applied on top of the built-in map
+	LanguageMap map[string]string
+
+	// GitHub App installation auth; used instead of GitHubToken when all three are set
+	GitHubAppID             int64
+	GitHubAppInstallationID int64
+	GitHubAppPrivateKey     string // PEM-encoded private key
 }
 
 // Load loads configuration from environment variables
@@ -29,17 +60,84 @@ func Load() *Config {
 		Environment:         getEnv("ENVIRONMENT", "development"),
 		GCPProjectID:        resolveProjectID(),
 		GitHubToken:         getEnv("GITHUB_TOKEN", ""),
+		GitHubBaseURL:       getEnv("GITHUB_BASE_URL", ""),
 		TZOffset:            getEnv("TZ_OFFSET", ""),
 		SyncIntervalMinutes: getEnvInt("SYNC_INTERVAL_MINUTES", 60),
 		SyncLockTTLMinutes:  getEnvInt("SYNC_LOCK_TTL_MINUTES", 10),
+		ShippableBranches:   getEnvList("SHIPPABLE_BRANCHES", []string{"main", "master"}),
+		FailureLabels:       getEnvList("FAILURE_LABELS", []string{"incident", "rollback", "hotfix"}),
+		ReviewSLAHours:      getEnvFloat("REVIEW_SLA_HOURS", 0),
+
+		ActiveContributorMinActivity: getEnvInt("ACTIVE_CONTRIBUTOR_MIN_ACTIVITY", 1),
+		MergeTimeFromLastApproval:    getEnv("MERGE_TIME_FROM_LAST_APPROVAL", "false") == "true",
+
+		ScoreWeightCycleTime:  getEnvFloat("SCORE_WEIGHT_CYCLE_TIME", 0.30),
+		ScoreWeightReview:     getEnvFloat("SCORE_WEIGHT_REVIEW", 0.25),
+		ScoreWeightDeployment: getEnvFloat("SCORE_WEIGHT_DEPLOYMENT", 0.25),
+		ScoreWeightQuality:    getEnvFloat("SCORE_WEIGHT_QUALITY", 0.20),
+
+		ScoreCycleTimeHours:    getEnvFloatList("SCORE_CYCLE_TIME_HOURS"),
+		ScoreFirstReviewHours:  getEnvFloatList("SCORE_FIRST_REVIEW_HOURS"),
+		ScoreReviewsPerPR:      getEnvFloatList("SCORE_REVIEWS_PER_PR"),
+		ScoreChangeFailureRate: getEnvFloatList("SCORE_CHANGE_FAILURE_RATE"),
+
+		LanguageMap: getEnvMap("LANGUAGE_MAP"),
+
+		GitHubAppID:             int64(getEnvInt("GITHUB_APP_ID", 0)),
+		GitHubAppInstallationID: int64(getEnvInt("GITHUB_APP_INSTALLATION_ID", 0)),
+		GitHubAppPrivateKey:     getEnv("GITHUB_APP_PRIVATE_KEY", ""),
 	}
 }
 
+// WithSettings returns a copy of the config with the fields set in the runtime settings
+// taking precedence over the environment.
+// 実行時設定で指定された項目を環境変数より優先したコピーを返す。
+func (c *Config) WithSettings(s *model.Settings) *Config {
+	merged := *c
+	if s == nil {
+		return &merged
+	}
+	if s.HasScoreWeights() {
+		merged.ScoreWeightCycleTime = s.ScoreWeightCycleTime
+		merged.ScoreWeightReview = s.ScoreWeightReview
+		merged.ScoreWeightDeployment = s.ScoreWeightDeployment
+		merged.ScoreWeightQuality = s.ScoreWeightQuality
+	}
+	for _, f := range []struct {
+		dst *[]float64
+		src []float64
+	}{
+		{&merged.ScoreCycleTimeHours, s.ScoreCycleTimeHours},
+		{&merged.ScoreFirstReviewHours, s.ScoreFirstReviewHours},
+		{&merged.ScoreReviewsPerPR, s.ScoreReviewsPerPR},
+		{&merged.ScoreChangeFailureRate, s.ScoreChangeFailureRate},
+	} {
+		if len(f.src) > 0 {
+			*f.dst = f.src
+		}
+	}
+	if len(s.FailureLabels) > 0 {
+		merged.FailureLabels = s.FailureLabels
+	}
+	if len(s.ShippableBranches) > 0 {
+		merged.ShippableBranches = s.ShippableBranches
+	}
+	if s.ReviewSLAHours > 0 {
+		merged.ReviewSLAHours = s.ReviewSLAHours
+	}
+	return &merged
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
 }
 
+// UsesGitHubApp reports whether GitHub App installation auth is configured.
+func (c *Config) UsesGitHubApp() bool {
+	return c.GitHubAppID != 0 && c.GitHubAppInstallationID != 0 && c.GitHubAppPrivateKey != ""
+}
+
 // IsProduction returns true if running in production mode
 func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
@@ -134,3 +232,67 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, ignoring empty items.
+// カンマ区切りのリストを読み込む。空の項目は無視する。
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
+// getEnvFloatList reads a comma-separated list of numbers. Returns nil when unset or when any item is invalid.
+// カンマ区切りの数値リストを読み込む。未設定または不正な項目がある場合は nil を返す。
+func getEnvFloatList(key string) []float64 {
+	items := getEnvList(key, nil)
+	result := make([]float64, 0, len(items))
+	for _, item := range items {
+		v, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil
+		}
+		result = append(result, v)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// getEnvMap reads comma-separated key=value pairs. Items without "=" are ignored.
+// カンマ区切りの key=value ペアを読み込む。"=" を含まない項目は無視する。
+func getEnvMap(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(item, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			continue
+		}
+		result[k] = strings.TrimSpace(v)
+	}
+	return result
+}
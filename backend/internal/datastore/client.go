@@ -1,11 +1,23 @@
 package datastore
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/datastore"
+	"cloud.google.com/go/datastore/apiv1/datastorepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 )
@@ -14,6 +26,7 @@ import (
 type Client struct {
 	client    *datastore.Client
 	projectID string
+	logger    *slog.Logger
 }
 
 // Kind names for Datastore entities
@@ -28,6 +41,7 @@ const (
 	KindMetricsCache = "MetricsCache"
 	KindBotUser      = "BotUser"
 	KindSyncLock     = "SyncLock"
+	KindSettings     = "Settings"
 )
 
 // NewClient creates a new Datastore client
@@ -40,9 +54,17 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 	return &Client{
 		client:    client,
 		projectID: projectID,
+		logger:    slog.Default(),
 	}, nil
 }
 
+// WithLogger returns a copy of the client that logs through logger.
+func (c *Client) WithLogger(logger *slog.Logger) *Client {
+	cc := *c
+	cc.logger = logger
+	return &cc
+}
+
 // Close closes the Datastore client
 func (c *Client) Close() error {
 	return c.client.Close()
@@ -52,6 +74,7 @@ func (c *Client) Close() error {
 
 // SaveRepository saves a repository to Datastore
 func (c *Client) SaveRepository(ctx context.Context, repo *model.Repository) error {
+	repo.SchemaVersion = model.CurrentSchemaVersion
 	key := datastore.NameKey(KindRepository, repo.ID, nil)
 	_, err := c.client.Put(ctx, key, repo)
 	return err
@@ -75,6 +98,74 @@ func (c *Client) ListRepositories(ctx context.Context) ([]*model.Repository, err
 	return repos, err
 }
 
+// ListRepositoryIDs lists the IDs of all repositories, in the same order as ListRepositories.
+// Keys-only, so no repository entity is loaded.
+func (c *Client) ListRepositoryIDs(ctx context.Context) ([]string, error) {
+	keys, err := c.client.GetAll(ctx, repositoryIDsQuery(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return keyNames(keys), nil
+}
+
+// keyNames returns the string IDs of name keys (repositories are keyed by their ID).
+func keyNames(keys []*datastore.Key) []string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Name
+	}
+	return names
+}
+
+// repositoryIDsQuery builds the keys-only query used by ListRepositoryIDs.
+func repositoryIDsQuery() *datastore.Query {
+	return datastore.NewQuery(KindRepository).Order("-updated_at").KeysOnly()
+}
+
+// ErrInvalidCursor is returned for a page cursor that cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListRepositoriesPage lists up to limit repositories in the order of ListRepositories,
+// starting at cursor (empty for the first page). The returned cursor is empty after the last page.
+func (c *Client) ListRepositoriesPage(ctx context.Context, cursor string, limit int) ([]*model.Repository, string, error) {
+	query := datastore.NewQuery(KindRepository).Order("-updated_at").Limit(limit)
+	if cursor != "" {
+		start, err := datastore.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		query = query.Start(start)
+	}
+
+	it := c.client.Run(ctx, query)
+	repos := make([]*model.Repository, 0, limit)
+	for {
+		repo := &model.Repository{}
+		if _, err := it.Next(repo); errors.Is(err, iterator.Done) {
+			break
+		} else if err != nil {
+			return nil, "", err
+		}
+		repos = append(repos, repo)
+	}
+	if len(repos) < limit {
+		return repos, "", nil
+	}
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, "", err
+	}
+	return repos, next.String(), nil
+}
+
+// ListRepositoriesByOwner lists the repositories of a GitHub owner (user or organization)
+func (c *Client) ListRepositoriesByOwner(ctx context.Context, owner string) ([]*model.Repository, error) {
+	var repos []*model.Repository
+	query := datastore.NewQuery(KindRepository).FilterField("owner", "=", owner)
+	_, err := c.client.GetAll(ctx, query, &repos)
+	return repos, err
+}
+
 // DeleteRepository deletes a repository
 func (c *Client) DeleteRepository(ctx context.Context, id string) error {
 	key := datastore.NameKey(KindRepository, id, nil)
@@ -87,6 +178,7 @@ func (c *Client) DeleteRepository(ctx context.Context, id string) error {
 func (c *Client) SavePullRequests(ctx context.Context, prs []*model.PullRequest) error {
 	keys := make([]*datastore.Key, len(prs))
 	for i, pr := range prs {
+		pr.SchemaVersion = model.CurrentSchemaVersion
 		keys[i] = datastore.NameKey(KindPullRequest, pr.ID, nil)
 	}
 
@@ -104,9 +196,49 @@ func (c *Client) GetPullRequest(ctx context.Context, id string) (*model.PullRequ
 	return pr, nil
 }
 
+// getBatchSize is the maximum number of keys per GetMulti call.
+const getBatchSize = 1000
+
+// GetPullRequests gets the stored pull requests with the given IDs, keyed by ID.
+// IDs without a stored pull request are left out.
+func (c *Client) GetPullRequests(ctx context.Context, ids []string) (map[string]*model.PullRequest, error) {
+	found := make(map[string]*model.PullRequest, len(ids))
+	for start := 0; start < len(ids); start += getBatchSize {
+		chunk := ids[start:min(start+getBatchSize, len(ids))]
+		keys := make([]*datastore.Key, len(chunk))
+		prs := make([]*model.PullRequest, len(chunk))
+		for i, id := range chunk {
+			keys[i] = datastore.NameKey(KindPullRequest, id, nil)
+			prs[i] = &model.PullRequest{}
+		}
+
+		err := c.client.GetMulti(ctx, keys, prs)
+		var multiErr datastore.MultiError
+		if err != nil && !errors.As(err, &multiErr) {
+			return nil, err
+		}
+		for i, id := range chunk {
+			if multiErr != nil && multiErr[i] != nil {
+				if errors.Is(multiErr[i], datastore.ErrNoSuchEntity) {
+					continue
+				}
+				return nil, multiErr[i]
+			}
+			found[id] = prs[i]
+		}
+	}
+	return found, nil
+}
+
 // ListPullRequests lists pull requests for a repository
 func (c *Client) ListPullRequests(ctx context.Context, repositoryID string, opts *QueryOptions) ([]*model.PullRequest, error) {
 	var prs []*model.PullRequest
+	_, err := c.client.GetAll(ctx, pullRequestsQuery(repositoryID, opts), &prs)
+	return prs, err
+}
+
+// pullRequestsQuery builds the query used by ListPullRequests.
+func pullRequestsQuery(repositoryID string, opts *QueryOptions) *datastore.Query {
 	query := datastore.NewQuery(KindPullRequest).
 		FilterField("repository_id", "=", repositoryID).
 		Order("-created_at")
@@ -119,23 +251,101 @@ func (c *Client) ListPullRequests(ctx context.Context, repositoryID string, opts
 			query = query.Limit(opts.Limit)
 		}
 	}
-
-	_, err := c.client.GetAll(ctx, query, &prs)
-	return prs, err
+	return query
 }
 
-// ListPullRequestsByDateRange lists PRs within a date range
+// ListPullRequestsByDateRange lists PRs within a date range.
+// Falls back to filtering in memory while the (repository_id, created_at) index is missing.
 func (c *Client) ListPullRequestsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.PullRequest, error) {
-	var prs []*model.PullRequest
-	query := datastore.NewQuery(KindPullRequest).
+	query := pullRequestsByDateRangeQuery(repositoryID, startDate, endDate)
+	fallback := datastore.NewQuery(KindPullRequest).FilterField("repository_id", "=", repositoryID)
+
+	return getAllWithIndexFallback(ctx, c.logger, getAllFunc[model.PullRequest](ctx, c.client), query, fallback,
+		func(pr *model.PullRequest) bool { return inRange(pr.CreatedAt, startDate, endDate) })
+}
+
+// pullRequestsByDateRangeQuery builds the query for PRs created within a date range.
+func pullRequestsByDateRangeQuery(repositoryID string, startDate, endDate time.Time) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
 		FilterField("repository_id", "=", repositoryID).
 		FilterField("created_at", ">=", startDate).
 		FilterField("created_at", "<=", endDate)
+}
 
-	_, err := c.client.GetAll(ctx, query, &prs)
+// ListPullRequestsUpdatedSince lists PRs updated at or after since.
+// Every PR opened, merged or closed after since is included, since each of those events bumps updated_at.
+func (c *Client) ListPullRequestsUpdatedSince(ctx context.Context, repositoryID string, since time.Time) ([]*model.PullRequest, error) {
+	var prs []*model.PullRequest
+	_, err := c.client.GetAll(ctx, pullRequestsUpdatedSinceQuery(repositoryID, since), &prs)
+	return prs, err
+}
+
+// pullRequestsUpdatedSinceQuery builds the updated-since PR query.
+// Requires the composite index (repository_id ASC, updated_at ASC).
+func pullRequestsUpdatedSinceQuery(repositoryID string, since time.Time) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", repositoryID).
+		FilterField("updated_at", ">=", since).
+		Order("updated_at")
+}
+
+// ListOpenPullRequests lists currently open PRs regardless of their creation date,
+// least recently updated first
+func (c *Client) ListOpenPullRequests(ctx context.Context, repositoryID string) ([]*model.PullRequest, error) {
+	var prs []*model.PullRequest
+	_, err := c.client.GetAll(ctx, openPullRequestsQuery(repositoryID), &prs)
+	return prs, err
+}
+
+// openPullRequestsQuery builds the open PR query.
+// Requires the composite index (repository_id ASC, state ASC, updated_at ASC).
+func openPullRequestsQuery(repositoryID string) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", repositoryID).
+		FilterField("state", "=", "open").
+		Order("updated_at")
+}
+
+// ListPullRequestsByAuthor lists all PRs of a repository opened by author, regardless of date.
+func (c *Client) ListPullRequestsByAuthor(ctx context.Context, repositoryID, author string) ([]*model.PullRequest, error) {
+	var prs []*model.PullRequest
+	_, err := c.client.GetAll(ctx, pullRequestsByAuthorQuery(repositoryID, author), &prs)
 	return prs, err
 }
 
+// pullRequestsByAuthorQuery builds the per-author PR query.
+// Equality filters only, so it is served by the built-in indexes.
+func pullRequestsByAuthorQuery(repositoryID, author string) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", repositoryID).
+		FilterField("author", "=", author)
+}
+
+// ListAuthors returns the distinct PR authors of a repository in alphabetical order.
+func (c *Client) ListAuthors(ctx context.Context, repositoryID string) ([]string, error) {
+	var rows []struct {
+		Author string `datastore:"author"`
+	}
+	if _, err := c.client.GetAll(ctx, authorsQuery(repositoryID), &rows); err != nil {
+		return nil, err
+	}
+	authors := make([]string, 0, len(rows))
+	for _, row := range rows {
+		authors = append(authors, row.Author)
+	}
+	return authors, nil
+}
+
+// authorsQuery builds the distinct author projection query.
+// Requires the composite index (repository_id ASC, author ASC).
+func authorsQuery(repositoryID string) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", repositoryID).
+		Project("author").
+		DistinctOn("author").
+		Order("author")
+}
+
 // Review operations
 
 // SaveReviews saves multiple reviews
@@ -146,6 +356,7 @@ func (c *Client) SaveReviews(ctx context.Context, reviews []*model.Review) error
 
 	keys := make([]*datastore.Key, len(reviews))
 	for i, r := range reviews {
+		r.SchemaVersion = model.CurrentSchemaVersion
 		keys[i] = datastore.NameKey(KindReview, r.ID, nil)
 	}
 
@@ -156,6 +367,12 @@ func (c *Client) SaveReviews(ctx context.Context, reviews []*model.Review) error
 // ListReviews lists reviews for a repository
 func (c *Client) ListReviews(ctx context.Context, repositoryID string, opts *QueryOptions) ([]*model.Review, error) {
 	var reviews []*model.Review
+	_, err := c.client.GetAll(ctx, reviewsQuery(repositoryID, opts), &reviews)
+	return reviews, err
+}
+
+// reviewsQuery builds the query used by ListReviews.
+func reviewsQuery(repositoryID string, opts *QueryOptions) *datastore.Query {
 	query := datastore.NewQuery(KindReview).
 		FilterField("repository_id", "=", repositoryID).
 		Order("-submitted_at")
@@ -168,21 +385,25 @@ func (c *Client) ListReviews(ctx context.Context, repositoryID string, opts *Que
 			query = query.Limit(opts.Limit)
 		}
 	}
-
-	_, err := c.client.GetAll(ctx, query, &reviews)
-	return reviews, err
+	return query
 }
 
-// ListReviewsByDateRange lists reviews within a date range
+// ListReviewsByDateRange lists reviews within a date range.
+// Falls back to filtering in memory while the (repository_id, submitted_at) index is missing.
 func (c *Client) ListReviewsByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.Review, error) {
-	var reviews []*model.Review
-	query := datastore.NewQuery(KindReview).
+	query := reviewsByDateRangeQuery(repositoryID, startDate, endDate)
+	fallback := datastore.NewQuery(KindReview).FilterField("repository_id", "=", repositoryID)
+
+	return getAllWithIndexFallback(ctx, c.logger, getAllFunc[model.Review](ctx, c.client), query, fallback,
+		func(r *model.Review) bool { return inRange(r.SubmittedAt, startDate, endDate) })
+}
+
+// reviewsByDateRangeQuery builds the query for reviews submitted within a date range.
+func reviewsByDateRangeQuery(repositoryID string, startDate, endDate time.Time) *datastore.Query {
+	return datastore.NewQuery(KindReview).
 		FilterField("repository_id", "=", repositoryID).
 		FilterField("submitted_at", ">=", startDate).
 		FilterField("submitted_at", "<=", endDate)
-
-	_, err := c.client.GetAll(ctx, query, &reviews)
-	return reviews, err
 }
 
 // Deployment operations
@@ -195,6 +416,10 @@ func (c *Client) SaveDeployments(ctx context.Context, deployments []*model.Deplo
 
 	keys := make([]*datastore.Key, len(deployments))
 	for i, d := range deployments {
+		if d.ID == "" {
+			d.ID = model.DeploymentID(d.RepositoryID, d.Environment, d.SHA, d.Ref, d.CreatedAt)
+		}
+		d.SchemaVersion = model.CurrentSchemaVersion
 		keys[i] = datastore.NameKey(KindDeployment, d.ID, nil)
 	}
 
@@ -205,6 +430,12 @@ func (c *Client) SaveDeployments(ctx context.Context, deployments []*model.Deplo
 // ListDeployments lists deployments for a repository
 func (c *Client) ListDeployments(ctx context.Context, repositoryID string, opts *QueryOptions) ([]*model.Deployment, error) {
 	var deployments []*model.Deployment
+	_, err := c.client.GetAll(ctx, deploymentsQuery(repositoryID, opts), &deployments)
+	return deployments, err
+}
+
+// deploymentsQuery builds the query used by ListDeployments.
+func deploymentsQuery(repositoryID string, opts *QueryOptions) *datastore.Query {
 	query := datastore.NewQuery(KindDeployment).
 		FilterField("repository_id", "=", repositoryID).
 		Order("-created_at")
@@ -217,9 +448,7 @@ func (c *Client) ListDeployments(ctx context.Context, repositoryID string, opts
 			query = query.Limit(opts.Limit)
 		}
 	}
-
-	_, err := c.client.GetAll(ctx, query, &deployments)
-	return deployments, err
+	return query
 }
 
 // Daily Metrics operations
@@ -249,14 +478,17 @@ func (c *Client) SaveDailyMetricsBatch(ctx context.Context, metricsList []*model
 // ListDailyMetrics lists daily metrics for a repository
 func (c *Client) ListDailyMetrics(ctx context.Context, repositoryID string, startDate, endDate time.Time) ([]*model.DailyMetrics, error) {
 	var metrics []*model.DailyMetrics
-	query := datastore.NewQuery(KindDailyMetrics).
+	_, err := c.client.GetAll(ctx, dailyMetricsQuery(repositoryID, startDate, endDate), &metrics)
+	return metrics, err
+}
+
+// dailyMetricsQuery builds the query used by ListDailyMetrics.
+func dailyMetricsQuery(repositoryID string, startDate, endDate time.Time) *datastore.Query {
+	return datastore.NewQuery(KindDailyMetrics).
 		FilterField("repository_id", "=", repositoryID).
 		FilterField("date", ">=", startDate).
 		FilterField("date", "<=", endDate).
 		Order("date")
-
-	_, err := c.client.GetAll(ctx, query, &metrics)
-	return metrics, err
 }
 
 // Team Member operations
@@ -303,15 +535,262 @@ func (c *Client) GetSprint(ctx context.Context, id string) (*model.Sprint, error
 	return sprint, nil
 }
 
+// DeleteSprint deletes a sprint by ID
+func (c *Client) DeleteSprint(ctx context.Context, id string) error {
+	key := datastore.NameKey(KindSprint, id, nil)
+	return c.client.Delete(ctx, key)
+}
+
 // ListSprints lists sprints for a repository
 func (c *Client) ListSprints(ctx context.Context, repositoryID string) ([]*model.Sprint, error) {
 	var sprints []*model.Sprint
-	query := datastore.NewQuery(KindSprint).
+	_, err := c.client.GetAll(ctx, sprintsQuery(repositoryID), &sprints)
+	return sprints, err
+}
+
+// sprintsQuery builds the query used by ListSprints.
+func sprintsQuery(repositoryID string) *datastore.Query {
+	return datastore.NewQuery(KindSprint).
 		FilterField("repository_id", "=", repositoryID).
 		Order("-start_date")
+}
 
-	_, err := c.client.GetAll(ctx, query, &sprints)
-	return sprints, err
+// Export operations
+
+// EachPullRequestByDateRange calls fn for each PR created within a date range,
+// reading with an iterator so the whole result is never held in memory.
+func (c *Client) EachPullRequestByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.PullRequest) error) error {
+	return runEach(ctx, c.client, pullRequestsByDateRangeQuery(repositoryID, startDate, endDate), fn)
+}
+
+// EachReviewByDateRange calls fn for each review submitted within a date range.
+func (c *Client) EachReviewByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Review) error) error {
+	return runEach(ctx, c.client, reviewsByDateRangeQuery(repositoryID, startDate, endDate), fn)
+}
+
+// EachDeploymentByDateRange calls fn for each deployment created within a date range.
+func (c *Client) EachDeploymentByDateRange(ctx context.Context, repositoryID string, startDate, endDate time.Time, fn func(*model.Deployment) error) error {
+	return runEach(ctx, c.client, deploymentsByDateRangeQuery(repositoryID, startDate, endDate), fn)
+}
+
+// deploymentsByDateRangeQuery builds the query for deployments created within a date range.
+func deploymentsByDateRangeQuery(repositoryID string, startDate, endDate time.Time) *datastore.Query {
+	return datastore.NewQuery(KindDeployment).
+		FilterField("repository_id", "=", repositoryID).
+		FilterField("created_at", ">=", startDate).
+		FilterField("created_at", "<=", endDate)
+}
+
+// runEach runs a query and calls fn for each entity; an error from fn stops the iteration.
+func runEach[T any](ctx context.Context, client *datastore.Client, query *datastore.Query, fn func(*T) error) error {
+	it := client.Run(ctx, query)
+	for {
+		entity := new(T)
+		if _, err := it.Next(entity); errors.Is(err, iterator.Done) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+}
+
+// Index fallback
+
+// getAllFunc returns a function running GetAll for entities of type T.
+func getAllFunc[T any](ctx context.Context, client *datastore.Client) func(*datastore.Query) ([]*T, error) {
+	return func(query *datastore.Query) ([]*T, error) {
+		var entities []*T
+		_, err := client.GetAll(ctx, query, &entities)
+		return entities, err
+	}
+}
+
+// getAllWithIndexFallback runs query; when Datastore rejects it for a missing composite index
+// (as on a fresh deployment before `terraform apply`), it runs fallback, a query served by the
+// built-in single-property indexes, and keeps the entities matching keep instead.
+// The fallback reads every entity matched by the equality filter, so a warning asks for the index.
+func getAllWithIndexFallback[T any](ctx context.Context, logger *slog.Logger, getAll func(*datastore.Query) ([]*T, error), query, fallback *datastore.Query, keep func(*T) bool) ([]*T, error) {
+	entities, err := getAll(query)
+	if err == nil || !isMissingIndex(err) {
+		return entities, err
+	}
+
+	logger.WarnContext(ctx, "composite index missing, filtering in memory; run terraform apply to create it", "error", err)
+	all, err := getAll(fallback)
+	if err != nil {
+		return nil, err
+	}
+	entities = all[:0]
+	for _, e := range all {
+		if keep(e) {
+			entities = append(entities, e)
+		}
+	}
+	return entities, nil
+}
+
+// isMissingIndex reports whether err is Datastore rejecting a query for lack of a composite index.
+func isMissingIndex(err error) bool {
+	return status.Code(err) == codes.FailedPrecondition && strings.Contains(strings.ToLower(err.Error()), "index")
+}
+
+// inRange reports whether t is within [start, end], matching the >= and <= query filters.
+func inRange(t, start, end time.Time) bool {
+	return !t.Before(start) && !t.After(end)
+}
+
+// Schema operations
+
+// SchemaVersionedKinds lists the kinds stamped with model.CurrentSchemaVersion on save.
+var SchemaVersionedKinds = []string{KindRepository, KindPullRequest, KindReview, KindDeployment}
+
+// CountBySchemaVersion counts the entities of a kind per schema version.
+// Entities saved before versioning have no schema_version property and cannot be
+// filtered on, so they are counted as version 0: the total minus every versioned count.
+func (c *Client) CountBySchemaVersion(ctx context.Context, kind string) (map[int]int64, error) {
+	total, err := c.count(ctx, datastore.NewQuery(kind))
+	if err != nil {
+		return nil, err
+	}
+	versioned := make(map[int]int64)
+	for v := 1; v <= model.CurrentSchemaVersion; v++ {
+		n, err := c.count(ctx, datastore.NewQuery(kind).FilterField("schema_version", "=", v))
+		if err != nil {
+			return nil, err
+		}
+		versioned[v] = n
+	}
+	return schemaVersionCounts(total, versioned), nil
+}
+
+// schemaVersionCounts attributes the entities not counted under any version to version 0.
+// Versions with no entities are omitted.
+func schemaVersionCounts(total int64, versioned map[int]int64) map[int]int64 {
+	counts := make(map[int]int64, len(versioned)+1)
+	for v, n := range versioned {
+		if n > 0 {
+			counts[v] = n
+		}
+		total -= n
+	}
+	if total > 0 {
+		counts[0] = total
+	}
+	return counts
+}
+
+// StatsKinds lists the kinds reported by the storage stats endpoint.
+var StatsKinds = []string{KindRepository, KindPullRequest, KindReview, KindDeployment, KindDailyMetrics}
+
+// CountKind counts the entities of a kind; a non-empty repositoryID limits the count to that repository.
+func (c *Client) CountKind(ctx context.Context, kind, repositoryID string) (int64, error) {
+	return c.count(ctx, countKindQuery(kind, repositoryID))
+}
+
+// countKindQuery builds the keys-only query counted by CountKind.
+// Repositories are matched by key; other kinds by their repository_id property.
+func countKindQuery(kind, repositoryID string) *datastore.Query {
+	query := datastore.NewQuery(kind).KeysOnly()
+	switch {
+	case repositoryID == "":
+		return query
+	case kind == KindRepository:
+		return query.FilterField("__key__", "=", datastore.NameKey(KindRepository, repositoryID, nil))
+	default:
+		return query.FilterField("repository_id", "=", repositoryID)
+	}
+}
+
+// count runs a COUNT aggregation over a query.
+func (c *Client) count(ctx context.Context, query *datastore.Query) (int64, error) {
+	result, err := c.client.RunAggregationQuery(ctx, query.NewAggregationQuery().WithCount("count"))
+	if err != nil {
+		return 0, err
+	}
+	value, ok := result["count"].(*datastorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result %T", result["count"])
+	}
+	return value.GetIntegerValue(), nil
+}
+
+// EachPullRequest calls fn for each PR of a repository.
+func (c *Client) EachPullRequest(ctx context.Context, repositoryID string, fn func(*model.PullRequest) error) error {
+	query := datastore.NewQuery(KindPullRequest).FilterField("repository_id", "=", repositoryID)
+	return runEach(ctx, c.client, query, fn)
+}
+
+// EachReview calls fn for each review of a repository.
+func (c *Client) EachReview(ctx context.Context, repositoryID string, fn func(*model.Review) error) error {
+	query := datastore.NewQuery(KindReview).FilterField("repository_id", "=", repositoryID)
+	return runEach(ctx, c.client, query, fn)
+}
+
+// EachDeployment calls fn for each deployment of a repository.
+func (c *Client) EachDeployment(ctx context.Context, repositoryID string, fn func(*model.Deployment) error) error {
+	query := datastore.NewQuery(KindDeployment).FilterField("repository_id", "=", repositoryID)
+	return runEach(ctx, c.client, query, fn)
+}
+
+// Retention operations
+
+// deleteBatchSize is the maximum number of keys per DeleteMulti call.
+const deleteBatchSize = 500
+
+// retentionFields maps the kinds pruned by the retention cleanup to the timestamp compared with the cutoff.
+// PRs use updated_at so that long-running PRs are kept while they are still active.
+// DailyMetrics are not listed: they are the aggregate kept after the raw data is pruned.
+var retentionFields = map[string]string{
+	KindPullRequest: "updated_at",
+	KindReview:      "submitted_at",
+	KindDeployment:  "created_at",
+}
+
+// RetentionKinds lists the kinds DeleteOlderThan accepts.
+var RetentionKinds = []string{KindPullRequest, KindReview, KindDeployment}
+
+// DeleteOlderThan deletes the entities of a kind in a repository whose timestamp is before cutoff,
+// returning the number deleted.
+func (c *Client) DeleteOlderThan(ctx context.Context, repositoryID, kind string, cutoff time.Time) (int, error) {
+	query, err := olderThanQuery(kind, repositoryID, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := c.client.GetAll(ctx, query, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s keys: %w", kind, err)
+	}
+	return deleteInChunks(ctx, keys, c.client.DeleteMulti)
+}
+
+// olderThanQuery builds the keys-only query for the entities DeleteOlderThan removes.
+// Requires the composite index on (repository_id, <timestamp>) already used by the date range queries.
+func olderThanQuery(kind, repositoryID string, cutoff time.Time) (*datastore.Query, error) {
+	field, ok := retentionFields[kind]
+	if !ok {
+		return nil, fmt.Errorf("kind %q is not subject to retention", kind)
+	}
+	return datastore.NewQuery(kind).
+		FilterField("repository_id", "=", repositoryID).
+		FilterField(field, "<", cutoff).
+		KeysOnly(), nil
+}
+
+// deleteInChunks deletes keys in batches of deleteBatchSize, returning the number deleted
+// before the first failure.
+func deleteInChunks(ctx context.Context, keys []*datastore.Key, deleteMulti func(context.Context, []*datastore.Key) error) (int, error) {
+	deleted := 0
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(keys))
+		if err := deleteMulti(ctx, keys[start:end]); err != nil {
+			return deleted, err
+		}
+		deleted = end
+	}
+	return deleted, nil
 }
 
 // QueryOptions options for queries
@@ -322,6 +801,41 @@ type QueryOptions struct {
 	Offset int
 }
 
+// cacheFormatGzip prefixes gzip-compressed cache bodies.
+// Entries written before compression hold raw JSON, which never starts with this byte.
+const cacheFormatGzip byte = 0x01
+
+// encodeCacheBody gzips body behind the format byte.
+func encodeCacheBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(cacheFormatGzip)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeCacheBody returns the original body of a stored entry; legacy uncompressed entries are returned as is.
+func decodeCacheBody(stored []byte) ([]byte, error) {
+	if len(stored) == 0 || stored[0] != cacheFormatGzip {
+		return stored, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(stored[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compressed cache body: %w", err)
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress cache body: %w", err)
+	}
+	return body, nil
+}
+
 // MetricsCacheEntry is a cache entry stored in Datastore.
 // Key format: "{endpoint}:{reposHash}:{start}:{end}".
 // e.g. "metrics/cycle-time:all:2026-01-06:2026-02-06"
@@ -329,13 +843,64 @@ type QueryOptions struct {
 // e.g. "team/members/14109108/stats:all:2026-01-06:2026-02-06"
 type MetricsCacheEntry struct {
 	Key       string    `datastore:"key"`
-	Body      []byte    `datastore:"body,noindex"`
+	Body      []byte    `datastore:"body,noindex"` // gzip behind cacheFormatGzip, or raw JSON for legacy entries
 	CreatedAt time.Time `datastore:"created_at"`
 	TTLSec    int       `datastore:"ttl_sec"`
+
+	Headers []MetricsCacheHeader `datastore:"headers,noindex"` // response headers kept with the body
+}
+
+// MetricsCacheHeader is a response header stored with a cache entry.
+type MetricsCacheHeader struct {
+	Name  string `datastore:"name,noindex"`
+	Value string `datastore:"value,noindex"`
 }
 
-// GetMetricsCache retrieves cache from Datastore. Returns nil if expired.
-func (c *Client) GetMetricsCache(ctx context.Context, cacheKey string) ([]byte, error) {
+// HeaderMap returns the stored headers by name, or nil when there are none.
+func (e *MetricsCacheEntry) HeaderMap() map[string]string {
+	if len(e.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(e.Headers))
+	for _, h := range e.Headers {
+		headers[h.Name] = h.Value
+	}
+	return headers
+}
+
+// Metrics cache TTL bounds
+const (
+	// DefaultMetricsCacheTTLSec is used for entries written (or previously stored) with TTLSec <= 0.
+	DefaultMetricsCacheTTLSec = 50 * 60
+	// MinMetricsCacheTTLSec is the shortest TTL an entry is stored with.
+	MinMetricsCacheTTLSec = 60
+	// MaxMetricsCacheBodySize is the largest body stored, leaving headroom under the ~1MiB entity limit.
+	MaxMetricsCacheBodySize = 900 * 1024
+)
+
+// ErrCacheBodyTooLarge is returned by PutMetricsCache for bodies still over MaxMetricsCacheBodySize
+// after compression.
+var ErrCacheBodyTooLarge = errors.New("cache body too large")
+
+// normalizeCacheTTL returns the TTL to store: non-positive values use the default,
+// and positive values are raised to the minimum.
+func normalizeCacheTTL(ttlSec int) int {
+	if ttlSec <= 0 {
+		return DefaultMetricsCacheTTLSec
+	}
+	return max(ttlSec, MinMetricsCacheTTLSec)
+}
+
+// expired reports whether the entry is past its TTL at now.
+// Entries stored with TTLSec <= 0 before the TTL was normalized on write are treated as having the default TTL.
+func (e *MetricsCacheEntry) expired(now time.Time) bool {
+	return now.Sub(e.CreatedAt) > time.Duration(normalizeCacheTTL(e.TTLSec))*time.Second
+}
+
+// GetMetricsCache retrieves cache from Datastore. Returns an error if expired.
+// The returned entry holds the decompressed body and its normalized TTL, so callers can
+// tell how much of the TTL is left.
+func (c *Client) GetMetricsCache(ctx context.Context, cacheKey string) (*MetricsCacheEntry, error) {
 	key := datastore.NameKey(KindMetricsCache, cacheKey, nil)
 	entry := &MetricsCacheEntry{}
 	if err := c.client.Get(ctx, key, entry); err != nil {
@@ -343,23 +908,43 @@ func (c *Client) GetMetricsCache(ctx context.Context, cacheKey string) ([]byte,
 	}
 
 	// Check TTL expiration
-	if time.Since(entry.CreatedAt) > time.Duration(entry.TTLSec)*time.Second {
+	if entry.expired(time.Now()) {
 		return nil, fmt.Errorf("cache expired")
 	}
 
-	return entry.Body, nil
+	body, err := decodeCacheBody(entry.Body)
+	if err != nil {
+		return nil, err
+	}
+	entry.Body = body
+	entry.TTLSec = normalizeCacheTTL(entry.TTLSec)
+	return entry, nil
 }
 
 // PutMetricsCache stores cache in Datastore.
-func (c *Client) PutMetricsCache(ctx context.Context, cacheKey string, body []byte, ttlSec int) error {
+// ttlSec <= 0 uses DefaultMetricsCacheTTLSec; shorter TTLs are raised to MinMetricsCacheTTLSec.
+// The body is stored gzip-compressed; bodies over MaxMetricsCacheBodySize after compression are rejected
+// with ErrCacheBodyTooLarge.
+// headers are stored alongside and returned by GetMetricsCache.
+func (c *Client) PutMetricsCache(ctx context.Context, cacheKey string, body []byte, headers map[string]string, ttlSec int) error {
+	encoded, err := encodeCacheBody(body)
+	if err != nil {
+		return fmt.Errorf("failed to compress cache body: %w", err)
+	}
+	if len(encoded) > MaxMetricsCacheBodySize {
+		return fmt.Errorf("%w: %d bytes compressed (max %d)", ErrCacheBodyTooLarge, len(encoded), MaxMetricsCacheBodySize)
+	}
 	key := datastore.NameKey(KindMetricsCache, cacheKey, nil)
 	entry := &MetricsCacheEntry{
 		Key:       cacheKey,
-		Body:      body,
+		Body:      encoded,
 		CreatedAt: time.Now(),
-		TTLSec:    ttlSec,
+		TTLSec:    normalizeCacheTTL(ttlSec),
 	}
-	_, err := c.client.Put(ctx, key, entry)
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		entry.Headers = append(entry.Headers, MetricsCacheHeader{Name: name, Value: headers[name]})
+	}
+	_, err = c.client.Put(ctx, key, entry)
 	return err
 }
 
@@ -381,11 +966,7 @@ func (c *Client) GetDataDateRange(ctx context.Context, repositoryID string) (*Da
 		CreatedAt time.Time `datastore:"created_at"`
 	}
 	var dates []prDate
-	q := datastore.NewQuery(KindPullRequest).
-		FilterField("repository_id", "=", repositoryID).
-		Order("-created_at").
-		Project("created_at")
-	if _, err := c.client.GetAll(ctx, q, &dates); err != nil {
+	if _, err := c.client.GetAll(ctx, pullRequestDatesQuery(repositoryID), &dates); err != nil {
 		return nil, fmt.Errorf("failed to get PR dates: %w", err)
 	}
 
@@ -403,6 +984,14 @@ func (c *Client) GetDataDateRange(ctx context.Context, repositoryID string) (*Da
 	return result, nil
 }
 
+// pullRequestDatesQuery builds the created_at projection used by GetDataDateRange.
+func pullRequestDatesQuery(repositoryID string) *datastore.Query {
+	return datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", repositoryID).
+		Order("-created_at").
+		Project("created_at")
+}
+
 // BotUser operations
 
 // SaveBotUser saves a custom bot user.
@@ -439,6 +1028,28 @@ func (c *Client) ListBotUsernames(ctx context.Context) ([]string, error) {
 	return usernames, nil
 }
 
+// Settings operations
+
+// settingsKeyName is the name of the single Settings entity.
+const settingsKeyName = "default"
+
+// GetSettings retrieves the runtime settings; empty settings are returned when none were saved.
+func (c *Client) GetSettings(ctx context.Context) (*model.Settings, error) {
+	key := datastore.NameKey(KindSettings, settingsKeyName, nil)
+	settings := &model.Settings{}
+	if err := c.client.Get(ctx, key, settings); err != nil && !errors.Is(err, datastore.ErrNoSuchEntity) {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSettings replaces the runtime settings.
+func (c *Client) SaveSettings(ctx context.Context, settings *model.Settings) error {
+	key := datastore.NameKey(KindSettings, settingsKeyName, nil)
+	_, err := c.client.Put(ctx, key, settings)
+	return err
+}
+
 // SyncLock operations
 
 // AcquireSyncLock acquires an exclusive lock using a transaction.
@@ -0,0 +1,438 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestOpenPullRequestsQuery(t *testing.T) {
+	got := openPullRequestsQuery("100")
+
+	want := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("state", "=", "open").
+		Order("updated_at")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("openPullRequestsQuery(\"100\") = %+v, want %+v", got, want)
+	}
+
+	// Filters on a different repository or ordering must not match
+	for name, other := range map[string]*datastore.Query{
+		"other repository": datastore.NewQuery(KindPullRequest).
+			FilterField("repository_id", "=", "200").
+			FilterField("state", "=", "open").
+			Order("updated_at"),
+		"descending order": datastore.NewQuery(KindPullRequest).
+			FilterField("repository_id", "=", "100").
+			FilterField("state", "=", "open").
+			Order("-updated_at"),
+		"no state filter": datastore.NewQuery(KindPullRequest).
+			FilterField("repository_id", "=", "100").
+			Order("updated_at"),
+	} {
+		if reflect.DeepEqual(got, other) {
+			t.Errorf("query unexpectedly equal to %s", name)
+		}
+	}
+}
+
+func TestAuthorsQuery(t *testing.T) {
+	got := authorsQuery("100")
+
+	want := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		Project("author").
+		DistinctOn("author").
+		Order("author")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("authorsQuery(\"100\") = %+v, want %+v", got, want)
+	}
+
+	// Without distinct-on every PR's author would be returned
+	notDistinct := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		Project("author").
+		Order("author")
+	if reflect.DeepEqual(got, notDistinct) {
+		t.Error("query unexpectedly equal to a non-distinct projection")
+	}
+}
+
+func TestPullRequestsUpdatedSinceQuery(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	got := pullRequestsUpdatedSinceQuery("100", since)
+
+	want := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("updated_at", ">=", since).
+		Order("updated_at")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pullRequestsUpdatedSinceQuery() = %+v, want %+v", got, want)
+	}
+
+	// Filtering on created_at would miss PRs merged or closed in the window
+	byCreated := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("created_at", ">=", since).
+		Order("created_at")
+	if reflect.DeepEqual(got, byCreated) {
+		t.Error("query unexpectedly filters on created_at")
+	}
+}
+
+func TestNormalizeCacheTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		ttlSec int
+		want   int
+	}{
+		{"zero uses default", 0, DefaultMetricsCacheTTLSec},
+		{"negative uses default", -30, DefaultMetricsCacheTTLSec},
+		{"below minimum is raised", 1, MinMetricsCacheTTLSec},
+		{"minimum is kept", MinMetricsCacheTTLSec, MinMetricsCacheTTLSec},
+		{"regular TTL is kept", 3000, 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCacheTTL(tt.ttlSec); got != tt.want {
+				t.Errorf("normalizeCacheTTL(%d) = %d, want %d", tt.ttlSec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsCacheEntryExpired(t *testing.T) {
+	now := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	defaultTTL := time.Duration(DefaultMetricsCacheTTLSec) * time.Second
+
+	tests := []struct {
+		name   string
+		age    time.Duration
+		ttlSec int
+		want   bool
+	}{
+		{"fresh entry", time.Minute, 3000, false},
+		{"past TTL", 3001 * time.Second, 3000, true},
+		{"zero TTL is not expired immediately", time.Second, 0, false},
+		{"zero TTL expires after the default", defaultTTL + time.Second, 0, true},
+		{"negative TTL is not expired immediately", time.Second, -1, false},
+		{"negative TTL expires after the default", defaultTTL + time.Second, -1, true},
+		{"short TTL is held for the minimum", 30 * time.Second, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &MetricsCacheEntry{CreatedAt: now.Add(-tt.age), TTLSec: tt.ttlSec}
+			if got := entry.expired(now); got != tt.want {
+				t.Errorf("expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheBodyRoundTrip(t *testing.T) {
+	var rows []string
+	for i := range 200 {
+		rows = append(rows, fmt.Sprintf(`{"date":"2026-01-%02d","prsOpened":%d,"prsMerged":%d,"avgCycleTime":12.5}`, i%28+1, i%7, i%5))
+	}
+	body := []byte(`{"dailyBreakdown":[` + strings.Join(rows, ",") + `]}`)
+
+	encoded, err := encodeCacheBody(body)
+	if err != nil {
+		t.Fatalf("encodeCacheBody: %v", err)
+	}
+	if encoded[0] != cacheFormatGzip {
+		t.Errorf("format byte = %#x, want %#x", encoded[0], cacheFormatGzip)
+	}
+	if len(encoded) >= len(body)/2 {
+		t.Errorf("encoded %d bytes from %d, want at least half the size saved for JSON", len(encoded), len(body))
+	}
+
+	decoded, err := decodeCacheBody(encoded)
+	if err != nil {
+		t.Fatalf("decodeCacheBody: %v", err)
+	}
+	if !bytes.Equal(decoded, body) {
+		t.Errorf("round trip changed the body")
+	}
+}
+
+func TestDecodeCacheBody_Legacy(t *testing.T) {
+	// Entries written before compression hold the raw JSON body
+	for _, legacy := range []string{`{"totalPRs":3}`, `[1,2,3]`, `"ok"`, ``} {
+		got, err := decodeCacheBody([]byte(legacy))
+		if err != nil {
+			t.Errorf("decodeCacheBody(%q) error = %v", legacy, err)
+			continue
+		}
+		if string(got) != legacy {
+			t.Errorf("decodeCacheBody(%q) = %q, want unchanged", legacy, got)
+		}
+	}
+}
+
+func TestDecodeCacheBody_Corrupt(t *testing.T) {
+	if _, err := decodeCacheBody([]byte{cacheFormatGzip, 'n', 'o', 't', 'g', 'z'}); err == nil {
+		t.Error("expected an error for a corrupt compressed body")
+	}
+}
+
+func TestPullRequestsByAuthorQuery(t *testing.T) {
+	got := pullRequestsByAuthorQuery("100", "alice")
+
+	want := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("author", "=", "alice")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pullRequestsByAuthorQuery = %+v, want %+v", got, want)
+	}
+
+	other := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("author", "=", "bob")
+	if reflect.DeepEqual(got, other) {
+		t.Error("query for another author should not match")
+	}
+}
+
+func TestSchemaVersionCounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int64
+		versioned map[int]int64
+		want      map[int]int64
+	}{
+		{"all unversioned", 5, map[int]int64{1: 0}, map[int]int64{0: 5}},
+		{"mixed", 10, map[int]int64{1: 7}, map[int]int64{0: 3, 1: 7}},
+		{"all current", 4, map[int]int64{1: 4}, map[int]int64{1: 4}},
+		{"empty kind", 0, map[int]int64{1: 0}, map[int]int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schemaVersionCounts(tt.total, tt.versioned)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("schemaVersionCounts(%d, %v) = %v, want %v", tt.total, tt.versioned, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountKindQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		kind         string
+		repositoryID string
+		want         *datastore.Query
+	}{
+		{"whole kind", KindPullRequest, "", datastore.NewQuery(KindPullRequest).KeysOnly()},
+		{"one repository", KindReview, "100", datastore.NewQuery(KindReview).KeysOnly().
+			FilterField("repository_id", "=", "100")},
+		{"daily metrics of one repository", KindDailyMetrics, "100", datastore.NewQuery(KindDailyMetrics).KeysOnly().
+			FilterField("repository_id", "=", "100")},
+		{"repository by key", KindRepository, "100", datastore.NewQuery(KindRepository).KeysOnly().
+			FilterField("__key__", "=", datastore.NameKey(KindRepository, "100", nil))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := countKindQuery(tt.kind, tt.repositoryID)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("countKindQuery(%q, %q) = %+v, want %+v", tt.kind, tt.repositoryID, got, tt.want)
+			}
+		})
+	}
+
+	// Counting must not load entity properties
+	if reflect.DeepEqual(countKindQuery(KindPullRequest, ""), datastore.NewQuery(KindPullRequest)) {
+		t.Error("countKindQuery should be keys-only")
+	}
+}
+
+func TestOlderThanQuery(t *testing.T) {
+	cutoff := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		kind  string
+		field string
+	}{
+		{KindPullRequest, "updated_at"},
+		{KindReview, "submitted_at"},
+		{KindDeployment, "created_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			got, err := olderThanQuery(tt.kind, "100", cutoff)
+			if err != nil {
+				t.Fatalf("olderThanQuery: %v", err)
+			}
+			want := datastore.NewQuery(tt.kind).
+				FilterField("repository_id", "=", "100").
+				FilterField(tt.field, "<", cutoff).
+				KeysOnly()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("olderThanQuery(%q) = %+v, want %+v", tt.kind, got, want)
+			}
+		})
+	}
+
+	// Daily metrics are the aggregate kept after pruning
+	for _, kind := range []string{KindDailyMetrics, KindRepository, KindSprint} {
+		if _, err := olderThanQuery(kind, "100", cutoff); err == nil {
+			t.Errorf("olderThanQuery(%q) should fail", kind)
+		}
+	}
+}
+
+func TestDeleteInChunks(t *testing.T) {
+	keys := make([]*datastore.Key, 2*deleteBatchSize+3)
+	for i := range keys {
+		keys[i] = datastore.NameKey(KindReview, fmt.Sprintf("r-%d", i), nil)
+	}
+
+	var sizes []int
+	deleted, err := deleteInChunks(t.Context(), keys, func(_ context.Context, batch []*datastore.Key) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("deleteInChunks: %v", err)
+	}
+	if deleted != len(keys) {
+		t.Errorf("deleted = %d, want %d", deleted, len(keys))
+	}
+	if want := []int{deleteBatchSize, deleteBatchSize, 3}; !slices.Equal(sizes, want) {
+		t.Errorf("batch sizes = %v, want %v", sizes, want)
+	}
+
+	// A failed batch stops the deletion and reports what was deleted before it
+	calls := 0
+	deleted, err = deleteInChunks(t.Context(), keys, func(_ context.Context, batch []*datastore.Key) error {
+		calls++
+		if calls == 2 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err == nil || deleted != deleteBatchSize {
+		t.Errorf("deleteInChunks with failure = %d, %v; want %d and an error", deleted, err, deleteBatchSize)
+	}
+
+	if deleted, err := deleteInChunks(t.Context(), nil, nil); deleted != 0 || err != nil {
+		t.Errorf("deleteInChunks(nil) = %d, %v", deleted, err)
+	}
+}
+
+func TestRepositoryIDsQuery(t *testing.T) {
+	want := datastore.NewQuery(KindRepository).Order("-updated_at").KeysOnly()
+	if got := repositoryIDsQuery(); !reflect.DeepEqual(got, want) {
+		t.Errorf("repositoryIDsQuery() = %+v, want %+v", got, want)
+	}
+
+	// Loading full entities defeats the point of the query
+	if reflect.DeepEqual(repositoryIDsQuery(), datastore.NewQuery(KindRepository).Order("-updated_at")) {
+		t.Error("repositoryIDsQuery should be keys-only")
+	}
+}
+
+func TestKeyNames(t *testing.T) {
+	keys := []*datastore.Key{
+		datastore.NameKey(KindRepository, "123456", nil),
+		datastore.NameKey(KindRepository, "789", nil),
+	}
+	if got, want := keyNames(keys), []string{"123456", "789"}; !slices.Equal(got, want) {
+		t.Errorf("keyNames = %v, want %v", got, want)
+	}
+	if got := keyNames(nil); got == nil || len(got) != 0 {
+		t.Errorf("keyNames(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestGetAllWithIndexFallback(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 6, 30, 23, 59, 59, 0, time.UTC)
+	query := datastore.NewQuery(KindPullRequest).
+		FilterField("repository_id", "=", "100").
+		FilterField("created_at", ">=", start).
+		FilterField("created_at", "<=", end)
+	fallback := datastore.NewQuery(KindPullRequest).FilterField("repository_id", "=", "100")
+	stored := []*model.PullRequest{
+		{ID: "before", CreatedAt: start.Add(-time.Second)},
+		{ID: "first", CreatedAt: start},
+		{ID: "inside", CreatedAt: start.AddDate(0, 0, 10)},
+		{ID: "last", CreatedAt: end},
+		{ID: "after", CreatedAt: end.Add(time.Second)},
+	}
+	keep := func(pr *model.PullRequest) bool { return inRange(pr.CreatedAt, start, end) }
+	missingIndex := status.Error(codes.FailedPrecondition, "no matching index found. recommended index is: ...")
+
+	t.Run("missing index falls back to the equality query", func(t *testing.T) {
+		var ran []*datastore.Query
+		getAll := func(q *datastore.Query) ([]*model.PullRequest, error) {
+			ran = append(ran, q)
+			if reflect.DeepEqual(q, query) {
+				return nil, fmt.Errorf("datastore: %w", missingIndex)
+			}
+			return slices.Clone(stored), nil
+		}
+
+		got, err := getAllWithIndexFallback(t.Context(), slog.Default(), getAll, query, fallback, keep)
+		if err != nil {
+			t.Fatalf("getAllWithIndexFallback: %v", err)
+		}
+		var ids []string
+		for _, pr := range got {
+			ids = append(ids, pr.ID)
+		}
+		if want := []string{"first", "inside", "last"}; !slices.Equal(ids, want) {
+			t.Errorf("IDs = %v, want %v", ids, want)
+		}
+		if len(ran) != 2 || !reflect.DeepEqual(ran[1], fallback) {
+			t.Errorf("ran %d queries, want the range query then the fallback", len(ran))
+		}
+	})
+
+	t.Run("index present", func(t *testing.T) {
+		calls := 0
+		getAll := func(q *datastore.Query) ([]*model.PullRequest, error) {
+			calls++
+			return stored[1:2], nil
+		}
+		got, err := getAllWithIndexFallback(t.Context(), slog.Default(), getAll, query, fallback, keep)
+		if err != nil || len(got) != 1 || calls != 1 {
+			t.Errorf("got %d entities, err %v, %d calls; want the query result without fallback", len(got), err, calls)
+		}
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		for _, queryErr := range []error{
+			status.Error(codes.Unavailable, "connection reset"),
+			status.Error(codes.FailedPrecondition, "transaction aborted"),
+		} {
+			calls := 0
+			getAll := func(q *datastore.Query) ([]*model.PullRequest, error) {
+				calls++
+				return nil, queryErr
+			}
+			if _, err := getAllWithIndexFallback(t.Context(), slog.Default(), getAll, query, fallback, keep); !errors.Is(err, queryErr) || calls != 1 {
+				t.Errorf("%v: err = %v after %d calls, want it returned without fallback", queryErr, err, calls)
+			}
+		}
+	})
+}
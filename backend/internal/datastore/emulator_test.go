@@ -0,0 +1,220 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// newEmulatorClient returns a Client connected to the Datastore emulator at DATASTORE_EMULATOR_HOST.
+// Each call uses its own project ID so tests don't see each other's entities.
+// The test is skipped when the emulator is not configured or not reachable.
+//
+//	gcloud beta emulators datastore start --host-port=localhost:8081 --no-store-on-disk
+//	DATASTORE_EMULATOR_HOST=localhost:8081 go test ./internal/datastore/...
+func newEmulatorClient(t *testing.T) *Client {
+	t.Helper()
+
+	host := os.Getenv("DATASTORE_EMULATOR_HOST")
+	if host == "" {
+		t.Skip("DATASTORE_EMULATOR_HOST not set; skipping emulator test")
+	}
+	conn, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("Datastore emulator not reachable at %s: %v", host, err)
+	}
+	_ = conn.Close()
+
+	projectID := fmt.Sprintf("dora-yaki-test-%d", time.Now().UnixNano())
+	client, err := NewClient(context.Background(), projectID)
+	if err != nil {
+		t.Fatalf("failed to create emulator client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestEmulator_SyncLockRace(t *testing.T) {
+	client := newEmulatorClient(t)
+	ctx := context.Background()
+
+	const contenders = 5
+	errs := make(chan error, contenders)
+	start := make(chan struct{})
+	for i := range contenders {
+		go func() {
+			<-start
+			errs <- client.AcquireSyncLock(ctx, "race", fmt.Sprintf("instance-%d", i), time.Minute)
+		}()
+	}
+	close(start)
+
+	var won int
+	for range contenders {
+		if err := <-errs; err == nil {
+			won++
+		}
+	}
+	if won != 1 {
+		t.Fatalf("%d of %d contenders acquired the lock, want exactly 1", won, contenders)
+	}
+
+	lock, err := client.GetSyncLock(ctx, "race")
+	if err != nil {
+		t.Fatalf("GetSyncLock: %v", err)
+	}
+	if !strings.HasPrefix(lock.LockedBy, "instance-") {
+		t.Errorf("lock held by %q, want one of the contenders", lock.LockedBy)
+	}
+}
+
+func TestEmulator_SyncLockRelease(t *testing.T) {
+	client := newEmulatorClient(t)
+	ctx := context.Background()
+
+	if err := client.AcquireSyncLock(ctx, "release", "owner", time.Minute); err != nil {
+		t.Fatalf("initial acquire: %v", err)
+	}
+
+	// Releasing with another holder's ID must keep the lock
+	if err := client.ReleaseSyncLock(ctx, "release", "intruder"); err != nil {
+		t.Fatalf("release by non-owner: %v", err)
+	}
+	if err := client.AcquireSyncLock(ctx, "release", "other", time.Minute); err == nil {
+		t.Fatal("lock acquired after a non-owner release, want it still held")
+	}
+
+	if err := client.ReleaseSyncLock(ctx, "release", "owner"); err != nil {
+		t.Fatalf("release by owner: %v", err)
+	}
+	if err := client.AcquireSyncLock(ctx, "release", "other", time.Minute); err != nil {
+		t.Errorf("acquire after owner release: %v", err)
+	}
+}
+
+func TestEmulator_SyncLockExpiry(t *testing.T) {
+	client := newEmulatorClient(t)
+	ctx := context.Background()
+
+	const ttl = 500 * time.Millisecond
+	if err := client.AcquireSyncLock(ctx, "expiry", "crashed", ttl); err != nil {
+		t.Fatalf("initial acquire: %v", err)
+	}
+	if err := client.AcquireSyncLock(ctx, "expiry", "next", ttl); err == nil {
+		t.Fatal("acquired a lock that has not expired")
+	}
+
+	// The holder never releases (e.g. the instance crashed); the lock frees up once the TTL passes
+	time.Sleep(ttl + 200*time.Millisecond)
+	if err := client.AcquireSyncLock(ctx, "expiry", "next", ttl); err != nil {
+		t.Fatalf("acquire after TTL expiry: %v", err)
+	}
+
+	lock, err := client.GetSyncLock(ctx, "expiry")
+	if err != nil {
+		t.Fatalf("GetSyncLock: %v", err)
+	}
+	if lock.LockedBy != "next" {
+		t.Errorf("lock held by %q, want %q", lock.LockedBy, "next")
+	}
+}
+
+func TestEmulator_MetricsCacheRoundTrip(t *testing.T) {
+	client := newEmulatorClient(t)
+	ctx := context.Background()
+
+	body := []byte(`{"totalPRs":42,"avgCycleTime":12.5}`)
+	headers := map[string]string{"X-Date-Range-Start": "2026-01-01T00:00:00Z"}
+	if err := client.PutMetricsCache(ctx, "metrics/cycle-time:all", body, headers, 60); err != nil {
+		t.Fatalf("PutMetricsCache: %v", err)
+	}
+	got, err := client.GetMetricsCache(ctx, "metrics/cycle-time:all")
+	if err != nil {
+		t.Fatalf("GetMetricsCache: %v", err)
+	}
+	if string(got.Body) != string(body) {
+		t.Errorf("GetMetricsCache body = %q, want %q", got.Body, body)
+	}
+	if got.TTLSec != 60 || got.CreatedAt.IsZero() {
+		t.Errorf("GetMetricsCache = created %v, TTL %ds, want a creation time and 60s", got.CreatedAt, got.TTLSec)
+	}
+	if !maps.Equal(got.HeaderMap(), headers) {
+		t.Errorf("GetMetricsCache headers = %v, want %v", got.HeaderMap(), headers)
+	}
+
+	// An entry written before compression is read back unchanged
+	legacy := &MetricsCacheEntry{Key: "legacy", Body: body, CreatedAt: time.Now(), TTLSec: 60}
+	if _, err := client.client.Put(ctx, datastore.NameKey(KindMetricsCache, "legacy", nil), legacy); err != nil {
+		t.Fatalf("put legacy entry: %v", err)
+	}
+	got, err = client.GetMetricsCache(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("GetMetricsCache(legacy): %v", err)
+	}
+	if string(got.Body) != string(body) {
+		t.Errorf("GetMetricsCache(legacy) body = %q, want %q", got.Body, body)
+	}
+
+	// A body that does not compress under the limit is rejected with the sentinel
+	noise := make([]byte, MaxMetricsCacheBodySize+1)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(noise)
+	if err := client.PutMetricsCache(ctx, "too-large", noise, nil, 60); !errors.Is(err, ErrCacheBodyTooLarge) {
+		t.Errorf("PutMetricsCache(noise) error = %v, want ErrCacheBodyTooLarge", err)
+	}
+}
+
+func TestEmulator_ListRepositoriesPage(t *testing.T) {
+	client := newEmulatorClient(t)
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		repo := &model.Repository{ID: fmt.Sprintf("repo-%d", i), UpdatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := client.SaveRepository(ctx, repo); err != nil {
+			t.Fatalf("SaveRepository: %v", err)
+		}
+	}
+
+	var paged []string
+	cursor := ""
+	for {
+		repos, next, err := client.ListRepositoriesPage(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("ListRepositoriesPage: %v", err)
+		}
+		for _, repo := range repos {
+			paged = append(paged, repo.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	ids, err := client.ListRepositoryIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListRepositoryIDs: %v", err)
+	}
+	want := []string{"repo-4", "repo-3", "repo-2", "repo-1", "repo-0"}
+	if strings.Join(paged, ",") != strings.Join(want, ",") {
+		t.Errorf("paged IDs = %v, want %v", paged, want)
+	}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("ListRepositoryIDs = %v, want %v", ids, want)
+	}
+
+	if _, _, err := client.ListRepositoriesPage(ctx, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ListRepositoriesPage(bogus) error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,51 @@
+package datastore
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// IndexProperty is one property of a composite index.
+type IndexProperty struct {
+	Name       string
+	Descending bool
+}
+
+// CompositeIndex is a composite index required by the client's queries.
+type CompositeIndex struct {
+	Kind       string
+	Properties []IndexProperty
+}
+
+// CompositeIndexes lists the composite indexes the queries in this package need.
+// Keep it in sync with terraform/datastore.tf; index.yaml is generated from it
+// (go test ./internal/datastore -run TestIndexYAML -update).
+var CompositeIndexes = []CompositeIndex{
+	{KindPullRequest, []IndexProperty{{Name: "repository_id"}, {Name: "created_at", Descending: true}}},
+	{KindPullRequest, []IndexProperty{{Name: "repository_id"}, {Name: "state"}, {Name: "updated_at"}}},
+	{KindPullRequest, []IndexProperty{{Name: "repository_id"}, {Name: "updated_at"}}},
+	{KindPullRequest, []IndexProperty{{Name: "repository_id"}, {Name: "author"}}},
+	{KindReview, []IndexProperty{{Name: "repository_id"}, {Name: "submitted_at", Descending: true}}},
+	{KindDeployment, []IndexProperty{{Name: "repository_id"}, {Name: "created_at", Descending: true}}},
+	{KindDailyMetrics, []IndexProperty{{Name: "repository_id"}, {Name: "date"}}},
+	{KindSprint, []IndexProperty{{Name: "repository_id"}, {Name: "start_date", Descending: true}}},
+}
+
+// IndexYAML renders CompositeIndexes in the index.yaml format read by
+// `gcloud datastore indexes create`.
+func IndexYAML() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated from datastore.CompositeIndexes; do not edit.\n")
+	buf.WriteString("# Regenerate: go test ./internal/datastore -run TestIndexYAML -update\n")
+	buf.WriteString("indexes:\n")
+	for _, index := range CompositeIndexes {
+		fmt.Fprintf(&buf, "\n- kind: %s\n  properties:\n", index.Kind)
+		for _, p := range index.Properties {
+			fmt.Fprintf(&buf, "  - name: %s\n", p.Name)
+			if p.Descending {
+				buf.WriteString("    direction: desc\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,283 @@
+package datastore
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+var update = flag.Bool("update", false, "rewrite index.yaml from CompositeIndexes")
+
+// indexYAMLPath is backend/index.yaml, relative to this package.
+const indexYAMLPath = "../../index.yaml"
+
+func TestIndexYAML(t *testing.T) {
+	want := IndexYAML()
+	if *update {
+		if err := os.WriteFile(indexYAMLPath, want, 0o644); err != nil {
+			t.Fatalf("write %s: %v", indexYAMLPath, err)
+		}
+	}
+	got, err := os.ReadFile(indexYAMLPath)
+	if err != nil {
+		t.Fatalf("read %s: %v", indexYAMLPath, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; run go test ./internal/datastore -run TestIndexYAML -update", indexYAMLPath)
+	}
+}
+
+// neededProperty is one property of the index a query needs.
+type neededProperty struct {
+	name         string
+	descending   bool
+	anyDirection bool // an inequality without a sort order can use either direction
+}
+
+// field returns the named field of a struct value, failing the test when it does not exist
+// (for instance after a datastore library upgrade renames the unexported query fields).
+func field(t *testing.T, v reflect.Value, name string) reflect.Value {
+	t.Helper()
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		t.Fatalf("%s has no field %q", v.Type(), name)
+	}
+	return f
+}
+
+// requiredIndex returns the composite index properties a query needs, or nil when the
+// built-in single-property indexes serve it: equality filters only, or filters, orders,
+// and projections on a single property.
+// The query's filters and orders are unexported, so they are read through reflection.
+func requiredIndex(t *testing.T, q *datastore.Query) []neededProperty {
+	t.Helper()
+	v := reflect.ValueOf(q).Elem()
+
+	var equality, inequality []string
+	filters := field(t, v, "filter")
+	for i := range filters.Len() {
+		f := filters.Index(i).Elem()
+		if f.Type() != reflect.TypeOf(datastore.PropertyFilter{}) {
+			t.Fatalf("unsupported filter type %s", f.Type())
+		}
+		name, op := field(t, f, "FieldName").String(), field(t, f, "Operator").String()
+		switch {
+		case op == "=":
+			equality = append(equality, name)
+		case !slices.Contains(inequality, name):
+			inequality = append(inequality, name)
+		}
+	}
+	if len(inequality) > 1 {
+		t.Fatalf("inequality filters on several properties %v", inequality)
+	}
+
+	var orders []neededProperty
+	ordersValue := field(t, v, "order")
+	for i := range ordersValue.Len() {
+		o := ordersValue.Index(i)
+		orders = append(orders, neededProperty{name: field(t, o, "FieldName").String(), descending: field(t, o, "Direction").Bool()})
+	}
+	var projection []string
+	projValue := field(t, v, "projection")
+	for i := range projValue.Len() {
+		projection = append(projection, projValue.Index(i).String())
+	}
+
+	var props []neededProperty
+	for _, name := range equality {
+		props = append(props, neededProperty{name: name})
+	}
+	if len(inequality) == 1 && (len(orders) == 0 || orders[0].name != inequality[0]) {
+		props = append(props, neededProperty{name: inequality[0], anyDirection: len(orders) == 0})
+	}
+	props = append(props, orders...)
+	for _, name := range projection {
+		if !slices.ContainsFunc(props, func(p neededProperty) bool { return p.name == name }) {
+			props = append(props, neededProperty{name: name})
+		}
+	}
+
+	if len(props) == len(equality) {
+		return nil
+	}
+	if len(equality) == 0 && len(props) == 1 {
+		return nil
+	}
+	return props
+}
+
+// covers reports whether index serves a query needing props: the equality properties
+// first in any order, then the rest in order with matching directions.
+func covers(index CompositeIndex, kind string, props []neededProperty, equalities int) bool {
+	if index.Kind != kind || len(index.Properties) != len(props) {
+		return false
+	}
+	for i, p := range props {
+		got := index.Properties[i]
+		if i < equalities {
+			if !slices.ContainsFunc(props[:equalities], func(e neededProperty) bool { return e.name == got.Name }) {
+				return false
+			}
+			continue
+		}
+		if got.Name != p.name || (!p.anyDirection && got.Descending != p.descending) {
+			return false
+		}
+	}
+	return true
+}
+
+// namedQuery is a query built by one of this package's query builders.
+type namedQuery struct {
+	name    string
+	builder string // name of the function that built query
+	query   *datastore.Query
+}
+
+// builtQueries returns every query builder in this package, with the options callers pass.
+func builtQueries(t *testing.T) []namedQuery {
+	t.Helper()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	mustQuery := func(q *datastore.Query, err error) *datastore.Query {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+
+	return []namedQuery{
+		{"repository IDs", "repositoryIDsQuery", repositoryIDsQuery()},
+		{"pull requests", "pullRequestsQuery", pullRequestsQuery("1", nil)},
+		{"pull requests by date range", "pullRequestsByDateRangeQuery", pullRequestsByDateRangeQuery("1", start, end)},
+		{"pull requests updated since", "pullRequestsUpdatedSinceQuery", pullRequestsUpdatedSinceQuery("1", start)},
+		{"open pull requests", "openPullRequestsQuery", openPullRequestsQuery("1")},
+		{"pull requests by author", "pullRequestsByAuthorQuery", pullRequestsByAuthorQuery("1", "alice")},
+		{"authors", "authorsQuery", authorsQuery("1")},
+		{"pull request dates", "pullRequestDatesQuery", pullRequestDatesQuery("1")},
+		{"reviews", "reviewsQuery", reviewsQuery("1", nil)},
+		{"reviews by date range", "reviewsByDateRangeQuery", reviewsByDateRangeQuery("1", start, end)},
+		{"deployments since", "deploymentsQuery", deploymentsQuery("1", &QueryOptions{Since: start})},
+		{"deployments by date range", "deploymentsByDateRangeQuery", deploymentsByDateRangeQuery("1", start, end)},
+		{"daily metrics", "dailyMetricsQuery", dailyMetricsQuery("1", start, end)},
+		{"sprints", "sprintsQuery", sprintsQuery("1")},
+		{"old pull requests", "olderThanQuery", mustQuery(olderThanQuery(KindPullRequest, "1", start))},
+		{"old reviews", "olderThanQuery", mustQuery(olderThanQuery(KindReview, "1", start))},
+		{"old deployments", "olderThanQuery", mustQuery(olderThanQuery(KindDeployment, "1", start))},
+		{"repository count", "countKindQuery", countKindQuery(KindRepository, "1")},
+		{"pull request count", "countKindQuery", countKindQuery(KindPullRequest, "1")},
+	}
+}
+
+func TestQueriesCoveredByIndexes(t *testing.T) {
+	used := make([]bool, len(CompositeIndexes))
+	for _, nq := range builtQueries(t) {
+		t.Run(nq.name, func(t *testing.T) {
+			props := requiredIndex(t, nq.query)
+			if props == nil {
+				return
+			}
+			kind := field(t, reflect.ValueOf(nq.query).Elem(), "kind").String()
+			equalities := countEqualities(t, nq.query)
+			for i, index := range CompositeIndexes {
+				if covers(index, kind, props, equalities) {
+					used[i] = true
+					return
+				}
+			}
+			t.Errorf("no composite index in CompositeIndexes covers %s %+v", kind, props)
+		})
+	}
+
+	// Stale definitions cost storage and write latency
+	for i, index := range CompositeIndexes {
+		if !used[i] {
+			t.Errorf("CompositeIndexes[%d] (%s %+v) is not used by any query", i, index.Kind, index.Properties)
+		}
+	}
+}
+
+// TestQueryBuildersRegistered keeps builtQueries complete: every function in this package
+// returning a *datastore.Query must be listed there, or its query would go unchecked.
+func TestQueryBuildersRegistered(t *testing.T) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var builders []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && returnsQuery(fn) {
+				builders = append(builders, fn.Name.Name)
+			}
+		}
+	}
+	if len(builders) == 0 {
+		t.Fatal("found no query builders; is the test running in the package directory?")
+	}
+
+	registered := make(map[string]bool)
+	for _, nq := range builtQueries(t) {
+		registered[nq.builder] = true
+	}
+	for _, name := range builders {
+		if !registered[name] {
+			t.Errorf("query builder %s is not listed in builtQueries", name)
+		}
+	}
+}
+
+// returnsQuery reports whether fn returns a *datastore.Query.
+func returnsQuery(fn *ast.FuncDecl) bool {
+	if fn.Type.Results == nil {
+		return false
+	}
+	for _, result := range fn.Type.Results.List {
+		star, ok := result.Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		sel, ok := star.X.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Query" {
+			continue
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "datastore" {
+			return true
+		}
+	}
+	return false
+}
+
+// countEqualities returns the number of equality filters of a query.
+func countEqualities(t *testing.T, q *datastore.Query) int {
+	t.Helper()
+	filters := field(t, reflect.ValueOf(q).Elem(), "filter")
+	n := 0
+	for i := range filters.Len() {
+		if field(t, filters.Index(i).Elem(), "Operator").String() == "=" {
+			n++
+		}
+	}
+	return n
+}
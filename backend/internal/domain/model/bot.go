@@ -1,9 +1,16 @@
 package model
 
-import "strings"
+import (
+	"path"
+	"strings"
+)
 
 // IsBot determines whether a username is a bot.
+// Custom entries containing "*" or "?" are glob patterns (path.Match syntax); other entries match exactly.
 func IsBot(username string, customBotUsernames []string) bool {
+	if username == "" {
+		return false
+	}
 	if strings.HasSuffix(username, "[bot]") {
 		return true
 	}
@@ -12,9 +19,23 @@ func IsBot(username string, customBotUsernames []string) bool {
 			return true
 		}
 	}
+	for _, bot := range customBotUsernames {
+		if !IsBotPattern(bot) {
+			continue
+		}
+		if ok, _ := path.Match(bot, username); ok {
+			return true
+		}
+	}
 	return false
 }
 
+// IsBotPattern reports whether a custom bot entry is a glob pattern.
+// GitHub logins cannot contain "*" or "?", so such entries never collide with exact names.
+func IsBotPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?")
+}
+
 // filterByBot is the generic bot filtering logic.
 // ボットフィルタリングの共通ロジック
 func filterByBot[T any](items []T, customBotUsernames []string, excludeBots, botsOnly bool, getUsername func(T) string) []T {
@@ -51,6 +51,39 @@ func TestIsBot_EmptyCustomList(t *testing.T) {
 	}
 }
 
+func TestIsBot_Patterns(t *testing.T) {
+	customBots := []string{"renovate", "*-bot", "ci-*", "deploy-?"}
+
+	tests := []struct {
+		name     string
+		username string
+		want     bool
+	}{
+		{"完全一致は従来通り", "renovate", true},
+		{"パターンでない登録は部分一致しない", "renovate-extra", false},
+		{"サフィックスのglob一致", "snyk-bot", true},
+		{"プレフィックスのglob一致", "ci-runner", true},
+		{"?は1文字に一致", "deploy-1", true},
+		{"?は2文字に一致しない", "deploy-12", false},
+		{"glob不一致", "robot", false},
+		{"[bot]サフィックスは引き続き判定", "dependabot[bot]", true},
+		{"空文字列はパターンがあっても非bot", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBot(tt.username, customBots); got != tt.want {
+				t.Errorf("IsBot(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+
+	// A pattern entry makes the previously unmatched name a bot
+	if !IsBot("renovate-extra", []string{"renovate*"}) {
+		t.Error(`IsBot("renovate-extra", ["renovate*"]) = false, want true`)
+	}
+}
+
 func TestFilterPullRequestsByBot(t *testing.T) {
 	customBots := []string{"renovate"}
 	prs := []*PullRequest{
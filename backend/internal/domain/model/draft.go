@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+// WasMostlyDraft reports whether the PR spent most of its life as a draft: it is still
+// a draft (including PRs merged or closed as drafts), or it was marked ready for review
+// more than halfway from creation to merge, close, or now.
+// Without ReadyForReviewAt only the current draft flag is known.
+func (pr *PullRequest) WasMostlyDraft(now time.Time) bool {
+	if pr.Draft {
+		return true
+	}
+	if pr.ReadyForReviewAt == nil {
+		return false
+	}
+	end := now
+	switch {
+	case pr.MergedAt != nil:
+		end = *pr.MergedAt
+	case pr.ClosedAt != nil:
+		end = *pr.ClosedAt
+	}
+	return pr.ReadyForReviewAt.Sub(pr.CreatedAt) > end.Sub(*pr.ReadyForReviewAt)
+}
+
+// FilterPullRequestsByDraft drops PRs that were mostly drafts unless includeDrafts is true.
+func FilterPullRequestsByDraft(prs []*PullRequest, includeDrafts bool) []*PullRequest {
+	if includeDrafts {
+		return prs
+	}
+	now := timeutil.Now()
+	result := make([]*PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		if !pr.WasMostlyDraft(now) {
+			result = append(result, pr)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWasMostlyDraft(t *testing.T) {
+	created := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		v := created.Add(time.Duration(hours) * time.Hour)
+		return &v
+	}
+	now := *at(100)
+
+	tests := []struct {
+		name string
+		pr   *PullRequest
+		want bool
+	}{
+		{"ドラフトのまま", &PullRequest{Draft: true}, true},
+		{"ドラフトのままマージ", &PullRequest{Draft: true, MergedAt: at(10)}, true},
+		{"ドラフト経験なし", &PullRequest{MergedAt: at(10)}, false},
+		{"前半でレディ", &PullRequest{ReadyForReviewAt: at(2), MergedAt: at(10)}, false},
+		{"後半でレディ", &PullRequest{ReadyForReviewAt: at(8), MergedAt: at(10)}, true},
+		{"ちょうど半分はドラフト扱いしない", &PullRequest{ReadyForReviewAt: at(5), MergedAt: at(10)}, false},
+		{"クローズ時刻で判定", &PullRequest{ReadyForReviewAt: at(8), ClosedAt: at(10)}, true},
+		{"オープンは現在時刻で判定", &PullRequest{ReadyForReviewAt: at(8)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pr.CreatedAt = created
+			if got := tt.pr.WasMostlyDraft(now); got != tt.want {
+				t.Errorf("WasMostlyDraft() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPullRequestsByDraft(t *testing.T) {
+	created := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	ready := created.Add(9 * time.Hour)
+	merged := created.Add(10 * time.Hour)
+	prs := []*PullRequest{
+		{ID: "1", CreatedAt: created, MergedAt: &merged},
+		{ID: "2", CreatedAt: created, Draft: true},
+		{ID: "3", CreatedAt: created, ReadyForReviewAt: &ready, MergedAt: &merged},
+	}
+
+	ids := func(prs []*PullRequest) []string {
+		var result []string
+		for _, pr := range prs {
+			result = append(result, pr.ID)
+		}
+		return result
+	}
+	if got := ids(FilterPullRequestsByDraft(prs, true)); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("includeDrafts=true got %v, want all", got)
+	}
+	if got := ids(FilterPullRequestsByDraft(prs, false)); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("includeDrafts=false got %v, want [1]", got)
+	}
+}
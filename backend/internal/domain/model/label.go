@@ -0,0 +1,57 @@
+package model
+
+import "strings"
+
+// FilterPullRequestsByLabel filters PRs by label, comparing names case-insensitively.
+// A PR is kept when it has any of the include labels (all of them when matchAll is true)
+// and none of the exclude labels. An empty include list keeps every PR not excluded.
+func FilterPullRequestsByLabel(prs []*PullRequest, include, exclude []string, matchAll bool) []*PullRequest {
+	if len(include) == 0 && len(exclude) == 0 {
+		return prs
+	}
+	result := make([]*PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		if hasAnyLabel(pr.Labels, exclude) {
+			continue
+		}
+		if len(include) > 0 {
+			if matchAll && !hasAllLabels(pr.Labels, include) {
+				continue
+			}
+			if !matchAll && !hasAnyLabel(pr.Labels, include) {
+				continue
+			}
+		}
+		result = append(result, pr)
+	}
+	return result
+}
+
+// hasAnyLabel reports whether labels contains any of want.
+func hasAnyLabel(labels, want []string) bool {
+	for _, w := range want {
+		if hasLabel(labels, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAllLabels reports whether labels contains every one of want.
+func hasAllLabels(labels, want []string) bool {
+	for _, w := range want {
+		if !hasLabel(labels, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(labels []string, name string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l, name) {
+			return true
+		}
+	}
+	return false
+}
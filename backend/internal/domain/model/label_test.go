@@ -0,0 +1,44 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterPullRequestsByLabel(t *testing.T) {
+	prs := []*PullRequest{
+		{ID: "1", Labels: []string{"feature"}},
+		{ID: "2", Labels: []string{"feature", "backend"}},
+		{ID: "3", Labels: []string{"dependencies"}},
+		{ID: "4", Labels: []string{"Feature", "dependencies"}},
+		{ID: "5"},
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		matchAll bool
+		wantIDs  []string
+	}{
+		{"フィルタなしは全件", nil, nil, false, []string{"1", "2", "3", "4", "5"}},
+		{"includeは大文字小文字を区別しない", []string{"FEATURE"}, nil, false, []string{"1", "2", "4"}},
+		{"複数includeはOR", []string{"backend", "dependencies"}, nil, false, []string{"2", "3", "4"}},
+		{"matchAllはAND", []string{"feature", "backend"}, nil, true, []string{"2"}},
+		{"excludeのみ", nil, []string{"dependencies"}, false, []string{"1", "2", "5"}},
+		{"excludeはincludeより優先", []string{"feature"}, []string{"dependencies"}, false, []string{"1", "2"}},
+		{"一致なし", []string{"docs"}, nil, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIDs []string
+			for _, pr := range FilterPullRequestsByLabel(prs, tt.include, tt.exclude, tt.matchAll) {
+				gotIDs = append(gotIDs, pr.ID)
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("got %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -11,11 +11,28 @@ type FileExtensionMetrics struct {
 	PRCount   int    `json:"prCount"`
 }
 
+// LanguageMetrics holds aggregated change statistics per language.
+type LanguageMetrics struct {
+	Language  string `json:"language"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Files     int    `json:"files"`
+	PRCount   int    `json:"prCount"`
+}
+
+// SizeBucketMetrics holds cycle time statistics for PRs of one size category.
+type SizeBucketMetrics struct {
+	Size         string  `json:"size"` // XS, S, M, L, XL
+	Count        int     `json:"count"`
+	AvgCycleTime float64 `json:"avgCycleTime"` // hours
+}
+
 // CycleTimeMetrics represents cycle time analysis data
 type CycleTimeMetrics struct {
 	Period          string                 `json:"period"`
 	StartDate       time.Time              `json:"startDate"`
 	EndDate         time.Time              `json:"endDate"`
+	Timezone        string                 `json:"timezone"` // location the date range was resolved in
 	TotalPRs        int                    `json:"totalPRs"`
 	AvgCycleTime    float64                `json:"avgCycleTime"`    // hours
 	AvgCodingTime   float64                `json:"avgCodingTime"`   // hours
@@ -27,6 +44,14 @@ type CycleTimeMetrics struct {
 	DailyBreakdown  []DailyMetrics         `json:"dailyBreakdown,omitempty"`
 	ByAuthor        []AuthorMetrics        `json:"byAuthor,omitempty"`
 	ByFileExtension []FileExtensionMetrics `json:"byFileExtension,omitempty"`
+	ByLanguage      []LanguageMetrics      `json:"byLanguage,omitempty"`
+	BySize          []SizeBucketMetrics    `json:"bySize,omitempty"`
+
+	// PRs closed without merge in the period; they have no cycle time and are not in TotalPRs
+	AbandonedPRCount int `json:"abandonedPRCount"`
+	// Merged PRs with a negative duration (left out of that phase's averages) or one longer
+	// than a year (kept), both signs of skewed or rewritten timestamps
+	DataQualityWarnings int `json:"dataQualityWarnings"`
 }
 
 // AuthorMetrics represents metrics for a specific author
@@ -34,8 +59,137 @@ type AuthorMetrics struct {
 	Author       string  `json:"author"`
 	PRCount      int     `json:"prCount"`
 	AvgCycleTime float64 `json:"avgCycleTime"`
-	Additions    int     `json:"additions"`
-	Deletions    int     `json:"deletions"`
+	// Median is less skewed than the mean by a single slow PR
+	MedianCycleTime float64 `json:"medianCycleTime"`
+	// Phase averages over the author's PRs with that phase, like the overall averages
+	AvgCodingTime float64 `json:"avgCodingTime"`
+	AvgPickupTime float64 `json:"avgPickupTime"`
+	AvgReviewTime float64 `json:"avgReviewTime"`
+	AvgMergeTime  float64 `json:"avgMergeTime"`
+	Additions     int     `json:"additions"`
+	Deletions     int     `json:"deletions"`
+}
+
+// CycleTimeTrend holds a rolling average of daily cycle time over a period.
+type CycleTimeTrend struct {
+	Period    string                `json:"period"`
+	StartDate time.Time             `json:"startDate"`
+	EndDate   time.Time             `json:"endDate"`
+	Timezone  string                `json:"timezone"`
+	Window    int                   `json:"window"` // days in the moving average
+	Points    []CycleTimeTrendPoint `json:"points"`
+}
+
+// CycleTimeTrendPoint is one day of a cycle time trend.
+// Days without merged PRs have no cycle time and are left out of the moving average.
+type CycleTimeTrendPoint struct {
+	Date          time.Time `json:"date"`
+	PRsMerged     int       `json:"prsMerged"`     // excluding bot-authored PRs
+	AvgCycleTime  *float64  `json:"avgCycleTime"`  // nil when no PRs were merged that day
+	MovingAverage *float64  `json:"movingAverage"` // nil when no PRs were merged in the window
+}
+
+// CycleTimeAnomalies lists the days of a period with unusually long average cycle time.
+type CycleTimeAnomalies struct {
+	Period     string             `json:"period"`
+	StartDate  time.Time          `json:"startDate"`
+	EndDate    time.Time          `json:"endDate"`
+	Timezone   string             `json:"timezone"`
+	Threshold  float64            `json:"threshold"`  // z-score above which a day is flagged
+	SampleDays int                `json:"sampleDays"` // days with merged PRs
+	Mean       float64            `json:"mean"`       // mean daily average cycle time (hours)
+	StdDev     float64            `json:"stdDev"`     // population standard deviation (hours)
+	Anomalies  []CycleTimeAnomaly `json:"anomalies"`
+}
+
+// CycleTimeAnomaly is one flagged day.
+type CycleTimeAnomaly struct {
+	Date         time.Time `json:"date"`
+	AvgCycleTime float64   `json:"avgCycleTime"`
+	ZScore       float64   `json:"zScore"`
+	PRsMerged    int       `json:"prsMerged"`
+}
+
+// ChurnMetrics summarizes lines added and deleted over a period.
+// DeletionRatio is deletions per added line; it is 0 when nothing was added.
+type ChurnMetrics struct {
+	Period         string        `json:"period"`
+	StartDate      time.Time     `json:"startDate"`
+	EndDate        time.Time     `json:"endDate"`
+	Timezone       string        `json:"timezone"`
+	TotalAdditions int           `json:"totalAdditions"`
+	TotalDeletions int           `json:"totalDeletions"`
+	TotalChurn     int           `json:"totalChurn"` // additions + deletions
+	DeletionRatio  float64       `json:"deletionRatio"`
+	Daily          []DailyChurn  `json:"daily"`
+	ByAuthor       []AuthorChurn `json:"byAuthor"`
+}
+
+// DailyChurn holds churn for a single day.
+type DailyChurn struct {
+	Date          time.Time `json:"date"`
+	Additions     int       `json:"additions"`
+	Deletions     int       `json:"deletions"`
+	Churn         int       `json:"churn"`
+	DeletionRatio float64   `json:"deletionRatio"`
+}
+
+// AuthorChurn holds churn for the merged PRs of a specific author.
+type AuthorChurn struct {
+	Author        string  `json:"author"`
+	PRCount       int     `json:"prCount"`
+	Additions     int     `json:"additions"`
+	Deletions     int     `json:"deletions"`
+	Churn         int     `json:"churn"`
+	DeletionRatio float64 `json:"deletionRatio"`
+}
+
+// ReviewCoverageMetrics shows how many merged PRs received at least one peer review.
+type ReviewCoverageMetrics struct {
+	Period       string                 `json:"period"`
+	StartDate    time.Time              `json:"startDate"`
+	EndDate      time.Time              `json:"endDate"`
+	Timezone     string                 `json:"timezone"`
+	MergedPRs    int                    `json:"mergedPRs"`
+	ReviewedPRs  int                    `json:"reviewedPRs"`
+	CoverageRate float64                `json:"coverageRate"` // percentage of merged PRs with a peer review
+	ByAuthor     []AuthorReviewCoverage `json:"byAuthor"`
+}
+
+// AuthorReviewCoverage holds review coverage of one author's merged PRs.
+type AuthorReviewCoverage struct {
+	Author       string  `json:"author"`
+	MergedPRs    int     `json:"mergedPRs"`
+	ReviewedPRs  int     `json:"reviewedPRs"`
+	CoverageRate float64 `json:"coverageRate"`
+}
+
+// CollaborationMetrics is the review collaboration graph: who reviews whose PRs.
+type CollaborationMetrics struct {
+	Period    string              `json:"period"`
+	StartDate time.Time           `json:"startDate"`
+	EndDate   time.Time           `json:"endDate"`
+	Timezone  string              `json:"timezone"`
+	Edges     []CollaborationEdge `json:"edges"`
+}
+
+// CollaborationEdge counts the reviews one reviewer submitted on one author's PRs.
+type CollaborationEdge struct {
+	Author   string `json:"author"`
+	Reviewer string `json:"reviewer"`
+	Count    int    `json:"count"`
+}
+
+// OnboardingMetrics describes how a contributor ramped up after opening their first PR.
+// First-merge fields are empty and TimeToFirstMerge is 0 when none of their PRs was merged.
+type OnboardingMetrics struct {
+	FirstPRAt           *time.Time `json:"firstPRAt,omitempty"`
+	FirstMergedAt       *time.Time `json:"firstMergedAt,omitempty"`
+	TimeToFirstMerge    float64    `json:"timeToFirstMerge"` // hours from first PR opened to first PR merged
+	PRsInFirst30Days    int        `json:"prsInFirst30Days"`
+	MergedInFirst30Days int        `json:"mergedInFirst30Days"`
+	TotalPRs            int        `json:"totalPRs"`
+	TotalMerged         int        `json:"totalMerged"`
 }
 
 // ReviewMetrics represents review analysis data
@@ -43,6 +197,7 @@ type ReviewMetrics struct {
 	Period               string          `json:"period"`
 	StartDate            time.Time       `json:"startDate"`
 	EndDate              time.Time       `json:"endDate"`
+	Timezone             string          `json:"timezone"`
 	TotalReviews         int             `json:"totalReviews"`
 	TotalComments        int             `json:"totalComments"`
 	AvgReviewsPerPR      float64         `json:"avgReviewsPerPR"`
@@ -50,6 +205,14 @@ type ReviewMetrics struct {
 	AvgTimeToFirstReview float64         `json:"avgTimeToFirstReview"` // hours
 	ApprovalRate         float64         `json:"approvalRate"`         // percentage
 	ChangesRequestedRate float64         `json:"changesRequestedRate"` // percentage
+	CommentedCount       int             `json:"commentedCount"`
+	CommentedRate        float64         `json:"commentedRate"` // percentage
+	DismissedCount       int             `json:"dismissedCount"`
+	DismissedRate        float64         `json:"dismissedRate"`   // percentage
+	SelfReviewCount      int             `json:"selfReviewCount"` // reviews submitted by the PR author
+	SLAHours             float64         `json:"slaHours"`        // first-review SLA threshold
+	SLABreachCount       int             `json:"slaBreachCount"`  // reviewed PRs whose first review exceeded the SLA
+	SLABreachRate        float64         `json:"slaBreachRate"`   // percentage of reviewed PRs
 	ByReviewer           []ReviewerStats `json:"byReviewer,omitempty"`
 }
 
@@ -67,17 +230,28 @@ type DORAMetrics struct {
 	Period    string    `json:"period"`
 	StartDate time.Time `json:"startDate"`
 	EndDate   time.Time `json:"endDate"`
+	Timezone  string    `json:"timezone"`
 
 	// Deployment Frequency
 	DeploymentCount     int     `json:"deploymentCount"`
 	DeploymentFrequency string  `json:"deploymentFrequency"` // daily, weekly, monthly, yearly
 	AvgDeploysPerDay    float64 `json:"avgDeploysPerDay"`
 
+	// Deployment frequency per deployment environment
+	ByEnvironment map[string]EnvDeployStats `json:"byEnvironment,omitempty"`
+
+	// Deployment reliability: successful / finished (success + failure) deployments.
+	// Unlike ChangeFailureRate (PR-based), this is derived from deployment statuses.
+	DeploymentSuccessRate float64 `json:"deploymentSuccessRate"` // percentage
+
 	// Lead Time for Changes
 	AvgLeadTime    float64 `json:"avgLeadTime"` // hours
 	MedianLeadTime float64 `json:"medianLeadTime"`
 	P90LeadTime    float64 `json:"p90LeadTime"`
 
+	// Requested lead time percentiles keyed as "p50", "p99.9", etc. (hours)
+	LeadTimePercentiles map[string]float64 `json:"leadTimePercentiles,omitempty"`
+
 	// Change Failure Rate
 	TotalChanges      int     `json:"totalChanges"`
 	FailedChanges     int     `json:"failedChanges"`
@@ -87,12 +261,34 @@ type DORAMetrics struct {
 	IncidentCount int     `json:"incidentCount"`
 	AvgMTTR       float64 `json:"avgMTTR"` // hours
 	MedianMTTR    float64 `json:"medianMTTR"`
+
+	// DORA performance band per metric
+	PerformanceLevel PerformanceLevel `json:"performanceLevel"`
+}
+
+// EnvDeployStats represents deployment frequency for a single environment.
+type EnvDeployStats struct {
+	DeploymentCount     int     `json:"deploymentCount"`
+	DeploymentFrequency string  `json:"deploymentFrequency"` // daily, weekly, monthly, yearly
+	AvgDeploysPerDay    float64 `json:"avgDeploysPerDay"`
+}
+
+// PerformanceLevel holds the DORA performance band (elite, high, medium, low) for each metric.
+// A band is empty when there is not enough data to classify the metric.
+type PerformanceLevel struct {
+	DeployFreq string `json:"deployFreq"`
+	LeadTime   string `json:"leadTime"`
+	CFR        string `json:"cfr"`
+	MTTR       string `json:"mttr"`
 }
 
 // ProductivityScore represents the overall productivity score
 type ProductivityScore struct {
 	RepositoryID    string           `json:"repositoryId"`
 	Period          string           `json:"period"`
+	StartDate       time.Time        `json:"startDate"`
+	EndDate         time.Time        `json:"endDate"`
+	Timezone        string           `json:"timezone"`
 	OverallScore    float64          `json:"overallScore"` // 0-100
 	CycleTimeScore  float64          `json:"cycleTimeScore"`
 	ReviewScore     float64          `json:"reviewScore"`
@@ -138,6 +334,10 @@ type SprintPerformance struct {
 	ActiveContributors int `json:"activeContributors"`
 	ReviewsSubmitted   int `json:"reviewsSubmitted"`
 
+	// Spillover: PRs opened in the sprint but not merged by its end
+	SpilloverCount int   `json:"spilloverCount"`
+	SpilloverPRs   []int `json:"spilloverPRs,omitempty"` // PR numbers, ascending
+
 	// Comparison with previous sprint
 	VelocityChange  float64 `json:"velocityChange"`
 	CycleTimeChange float64 `json:"cycleTimeChange"`
@@ -1,6 +1,19 @@
 package model
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// CurrentSchemaVersion is the schema version stamped on Repository, PullRequest, Review,
+// and Deployment entities when they are saved. Entities saved before versioning have no
+// schema_version property and load as 0.
+//
+//	1: PullRequest.Labels, Repository.Topics
+const CurrentSchemaVersion = 1
 
 // Repository represents a GitHub repository
 type Repository struct {
@@ -9,15 +22,24 @@ type Repository struct {
 	Name           string     `json:"name" datastore:"name"`
 	FullName       string     `json:"fullName" datastore:"full_name"`
 	Private        bool       `json:"private" datastore:"private"`
+	Archived       bool       `json:"archived" datastore:"archived"`
+	Topics         []string   `json:"topics,omitempty" datastore:"topics"`
 	CreatedAt      time.Time  `json:"createdAt" datastore:"created_at"`
 	UpdatedAt      time.Time  `json:"updatedAt" datastore:"updated_at"`
 	LastSyncedAt   *time.Time `json:"lastSyncedAt,omitempty" datastore:"last_synced_at"`
 	ProcessStartAt *time.Time `json:"processStartAt,omitempty" datastore:"process_start_at"`
+
+	// Last failed sync; cleared by the next complete sync
+	LastSyncError   string     `json:"lastSyncError,omitempty" datastore:"last_sync_error,noindex"`
+	LastSyncErrorAt *time.Time `json:"lastSyncErrorAt,omitempty" datastore:"last_sync_error_at"`
+
+	SchemaVersion int `json:"schemaVersion,omitempty" datastore:"schema_version"`
 }
 
 // FileExtStats holds change statistics per file extension.
 type FileExtStats struct {
 	Extension string `json:"extension" datastore:"extension"`
+	Language  string `json:"language,omitempty" datastore:"language"` // empty when the extension is not mapped
 	Additions int    `json:"additions" datastore:"additions"`
 	Deletions int    `json:"deletions" datastore:"deletions"`
 	Files     int    `json:"files" datastore:"files"`
@@ -25,30 +47,52 @@ type FileExtStats struct {
 
 // PullRequest represents a GitHub pull request
 type PullRequest struct {
-	ID            string         `json:"id" datastore:"id"`
-	RepositoryID  string         `json:"repositoryId" datastore:"repository_id"`
-	Number        int            `json:"number" datastore:"number"`
-	Title         string         `json:"title" datastore:"title,noindex"`
-	Author        string         `json:"author" datastore:"author"`
-	State         string         `json:"state" datastore:"state"`
-	Draft         bool           `json:"draft" datastore:"draft"`
-	CreatedAt     time.Time      `json:"createdAt" datastore:"created_at"`
-	UpdatedAt     time.Time      `json:"updatedAt" datastore:"updated_at"`
-	MergedAt      *time.Time     `json:"mergedAt,omitempty" datastore:"merged_at"`
-	ClosedAt      *time.Time     `json:"closedAt,omitempty" datastore:"closed_at"`
-	FirstCommitAt *time.Time     `json:"firstCommitAt,omitempty" datastore:"first_commit_at"`
-	FirstReviewAt *time.Time     `json:"firstReviewAt,omitempty" datastore:"first_review_at"`
-	ApprovedAt    *time.Time     `json:"approvedAt,omitempty" datastore:"approved_at"`
-	Additions     int            `json:"additions" datastore:"additions"`
-	Deletions     int            `json:"deletions" datastore:"deletions"`
-	ChangedFiles  int            `json:"changedFiles" datastore:"changed_files"`
-	CommitCount   int            `json:"commitCount" datastore:"commit_count"`
-	FileExtStats  []FileExtStats `json:"fileExtStats,omitempty" datastore:"file_ext_stats,flatten"`
+	ID            string     `json:"id" datastore:"id"`
+	RepositoryID  string     `json:"repositoryId" datastore:"repository_id"`
+	Number        int        `json:"number" datastore:"number"`
+	Title         string     `json:"title" datastore:"title,noindex"`
+	Author        string     `json:"author" datastore:"author"`
+	State         string     `json:"state" datastore:"state"`
+	Draft         bool       `json:"draft" datastore:"draft"`
+	BaseRef       string     `json:"baseRef,omitempty" datastore:"base_ref"`
+	Labels        []string   `json:"labels,omitempty" datastore:"labels"`
+	CreatedAt     time.Time  `json:"createdAt" datastore:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" datastore:"updated_at"`
+	MergedAt      *time.Time `json:"mergedAt,omitempty" datastore:"merged_at"`
+	ClosedAt      *time.Time `json:"closedAt,omitempty" datastore:"closed_at"`
+	FirstCommitAt *time.Time `json:"firstCommitAt,omitempty" datastore:"first_commit_at"`
+	FirstReviewAt *time.Time `json:"firstReviewAt,omitempty" datastore:"first_review_at"`
+	ApprovedAt    *time.Time `json:"approvedAt,omitempty" datastore:"approved_at"`
+	// Last approval before the merge; set only when the collector tracks effective approvals
+	LastApprovedAt *time.Time `json:"lastApprovedAt,omitempty" datastore:"last_approved_at"`
+	// Last time the draft was marked ready for review; collected by the GraphQL backend only
+	ReadyForReviewAt *time.Time     `json:"readyForReviewAt,omitempty" datastore:"ready_for_review_at"`
+	Additions        int            `json:"additions" datastore:"additions"`
+	Deletions        int            `json:"deletions" datastore:"deletions"`
+	ChangedFiles     int            `json:"changedFiles" datastore:"changed_files"`
+	CommitCount      int            `json:"commitCount" datastore:"commit_count"`
+	FileExtStats     []FileExtStats `json:"fileExtStats,omitempty" datastore:"file_ext_stats,flatten"`
+	SchemaVersion    int            `json:"schemaVersion,omitempty" datastore:"schema_version"`
+}
+
+// HoursFunc measures the duration between two times in hours.
+type HoursFunc func(start, end time.Time) float64
+
+// WallClockHours measures elapsed wall-clock hours.
+// 経過時間（実時間）を時間単位で返す
+func WallClockHours(start, end time.Time) float64 {
+	return end.Sub(start).Hours()
 }
 
 // CycleTimeHours returns the total cycle time of the PR in hours.
 // PRの全体サイクルタイム（時間単位）を返す
 func (pr *PullRequest) CycleTimeHours() float64 {
+	return pr.CycleTimeHoursWith(WallClockHours)
+}
+
+// CycleTimeHoursWith returns the total cycle time measured with hours.
+// 指定した計測関数でサイクルタイムを返す
+func (pr *PullRequest) CycleTimeHoursWith(hours HoursFunc) float64 {
 	if pr.MergedAt == nil {
 		return 0
 	}
@@ -56,43 +100,84 @@ func (pr *PullRequest) CycleTimeHours() float64 {
 	if pr.FirstCommitAt != nil && pr.FirstCommitAt.Before(pr.CreatedAt) {
 		start = *pr.FirstCommitAt
 	}
-	return pr.MergedAt.Sub(start).Hours()
+	return hours(start, *pr.MergedAt)
 }
 
 // CodingTimeHours returns the coding time (first commit to PR creation) in hours.
 // コーディング時間（時間単位）を返す
 func (pr *PullRequest) CodingTimeHours() float64 {
+	return pr.CodingTimeHoursWith(WallClockHours)
+}
+
+// CodingTimeHoursWith returns the coding time measured with hours.
+// 指定した計測関数でコーディング時間を返す
+func (pr *PullRequest) CodingTimeHoursWith(hours HoursFunc) float64 {
 	if pr.FirstCommitAt == nil {
 		return 0
 	}
-	return pr.CreatedAt.Sub(*pr.FirstCommitAt).Hours()
+	return hours(*pr.FirstCommitAt, pr.CreatedAt)
 }
 
 // PickupTimeHours returns the time until first review in hours.
 // レビュー開始までの待ち時間（時間単位）を返す
 func (pr *PullRequest) PickupTimeHours() float64 {
+	return pr.PickupTimeHoursWith(WallClockHours)
+}
+
+// PickupTimeHoursWith returns the time until first review measured with hours.
+// 指定した計測関数でレビュー開始までの待ち時間を返す
+func (pr *PullRequest) PickupTimeHoursWith(hours HoursFunc) float64 {
 	if pr.FirstReviewAt == nil {
 		return 0
 	}
-	return pr.FirstReviewAt.Sub(pr.CreatedAt).Hours()
+	return hours(pr.CreatedAt, *pr.FirstReviewAt)
 }
 
 // ReviewTimeHours returns the review time (first review to approval) in hours.
 // レビュー時間（時間単位）を返す
 func (pr *PullRequest) ReviewTimeHours() float64 {
+	return pr.ReviewTimeHoursWith(WallClockHours)
+}
+
+// ReviewTimeHoursWith returns the review time measured with hours.
+// 指定した計測関数でレビュー時間を返す
+func (pr *PullRequest) ReviewTimeHoursWith(hours HoursFunc) float64 {
 	if pr.FirstReviewAt == nil || pr.ApprovedAt == nil {
 		return 0
 	}
-	return pr.ApprovedAt.Sub(*pr.FirstReviewAt).Hours()
+	return hours(*pr.FirstReviewAt, *pr.ApprovedAt)
 }
 
 // MergeTimeHours returns the time from approval to merge in hours.
 // 承認からマージまでの時間（時間単位）を返す
 func (pr *PullRequest) MergeTimeHours() float64 {
-	if pr.ApprovedAt == nil || pr.MergedAt == nil {
+	return pr.MergeTimeHoursWith(WallClockHours)
+}
+
+// MergeTimeHoursWith returns the time from the effective approval to merge measured with hours.
+// 指定した計測関数で有効な承認からマージまでの時間を返す
+func (pr *PullRequest) MergeTimeHoursWith(hours HoursFunc) float64 {
+	approvedAt := pr.EffectiveApprovedAt()
+	if approvedAt == nil || pr.MergedAt == nil {
 		return 0
 	}
-	return pr.MergedAt.Sub(*pr.ApprovedAt).Hours()
+	return hours(*approvedAt, *pr.MergedAt)
+}
+
+// EffectiveApprovedAt returns the approval merge time counts from: the last approval before
+// the merge when it was collected, otherwise the first approval.
+// マージ時間の起点となる承認時刻を返す
+func (pr *PullRequest) EffectiveApprovedAt() *time.Time {
+	if pr.LastApprovedAt != nil {
+		return pr.LastApprovedAt
+	}
+	return pr.ApprovedAt
+}
+
+// ReviewKey returns the key reviews use to reference this PR ("{repositoryID}#{number}").
+// レビューの PullRequestID と突き合わせるためのキーを返す
+func (pr *PullRequest) ReviewKey() string {
+	return fmt.Sprintf("%s#%d", pr.RepositoryID, pr.Number)
 }
 
 // Review represents a GitHub pull request review
@@ -105,6 +190,7 @@ type Review struct {
 	Body          string    `json:"body" datastore:"body,noindex"`
 	SubmittedAt   time.Time `json:"submittedAt" datastore:"submitted_at"`
 	CommentsCount int       `json:"commentsCount" datastore:"comments_count"`
+	SchemaVersion int       `json:"schemaVersion,omitempty" datastore:"schema_version"`
 }
 
 // Deployment represents a deployment/release event
@@ -117,6 +203,21 @@ type Deployment struct {
 	Status       string    `json:"status" datastore:"status"` // success, failure, pending
 	CreatedAt    time.Time `json:"createdAt" datastore:"created_at"`
 	DeployedAt   time.Time `json:"deployedAt" datastore:"deployed_at"`
+
+	SchemaVersion int `json:"schemaVersion,omitempty" datastore:"schema_version"`
+}
+
+// DeploymentID returns a deterministic ID for a deployment without a source ID, so saving
+// the same deployment twice (e.g. a retried import) upserts one record.
+// The components are length-prefixed before hashing, so values containing separators
+// (environments like "prod-eu") or a ref that looks like a SHA cannot collide with another tuple.
+// createdAt is compared at second precision, independent of time zone.
+func DeploymentID(repositoryID, environment, sha, ref string, createdAt time.Time) string {
+	h := sha256.New()
+	for _, part := range []string{repositoryID, environment, sha, ref, strconv.FormatInt(createdAt.Unix(), 10)} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return "deploy-" + hex.EncodeToString(h.Sum(nil))
 }
 
 // DailyMetrics represents aggregated metrics for a repository on a specific date
@@ -125,7 +226,7 @@ type DailyMetrics struct {
 	RepositoryID string    `json:"repositoryId" datastore:"repository_id"`
 	Date         time.Time `json:"date" datastore:"date"`
 
-	// Cycle Time metrics (in hours)
+	// Cycle Time metrics (in hours) over the PRs merged that day, excluding bot-authored ones
 	AvgCycleTime  float64 `json:"avgCycleTime" datastore:"avg_cycle_time"`
 	AvgCodingTime float64 `json:"avgCodingTime" datastore:"avg_coding_time"`
 	AvgPickupTime float64 `json:"avgPickupTime" datastore:"avg_pickup_time"`
@@ -133,9 +234,12 @@ type DailyMetrics struct {
 	AvgMergeTime  float64 `json:"avgMergeTime" datastore:"avg_merge_time"`
 
 	// PR Metrics
-	PRsOpened int `json:"prsOpened" datastore:"prs_opened"`
-	PRsMerged int `json:"prsMerged" datastore:"prs_merged"`
-	PRsClosed int `json:"prsClosed" datastore:"prs_closed"`
+	PRsOpened   int `json:"prsOpened" datastore:"prs_opened"`
+	PRsMerged   int `json:"prsMerged" datastore:"prs_merged"`
+	PRsClosed   int `json:"prsClosed" datastore:"prs_closed"`
+	OpenPRCount int `json:"openPRCount" datastore:"open_pr_count"` // still open at end of day (standing WIP)
+	// Bot-authored PRs among PRsMerged, left out of the cycle time averages
+	BotPRsMerged int `json:"botPRsMerged" datastore:"bot_prs_merged"`
 
 	// Review Metrics
 	ReviewsSubmitted int     `json:"reviewsSubmitted" datastore:"reviews_submitted"`
@@ -153,11 +257,18 @@ type DailyMetrics struct {
 	ActiveContributors int `json:"activeContributors" datastore:"active_contributors"`
 }
 
+// AveragedPRs returns the number of merged PRs the cycle time averages cover.
+// 平均値の対象となったマージ済みPR数（ボットを除く）を返す
+func (dm *DailyMetrics) AveragedPRs() int {
+	return dm.PRsMerged - dm.BotPRsMerged
+}
+
 // TeamMember represents a team member
 type TeamMember struct {
 	ID        string    `json:"id" datastore:"id"`
 	Login     string    `json:"login" datastore:"login"`
 	Name      string    `json:"name" datastore:"name"`
+	Email     string    `json:"email,omitempty" datastore:"email,noindex"` // public profile email only
 	AvatarURL string    `json:"avatarUrl" datastore:"avatar_url"`
 	CreatedAt time.Time `json:"createdAt" datastore:"created_at"`
 }
@@ -165,9 +276,37 @@ type TeamMember struct {
 // BotUser represents a custom registered bot user.
 type BotUser struct {
 	Username  string    `json:"username" datastore:"username"`
+	Pattern   bool      `json:"pattern" datastore:"pattern"` // Username is a glob pattern (e.g. "ci-*")
 	CreatedAt time.Time `json:"createdAt" datastore:"created_at"`
 }
 
+// Settings holds runtime configuration edited through the API, stored as one Datastore entity.
+// Unset fields (zero or empty) fall back to the environment configuration.
+type Settings struct {
+	// Productivity score weights; all four zero means unset
+	ScoreWeightCycleTime  float64 `json:"scoreWeightCycleTime,omitempty" datastore:"score_weight_cycle_time,noindex"`
+	ScoreWeightReview     float64 `json:"scoreWeightReview,omitempty" datastore:"score_weight_review,noindex"`
+	ScoreWeightDeployment float64 `json:"scoreWeightDeployment,omitempty" datastore:"score_weight_deployment,noindex"`
+	ScoreWeightQuality    float64 `json:"scoreWeightQuality,omitempty" datastore:"score_weight_quality,noindex"`
+
+	// Productivity score benchmarks (see SCORE_* environment variables)
+	ScoreCycleTimeHours    []float64 `json:"scoreCycleTimeHours,omitempty" datastore:"score_cycle_time_hours,noindex"`
+	ScoreFirstReviewHours  []float64 `json:"scoreFirstReviewHours,omitempty" datastore:"score_first_review_hours,noindex"`
+	ScoreReviewsPerPR      []float64 `json:"scoreReviewsPerPR,omitempty" datastore:"score_reviews_per_pr,noindex"`
+	ScoreChangeFailureRate []float64 `json:"scoreChangeFailureRate,omitempty" datastore:"score_change_failure_rate,noindex"`
+
+	FailureLabels     []string `json:"failureLabels,omitempty" datastore:"failure_labels,noindex"`
+	ShippableBranches []string `json:"shippableBranches,omitempty" datastore:"shippable_branches,noindex"`
+	ReviewSLAHours    float64  `json:"reviewSlaHours,omitempty" datastore:"review_sla_hours,noindex"`
+
+	UpdatedAt time.Time `json:"updatedAt" datastore:"updated_at"`
+}
+
+// HasScoreWeights reports whether the settings set productivity score weights.
+func (s *Settings) HasScoreWeights() bool {
+	return s.ScoreWeightCycleTime != 0 || s.ScoreWeightReview != 0 || s.ScoreWeightDeployment != 0 || s.ScoreWeightQuality != 0
+}
+
 // Sprint represents a development sprint
 type Sprint struct {
 	ID           string    `json:"id" datastore:"id"`
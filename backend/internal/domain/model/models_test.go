@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeploymentID(t *testing.T) {
+	createdAt := time.Date(2025, 6, 3, 9, 0, 0, 0, time.UTC)
+	id := DeploymentID("repo-1", "production", "abc123", "main", createdAt)
+
+	// The same instant in another zone keys the same record
+	if got := DeploymentID("repo-1", "production", "abc123", "main", createdAt.In(time.FixedZone("JST", 9*60*60))); got != id {
+		t.Errorf("DeploymentID in JST = %q, want %q", got, id)
+	}
+	for name, other := range map[string]string{
+		"repository":  DeploymentID("repo-2", "production", "abc123", "main", createdAt),
+		"environment": DeploymentID("repo-1", "staging", "abc123", "main", createdAt),
+		"sha":         DeploymentID("repo-1", "production", "def456", "main", createdAt),
+		"ref":         DeploymentID("repo-1", "production", "abc123", "release", createdAt),
+		"createdAt":   DeploymentID("repo-1", "production", "abc123", "main", createdAt.Add(time.Second)),
+	} {
+		if other == id {
+			t.Errorf("different %s produced the same ID %q", name, id)
+		}
+	}
+}
+
+func TestDeploymentID_SeparatorCollisions(t *testing.T) {
+	createdAt := time.Date(2025, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		a, b [4]string // repositoryID, environment, sha, ref
+	}{
+		// Joined with "-" both read "repo-prod-eu-abc123"
+		{"dash in repository and environment", [4]string{"repo-prod", "eu", "abc123", ""}, [4]string{"repo", "prod-eu", "abc123", ""}},
+		{"dash in environment and sha", [4]string{"repo", "prod-eu", "abc123", ""}, [4]string{"repo", "prod", "eu-abc123", ""}},
+		// A ref named like a SHA is not the SHA
+		{"ref that looks like a sha", [4]string{"repo", "prod", "abc123", ""}, [4]string{"repo", "prod", "", "abc123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := DeploymentID(tt.a[0], tt.a[1], tt.a[2], tt.a[3], createdAt)
+			b := DeploymentID(tt.b[0], tt.b[1], tt.b[2], tt.b[3], createdAt)
+			if a == b {
+				t.Errorf("%v and %v share the ID %q", tt.a, tt.b, a)
+			}
+		})
+	}
+}
@@ -6,7 +6,8 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/google/go-github/v82/github"
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v88/github"
 	"golang.org/x/oauth2"
 
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
@@ -26,15 +27,54 @@ func NewClient(token string) *Client {
 	tc := oauth2.NewClient(ctx, ts)
 
 	return &Client{
-		client: github.NewClient(tc),
+		client: newGitHubClient(tc),
 	}
 }
 
+// NewAppClient creates a GitHub API client authenticated as a GitHub App installation.
+// Installation tokens are minted from the App's private key (PEM) and refreshed before they expire.
+func NewAppClient(appID, installationID int64, privateKey []byte) (*Client, error) {
+	return newAppClient(http.DefaultTransport, appID, installationID, privateKey)
+}
+
+func newAppClient(tr http.RoundTripper, appID, installationID int64, privateKey []byte) (*Client, error) {
+	itr, err := ghinstallation.New(tr, appID, installationID, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub App transport: %w", err)
+	}
+
+	return &Client{
+		client: newGitHubClient(&http.Client{Transport: itr}),
+	}, nil
+}
+
+// NewEnterpriseClient creates a GitHub API client for a GitHub Enterprise Server instance.
+// baseURL is the API root (e.g. "https://ghe.example.com/api/v3/"); a host-only URL gets the
+// "/api/v3/" and "/api/uploads/" suffixes added.
+func NewEnterpriseClient(token, baseURL string) (*Client, error) {
+	tc := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	ghClient, err := github.NewClient(github.WithHTTPClient(tc), github.WithEnterpriseURLs(baseURL, baseURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub base URL: %w", err)
+	}
+	return &Client{client: ghClient}, nil
+}
+
 // NewClientWithHTTPClient creates a new GitHub client with a custom HTTP client
 func NewClientWithHTTPClient(httpClient *http.Client) *Client {
 	return &Client{
-		client: github.NewClient(httpClient),
+		client: newGitHubClient(httpClient),
+	}
+}
+
+// newGitHubClient creates a go-github client on httpClient (nil for a default client).
+func newGitHubClient(httpClient *http.Client) *github.Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
 	}
+	// Cannot fail: a non-nil HTTP client is the only option
+	client, _ := github.NewClient(github.WithHTTPClient(httpClient))
+	return client
 }
 
 // GetRepository fetches repository information
@@ -44,15 +84,22 @@ func (c *Client) GetRepository(ctx context.Context, owner, repo string) (*model.
 		return nil, fmt.Errorf("failed to get repository: %w", err)
 	}
 
+	return convertRepository(r), nil
+}
+
+// convertRepository converts a GitHub repository to the domain model.
+func convertRepository(r *github.Repository) *model.Repository {
 	return &model.Repository{
 		ID:        fmt.Sprintf("%d", r.GetID()),
 		Owner:     r.GetOwner().GetLogin(),
 		Name:      r.GetName(),
 		FullName:  r.GetFullName(),
 		Private:   r.GetPrivate(),
+		Archived:  r.GetArchived(),
+		Topics:    r.Topics,
 		CreatedAt: r.GetCreatedAt().Time,
 		UpdatedAt: r.GetUpdatedAt().Time,
-	}, nil
+	}
 }
 
 // ListPullRequests fetches pull requests for a repository
@@ -80,6 +127,71 @@ func (c *Client) ListPullRequests(ctx context.Context, owner, repo string, opts
 	return result, nil
 }
 
+// SearchPullRequests searches a repository's pull requests updated in a window (updated desc).
+// Search results are issues, so IDs, base branch, and size come only from GetPullRequest.
+func (c *Client) SearchPullRequests(ctx context.Context, owner, repo string, opts *PullRequestSearchOptions) (*PullRequestSearchResult, error) {
+	result, _, err := c.client.Search.Issues(ctx, searchPullRequestsQuery(owner, repo, opts), &github.SearchOptions{
+		Sort:  "updated",
+		Order: "desc",
+		ListOptions: github.ListOptions{
+			Page:    opts.Page,
+			PerPage: opts.PerPage,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search pull requests: %w", err)
+	}
+
+	prs := make([]*model.PullRequest, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		prs = append(prs, convertSearchIssue(issue, owner, repo))
+	}
+
+	return &PullRequestSearchResult{PullRequests: prs, Total: result.GetTotal()}, nil
+}
+
+// searchPullRequestsQuery builds the search query for PRs updated since opts.Since (and created by opts.Until).
+func searchPullRequestsQuery(owner, repo string, opts *PullRequestSearchOptions) string {
+	query := fmt.Sprintf("repo:%s/%s is:pr updated:>=%s", owner, repo, opts.Since.UTC().Format(time.RFC3339))
+	if !opts.Until.IsZero() {
+		query += " created:<=" + opts.Until.UTC().Format(time.RFC3339)
+	}
+	switch opts.State {
+	case "open":
+		query += " is:open"
+	case "closed":
+		query += " is:closed"
+	}
+	return query
+}
+
+// convertSearchIssue converts a pull request search result to the domain model.
+func convertSearchIssue(issue *github.Issue, owner, repo string) *model.PullRequest {
+	result := &model.PullRequest{
+		RepositoryID: fmt.Sprintf("%s/%s", owner, repo), // replaced by the numeric ID from GetPullRequest
+		Number:       issue.GetNumber(),
+		Title:        issue.GetTitle(),
+		Author:       issue.GetUser().GetLogin(),
+		State:        issue.GetState(),
+		Draft:        issue.GetDraft(),
+		Labels:       labelNames(issue.Labels),
+		CreatedAt:    issue.GetCreatedAt().Time,
+		UpdatedAt:    issue.GetUpdatedAt().Time,
+	}
+
+	if issue.ClosedAt != nil {
+		t := issue.GetClosedAt().Time
+		result.ClosedAt = &t
+	}
+
+	if links := issue.GetPullRequestLinks(); links != nil && links.MergedAt != nil {
+		t := links.GetMergedAt().Time
+		result.MergedAt = &t
+	}
+
+	return result
+}
+
 // GetPullRequest fetches a specific pull request
 func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, number int) (*model.PullRequest, error) {
 	pr, _, err := c.client.PullRequests.Get(ctx, owner, repo, number)
@@ -148,6 +260,39 @@ func (c *Client) ListReleases(ctx context.Context, owner, repo string, opts *Lis
 	return releases, nil
 }
 
+// ListTags fetches tags for a repository
+func (c *Client) ListTags(ctx context.Context, owner, repo string, opts *ListOptions) ([]*github.RepositoryTag, error) {
+	ghOpts := &github.ListOptions{
+		Page:    opts.Page,
+		PerPage: opts.PerPage,
+	}
+
+	tags, _, err := c.client.Repositories.ListTags(ctx, owner, repo, ghOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// GetCommitTime fetches the time a commit was committed
+func (c *Client) GetCommitTime(ctx context.Context, owner, repo, sha string) (time.Time, error) {
+	commit, _, err := c.client.Git.GetCommit(ctx, owner, repo, sha)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get commit: %w", err)
+	}
+	return commitTime(commit), nil
+}
+
+// commitTime returns the committer date of a commit, falling back to the author date.
+// The committer date is when the commit landed on the branch, which is closer to a release cut.
+func commitTime(commit *github.Commit) time.Time {
+	if t := commit.GetCommitter().GetDate(); !t.IsZero() {
+		return t.Time
+	}
+	return commit.GetAuthor().GetDate().Time
+}
+
 // ListDeployments fetches deployments for a repository
 func (c *Client) ListDeployments(ctx context.Context, owner, repo string, opts *DeploymentListOptions, repositoryID string) ([]*model.Deployment, error) {
 	ghOpts := &github.DeploymentsListOptions{
@@ -179,6 +324,32 @@ func (c *Client) ListDeployments(ctx context.Context, owner, repo string, opts *
 	return result, nil
 }
 
+// GetDeploymentStatus fetches the latest status of a deployment, normalized to
+// success, failure, or pending. Returns "pending" when no status has been reported.
+func (c *Client) GetDeploymentStatus(ctx context.Context, owner, repo string, deploymentID int64) (string, error) {
+	// Statuses are returned newest first
+	statuses, _, err := c.client.Repositories.ListDeploymentStatuses(ctx, owner, repo, deploymentID, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		return "", fmt.Errorf("failed to list deployment statuses: %w", err)
+	}
+	if len(statuses) == 0 {
+		return "pending", nil
+	}
+	return normalizeDeploymentState(statuses[0].GetState()), nil
+}
+
+// normalizeDeploymentState maps a GitHub deployment status state to success, failure, or pending.
+func normalizeDeploymentState(state string) string {
+	switch state {
+	case "success", "inactive": // inactive: superseded by a newer successful deployment
+		return "success"
+	case "failure", "error":
+		return "failure"
+	default: // pending, queued, in_progress
+		return "pending"
+	}
+}
+
 // ListContributors fetches contributors for a repository
 func (c *Client) ListContributors(ctx context.Context, owner, repo string) ([]*model.TeamMember, error) {
 	contributors, _, err := c.client.Repositories.ListContributors(ctx, owner, repo, nil)
@@ -198,6 +369,23 @@ func (c *Client) ListContributors(ctx context.Context, owner, repo string) ([]*m
 	return result, nil
 }
 
+// GetUser fetches a user's profile as a team member
+func (c *Client) GetUser(ctx context.Context, login string) (*model.TeamMember, error) {
+	user, _, err := c.client.Users.Get(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", login, err)
+	}
+
+	return &model.TeamMember{
+		ID:        fmt.Sprintf("%d", user.GetID()),
+		Login:     user.GetLogin(),
+		Name:      user.GetName(),
+		Email:     user.GetEmail(),
+		AvatarURL: user.GetAvatarURL(),
+		CreatedAt: user.GetCreatedAt().Time,
+	}, nil
+}
+
 // GitHubUser represents authenticated user information.
 type GitHubUser struct {
 	Login     string   `json:"login"`
@@ -259,6 +447,8 @@ func (c *Client) convertPullRequest(pr *github.PullRequest, owner, repo string)
 		Author:       pr.GetUser().GetLogin(),
 		State:        pr.GetState(),
 		Draft:        pr.GetDraft(),
+		BaseRef:      pr.GetBase().GetRef(),
+		Labels:       labelNames(pr.Labels),
 		CreatedAt:    pr.GetCreatedAt().Time,
 		UpdatedAt:    pr.GetUpdatedAt().Time,
 		Additions:    pr.GetAdditions(),
@@ -280,6 +470,15 @@ func (c *Client) convertPullRequest(pr *github.PullRequest, owner, repo string)
 	return result
 }
 
+// labelNames returns the names of labels (nil when there are none).
+func labelNames(labels []*github.Label) []string {
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
 // PullRequestListOptions options for listing pull requests
 type PullRequestListOptions struct {
 	State     string
@@ -289,6 +488,21 @@ type PullRequestListOptions struct {
 	PerPage   int
 }
 
+// PullRequestSearchOptions options for searching pull requests
+type PullRequestSearchOptions struct {
+	Since   time.Time
+	Until   time.Time // zero for no upper bound
+	State   string    // all, open, closed
+	Page    int
+	PerPage int
+}
+
+// PullRequestSearchResult is one page of pull request search results.
+type PullRequestSearchResult struct {
+	PullRequests []*model.PullRequest
+	Total        int // total matches across all pages
+}
+
 // ListOptions generic list options
 type ListOptions struct {
 	Page    int
@@ -0,0 +1,262 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v88/github"
+)
+
+func TestNormalizeDeploymentState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"success", "success"},
+		{"inactive", "success"},
+		{"failure", "failure"},
+		{"error", "failure"},
+		{"pending", "pending"},
+		{"queued", "pending"},
+		{"in_progress", "pending"},
+		{"", "pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := normalizeDeploymentState(tt.state); got != tt.want {
+				t.Errorf("normalizeDeploymentState(%q) = %q, want %q", tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRepository(t *testing.T) {
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	r := &github.Repository{
+		ID:        github.Ptr(int64(42)),
+		Owner:     &github.User{Login: github.Ptr("compasstechlab")},
+		Name:      github.Ptr("dora-yaki"),
+		FullName:  github.Ptr("compasstechlab/dora-yaki"),
+		Private:   github.Ptr(true),
+		Archived:  github.Ptr(true),
+		Topics:    []string{"platform", "metrics"},
+		CreatedAt: &github.Timestamp{Time: created},
+	}
+
+	got := convertRepository(r)
+	if got.ID != "42" || got.Owner != "compasstechlab" || got.FullName != "compasstechlab/dora-yaki" || !got.Private {
+		t.Errorf("unexpected repository fields: %+v", got)
+	}
+	if !got.Archived {
+		t.Error("Archived = false, want true")
+	}
+	if !got.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, created)
+	}
+	if !reflect.DeepEqual(got.Topics, []string{"platform", "metrics"}) {
+		t.Errorf("Topics = %v, want [platform metrics]", got.Topics)
+	}
+
+	// Repositories without topics keep a nil slice
+	if got := convertRepository(&github.Repository{ID: github.Ptr(int64(1))}); got.Topics != nil {
+		t.Errorf("Topics = %v, want nil", got.Topics)
+	}
+}
+
+func TestConvertPullRequest(t *testing.T) {
+	merged := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	pr := &github.PullRequest{
+		ID:     github.Ptr(int64(1001)),
+		Number: github.Ptr(12),
+		Title:  github.Ptr("Roll back session cache"),
+		User:   &github.User{Login: github.Ptr("alice")},
+		State:  github.Ptr("closed"),
+		Base: &github.PullRequestBranch{
+			Ref:  github.Ptr("main"),
+			Repo: &github.Repository{ID: github.Ptr(int64(42))},
+		},
+		Labels: []*github.Label{
+			{Name: github.Ptr("rollback")},
+			{Name: github.Ptr("area/auth")},
+		},
+		MergedAt: &github.Timestamp{Time: merged},
+	}
+
+	got := (&Client{}).convertPullRequest(pr, "o", "r")
+	if got.ID != "1001" || got.RepositoryID != "42" || got.BaseRef != "main" || got.Author != "alice" {
+		t.Errorf("unexpected PR fields: %+v", got)
+	}
+	if !reflect.DeepEqual(got.Labels, []string{"rollback", "area/auth"}) {
+		t.Errorf("Labels = %v, want [rollback area/auth]", got.Labels)
+	}
+	if got.MergedAt == nil || !got.MergedAt.Equal(merged) {
+		t.Errorf("MergedAt = %v, want %v", got.MergedAt, merged)
+	}
+
+	// Unlabeled PRs keep a nil slice; the List API omits the base repository
+	bare := (&Client{}).convertPullRequest(&github.PullRequest{Number: github.Ptr(13)}, "o", "r")
+	if bare.Labels != nil || bare.RepositoryID != "o/r" {
+		t.Errorf("Labels = %v, RepositoryID = %q; want nil and o/r", bare.Labels, bare.RepositoryID)
+	}
+}
+
+func TestConvertSearchIssue(t *testing.T) {
+	created := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	merged := created.Add(26 * time.Hour)
+	issue := &github.Issue{
+		ID:        github.Ptr(int64(900)), // issue ID, not the PR ID
+		Number:    github.Ptr(12),
+		Title:     github.Ptr("Add login"),
+		User:      &github.User{Login: github.Ptr("alice")},
+		State:     github.Ptr("closed"),
+		Draft:     github.Ptr(false),
+		CreatedAt: &github.Timestamp{Time: created},
+		UpdatedAt: &github.Timestamp{Time: merged},
+		ClosedAt:  &github.Timestamp{Time: merged},
+		PullRequestLinks: &github.PullRequestLinks{
+			MergedAt: &github.Timestamp{Time: merged},
+		},
+	}
+
+	got := convertSearchIssue(issue, "o", "r")
+	if got.ID != "" || got.RepositoryID != "o/r" || got.Number != 12 || got.Title != "Add login" || got.Author != "alice" || got.State != "closed" {
+		t.Errorf("unexpected PR fields: %+v", got)
+	}
+	if !got.CreatedAt.Equal(created) || !got.UpdatedAt.Equal(merged) {
+		t.Errorf("CreatedAt = %v, UpdatedAt = %v", got.CreatedAt, got.UpdatedAt)
+	}
+	if got.MergedAt == nil || !got.MergedAt.Equal(merged) || got.ClosedAt == nil || !got.ClosedAt.Equal(merged) {
+		t.Errorf("MergedAt = %v, ClosedAt = %v, want %v", got.MergedAt, got.ClosedAt, merged)
+	}
+
+	// Open PRs have neither time
+	open := convertSearchIssue(&github.Issue{Number: github.Ptr(13), State: github.Ptr("open")}, "o", "r")
+	if open.MergedAt != nil || open.ClosedAt != nil {
+		t.Errorf("open PR MergedAt = %v, ClosedAt = %v", open.MergedAt, open.ClosedAt)
+	}
+}
+
+func TestSearchPullRequestsQuery(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		opts *PullRequestSearchOptions
+		want string
+	}{
+		{"since only", &PullRequestSearchOptions{Since: since, State: "all"}, "repo:o/r is:pr updated:>=2025-06-01T00:00:00Z"},
+		{"window", &PullRequestSearchOptions{Since: since, Until: until, State: "all"}, "repo:o/r is:pr updated:>=2025-06-01T00:00:00Z created:<=2025-06-30T23:59:59Z"},
+		{"open", &PullRequestSearchOptions{Since: since, State: "open"}, "repo:o/r is:pr updated:>=2025-06-01T00:00:00Z is:open"},
+		{"closed", &PullRequestSearchOptions{Since: since, State: "closed"}, "repo:o/r is:pr updated:>=2025-06-01T00:00:00Z is:closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchPullRequestsQuery("o", "r", tt.opts); got != tt.want {
+				t.Errorf("searchPullRequestsQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitTime(t *testing.T) {
+	authored := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	committed := authored.Add(3 * time.Hour)
+
+	tests := []struct {
+		name   string
+		commit *github.Commit
+		want   time.Time
+	}{
+		{"committer date", &github.Commit{
+			Author:    &github.CommitAuthor{Date: &github.Timestamp{Time: authored}},
+			Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: committed}},
+		}, committed},
+		{"falls back to author date", &github.Commit{
+			Author: &github.CommitAuthor{Date: &github.Timestamp{Time: authored}},
+		}, authored},
+		{"no dates", &github.Commit{}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commitTime(tt.commit); !got.Equal(tt.want) {
+				t.Errorf("commitTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEnterpriseClient(t *testing.T) {
+	c, err := NewEnterpriseClient("token", "https://ghe.example.com")
+	if err != nil {
+		t.Fatalf("NewEnterpriseClient() error = %v", err)
+	}
+	if got := c.client.BaseURL(); got != "https://ghe.example.com/api/v3/" {
+		t.Errorf("BaseURL = %q, want https://ghe.example.com/api/v3/", got)
+	}
+	if got := c.client.UploadURL(); got != "https://ghe.example.com/api/uploads/" {
+		t.Errorf("UploadURL = %q, want https://ghe.example.com/api/uploads/", got)
+	}
+
+	if _, err := NewEnterpriseClient("token", "://bad"); err == nil {
+		t.Error("NewEnterpriseClient() with an invalid URL: want error")
+	}
+}
+
+// appAPIStub answers the installation token exchange and records the token used on API calls.
+type appAPIStub struct {
+	tokenRequests int
+	apiAuth       string
+}
+
+func (s *appAPIStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/json")
+	if req.URL.Path == "/app/installations/99/access_tokens" {
+		s.tokenRequests++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token": "ghs_installation", "expires_at": "` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`))
+		return w.Result(), nil
+	}
+	s.apiAuth = req.Header.Get("Authorization")
+	_, _ = w.Write([]byte(`{"id": 1, "name": "r", "full_name": "o/r", "owner": {"login": "o"}}`))
+	return w.Result(), nil
+}
+
+func TestNewAppClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	stub := &appAPIStub{}
+	c, err := newAppClient(stub, 12, 99, privateKey)
+	if err != nil {
+		t.Fatalf("newAppClient() error = %v", err)
+	}
+	if _, err := c.GetRepository(context.Background(), "o", "r"); err != nil {
+		t.Fatalf("GetRepository() error = %v", err)
+	}
+	if stub.tokenRequests != 1 {
+		t.Errorf("token requests = %d, want 1", stub.tokenRequests)
+	}
+	if stub.apiAuth != "token ghs_installation" {
+		t.Errorf("Authorization = %q, want the installation token", stub.apiAuth)
+	}
+
+	if _, err := NewAppClient(12, 99, []byte("not a key")); err == nil {
+		t.Error("NewAppClient() with an invalid key: want error")
+	}
+}
@@ -2,33 +2,84 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/go-github/v82/github"
+	"github.com/google/go-github/v88/github"
 
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
 	"github.com/compasstechlab/dora-yaki/internal/timeutil"
 )
 
+// collectorClient is the subset of the GitHub API used by Collector.
+type collectorClient interface {
+	GetRepository(ctx context.Context, owner, repo string) (*model.Repository, error)
+	ListPullRequests(ctx context.Context, owner, repo string, opts *PullRequestListOptions) ([]*model.PullRequest, error)
+	ListPullRequestsGraphQL(ctx context.Context, owner, repo string, opts *GraphQLPullRequestListOptions) (*GraphQLPullRequestPage, error)
+	SearchPullRequests(ctx context.Context, owner, repo string, opts *PullRequestSearchOptions) (*PullRequestSearchResult, error)
+	GetPullRequest(ctx context.Context, owner, repo string, number int) (*model.PullRequest, error)
+	ListPullRequestFiles(ctx context.Context, owner, repo string, number int) ([]*github.CommitFile, error)
+	GetFirstCommitTime(ctx context.Context, owner, repo string, prNumber int) (*time.Time, error)
+	ListPullRequestReviews(ctx context.Context, owner, repo string, number int, repositoryID string) ([]*model.Review, error)
+	ListReviewComments(ctx context.Context, owner, repo string, number int) ([]*github.PullRequestComment, error)
+	ListDeployments(ctx context.Context, owner, repo string, opts *DeploymentListOptions, repositoryID string) ([]*model.Deployment, error)
+	ListReleases(ctx context.Context, owner, repo string, opts *ListOptions) ([]*github.RepositoryRelease, error)
+	ListTags(ctx context.Context, owner, repo string, opts *ListOptions) ([]*github.RepositoryTag, error)
+	GetCommitTime(ctx context.Context, owner, repo, sha string) (time.Time, error)
+	GetDeploymentStatus(ctx context.Context, owner, repo string, deploymentID int64) (string, error)
+	ListContributors(ctx context.Context, owner, repo string) ([]*model.TeamMember, error)
+	GetUser(ctx context.Context, login string) (*model.TeamMember, error)
+}
+
 // Collector handles collecting metrics data from GitHub
 type Collector struct {
-	client *Client
-	logger *slog.Logger
+	client       collectorClient
+	logger       *slog.Logger
+	languages    LanguageMap
+	retryBackoff time.Duration // wait before the n-th retry is n * retryBackoff
+	lastApproval bool          // also record the last approval before the merge
+	profiles     *profileCache // shared by copies made with the With* methods
 }
 
+// pageFetchAttempts is the number of tries for a single list page before giving up.
+const pageFetchAttempts = 3
+
 // NewCollector creates a new Collector
 func NewCollector(client *Client, logger *slog.Logger) *Collector {
 	return &Collector{
-		client: client,
-		logger: logger,
+		client:       client,
+		logger:       logger,
+		languages:    DefaultLanguageMap(),
+		retryBackoff: 2 * time.Second,
+		profiles:     newProfileCache(),
 	}
 }
 
+// WithLanguageMap returns a copy of the collector that labels file stats using languages.
+func (c *Collector) WithLanguageMap(languages LanguageMap) *Collector {
+	cc := *c
+	cc.languages = languages
+	return &cc
+}
+
+// WithLastApproval returns a copy of the collector that also records each PR's last approval
+// before its merge (LastApprovedAt) when enabled, so merge time counts from a re-approval
+// after requested changes rather than from the first approval.
+func (c *Collector) WithLastApproval(enabled bool) *Collector {
+	cc := *c
+	cc.lastApproval = enabled
+	return &cc
+}
+
 // CollectOptions options for data collection
 type CollectOptions struct {
 	Since    time.Time
@@ -36,8 +87,38 @@ type CollectOptions struct {
 	State    string // all, open, closed
 	PerPage  int
 	MaxPages int
+
+	// CollectFileStats enables per-file stats (one extra API call per PR).
+	CollectFileStats bool
+
+	// UseReleases also records published releases as deployments (environment "release"),
+	// for repositories that cut releases instead of using GitHub Deployments.
+	UseReleases bool
+
+	// TagPattern, when set, also records tags matching this glob (e.g. "v*") as deployments
+	// (environment "tag"), dated at the tagged commit. One extra API call per matching tag.
+	TagPattern string
+
+	// Backend selects the API pull requests and reviews are collected with (BackendREST by default).
+	Backend string
 }
 
+// Pull request collection backends
+const (
+	BackendREST    = "rest"    // list calls plus several calls per PR
+	BackendGraphQL = "graphql" // PRs with files and reviews in one paginated query; falls back to REST
+	BackendSearch  = "search"  // only PRs updated in the window via the Search API; falls back to REST
+)
+
+// graphQLPageSize is the number of PRs per GraphQL query; each PR carries up to 100 files and 100 reviews.
+const graphQLPageSize = 50
+
+// searchResultCap is the most results the Search API returns for one query.
+const searchResultCap = 1000
+
+// errSearchResultCap reports a search matching more PRs than the Search API can return.
+var errSearchResultCap = errors.New("search matches more pull requests than the Search API returns")
+
 // DefaultCollectOptions returns default collection options
 func DefaultCollectOptions() *CollectOptions {
 	return &CollectOptions{
@@ -46,6 +127,8 @@ func DefaultCollectOptions() *CollectOptions {
 		State:    "all",
 		PerPage:  100,
 		MaxPages: 10,
+
+		CollectFileStats: true,
 	}
 }
 
@@ -56,6 +139,8 @@ func CollectOptionsForRange(syncRange string) *CollectOptions {
 		Until:   now,
 		State:   "all",
 		PerPage: 100,
+
+		CollectFileStats: true,
 	}
 
 	switch syncRange {
@@ -89,9 +174,36 @@ type CollectedData struct {
 	Reviews      []*model.Review
 	Deployments  []*model.Deployment
 	TeamMembers  []*model.TeamMember
+
+	// Partial is set when a stage failed; the data holds whatever was gathered before the failure.
+	Partial      bool
+	Errors       []string
+	FailedStages []string
 }
 
-// CollectAll collects all data for a repository
+// Collection stages of CollectAll, as reported in FailedStages
+const (
+	StagePullRequests = "pull requests"
+	StageReviews      = "reviews"
+	StageDeployments  = "deployments"
+	StageContributors = "contributors"
+)
+
+// addError records a failed collection stage.
+func (d *CollectedData) addError(stage string, err error) {
+	d.Partial = true
+	d.Errors = append(d.Errors, fmt.Sprintf("%s: %v", stage, err))
+	d.FailedStages = append(d.FailedStages, stage)
+}
+
+// PullRequestsComplete reports whether the pull request stage finished without errors,
+// whatever happened in the later stages.
+func (d *CollectedData) PullRequestsComplete() bool {
+	return !slices.Contains(d.FailedStages, StagePullRequests)
+}
+
+// CollectAll collects all data for a repository.
+// Only a repository lookup failure is fatal; other stage failures mark the result as partial.
 func (c *Collector) CollectAll(ctx context.Context, owner, repo string, opts *CollectOptions) (*CollectedData, error) {
 	if opts == nil {
 		opts = DefaultCollectOptions()
@@ -115,17 +227,45 @@ func (c *Collector) CollectAll(ctx context.Context, owner, repo string, opts *Co
 	repoID := repoInfo.ID // Use numeric ID for subsequent collection
 	c.logger.Info("repository info collected", "repoID", repoID, "fullName", repoInfo.FullName)
 
-	// Collect pull requests
-	prs, err := c.CollectPullRequests(ctx, owner, repo, opts)
+	// Collect pull requests (with their reviews on the GraphQL backend)
+	var prs []*model.PullRequest
+	var reviews []*model.Review
+	collected, reviewsCollected := false, false
+	switch opts.Backend {
+	case BackendGraphQL:
+		prs, reviews, err = c.CollectPullRequestsGraphQL(ctx, owner, repo, opts)
+		if err != nil && len(prs) == 0 {
+			c.logger.Warn("GraphQL pull request collection failed, falling back to REST", "error", err)
+		} else {
+			collected, reviewsCollected = true, true
+		}
+	case BackendSearch:
+		prs, err = c.CollectPullRequestsBySearch(ctx, owner, repo, opts)
+		if errors.Is(err, errSearchResultCap) {
+			c.logger.Warn("pull request search hit the result cap, falling back to REST", "error", err)
+		} else {
+			collected = true
+		}
+	}
+	if !collected {
+		prs, err = c.CollectPullRequests(ctx, owner, repo, opts)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect pull requests: %w", err)
+		c.logger.Warn("failed to collect pull requests, keeping collected ones",
+			"collected", len(prs),
+			"error", err,
+		)
+		data.addError(StagePullRequests, err)
 	}
 	data.PullRequests = prs
 
 	// Collect reviews for each PR
-	reviews, err := c.CollectReviews(ctx, owner, repo, prs, repoID)
-	if err != nil {
-		c.logger.Warn("failed to collect some reviews", "error", err)
+	if !reviewsCollected {
+		reviews, err = c.CollectReviews(ctx, owner, repo, prs, repoID)
+		if err != nil {
+			c.logger.Warn("failed to collect some reviews", "error", err)
+			data.addError(StageReviews, err)
+		}
 	}
 	data.Reviews = reviews
 
@@ -133,6 +273,7 @@ func (c *Collector) CollectAll(ctx context.Context, owner, repo string, opts *Co
 	deployments, err := c.CollectDeployments(ctx, owner, repo, opts, repoID)
 	if err != nil {
 		c.logger.Warn("failed to collect deployments", "error", err)
+		data.addError(StageDeployments, err)
 	}
 	data.Deployments = deployments
 
@@ -141,7 +282,9 @@ func (c *Collector) CollectAll(ctx context.Context, owner, repo string, opts *Co
 	members, err := c.client.ListContributors(ctx, owner, repo)
 	if err != nil {
 		c.logger.Warn("failed to collect contributors", "error", err)
+		data.addError(StageContributors, err)
 	}
+	c.enrichTeamMembers(ctx, members)
 	data.TeamMembers = members
 
 	c.logger.Info("data collection completed",
@@ -149,12 +292,14 @@ func (c *Collector) CollectAll(ctx context.Context, owner, repo string, opts *Co
 		"reviews", len(data.Reviews),
 		"deployments", len(data.Deployments),
 		"members", len(data.TeamMembers),
+		"partial", data.Partial,
 	)
 
 	return data, nil
 }
 
-// CollectPullRequests collects pull requests from GitHub
+// CollectPullRequests collects pull requests from GitHub.
+// When a page cannot be fetched, the PRs collected so far are returned along with the error.
 func (c *Collector) CollectPullRequests(ctx context.Context, owner, repo string, opts *CollectOptions) ([]*model.PullRequest, error) {
 	c.logger.Info("collecting pull requests",
 		"owner", owner, "repo", repo,
@@ -173,9 +318,11 @@ func (c *Collector) CollectPullRequests(ctx context.Context, owner, repo string,
 			PerPage:   opts.PerPage,
 		}
 
-		prs, err := c.client.ListPullRequests(ctx, owner, repo, listOpts)
+		prs, err := withRetry(ctx, c, "list pull requests", func() ([]*model.PullRequest, error) {
+			return c.client.ListPullRequests(ctx, owner, repo, listOpts)
+		})
 		if err != nil {
-			return nil, err
+			return allPRs, err
 		}
 
 		if len(prs) == 0 {
@@ -194,6 +341,10 @@ func (c *Collector) CollectPullRequests(ctx context.Context, owner, repo string,
 				)
 				return allPRs, nil
 			}
+			// PRs opened after the window cannot belong to it; skip before any enrichment calls
+			if !opts.Until.IsZero() && pr.CreatedAt.After(opts.Until) {
+				continue
+			}
 
 			// Fetch PR details to supplement stats (not available from List API)
 			prDetail, err := c.client.GetPullRequest(ctx, owner, repo, pr.Number)
@@ -209,28 +360,7 @@ func (c *Collector) CollectPullRequests(ctx context.Context, owner, repo string,
 				pr.CommitCount = prDetail.CommitCount
 			}
 
-			// Fetch file stats by extension
-			files, err := c.client.ListPullRequestFiles(ctx, owner, repo, pr.Number)
-			if err != nil {
-				c.logger.Warn("failed to list pull request files",
-					"pr", pr.Number,
-					"error", err,
-				)
-			} else {
-				pr.FileExtStats = aggregateFileExtStats(files)
-			}
-
-			// Enrich PR with first commit time
-			firstCommitTime, err := c.client.GetFirstCommitTime(ctx, owner, repo, pr.Number)
-			if err != nil {
-				c.logger.Warn("failed to get first commit time",
-					"pr", pr.Number,
-					"error", err,
-				)
-			} else {
-				pr.FirstCommitAt = firstCommitTime
-			}
-
+			c.enrichPullRequest(ctx, owner, repo, pr, opts)
 			allPRs = append(allPRs, pr)
 
 			// Progress log
@@ -252,6 +382,142 @@ func (c *Collector) CollectPullRequests(ctx context.Context, owner, repo string,
 	return allPRs, nil
 }
 
+// enrichPullRequest adds file stats (when enabled) and the first commit time to a PR.
+func (c *Collector) enrichPullRequest(ctx context.Context, owner, repo string, pr *model.PullRequest, opts *CollectOptions) {
+	// Fetch file stats by extension
+	if opts.CollectFileStats {
+		files, err := c.client.ListPullRequestFiles(ctx, owner, repo, pr.Number)
+		if err != nil {
+			c.logger.Warn("failed to list pull request files",
+				"pr", pr.Number,
+				"error", err,
+			)
+		} else {
+			pr.FileExtStats = aggregateFileExtStats(files, c.languages)
+		}
+	}
+
+	// Enrich PR with first commit time
+	firstCommitTime, err := c.client.GetFirstCommitTime(ctx, owner, repo, pr.Number)
+	if err != nil {
+		c.logger.Warn("failed to get first commit time",
+			"pr", pr.Number,
+			"error", err,
+		)
+	} else {
+		pr.FirstCommitAt = firstCommitTime
+	}
+}
+
+// CollectPullRequestsBySearch collects pull requests updated in the window with the Search API,
+// so stale PRs are never listed. It returns errSearchResultCap before any enrichment when the
+// search matches more than the API returns (1000), leaving the caller to list instead.
+// When a page cannot be fetched, the PRs collected so far are returned along with the error.
+func (c *Collector) CollectPullRequestsBySearch(ctx context.Context, owner, repo string, opts *CollectOptions) ([]*model.PullRequest, error) {
+	c.logger.Info("collecting pull requests with search",
+		"owner", owner, "repo", repo,
+		"state", opts.State, "since", opts.Since,
+	)
+
+	var allPRs []*model.PullRequest
+	searchOpts := &PullRequestSearchOptions{
+		Since:   opts.Since,
+		Until:   opts.Until,
+		State:   opts.State,
+		PerPage: opts.PerPage,
+	}
+
+	for page := 1; page <= opts.MaxPages; page++ {
+		searchOpts.Page = page
+		result, err := withRetry(ctx, c, "search pull requests", func() (*PullRequestSearchResult, error) {
+			return c.client.SearchPullRequests(ctx, owner, repo, searchOpts)
+		})
+		if err != nil {
+			return allPRs, err
+		}
+		if page == 1 && result.Total > searchResultCap {
+			return nil, fmt.Errorf("%w: %d matches", errSearchResultCap, result.Total)
+		}
+
+		for _, found := range result.PullRequests {
+			// Search results lack IDs, base branch, and size; the detail carries all of them
+			pr, err := c.client.GetPullRequest(ctx, owner, repo, found.Number)
+			if err != nil {
+				c.logger.Warn("failed to get pull request details, skipping",
+					"pr", found.Number,
+					"error", err,
+				)
+				continue
+			}
+
+			c.enrichPullRequest(ctx, owner, repo, pr, opts)
+			allPRs = append(allPRs, pr)
+		}
+
+		if len(result.PullRequests) < opts.PerPage || page*opts.PerPage >= result.Total {
+			break
+		}
+	}
+
+	c.logger.Info("pull request collection finished", "total", len(allPRs))
+	return allPRs, nil
+}
+
+// SyncEstimate previews a sync from pull request list calls alone.
+type SyncEstimate struct {
+	Pages        int `json:"pages"`        // PR list pages fetched
+	PullRequests int `json:"pullRequests"` // PRs in the window that a sync would collect
+	APICalls     int `json:"apiCalls"`     // estimated calls for list pages and per-PR enrichment
+}
+
+// EstimatePullRequests pages through the pull request list as CollectPullRequests does,
+// but skips per-PR enrichment and returns counts instead of PRs.
+func (c *Collector) EstimatePullRequests(ctx context.Context, owner, repo string, opts *CollectOptions) (*SyncEstimate, error) {
+	estimate := &SyncEstimate{}
+
+	// Detail and first commit time, plus the file listing when enabled
+	perPRCalls := 2
+	if opts.CollectFileStats {
+		perPRCalls++
+	}
+
+pages:
+	for page := 1; page <= opts.MaxPages; page++ {
+		listOpts := &PullRequestListOptions{
+			State:     opts.State,
+			Sort:      "updated",
+			Direction: "desc",
+			Page:      page,
+			PerPage:   opts.PerPage,
+		}
+
+		prs, err := withRetry(ctx, c, "list pull requests", func() ([]*model.PullRequest, error) {
+			return c.client.ListPullRequests(ctx, owner, repo, listOpts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		estimate.Pages++
+
+		for _, pr := range prs {
+			if pr.UpdatedAt.Before(opts.Since) {
+				break pages
+			}
+			if !opts.Until.IsZero() && pr.CreatedAt.After(opts.Until) {
+				continue
+			}
+			estimate.PullRequests++
+		}
+
+		if len(prs) < opts.PerPage {
+			break
+		}
+	}
+
+	estimate.APICalls = estimate.Pages + estimate.PullRequests*perPRCalls
+	return estimate, nil
+}
+
 // CollectReviews collects reviews for pull requests
 func (c *Collector) CollectReviews(ctx context.Context, owner, repo string, prs []*model.PullRequest, repositoryID string) ([]*model.Review, error) {
 	c.logger.Info("collecting reviews", "targetPRs", len(prs))
@@ -269,19 +535,7 @@ func (c *Collector) CollectReviews(ctx context.Context, owner, repo string, prs
 			continue
 		}
 
-		// Enrich PR with first review time
-		for _, review := range reviews {
-			if pr.FirstReviewAt == nil || review.SubmittedAt.Before(*pr.FirstReviewAt) {
-				pr.FirstReviewAt = &review.SubmittedAt
-			}
-
-			// Track first approval time
-			if review.State == "APPROVED" {
-				if pr.ApprovedAt == nil || review.SubmittedAt.Before(*pr.ApprovedAt) {
-					pr.ApprovedAt = &review.SubmittedAt
-				}
-			}
-		}
+		applyReviewTimes(pr, reviews, c.lastApproval)
 
 		// Get comment counts for reviews
 		comments, err := c.client.ListReviewComments(ctx, owner, repo, pr.Number)
@@ -321,8 +575,176 @@ func (c *Collector) CollectReviews(ctx context.Context, owner, repo string, prs
 	return allReviews, nil
 }
 
-// CollectDeployments collects deployment data
+// EnrichPullRequestTimes re-fetches the first commit time of PRs missing FirstCommitAt and the
+// reviews of PRs missing FirstReviewAt, updating the PRs in place. It returns the fetched reviews,
+// which the caller saves along with the PRs. Failed lookups are logged and leave the field nil.
+func (c *Collector) EnrichPullRequestTimes(ctx context.Context, owner, repo string, prs []*model.PullRequest, repositoryID string) []*model.Review {
+	var unreviewed []*model.PullRequest
+	for _, pr := range prs {
+		if pr.FirstCommitAt == nil {
+			firstCommitTime, err := c.client.GetFirstCommitTime(ctx, owner, repo, pr.Number)
+			if err != nil {
+				c.logger.Warn("failed to get first commit time",
+					"pr", pr.Number,
+					"error", err,
+				)
+			} else {
+				pr.FirstCommitAt = firstCommitTime
+			}
+		}
+		if pr.FirstReviewAt == nil {
+			unreviewed = append(unreviewed, pr)
+		}
+	}
+	if len(unreviewed) == 0 {
+		return nil
+	}
+	reviews, _ := c.CollectReviews(ctx, owner, repo, unreviewed, repositoryID)
+	return reviews
+}
+
+// applyReviewTimes sets the PR's first review and first approval times from its reviews,
+// and with lastApproval the last approval submitted before the merge.
+func applyReviewTimes(pr *model.PullRequest, reviews []*model.Review, lastApproval bool) {
+	for _, review := range reviews {
+		if pr.FirstReviewAt == nil || review.SubmittedAt.Before(*pr.FirstReviewAt) {
+			pr.FirstReviewAt = &review.SubmittedAt
+		}
+
+		if review.State != "APPROVED" {
+			continue
+		}
+		// Track first approval time
+		if pr.ApprovedAt == nil || review.SubmittedAt.Before(*pr.ApprovedAt) {
+			pr.ApprovedAt = &review.SubmittedAt
+		}
+		// Approvals after the merge do not gate it
+		if lastApproval && (pr.MergedAt == nil || !review.SubmittedAt.After(*pr.MergedAt)) {
+			if pr.LastApprovedAt == nil || review.SubmittedAt.After(*pr.LastApprovedAt) {
+				pr.LastApprovedAt = &review.SubmittedAt
+			}
+		}
+	}
+}
+
+// CollectPullRequestsGraphQL collects pull requests and their reviews with the GraphQL API,
+// examining as many PRs as CollectPullRequests would page through. PRs with more files or
+// reviews than one query returns get those from the REST API.
+// When a page cannot be fetched, the data collected so far is returned along with the error.
+func (c *Collector) CollectPullRequestsGraphQL(ctx context.Context, owner, repo string, opts *CollectOptions) ([]*model.PullRequest, []*model.Review, error) {
+	c.logger.Info("collecting pull requests with GraphQL",
+		"owner", owner, "repo", repo,
+		"state", opts.State, "maxPages", opts.MaxPages,
+	)
+
+	var allPRs []*model.PullRequest
+	var allReviews []*model.Review
+	listOpts := &GraphQLPullRequestListOptions{
+		State:     opts.State,
+		PerPage:   min(opts.PerPage, graphQLPageSize),
+		WithFiles: opts.CollectFileStats,
+	}
+	limit := opts.MaxPages * opts.PerPage
+
+	for examined := 0; examined < limit; {
+		page, err := withRetry(ctx, c, "list pull requests (GraphQL)", func() (*GraphQLPullRequestPage, error) {
+			return c.client.ListPullRequestsGraphQL(ctx, owner, repo, listOpts)
+		})
+		if err != nil {
+			return allPRs, allReviews, err
+		}
+
+		for _, gpr := range page.PullRequests {
+			examined++
+			pr := gpr.PullRequest
+			if pr.UpdatedAt.Before(opts.Since) {
+				c.logger.Info("reached date boundary, stopping PR collection",
+					"total", len(allPRs), "boundaryPR", pr.Number,
+				)
+				return allPRs, allReviews, nil
+			}
+			if !opts.Until.IsZero() && pr.CreatedAt.After(opts.Until) {
+				continue
+			}
+
+			if opts.CollectFileStats {
+				files := gpr.Files
+				if gpr.MoreFiles {
+					if all, err := c.client.ListPullRequestFiles(ctx, owner, repo, pr.Number); err != nil {
+						c.logger.Warn("failed to list pull request files", "pr", pr.Number, "error", err)
+					} else {
+						files = all
+					}
+				}
+				pr.FileExtStats = aggregateFileExtStats(files, c.languages)
+			}
+
+			reviews := gpr.Reviews
+			if gpr.MoreReviews {
+				reviews, _ = c.CollectReviews(ctx, owner, repo, []*model.PullRequest{pr}, pr.RepositoryID)
+			} else {
+				sumCommentsByReviewer(reviews)
+				applyReviewTimes(pr, reviews, c.lastApproval)
+			}
+
+			allPRs = append(allPRs, pr)
+			allReviews = append(allReviews, reviews...)
+		}
+
+		if !page.HasNextPage || len(page.PullRequests) == 0 {
+			break
+		}
+		listOpts.After = page.EndCursor
+	}
+
+	c.logger.Info("pull request collection finished",
+		"total", len(allPRs), "reviews", len(allReviews),
+	)
+	return allPRs, allReviews, nil
+}
+
+// sumCommentsByReviewer replaces each review's own comment count with the reviewer's total
+// on the PR, matching the counts CollectReviews assigns.
+func sumCommentsByReviewer(reviews []*model.Review) {
+	totals := make(map[string]int)
+	for _, review := range reviews {
+		totals[review.Reviewer] += review.CommentsCount
+	}
+	for _, review := range reviews {
+		review.CommentsCount = totals[review.Reviewer]
+	}
+}
+
+// CollectDeployments collects deployment data, plus published releases when opts.UseReleases is set
+// and matching tags when opts.TagPattern is set.
+// When a page cannot be fetched, the deployments collected so far are returned along with the error.
 func (c *Collector) CollectDeployments(ctx context.Context, owner, repo string, opts *CollectOptions, repositoryID string) ([]*model.Deployment, error) {
+	deployments, err := c.collectGitHubDeployments(ctx, owner, repo, opts, repositoryID)
+	if err != nil {
+		return deployments, err
+	}
+
+	if opts.UseReleases {
+		releases, err := c.collectReleases(ctx, owner, repo, opts, repositoryID)
+		deployments = mergeDeploymentsByRef(deployments, releases)
+		if err != nil {
+			return deployments, err
+		}
+	}
+
+	if opts.TagPattern != "" {
+		tags, err := c.collectTags(ctx, owner, repo, opts, repositoryID)
+		deployments = mergeDeploymentsByRef(deployments, tags)
+		if err != nil {
+			return deployments, err
+		}
+	}
+
+	return deployments, nil
+}
+
+// collectGitHubDeployments collects GitHub Deployments with their latest status.
+func (c *Collector) collectGitHubDeployments(ctx context.Context, owner, repo string, opts *CollectOptions, repositoryID string) ([]*model.Deployment, error) {
 	c.logger.Info("collecting deployments", "owner", owner, "repo", repo)
 
 	var allDeployments []*model.Deployment
@@ -333,9 +755,11 @@ func (c *Collector) CollectDeployments(ctx context.Context, owner, repo string,
 			PerPage: opts.PerPage,
 		}
 
-		deployments, err := c.client.ListDeployments(ctx, owner, repo, listOpts, repositoryID)
+		deployments, err := withRetry(ctx, c, "list deployments", func() ([]*model.Deployment, error) {
+			return c.client.ListDeployments(ctx, owner, repo, listOpts, repositoryID)
+		})
 		if err != nil {
-			return nil, err
+			return allDeployments, err
 		}
 
 		if len(deployments) == 0 {
@@ -353,6 +777,24 @@ func (c *Collector) CollectDeployments(ctx context.Context, owner, repo string,
 				)
 				return allDeployments, nil
 			}
+			// Newer than the window; skip before the status lookup
+			if !opts.Until.IsZero() && d.CreatedAt.After(opts.Until) {
+				continue
+			}
+
+			// Enrich with the latest deployment status
+			if deploymentID, err := strconv.ParseInt(d.ID, 10, 64); err == nil {
+				status, err := c.client.GetDeploymentStatus(ctx, owner, repo, deploymentID)
+				if err != nil {
+					c.logger.Warn("failed to get deployment status",
+						"deployment", d.ID,
+						"error", err,
+					)
+				} else {
+					d.Status = status
+				}
+			}
+
 			allDeployments = append(allDeployments, d)
 		}
 
@@ -365,6 +807,251 @@ func (c *Collector) CollectDeployments(ctx context.Context, owner, repo string,
 	return allDeployments, nil
 }
 
+// collectReleases collects published releases in the window as synthetic deployments.
+// Releases are listed newest first, so collection stops at the first one published before opts.Since.
+func (c *Collector) collectReleases(ctx context.Context, owner, repo string, opts *CollectOptions, repositoryID string) ([]*model.Deployment, error) {
+	c.logger.Info("collecting releases", "owner", owner, "repo", repo)
+
+	var allReleases []*model.Deployment
+
+	for page := 1; page <= opts.MaxPages; page++ {
+		listOpts := &ListOptions{
+			Page:    page,
+			PerPage: opts.PerPage,
+		}
+
+		releases, err := withRetry(ctx, c, "list releases", func() ([]*github.RepositoryRelease, error) {
+			return c.client.ListReleases(ctx, owner, repo, listOpts)
+		})
+		if err != nil {
+			return allReleases, err
+		}
+
+		if len(releases) == 0 {
+			break
+		}
+
+		for _, r := range releases {
+			d := releaseDeployment(r, repositoryID)
+			if d == nil {
+				continue
+			}
+			if d.CreatedAt.Before(opts.Since) {
+				c.logger.Info("reached date boundary, stopping release collection",
+					"total", len(allReleases),
+				)
+				return allReleases, nil
+			}
+			if !opts.Until.IsZero() && d.CreatedAt.After(opts.Until) {
+				continue
+			}
+			allReleases = append(allReleases, d)
+		}
+
+		if len(releases) < opts.PerPage {
+			break
+		}
+	}
+
+	c.logger.Info("release collection finished", "total", len(allReleases))
+	return allReleases, nil
+}
+
+// releaseDeployment converts a published release into a successful deployment to the "release" environment.
+// Returns nil for drafts and releases that have not been published.
+func releaseDeployment(r *github.RepositoryRelease, repositoryID string) *model.Deployment {
+	if r.GetDraft() || r.PublishedAt == nil {
+		return nil
+	}
+	publishedAt := r.GetPublishedAt().Time
+	return &model.Deployment{
+		ID:           fmt.Sprintf("release-%d", r.GetID()), // release IDs are not unique against deployment IDs
+		RepositoryID: repositoryID,
+		Environment:  "release",
+		Ref:          r.GetTagName(),
+		SHA:          r.GetTargetCommitish(),
+		Status:       "success",
+		CreatedAt:    publishedAt,
+		DeployedAt:   publishedAt,
+	}
+}
+
+// collectTags collects tags matching opts.TagPattern whose commit falls in the window as synthetic deployments.
+// Tags are not listed in date order, so every page up to opts.MaxPages is read.
+func (c *Collector) collectTags(ctx context.Context, owner, repo string, opts *CollectOptions, repositoryID string) ([]*model.Deployment, error) {
+	c.logger.Info("collecting tags", "owner", owner, "repo", repo, "pattern", opts.TagPattern)
+
+	var allTags []*model.Deployment
+
+	for page := 1; page <= opts.MaxPages; page++ {
+		listOpts := &ListOptions{
+			Page:    page,
+			PerPage: opts.PerPage,
+		}
+
+		tags, err := withRetry(ctx, c, "list tags", func() ([]*github.RepositoryTag, error) {
+			return c.client.ListTags(ctx, owner, repo, listOpts)
+		})
+		if err != nil {
+			return allTags, err
+		}
+
+		for _, tag := range tags {
+			if ok, _ := path.Match(opts.TagPattern, tag.GetName()); !ok {
+				continue
+			}
+			sha := tag.GetCommit().GetSHA()
+			committedAt, err := c.client.GetCommitTime(ctx, owner, repo, sha)
+			if err != nil {
+				c.logger.Warn("failed to get tag commit time",
+					"tag", tag.GetName(),
+					"error", err,
+				)
+				continue
+			}
+			if committedAt.Before(opts.Since) || (!opts.Until.IsZero() && committedAt.After(opts.Until)) {
+				continue
+			}
+			allTags = append(allTags, &model.Deployment{
+				ID:           "tag-" + repositoryID + "-" + tag.GetName(), // tag names repeat across repositories
+				RepositoryID: repositoryID,
+				Environment:  "tag",
+				Ref:          tag.GetName(),
+				SHA:          sha,
+				Status:       "success",
+				CreatedAt:    committedAt,
+				DeployedAt:   committedAt,
+			})
+		}
+
+		if len(tags) < opts.PerPage {
+			break
+		}
+	}
+
+	c.logger.Info("tag collection finished", "total", len(allTags))
+	return allTags, nil
+}
+
+// mergeDeploymentsByRef appends synthetic deployments (releases, tags) whose ref was not already
+// collected, so a release or tag that triggers a deployment is counted once.
+func mergeDeploymentsByRef(deployments, extra []*model.Deployment) []*model.Deployment {
+	deployedRefs := make(map[string]bool, len(deployments))
+	for _, d := range deployments {
+		deployedRefs[d.Ref] = true
+	}
+	for _, d := range extra {
+		if !deployedRefs[d.Ref] {
+			deployments = append(deployments, d)
+		}
+	}
+	return deployments
+}
+
+// Profile cache lifetimes: profiles rarely change, while a failed lookup may be a transient error
+const (
+	profileCacheTTL       = 24 * time.Hour
+	failedProfileCacheTTL = time.Hour
+)
+
+// profileCache holds the user profiles a Collector has fetched, so a job syncing many repositories
+// looks each contributor up once per TTL rather than once per repository.
+type profileCache struct {
+	mu      sync.Mutex
+	entries map[string]profileCacheEntry
+}
+
+type profileCacheEntry struct {
+	user      *model.TeamMember // nil when the lookup failed
+	fetchedAt time.Time
+}
+
+func newProfileCache() *profileCache {
+	return &profileCache{entries: make(map[string]profileCacheEntry)}
+}
+
+// get returns the cached profile for login, if it has not expired at now.
+func (pc *profileCache) get(login string, now time.Time) (*model.TeamMember, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	entry, ok := pc.entries[login]
+	if !ok {
+		return nil, false
+	}
+	ttl := profileCacheTTL
+	if entry.user == nil {
+		ttl = failedProfileCacheTTL
+	}
+	if now.Sub(entry.fetchedAt) > ttl {
+		delete(pc.entries, login)
+		return nil, false
+	}
+	return entry.user, true
+}
+
+func (pc *profileCache) put(login string, user *model.TeamMember, now time.Time) {
+	pc.mu.Lock()
+	pc.entries[login] = profileCacheEntry{user: user, fetchedAt: now}
+	pc.mu.Unlock()
+}
+
+// enrichTeamMembers fills in profile details (name, email, account creation date) for members.
+// Profiles are cached on the collector (see profileCache), so each login is looked up at most once
+// per TTL across collections. Members whose profile cannot be fetched are left as listed.
+func (c *Collector) enrichTeamMembers(ctx context.Context, members []*model.TeamMember) {
+	profiles := c.profiles
+	if profiles == nil {
+		// Collectors not built by NewCollector still look each login up once per collection
+		profiles = newProfileCache()
+	}
+	for _, m := range members {
+		now := time.Now()
+		user, ok := profiles.get(m.Login, now)
+		if !ok {
+			var err error
+			user, err = c.client.GetUser(ctx, m.Login)
+			if err != nil {
+				c.logger.Warn("failed to get user profile",
+					"login", m.Login,
+					"error", err,
+				)
+			}
+			profiles.put(m.Login, user, now) // nil on failure, so a failing login is not retried right away
+		}
+		if user == nil {
+			continue
+		}
+		m.Name = user.Name
+		m.Email = user.Email
+		m.CreatedAt = user.CreatedAt
+	}
+}
+
+// withRetry calls fetch up to pageFetchAttempts times, backing off between attempts.
+func withRetry[T any](ctx context.Context, c *Collector, op string, fetch func() (T, error)) (T, error) {
+	var result T
+	var err error
+	for attempt := 1; attempt <= pageFetchAttempts; attempt++ {
+		if result, err = fetch(); err == nil {
+			return result, nil
+		}
+		if attempt == pageFetchAttempts {
+			break
+		}
+		c.logger.Warn("fetch failed, retrying",
+			"operation", op,
+			"attempt", attempt,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(time.Duration(attempt) * c.retryBackoff):
+		}
+	}
+	return result, err
+}
+
 // SyncRepository syncs data for a specific repository
 func (c *Collector) SyncRepository(ctx context.Context, owner, repo string, lastSyncTime *time.Time) (*CollectedData, error) {
 	opts := DefaultCollectOptions()
@@ -376,9 +1063,11 @@ func (c *Collector) SyncRepository(ctx context.Context, owner, repo string, last
 	return c.CollectAll(ctx, owner, repo, opts)
 }
 
-// aggregateFileExtStats aggregates change stats by file extension.
-func aggregateFileExtStats(files []*github.CommitFile) []model.FileExtStats {
-	statsMap := make(map[string]*model.FileExtStats)
+// aggregateFileExtStats aggregates change stats by file extension and language.
+// Files without an extension are split by language when their filename is mapped (e.g. Dockerfile).
+func aggregateFileExtStats(files []*github.CommitFile, languages LanguageMap) []model.FileExtStats {
+	type key struct{ ext, language string }
+	statsMap := make(map[key]*model.FileExtStats)
 
 	for _, f := range files {
 		filename := f.GetFilename()
@@ -386,11 +1075,12 @@ func aggregateFileExtStats(files []*github.CommitFile) []model.FileExtStats {
 		if ext == "" {
 			ext = "(no ext)"
 		}
+		k := key{ext, languages.Language(filename)}
 
-		s, ok := statsMap[ext]
+		s, ok := statsMap[k]
 		if !ok {
-			s = &model.FileExtStats{Extension: ext}
-			statsMap[ext] = s
+			s = &model.FileExtStats{Extension: ext, Language: k.language}
+			statsMap[k] = s
 		}
 		s.Additions += f.GetAdditions()
 		s.Deletions += f.GetDeletions()
@@ -0,0 +1,826 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v88/github"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// fakeClient serves a fixed set of pull requests on page 1 and counts calls.
+type fakeClient struct {
+	prs       []*model.PullRequest
+	listFiles int
+	reviews   []*model.Review // served for every PR
+
+	listPRCalls    int
+	prPageFailures map[int]int // page -> number of failing calls before it succeeds
+
+	graphQLPages []*GraphQLPullRequestPage // served in order
+	graphQLErr   error
+	graphQLCalls int
+
+	searchResult *PullRequestSearchResult // served on page 1
+	searchCalls  int
+
+	deployments    []*model.Deployment
+	deploymentsErr error
+	statusCalls    int
+
+	releases    []*github.RepositoryRelease
+	tags        []*github.RepositoryTag
+	commitTimes map[string]time.Time // sha -> commit time
+	commitCalls int
+
+	contributors []*model.TeamMember
+	users        map[string]*model.TeamMember
+	getUserCalls map[string]int
+}
+
+func (f *fakeClient) GetRepository(_ context.Context, owner, repo string) (*model.Repository, error) {
+	return &model.Repository{ID: "1", Owner: owner, Name: repo, FullName: owner + "/" + repo}, nil
+}
+
+func (f *fakeClient) ListPullRequests(_ context.Context, _, _ string, opts *PullRequestListOptions) ([]*model.PullRequest, error) {
+	f.listPRCalls++
+	if f.prPageFailures[opts.Page] > 0 {
+		f.prPageFailures[opts.Page]--
+		return nil, errors.New("502 bad gateway")
+	}
+	if opts.Page > 1 {
+		return nil, nil
+	}
+	return f.prs, nil
+}
+
+func (f *fakeClient) ListPullRequestsGraphQL(_ context.Context, _, _ string, opts *GraphQLPullRequestListOptions) (*GraphQLPullRequestPage, error) {
+	f.graphQLCalls++
+	if f.graphQLErr != nil {
+		return nil, f.graphQLErr
+	}
+	if f.graphQLCalls > len(f.graphQLPages) {
+		return &GraphQLPullRequestPage{}, nil
+	}
+	if want := fmt.Sprintf("cursor-%d", f.graphQLCalls-1); f.graphQLCalls > 1 && opts.After != want {
+		return nil, fmt.Errorf("after = %q, want %q", opts.After, want)
+	}
+	return f.graphQLPages[f.graphQLCalls-1], nil
+}
+
+func (f *fakeClient) SearchPullRequests(_ context.Context, _, _ string, opts *PullRequestSearchOptions) (*PullRequestSearchResult, error) {
+	f.searchCalls++
+	if f.searchResult == nil || opts.Page > 1 {
+		return &PullRequestSearchResult{}, nil
+	}
+	return f.searchResult, nil
+}
+
+func (f *fakeClient) GetPullRequest(_ context.Context, _, _ string, number int) (*model.PullRequest, error) {
+	return &model.PullRequest{Number: number, Additions: 10, ChangedFiles: 1}, nil
+}
+
+func (f *fakeClient) ListPullRequestFiles(_ context.Context, _, _ string, _ int) ([]*github.CommitFile, error) {
+	f.listFiles++
+	return []*github.CommitFile{
+		{Filename: github.Ptr("main.go"), Additions: github.Ptr(10)},
+	}, nil
+}
+
+func (f *fakeClient) GetFirstCommitTime(_ context.Context, _, _ string, _ int) (*time.Time, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) ListPullRequestReviews(_ context.Context, _, _ string, _ int, _ string) ([]*model.Review, error) {
+	return f.reviews, nil
+}
+
+func (f *fakeClient) ListReviewComments(_ context.Context, _, _ string, _ int) ([]*github.PullRequestComment, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) ListDeployments(_ context.Context, _, _ string, opts *DeploymentListOptions, _ string) ([]*model.Deployment, error) {
+	if f.deploymentsErr != nil || opts.Page > 1 {
+		return nil, f.deploymentsErr
+	}
+	return f.deployments, nil
+}
+
+func (f *fakeClient) ListReleases(_ context.Context, _, _ string, opts *ListOptions) ([]*github.RepositoryRelease, error) {
+	if opts.Page > 1 {
+		return nil, nil
+	}
+	return f.releases, nil
+}
+
+func (f *fakeClient) ListTags(_ context.Context, _, _ string, opts *ListOptions) ([]*github.RepositoryTag, error) {
+	if opts.Page > 1 {
+		return nil, nil
+	}
+	return f.tags, nil
+}
+
+func (f *fakeClient) GetCommitTime(_ context.Context, _, _, sha string) (time.Time, error) {
+	f.commitCalls++
+	if t, ok := f.commitTimes[sha]; ok {
+		return t, nil
+	}
+	return time.Time{}, errors.New("404 not found")
+}
+
+func (f *fakeClient) GetDeploymentStatus(_ context.Context, _, _ string, _ int64) (string, error) {
+	f.statusCalls++
+	return "success", nil
+}
+
+func (f *fakeClient) ListContributors(_ context.Context, _, _ string) ([]*model.TeamMember, error) {
+	return f.contributors, nil
+}
+
+func (f *fakeClient) GetUser(_ context.Context, login string) (*model.TeamMember, error) {
+	if f.getUserCalls == nil {
+		f.getUserCalls = make(map[string]int)
+	}
+	f.getUserCalls[login]++
+	if u, ok := f.users[login]; ok {
+		return u, nil
+	}
+	return nil, errors.New("404 not found")
+}
+
+func TestCollectPullRequestsFileStats(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		collectFileStats bool
+		wantListFiles    int
+		wantStats        bool
+	}{
+		{"enabled", true, 2, true},
+		{"disabled", false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeClient{prs: []*model.PullRequest{
+				{Number: 1, UpdatedAt: now},
+				{Number: 2, UpdatedAt: now},
+			}}
+			c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			opts := &CollectOptions{
+				Since:            now.AddDate(0, 0, -7),
+				Until:            now,
+				State:            "all",
+				PerPage:          100,
+				MaxPages:         1,
+				CollectFileStats: tt.collectFileStats,
+			}
+
+			prs, err := c.CollectPullRequests(context.Background(), "o", "r", opts)
+			if err != nil {
+				t.Fatalf("CollectPullRequests() error = %v", err)
+			}
+			if len(prs) != 2 {
+				t.Fatalf("len(prs) = %d, want 2", len(prs))
+			}
+			if fake.listFiles != tt.wantListFiles {
+				t.Errorf("ListPullRequestFiles calls = %d, want %d", fake.listFiles, tt.wantListFiles)
+			}
+			for _, pr := range prs {
+				if got := len(pr.FileExtStats) > 0; got != tt.wantStats {
+					t.Errorf("PR #%d has file stats = %v, want %v", pr.Number, got, tt.wantStats)
+				}
+				if pr.Additions != 10 {
+					t.Errorf("PR #%d Additions = %d, want 10", pr.Number, pr.Additions)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectReviewsLastApproval(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2026, 1, 5, hour, 0, 0, 0, time.UTC) }
+	fake := &fakeClient{reviews: []*model.Review{
+		{ID: "1", State: "APPROVED", SubmittedAt: at(10)},
+		{ID: "2", State: "CHANGES_REQUESTED", SubmittedAt: at(11)},
+		{ID: "3", State: "APPROVED", SubmittedAt: at(14)},
+		// A late approval after the merge does not gate it
+		{ID: "4", State: "APPROVED", SubmittedAt: at(20)},
+	}}
+	base := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name          string
+		collector     *Collector
+		wantLast      *time.Time
+		wantMergeTime float64
+	}{
+		{"first approval by default", base, nil, 6},
+		{"last approval before merge", base.WithLastApproval(true), func() *time.Time { t := at(14); return &t }(), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergedAt := at(16)
+			pr := &model.PullRequest{Number: 1, CreatedAt: at(9), MergedAt: &mergedAt}
+			if _, err := tt.collector.CollectReviews(context.Background(), "o", "r", []*model.PullRequest{pr}, "1"); err != nil {
+				t.Fatal(err)
+			}
+
+			if pr.ApprovedAt == nil || !pr.ApprovedAt.Equal(at(10)) {
+				t.Errorf("ApprovedAt = %v, want the first approval %v", pr.ApprovedAt, at(10))
+			}
+			if (pr.LastApprovedAt == nil) != (tt.wantLast == nil) || (tt.wantLast != nil && !pr.LastApprovedAt.Equal(*tt.wantLast)) {
+				t.Errorf("LastApprovedAt = %v, want %v", pr.LastApprovedAt, tt.wantLast)
+			}
+			if got := pr.MergeTimeHours(); got != tt.wantMergeTime {
+				t.Errorf("MergeTimeHours() = %v, want %v", got, tt.wantMergeTime)
+			}
+			// Review time still ends at the first approval
+			if got := pr.ReviewTimeHours(); got != 0 {
+				t.Errorf("ReviewTimeHours() = %v, want 0 (first review is the first approval)", got)
+			}
+		})
+	}
+}
+
+func TestCollectOptionsForRangeCollectsFileStats(t *testing.T) {
+	for _, r := range []string{"day", "week", "month", "6month", "year", "full"} {
+		if !CollectOptionsForRange(r).CollectFileStats {
+			t.Errorf("CollectOptionsForRange(%q).CollectFileStats = false, want true", r)
+		}
+	}
+	if !DefaultCollectOptions().CollectFileStats {
+		t.Error("DefaultCollectOptions().CollectFileStats = false, want true")
+	}
+}
+
+func TestCollectAllPartial(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		prPageFailures map[int]int
+		deploymentsErr error
+		wantPRs        int
+		wantListCalls  int
+		wantErrors     int
+		wantFailed     []string
+	}{
+		{
+			name:          "complete",
+			wantPRs:       2,
+			wantListCalls: 2,
+		},
+		{
+			name:           "transient page failure is retried",
+			prPageFailures: map[int]int{2: pageFetchAttempts - 1},
+			wantPRs:        2,
+			wantListCalls:  2 + pageFetchAttempts - 1,
+		},
+		{
+			name:           "persistent page failure keeps earlier pages",
+			prPageFailures: map[int]int{2: pageFetchAttempts},
+			wantPRs:        2,
+			wantListCalls:  1 + pageFetchAttempts,
+			wantErrors:     1,
+			wantFailed:     []string{StagePullRequests},
+		},
+		{
+			name:           "deployment stage failure",
+			deploymentsErr: errors.New("403 forbidden"),
+			wantPRs:        2,
+			wantListCalls:  2,
+			wantErrors:     1,
+			wantFailed:     []string{StageDeployments},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeClient{
+				prs: []*model.PullRequest{
+					{Number: 1, UpdatedAt: now},
+					{Number: 2, UpdatedAt: now},
+				},
+				prPageFailures: tt.prPageFailures,
+				deploymentsErr: tt.deploymentsErr,
+			}
+			c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			opts := &CollectOptions{
+				Since:    now.AddDate(0, 0, -7),
+				Until:    now,
+				State:    "all",
+				PerPage:  2, // a full first page forces a second page request
+				MaxPages: 3,
+			}
+
+			data, err := c.CollectAll(context.Background(), "o", "r", opts)
+			if err != nil {
+				t.Fatalf("CollectAll() error = %v", err)
+			}
+			if len(data.PullRequests) != tt.wantPRs {
+				t.Errorf("len(PullRequests) = %d, want %d", len(data.PullRequests), tt.wantPRs)
+			}
+			if fake.listPRCalls != tt.wantListCalls {
+				t.Errorf("ListPullRequests calls = %d, want %d", fake.listPRCalls, tt.wantListCalls)
+			}
+			if len(data.Errors) != tt.wantErrors || data.Partial != (tt.wantErrors > 0) {
+				t.Errorf("Partial = %v, Errors = %v, want %d errors", data.Partial, data.Errors, tt.wantErrors)
+			}
+			if !slices.Equal(data.FailedStages, tt.wantFailed) {
+				t.Errorf("FailedStages = %v, want %v", data.FailedStages, tt.wantFailed)
+			}
+			if want := !slices.Contains(tt.wantFailed, StagePullRequests); data.PullRequestsComplete() != want {
+				t.Errorf("PullRequestsComplete() = %v, want %v", data.PullRequestsComplete(), want)
+			}
+		})
+	}
+}
+
+func TestCollectAllGraphQL(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	graphQLPR := func(number int, updatedAt time.Time, reviews ...*model.Review) *GraphQLPullRequest {
+		return &GraphQLPullRequest{
+			PullRequest: &model.PullRequest{RepositoryID: "1", Number: number, CreatedAt: updatedAt, UpdatedAt: updatedAt},
+			Files:       []*github.CommitFile{{Filename: github.Ptr("main.go"), Additions: github.Ptr(3)}},
+			Reviews:     reviews,
+		}
+	}
+	pages := func() []*GraphQLPullRequestPage {
+		return []*GraphQLPullRequestPage{
+			{
+				PullRequests: []*GraphQLPullRequest{
+					graphQLPR(3, now,
+						&model.Review{Reviewer: "bob", State: "COMMENTED", SubmittedAt: now.Add(-2 * time.Hour), CommentsCount: 2},
+						&model.Review{Reviewer: "bob", State: "APPROVED", SubmittedAt: now.Add(-time.Hour), CommentsCount: 1},
+					),
+				},
+				HasNextPage: true,
+				EndCursor:   "cursor-1",
+			},
+			{
+				PullRequests: []*GraphQLPullRequest{
+					graphQLPR(2, now.AddDate(0, 0, -1)),
+					graphQLPR(1, now.AddDate(0, 0, -30)), // before the window
+				},
+				HasNextPage: true,
+				EndCursor:   "cursor-2",
+			},
+		}
+	}
+	opts := func() *CollectOptions {
+		return &CollectOptions{
+			Since:            now.AddDate(0, 0, -7),
+			Until:            now,
+			State:            "all",
+			PerPage:          100,
+			MaxPages:         3,
+			CollectFileStats: true,
+			Backend:          BackendGraphQL,
+		}
+	}
+
+	t.Run("graphql", func(t *testing.T) {
+		fake := &fakeClient{graphQLPages: pages()}
+		c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+		data, err := c.CollectAll(context.Background(), "o", "r", opts())
+		if err != nil {
+			t.Fatalf("CollectAll() error = %v", err)
+		}
+		if len(data.PullRequests) != 2 || len(data.Reviews) != 2 || data.Partial {
+			t.Fatalf("collected %d PRs, %d reviews (partial %v), want 2, 2", len(data.PullRequests), len(data.Reviews), data.Partial)
+		}
+		if fake.graphQLCalls != 2 || fake.listPRCalls != 0 || fake.listFiles != 0 {
+			t.Errorf("calls: graphql %d, REST list %d, REST files %d; want 2, 0, 0", fake.graphQLCalls, fake.listPRCalls, fake.listFiles)
+		}
+
+		pr := data.PullRequests[0]
+		if pr.ApprovedAt == nil || !pr.ApprovedAt.Equal(now.Add(-time.Hour)) || pr.FirstReviewAt == nil || !pr.FirstReviewAt.Equal(now.Add(-2*time.Hour)) {
+			t.Errorf("review times = first %v, approved %v", pr.FirstReviewAt, pr.ApprovedAt)
+		}
+		if len(pr.FileExtStats) != 1 || pr.FileExtStats[0].Additions != 3 {
+			t.Errorf("FileExtStats = %+v, want main.go stats", pr.FileExtStats)
+		}
+		// Comment counts are per reviewer on the PR, as on the REST path
+		for _, review := range data.Reviews {
+			if review.CommentsCount != 3 {
+				t.Errorf("CommentsCount = %d, want 3", review.CommentsCount)
+			}
+		}
+	})
+
+	t.Run("falls back to REST", func(t *testing.T) {
+		fake := &fakeClient{
+			prs:        []*model.PullRequest{{Number: 1, UpdatedAt: now}},
+			graphQLErr: errors.New("graphql: something went wrong"),
+		}
+		c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+		data, err := c.CollectAll(context.Background(), "o", "r", opts())
+		if err != nil {
+			t.Fatalf("CollectAll() error = %v", err)
+		}
+		if len(data.PullRequests) != 1 || data.Partial {
+			t.Errorf("collected %d PRs (partial %v), want 1 from REST", len(data.PullRequests), data.Partial)
+		}
+		if fake.listPRCalls == 0 {
+			t.Error("REST ListPullRequests was not called after the GraphQL failure")
+		}
+	})
+}
+
+func TestCollectAllSearch(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	opts := &CollectOptions{
+		Since:    now.AddDate(0, 0, -7),
+		Until:    now,
+		State:    "all",
+		PerPage:  100,
+		MaxPages: 3,
+		Backend:  BackendSearch,
+	}
+
+	t.Run("search", func(t *testing.T) {
+		fake := &fakeClient{searchResult: &PullRequestSearchResult{
+			PullRequests: []*model.PullRequest{{Number: 7, UpdatedAt: now}, {Number: 6, UpdatedAt: now}},
+			Total:        2,
+		}}
+		c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+		data, err := c.CollectAll(context.Background(), "o", "r", opts)
+		if err != nil {
+			t.Fatalf("CollectAll() error = %v", err)
+		}
+		if len(data.PullRequests) != 2 || data.Partial {
+			t.Fatalf("collected %d PRs (partial %v), want 2", len(data.PullRequests), data.Partial)
+		}
+		// PRs come from the detail call, which carries the size
+		if pr := data.PullRequests[0]; pr.Number != 7 || pr.Additions != 10 {
+			t.Errorf("PR = #%d +%d, want #7 +10", pr.Number, pr.Additions)
+		}
+		if fake.searchCalls != 1 || fake.listPRCalls != 0 {
+			t.Errorf("calls: search %d, REST list %d; want 1, 0", fake.searchCalls, fake.listPRCalls)
+		}
+	})
+
+	t.Run("falls back to REST at the result cap", func(t *testing.T) {
+		fake := &fakeClient{
+			prs: []*model.PullRequest{{Number: 1, UpdatedAt: now}},
+			searchResult: &PullRequestSearchResult{
+				PullRequests: []*model.PullRequest{{Number: 7, UpdatedAt: now}},
+				Total:        searchResultCap + 1,
+			},
+		}
+		c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+		data, err := c.CollectAll(context.Background(), "o", "r", opts)
+		if err != nil {
+			t.Fatalf("CollectAll() error = %v", err)
+		}
+		if len(data.PullRequests) != 1 || data.PullRequests[0].Number != 1 || data.Partial {
+			t.Errorf("collected %d PRs (partial %v), want #1 from REST", len(data.PullRequests), data.Partial)
+		}
+		if fake.listPRCalls == 0 {
+			t.Error("REST ListPullRequests was not called after hitting the result cap")
+		}
+	})
+}
+
+func TestCollectPullRequestsWindow(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	// Sorted by updated_at descending, as the List API returns them
+	fake := &fakeClient{prs: []*model.PullRequest{
+		{Number: 5, CreatedAt: until.Add(time.Hour), UpdatedAt: until.AddDate(0, 1, 0)},    // opened after the window
+		{Number: 4, CreatedAt: until, UpdatedAt: until.AddDate(0, 0, 20)},                  // opened at the boundary
+		{Number: 3, CreatedAt: since.AddDate(0, 1, 0), UpdatedAt: until.AddDate(0, 0, 10)}, // opened in window, updated later
+		{Number: 2, CreatedAt: since.AddDate(0, 0, 5), UpdatedAt: since.AddDate(0, 0, 6)},
+		{Number: 1, CreatedAt: since.AddDate(0, -2, 0), UpdatedAt: since.Add(-time.Hour)}, // last update before the window
+	}}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	opts := &CollectOptions{
+		Since:            since,
+		Until:            until,
+		State:            "all",
+		PerPage:          100,
+		MaxPages:         1,
+		CollectFileStats: true,
+	}
+
+	prs, err := c.CollectPullRequests(context.Background(), "o", "r", opts)
+	if err != nil {
+		t.Fatalf("CollectPullRequests() error = %v", err)
+	}
+	var got []int
+	for _, pr := range prs {
+		got = append(got, pr.Number)
+	}
+	if len(got) != 3 || got[0] != 4 || got[1] != 3 || got[2] != 2 {
+		t.Errorf("collected PRs = %v, want [4 3 2]", got)
+	}
+	if fake.listFiles != 3 {
+		t.Errorf("ListPullRequestFiles calls = %d, want 3 (no enrichment for skipped PRs)", fake.listFiles)
+	}
+}
+
+func TestEstimatePullRequests(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	fake := &fakeClient{prs: []*model.PullRequest{
+		{Number: 3, CreatedAt: until.Add(time.Hour), UpdatedAt: until.AddDate(0, 1, 0)}, // opened after the window
+		{Number: 2, CreatedAt: since.AddDate(0, 0, 5), UpdatedAt: since.AddDate(0, 0, 6)},
+		{Number: 1, CreatedAt: since.AddDate(0, -2, 0), UpdatedAt: since.Add(-time.Hour)}, // last update before the window
+	}}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	opts := &CollectOptions{
+		Since:            since,
+		Until:            until,
+		State:            "all",
+		PerPage:          100,
+		MaxPages:         5,
+		CollectFileStats: true,
+	}
+
+	got, err := c.EstimatePullRequests(context.Background(), "o", "r", opts)
+	if err != nil {
+		t.Fatalf("EstimatePullRequests() error = %v", err)
+	}
+	want := SyncEstimate{Pages: 1, PullRequests: 1, APICalls: 4}
+	if *got != want {
+		t.Errorf("estimate = %+v, want %+v", *got, want)
+	}
+	if fake.listFiles != 0 {
+		t.Errorf("ListPullRequestFiles calls = %d, want 0 in an estimate", fake.listFiles)
+	}
+}
+
+func TestCollectDeploymentsWindow(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	// Sorted by created_at descending, as the API returns them
+	fake := &fakeClient{deployments: []*model.Deployment{
+		{ID: "5", CreatedAt: until.Add(time.Second)}, // just after the window
+		{ID: "4", CreatedAt: until},
+		{ID: "3", CreatedAt: since.AddDate(0, 1, 0)},
+		{ID: "2", CreatedAt: since},
+		{ID: "1", CreatedAt: since.Add(-time.Second)}, // just before the window
+	}}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	opts := &CollectOptions{Since: since, Until: until, PerPage: 100, MaxPages: 1}
+
+	deployments, err := c.CollectDeployments(context.Background(), "o", "r", opts, "1")
+	if err != nil {
+		t.Fatalf("CollectDeployments() error = %v", err)
+	}
+	var got []string
+	for _, d := range deployments {
+		got = append(got, d.ID)
+	}
+	if strings.Join(got, ",") != "4,3,2" {
+		t.Errorf("collected deployments = %v, want [4 3 2]", got)
+	}
+	if fake.statusCalls != 3 {
+		t.Errorf("GetDeploymentStatus calls = %d, want 3", fake.statusCalls)
+	}
+}
+
+func TestCollectAllEnrichesTeamMembers(t *testing.T) {
+	joined := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	fake := &fakeClient{
+		contributors: []*model.TeamMember{
+			{ID: "1", Login: "alice", AvatarURL: "https://avatars/alice"},
+			{ID: "2", Login: "ghost"},
+			{ID: "1", Login: "alice"}, // listed twice
+		},
+		users: map[string]*model.TeamMember{
+			"alice": {ID: "1", Login: "alice", Name: "Alice Example", Email: "alice@example.com", CreatedAt: joined},
+		},
+	}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	opts := &CollectOptions{Since: now.AddDate(0, 0, -1), Until: now, PerPage: 100, MaxPages: 1}
+
+	data, err := c.CollectAll(context.Background(), "o", "r", opts)
+	if err != nil {
+		t.Fatalf("CollectAll() error = %v", err)
+	}
+
+	alice := data.TeamMembers[0]
+	if alice.Name != "Alice Example" || alice.Email != "alice@example.com" || !alice.CreatedAt.Equal(joined) {
+		t.Errorf("alice = %+v, want name, email and creation date populated", alice)
+	}
+	if alice.AvatarURL != "https://avatars/alice" {
+		t.Errorf("AvatarURL = %q, want the listed value kept", alice.AvatarURL)
+	}
+	if data.TeamMembers[2].Name != "Alice Example" {
+		t.Errorf("duplicate alice Name = %q, want cached profile applied", data.TeamMembers[2].Name)
+	}
+	if ghost := data.TeamMembers[1]; ghost.Name != "" || ghost.Login != "ghost" {
+		t.Errorf("ghost = %+v, want left as listed", ghost)
+	}
+	for login, n := range fake.getUserCalls {
+		if n != 1 {
+			t.Errorf("GetUser(%q) called %d times, want 1", login, n)
+		}
+	}
+	if data.Partial {
+		t.Errorf("Partial = true, want profile lookup failures to be non-fatal: %v", data.Errors)
+	}
+}
+
+func TestCollectAllCachesProfilesAcrossCollections(t *testing.T) {
+	fake := &fakeClient{
+		contributors: []*model.TeamMember{{ID: "1", Login: "alice"}, {ID: "2", Login: "ghost"}},
+		users: map[string]*model.TeamMember{
+			"alice": {ID: "1", Login: "alice", Name: "Alice Example"},
+		},
+	}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil)), profiles: newProfileCache()}
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	opts := &CollectOptions{Since: now.AddDate(0, 0, -1), Until: now, PerPage: 100, MaxPages: 1}
+	collect := func() *CollectedData {
+		t.Helper()
+		data, err := c.CollectAll(context.Background(), "o", "r", opts)
+		if err != nil {
+			t.Fatalf("CollectAll() error = %v", err)
+		}
+		return data
+	}
+	age := func(login string, d time.Duration) {
+		c.profiles.mu.Lock()
+		entry := c.profiles.entries[login]
+		entry.fetchedAt = entry.fetchedAt.Add(-d)
+		c.profiles.entries[login] = entry
+		c.profiles.mu.Unlock()
+	}
+
+	// A second repository with the same contributors is served from the cache,
+	// and so is a copy of the collector
+	collect()
+	c = c.WithLastApproval(true)
+	if data := collect(); data.TeamMembers[0].Name != "Alice Example" {
+		t.Errorf("cached alice Name = %q, want the cached profile applied", data.TeamMembers[0].Name)
+	}
+	if fake.getUserCalls["alice"] != 1 || fake.getUserCalls["ghost"] != 1 {
+		t.Errorf("GetUser calls = %v, want one per login", fake.getUserCalls)
+	}
+
+	// A failed lookup is retried sooner than a found profile is refreshed
+	age("alice", 2*failedProfileCacheTTL)
+	age("ghost", 2*failedProfileCacheTTL)
+	collect()
+	if fake.getUserCalls["alice"] != 1 || fake.getUserCalls["ghost"] != 2 {
+		t.Errorf("GetUser calls = %v, want ghost retried and alice still cached", fake.getUserCalls)
+	}
+	age("alice", profileCacheTTL)
+	collect()
+	if fake.getUserCalls["alice"] != 2 {
+		t.Errorf("GetUser(alice) called %d times, want a refresh after the TTL", fake.getUserCalls["alice"])
+	}
+}
+
+func TestReleaseDeployment(t *testing.T) {
+	published := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	got := releaseDeployment(&github.RepositoryRelease{
+		ID:              github.Ptr(int64(7)),
+		TagName:         github.Ptr("v1.2.0"),
+		TargetCommitish: github.Ptr("main"),
+		CreatedAt:       &github.Timestamp{Time: published.Add(-time.Hour)},
+		PublishedAt:     &github.Timestamp{Time: published},
+	}, "1")
+	want := &model.Deployment{
+		ID:           "release-7",
+		RepositoryID: "1",
+		Environment:  "release",
+		Ref:          "v1.2.0",
+		SHA:          "main",
+		Status:       "success",
+		CreatedAt:    published,
+		DeployedAt:   published,
+	}
+	if got == nil || *got != *want {
+		t.Errorf("releaseDeployment() = %+v, want %+v", got, want)
+	}
+
+	if got := releaseDeployment(&github.RepositoryRelease{ID: github.Ptr(int64(8)), Draft: github.Ptr(true)}, "1"); got != nil {
+		t.Errorf("draft release = %+v, want nil", got)
+	}
+}
+
+func TestCollectDeploymentsUseReleases(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	release := func(id int64, tag string, published time.Time) *github.RepositoryRelease {
+		return &github.RepositoryRelease{ID: github.Ptr(id), TagName: github.Ptr(tag), PublishedAt: &github.Timestamp{Time: published}}
+	}
+
+	fake := &fakeClient{
+		deployments: []*model.Deployment{
+			{ID: "100", Ref: "v1.1.0", CreatedAt: since.AddDate(0, 1, 0)},
+		},
+		// Sorted newest first, as the API returns them
+		releases: []*github.RepositoryRelease{
+			release(4, "v1.3.0", until.Add(time.Hour)), // after the window
+			release(3, "v1.2.0", since.AddDate(0, 2, 0)),
+			{ID: github.Ptr(int64(5)), TagName: github.Ptr("v1.2.1"), Draft: github.Ptr(true)},
+			release(2, "v1.1.0", since.AddDate(0, 1, 0)), // already deployed
+			release(1, "v1.0.0", since.Add(-time.Hour)),  // before the window
+		},
+	}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	opts := &CollectOptions{Since: since, Until: until, PerPage: 100, MaxPages: 1}
+
+	collect := func() []string {
+		t.Helper()
+		deployments, err := c.CollectDeployments(context.Background(), "o", "r", opts, "1")
+		if err != nil {
+			t.Fatalf("CollectDeployments() error = %v", err)
+		}
+		var ids []string
+		for _, d := range deployments {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	if got := strings.Join(collect(), ","); got != "100" {
+		t.Errorf("without UseReleases = %s, want 100", got)
+	}
+
+	opts.UseReleases = true
+	if got := strings.Join(collect(), ","); got != "100,release-3" {
+		t.Errorf("with UseReleases = %s, want 100,release-3", got)
+	}
+}
+
+func TestCollectDeploymentsTagPattern(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	tag := func(name, sha string) *github.RepositoryTag {
+		return &github.RepositoryTag{Name: github.Ptr(name), Commit: &github.Commit{SHA: github.Ptr(sha)}}
+	}
+
+	fake := &fakeClient{
+		deployments: []*model.Deployment{
+			{ID: "100", Ref: "v1.1.0", CreatedAt: since.AddDate(0, 1, 0)},
+		},
+		// Not in date order, as the API returns them
+		tags: []*github.RepositoryTag{
+			tag("v1.2.0", "c3"),
+			tag("nightly-20240210", "c4"), // does not match the pattern
+			tag("v1.1.0", "c2"),           // already deployed
+			tag("v2.0.0", "c5"),           // after the window
+			tag("v1.0.0", "c1"),           // before the window
+			tag("v1.0.1", "missing"),      // commit lookup fails
+		},
+		commitTimes: map[string]time.Time{
+			"c1": since.Add(-time.Hour),
+			"c2": since.AddDate(0, 1, 0),
+			"c3": since.AddDate(0, 2, 0),
+			"c4": since.AddDate(0, 1, 9),
+			"c5": until.Add(time.Hour),
+		},
+	}
+	c := &Collector{client: fake, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	opts := &CollectOptions{Since: since, Until: until, PerPage: 100, MaxPages: 1, TagPattern: "v*"}
+
+	deployments, err := c.CollectDeployments(context.Background(), "o", "r", opts, "1")
+	if err != nil {
+		t.Fatalf("CollectDeployments() error = %v", err)
+	}
+	if len(deployments) != 2 {
+		t.Fatalf("collected %d deployments, want the deployment and tag v1.2.0", len(deployments))
+	}
+	got := deployments[1]
+	want := model.Deployment{
+		ID:           "tag-1-v1.2.0",
+		RepositoryID: "1",
+		Environment:  "tag",
+		Ref:          "v1.2.0",
+		SHA:          "c3",
+		Status:       "success",
+		CreatedAt:    since.AddDate(0, 2, 0),
+		DeployedAt:   since.AddDate(0, 2, 0),
+	}
+	if *got != want {
+		t.Errorf("tag deployment = %+v, want %+v", *got, want)
+	}
+	// Only tags matching the pattern cost a commit lookup
+	if fake.commitCalls != 5 {
+		t.Errorf("GetCommitTime calls = %d, want 5", fake.commitCalls)
+	}
+}
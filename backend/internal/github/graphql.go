@@ -0,0 +1,311 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v88/github"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// pullRequestsQuery fetches a page of PRs (updated desc) with the data the REST path
+// gathers in separate per-PR calls: size, first commit, changed files, and reviews.
+const pullRequestsQuery = `query($owner: String!, $name: String!, $first: Int!, $after: String, $states: [PullRequestState!], $withFiles: Boolean!) {
+  repository(owner: $owner, name: $name) {
+    databaseId
+    pullRequests(first: $first, after: $after, states: $states, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        number
+        title
+        state
+        isDraft
+        baseRefName
+        createdAt
+        updatedAt
+        mergedAt
+        closedAt
+        additions
+        deletions
+        changedFiles
+        author { login }
+        labels(first: 20) { nodes { name } }
+        timelineItems(itemTypes: [READY_FOR_REVIEW_EVENT], last: 1) { nodes { ... on ReadyForReviewEvent { createdAt } } }
+        commits(first: 1) { totalCount nodes { commit { authoredDate } } }
+        files(first: 100) @include(if: $withFiles) { pageInfo { hasNextPage } nodes { path additions deletions } }
+        reviews(first: 100) { pageInfo { hasNextPage } nodes { databaseId state body submittedAt author { login } comments { totalCount } } }
+      }
+    }
+  }
+}`
+
+// GraphQLPullRequestListOptions options for listing pull requests with the GraphQL API
+type GraphQLPullRequestListOptions struct {
+	State     string // all, open, closed
+	After     string // end cursor of the previous page
+	PerPage   int
+	WithFiles bool
+}
+
+// GraphQLPullRequestPage is one page of pull requests from the GraphQL API, newest update first.
+type GraphQLPullRequestPage struct {
+	PullRequests []*GraphQLPullRequest
+	HasNextPage  bool
+	EndCursor    string
+}
+
+// GraphQLPullRequest is a pull request with the files and reviews fetched alongside it.
+// MoreFiles and MoreReviews are set when the PR has more than one query's worth (100).
+type GraphQLPullRequest struct {
+	PullRequest *model.PullRequest
+	Files       []*github.CommitFile
+	MoreFiles   bool
+	Reviews     []*model.Review
+	MoreReviews bool
+}
+
+// ListPullRequestsGraphQL fetches a page of pull requests with the GraphQL API
+func (c *Client) ListPullRequestsGraphQL(ctx context.Context, owner, repo string, opts *GraphQLPullRequestListOptions) (*GraphQLPullRequestPage, error) {
+	variables := map[string]any{
+		"owner":     owner,
+		"name":      repo,
+		"first":     opts.PerPage,
+		"states":    graphQLPullRequestStates(opts.State),
+		"withFiles": opts.WithFiles,
+	}
+	if opts.After != "" {
+		variables["after"] = opts.After
+	}
+
+	var data struct {
+		Repository *gqlRepository `json:"repository"`
+	}
+	if err := c.graphQL(ctx, pullRequestsQuery, variables, &data); err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	if data.Repository == nil {
+		return nil, fmt.Errorf("failed to list pull requests: repository %s/%s not found", owner, repo)
+	}
+
+	return convertGraphQLPullRequests(data.Repository), nil
+}
+
+// graphQL runs a GraphQL query and decodes its data into v.
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]any, v any) error {
+	req, err := c.client.NewRequest(ctx, "POST", c.graphQLURL(), map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.client.Do(req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return errors.New("graphql: " + strings.Join(messages, "; "))
+	}
+
+	return json.Unmarshal(resp.Data, v)
+}
+
+// graphQLURL returns the GraphQL endpoint for the client's API base URL.
+// GitHub Enterprise Server serves it at /api/graphql rather than under /api/v3/.
+func (c *Client) graphQLURL() string {
+	base := c.client.BaseURL()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
+
+// graphQLPullRequestStates maps a REST state filter to GraphQL states (nil for all).
+func graphQLPullRequestStates(state string) []string {
+	switch state {
+	case "open":
+		return []string{"OPEN"}
+	case "closed":
+		return []string{"CLOSED", "MERGED"}
+	default:
+		return nil
+	}
+}
+
+type gqlRepository struct {
+	DatabaseID   int64 `json:"databaseId"`
+	PullRequests struct {
+		PageInfo gqlPageInfo      `json:"pageInfo"`
+		Nodes    []gqlPullRequest `json:"nodes"`
+	} `json:"pullRequests"`
+}
+
+type gqlPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type gqlActor struct {
+	Login string `json:"login"`
+}
+
+type gqlPullRequest struct {
+	DatabaseID   int64      `json:"databaseId"`
+	Number       int        `json:"number"`
+	Title        string     `json:"title"`
+	State        string     `json:"state"` // OPEN, CLOSED, MERGED
+	IsDraft      bool       `json:"isDraft"`
+	BaseRefName  string     `json:"baseRefName"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	MergedAt     *time.Time `json:"mergedAt"`
+	ClosedAt     *time.Time `json:"closedAt"`
+	Additions    int        `json:"additions"`
+	Deletions    int        `json:"deletions"`
+	ChangedFiles int        `json:"changedFiles"`
+	Author       *gqlActor  `json:"author"` // nil for deleted users
+	Labels       struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	TimelineItems struct {
+		Nodes []struct {
+			CreatedAt time.Time `json:"createdAt"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	Commits struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Commit struct {
+				AuthoredDate time.Time `json:"authoredDate"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+	Files *struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Path      string `json:"path"`
+			Additions int    `json:"additions"`
+			Deletions int    `json:"deletions"`
+		} `json:"nodes"`
+	} `json:"files"`
+	Reviews struct {
+		PageInfo gqlPageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			DatabaseID  int64     `json:"databaseId"`
+			State       string    `json:"state"`
+			Body        string    `json:"body"`
+			SubmittedAt time.Time `json:"submittedAt"`
+			Author      *gqlActor `json:"author"`
+			Comments    struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"comments"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+}
+
+func (a *gqlActor) login() string {
+	if a == nil {
+		return ""
+	}
+	return a.Login
+}
+
+// convertGraphQLPullRequests converts a GraphQL pull request page to the domain model,
+// using the same IDs and state values as the REST conversion.
+func convertGraphQLPullRequests(r *gqlRepository) *GraphQLPullRequestPage {
+	repoID := fmt.Sprintf("%d", r.DatabaseID)
+	page := &GraphQLPullRequestPage{
+		HasNextPage: r.PullRequests.PageInfo.HasNextPage,
+		EndCursor:   r.PullRequests.PageInfo.EndCursor,
+	}
+
+	for _, node := range r.PullRequests.Nodes {
+		pr := &model.PullRequest{
+			ID:           fmt.Sprintf("%d", node.DatabaseID),
+			RepositoryID: repoID,
+			Number:       node.Number,
+			Title:        node.Title,
+			Author:       node.Author.login(),
+			State:        "closed",
+			Draft:        node.IsDraft,
+			BaseRef:      node.BaseRefName,
+			CreatedAt:    node.CreatedAt,
+			UpdatedAt:    node.UpdatedAt,
+			MergedAt:     node.MergedAt,
+			ClosedAt:     node.ClosedAt,
+			Additions:    node.Additions,
+			Deletions:    node.Deletions,
+			ChangedFiles: node.ChangedFiles,
+			CommitCount:  node.Commits.TotalCount,
+		}
+		if node.State == "OPEN" {
+			pr.State = "open"
+		}
+		for _, l := range node.Labels.Nodes {
+			pr.Labels = append(pr.Labels, l.Name)
+		}
+		// The last time the PR left draft; earlier drafts of a PR toggled back and forth are not kept
+		if len(node.TimelineItems.Nodes) > 0 {
+			t := node.TimelineItems.Nodes[0].CreatedAt
+			pr.ReadyForReviewAt = &t
+		}
+		// The first commit in PR order; the REST path takes the earliest author date
+		if len(node.Commits.Nodes) > 0 {
+			t := node.Commits.Nodes[0].Commit.AuthoredDate
+			pr.FirstCommitAt = &t
+		}
+
+		gpr := &GraphQLPullRequest{PullRequest: pr}
+		if node.Files != nil {
+			gpr.MoreFiles = node.Files.PageInfo.HasNextPage
+			for _, f := range node.Files.Nodes {
+				gpr.Files = append(gpr.Files, &github.CommitFile{
+					Filename:  github.Ptr(f.Path),
+					Additions: github.Ptr(f.Additions),
+					Deletions: github.Ptr(f.Deletions),
+				})
+			}
+		}
+
+		gpr.MoreReviews = node.Reviews.PageInfo.HasNextPage
+		for _, rv := range node.Reviews.Nodes {
+			// Pending reviews have no submission time
+			if rv.SubmittedAt.IsZero() {
+				continue
+			}
+			gpr.Reviews = append(gpr.Reviews, &model.Review{
+				ID:            fmt.Sprintf("%d", rv.DatabaseID),
+				PullRequestID: pr.ReviewKey(),
+				RepositoryID:  repoID,
+				Reviewer:      rv.Author.login(),
+				State:         rv.State,
+				Body:          rv.Body,
+				SubmittedAt:   rv.SubmittedAt,
+				// Review comments of this review; summed per reviewer by the collector
+				CommentsCount: rv.Comments.TotalCount,
+			})
+		}
+
+		page.PullRequests = append(page.PullRequests, gpr)
+	}
+
+	return page
+}
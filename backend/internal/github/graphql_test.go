@@ -0,0 +1,118 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// graphQLStub serves a recorded GraphQL response and keeps the last request.
+type graphQLStub struct {
+	response []byte
+	path     string
+	body     struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+}
+
+func (s *graphQLStub) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.path = req.URL.Path
+	raw, _ := io.ReadAll(req.Body)
+	_ = json.Unmarshal(raw, &s.body)
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(s.response)
+	return w.Result(), nil
+}
+
+func TestListPullRequestsGraphQL(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/graphql_pull_requests.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &graphQLStub{response: recorded}
+	c := NewClientWithHTTPClient(&http.Client{Transport: stub})
+
+	page, err := c.ListPullRequestsGraphQL(context.Background(), "o", "r", &GraphQLPullRequestListOptions{
+		State:     "closed",
+		After:     "Y3Vyc29yOjE=",
+		PerPage:   50,
+		WithFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("ListPullRequestsGraphQL() error = %v", err)
+	}
+
+	if stub.path != "/graphql" {
+		t.Errorf("request path = %q, want /graphql", stub.path)
+	}
+	if got := stub.body.Variables["states"]; !reflect.DeepEqual(got, []any{"CLOSED", "MERGED"}) {
+		t.Errorf("states = %v, want [CLOSED MERGED]", got)
+	}
+	if stub.body.Variables["after"] != "Y3Vyc29yOjE=" || stub.body.Variables["withFiles"] != true {
+		t.Errorf("variables = %v", stub.body.Variables)
+	}
+
+	if !page.HasNextPage || page.EndCursor != "Y3Vyc29yOjI=" || len(page.PullRequests) != 2 {
+		t.Fatalf("page = next %v, cursor %q, %d PRs", page.HasNextPage, page.EndCursor, len(page.PullRequests))
+	}
+
+	merged := page.PullRequests[0]
+	pr := merged.PullRequest
+	if pr.ID != "1001" || pr.RepositoryID != "42" || pr.Number != 12 || pr.Author != "alice" || pr.State != "closed" || pr.BaseRef != "main" {
+		t.Errorf("unexpected PR fields: %+v", pr)
+	}
+	if !reflect.DeepEqual(pr.Labels, []string{"hotfix"}) {
+		t.Errorf("Labels = %v, want [hotfix]", pr.Labels)
+	}
+	if pr.MergedAt == nil || !pr.MergedAt.Equal(time.Date(2026, 1, 6, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("MergedAt = %v", pr.MergedAt)
+	}
+	if pr.Additions != 120 || pr.Deletions != 30 || pr.ChangedFiles != 2 || pr.CommitCount != 3 {
+		t.Errorf("size = +%d -%d, %d files, %d commits", pr.Additions, pr.Deletions, pr.ChangedFiles, pr.CommitCount)
+	}
+	if pr.ReadyForReviewAt == nil || !pr.ReadyForReviewAt.Equal(time.Date(2026, 1, 5, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReadyForReviewAt = %v", pr.ReadyForReviewAt)
+	}
+	if pr.FirstCommitAt == nil || !pr.FirstCommitAt.Equal(time.Date(2026, 1, 4, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("FirstCommitAt = %v", pr.FirstCommitAt)
+	}
+	if len(merged.Files) != 2 || merged.Files[0].GetFilename() != "web/login.tsx" || merged.Files[0].GetAdditions() != 100 || merged.MoreFiles {
+		t.Errorf("files = %v (more %v)", merged.Files, merged.MoreFiles)
+	}
+	// The pending review has not been submitted and is skipped
+	if len(merged.Reviews) != 2 {
+		t.Fatalf("len(Reviews) = %d, want 2", len(merged.Reviews))
+	}
+	review := merged.Reviews[0]
+	if review.ID != "5001" || review.PullRequestID != "42#12" || review.RepositoryID != "42" || review.Reviewer != "bob" || review.State != "COMMENTED" || review.CommentsCount != 3 {
+		t.Errorf("unexpected review fields: %+v", review)
+	}
+
+	open := page.PullRequests[1].PullRequest
+	if open.State != "open" || !open.Draft || open.Author != "" || open.MergedAt != nil || open.FirstCommitAt != nil || open.ReadyForReviewAt != nil || open.Labels != nil {
+		t.Errorf("unexpected open PR fields: %+v", open)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	if got := NewClient("token").graphQLURL(); got != "https://api.github.com/graphql" {
+		t.Errorf("github.com graphQLURL() = %q", got)
+	}
+
+	c, err := NewEnterpriseClient("token", "https://ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.graphQLURL(); got != "https://ghe.example.com/api/graphql" {
+		t.Errorf("enterprise graphQLURL() = %q, want https://ghe.example.com/api/graphql", got)
+	}
+}
@@ -0,0 +1,88 @@
+package github
+
+import (
+	"path"
+	"strings"
+)
+
+// LanguageMap maps a lowercase file extension (".ts") or base filename ("dockerfile")
+// to a language label used to group file stats.
+type LanguageMap map[string]string
+
+// DefaultLanguageMap returns the built-in extension to language mapping.
+func DefaultLanguageMap() LanguageMap {
+	return LanguageMap{
+		".go":        "Go",
+		".ts":        "TypeScript",
+		".tsx":       "TypeScript",
+		".mts":       "TypeScript",
+		".cts":       "TypeScript",
+		".js":        "JavaScript",
+		".jsx":       "JavaScript",
+		".mjs":       "JavaScript",
+		".cjs":       "JavaScript",
+		".svelte":    "Svelte",
+		".vue":       "Vue",
+		".py":        "Python",
+		".rb":        "Ruby",
+		".java":      "Java",
+		".kt":        "Kotlin",
+		".kts":       "Kotlin",
+		".swift":     "Swift",
+		".rs":        "Rust",
+		".c":         "C",
+		".h":         "C",
+		".cc":        "C++",
+		".cpp":       "C++",
+		".hpp":       "C++",
+		".cs":        "C#",
+		".php":       "PHP",
+		".scala":     "Scala",
+		".sh":        "Shell",
+		".bash":      "Shell",
+		".sql":       "SQL",
+		".html":      "HTML",
+		".css":       "CSS",
+		".scss":      "CSS",
+		".sass":      "CSS",
+		".less":      "CSS",
+		".md":        "Markdown",
+		".mdx":       "Markdown",
+		".json":      "JSON",
+		".yaml":      "YAML",
+		".yml":       "YAML",
+		".toml":      "TOML",
+		".tf":        "Terraform",
+		".tfvars":    "Terraform",
+		".proto":     "Protocol Buffers",
+		"dockerfile": "Dockerfile",
+		"makefile":   "Makefile",
+	}
+}
+
+// Merge returns a copy of m with overrides applied. An empty label removes the mapping.
+func (m LanguageMap) Merge(overrides map[string]string) LanguageMap {
+	merged := make(LanguageMap, len(m)+len(overrides))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if v = strings.TrimSpace(v); v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Language returns the language label for filename, or "" when it is not mapped.
+// The base filename takes precedence over the extension.
+func (m LanguageMap) Language(filename string) string {
+	base := strings.ToLower(path.Base(filename))
+	if lang, ok := m[base]; ok {
+		return lang
+	}
+	return m[path.Ext(base)]
+}
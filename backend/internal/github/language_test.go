@@ -0,0 +1,84 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v88/github"
+)
+
+func TestLanguageMap_Language(t *testing.T) {
+	m := DefaultLanguageMap()
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"src/app.ts", "TypeScript"},
+		{"src/App.TSX", "TypeScript"},
+		{"main.go", "Go"},
+		{"Dockerfile", "Dockerfile"},
+		{"backend/Makefile", "Makefile"},
+		{"LICENSE", ""},
+		{"data.xyz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := m.Language(tt.filename); got != tt.want {
+				t.Errorf("Language(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguageMap_Merge(t *testing.T) {
+	base := DefaultLanguageMap()
+	m := base.Merge(map[string]string{
+		".TSX":        "React",
+		"Jenkinsfile": "Groovy",
+		".md":         "",
+	})
+
+	if got := m.Language("a.tsx"); got != "React" {
+		t.Errorf("Language(a.tsx) = %q, want React", got)
+	}
+	if got := m.Language("Jenkinsfile"); got != "Groovy" {
+		t.Errorf("Language(Jenkinsfile) = %q, want Groovy", got)
+	}
+	if got := m.Language("README.md"); got != "" {
+		t.Errorf("Language(README.md) = %q, want empty", got)
+	}
+	if got := base.Language("a.tsx"); got != "TypeScript" {
+		t.Errorf("Merge modified the base map: Language(a.tsx) = %q", got)
+	}
+}
+
+func TestAggregateFileExtStats_Languages(t *testing.T) {
+	files := []*github.CommitFile{
+		{Filename: github.Ptr("a.ts"), Additions: github.Ptr(10)},
+		{Filename: github.Ptr("b.tsx"), Additions: github.Ptr(5)},
+		{Filename: github.Ptr("c.tsx"), Deletions: github.Ptr(2)},
+		{Filename: github.Ptr("Dockerfile"), Additions: github.Ptr(3)},
+		{Filename: github.Ptr("LICENSE"), Additions: github.Ptr(1)},
+	}
+
+	got := aggregateFileExtStats(files, DefaultLanguageMap())
+
+	type key struct{ ext, language string }
+	byKey := make(map[key][2]int) // files, lines
+	for _, s := range got {
+		byKey[key{s.Extension, s.Language}] = [2]int{s.Files, s.Additions + s.Deletions}
+	}
+	want := map[key][2]int{
+		{".ts", "TypeScript"}:      {1, 10},
+		{".tsx", "TypeScript"}:     {2, 7},
+		{"(no ext)", "Dockerfile"}: {1, 3},
+		{"(no ext)", ""}:           {1, 1},
+	}
+	if len(byKey) != len(want) {
+		t.Fatalf("got %+v, want %d entries", got, len(want))
+	}
+	for k, w := range want {
+		if byKey[k] != w {
+			t.Errorf("%v = %v, want %v", k, byKey[k], w)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
@@ -10,17 +11,38 @@ import (
 
 // Aggregator handles metrics aggregation
 type Aggregator struct {
-	calculator *Calculator
+	calculator   *Calculator
+	botUsernames []string // custom bot entries for model.IsBot
+	minActivity  int      // activities a day needs to count as an active contributor
 }
 
 // NewAggregator creates a new Aggregator
 func NewAggregator() *Aggregator {
 	return &Aggregator{
-		calculator: NewCalculator(),
+		calculator:  NewCalculator(),
+		minActivity: 1,
 	}
 }
 
-// AggregateDailyMetrics aggregates metrics for a specific date
+// WithBotUsernames returns a copy of the aggregator that also treats the custom bot entries
+// (exact names or glob patterns) as bots. Bots never count as active contributors.
+func (a *Aggregator) WithBotUsernames(botUsernames []string) *Aggregator {
+	aa := *a
+	aa.botUsernames = botUsernames
+	return &aa
+}
+
+// WithMinActivity returns a copy of the aggregator that counts a daily active contributor only
+// after at least n activities that day (PRs opened, PRs merged, and reviews submitted).
+// Values below 1 mean 1.
+func (a *Aggregator) WithMinActivity(n int) *Aggregator {
+	aa := *a
+	aa.minActivity = max(n, 1)
+	return &aa
+}
+
+// AggregateDailyMetrics aggregates metrics for a specific date.
+// The day is taken in the configured location (timeutil.Location), whatever the location of date.
 func (a *Aggregator) AggregateDailyMetrics(
 	repositoryID string,
 	date time.Time,
@@ -28,12 +50,17 @@ func (a *Aggregator) AggregateDailyMetrics(
 	reviews []*model.Review,
 	deployments []*model.Deployment,
 ) *model.DailyMetrics {
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	local := date.In(timeutil.Location())
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	endOfDay := startOfDay.AddDate(0, 0, 1)
 
 	// Filter data for this day
 	var dayPRsOpened, dayPRsMerged, dayPRsClosed []*model.PullRequest
+	openPRCount := 0
 	for _, pr := range prs {
+		if isOpenAt(pr, endOfDay) {
+			openPRCount++
+		}
 		if !pr.CreatedAt.Before(startOfDay) && pr.CreatedAt.Before(endOfDay) {
 			dayPRsOpened = append(dayPRsOpened, pr)
 		}
@@ -59,8 +86,9 @@ func (a *Aggregator) AggregateDailyMetrics(
 		}
 	}
 
-	// Calculate cycle time for merged PRs
-	cycleTimeMetrics := a.calculator.CalculateCycleTime(dayPRsMerged, startOfDay, endOfDay)
+	// Calculate cycle time for merged PRs; bot PRs (dependency bumps and the like) would skew it
+	humanPRsMerged := model.FilterPullRequestsByBot(dayPRsMerged, a.botUsernames, true, false)
+	cycleTimeMetrics := a.calculator.CalculateCycleTime(humanPRsMerged, startOfDay, endOfDay)
 
 	// Calculate code changes
 	totalAdditions, totalDeletions := 0, 0
@@ -70,15 +98,15 @@ func (a *Aggregator) AggregateDailyMetrics(
 	}
 
 	// Count active contributors
-	contributors := make(map[string]bool)
+	activities := make(map[string]int)
 	for _, pr := range dayPRsOpened {
-		contributors[pr.Author] = true
+		activities[pr.Author]++
 	}
 	for _, pr := range dayPRsMerged {
-		contributors[pr.Author] = true
+		activities[pr.Author]++
 	}
 	for _, r := range dayReviews {
-		contributors[r.Reviewer] = true
+		activities[r.Reviewer]++
 	}
 
 	// Calculate reviews per PR
@@ -88,7 +116,7 @@ func (a *Aggregator) AggregateDailyMetrics(
 	}
 
 	return &model.DailyMetrics{
-		ID:                 fmt.Sprintf("%s:%s", repositoryID, date.Format("2006-01-02")),
+		ID:                 fmt.Sprintf("%s:%s", repositoryID, startOfDay.Format("2006-01-02")),
 		RepositoryID:       repositoryID,
 		Date:               startOfDay,
 		AvgCycleTime:       cycleTimeMetrics.AvgCycleTime,
@@ -98,14 +126,41 @@ func (a *Aggregator) AggregateDailyMetrics(
 		AvgMergeTime:       cycleTimeMetrics.AvgMergeTime,
 		PRsOpened:          len(dayPRsOpened),
 		PRsMerged:          len(dayPRsMerged),
+		BotPRsMerged:       len(dayPRsMerged) - len(humanPRsMerged),
 		PRsClosed:          len(dayPRsClosed),
+		OpenPRCount:        openPRCount,
 		ReviewsSubmitted:   len(dayReviews),
 		AvgReviewsPerPR:    avgReviewsPerPR,
 		TotalAdditions:     totalAdditions,
 		TotalDeletions:     totalDeletions,
 		DeploymentCount:    len(dayDeployments),
-		ActiveContributors: len(contributors),
+		ActiveContributors: a.countActiveContributors(activities),
+	}
+}
+
+// countActiveContributors counts the non-bot users with at least minActivity activities.
+func (a *Aggregator) countActiveContributors(activities map[string]int) int {
+	count := 0
+	for user, n := range activities {
+		if n >= a.minActivity && !model.IsBot(user, a.botUsernames) {
+			count++
+		}
+	}
+	return count
+}
+
+// isOpenAt reports whether the PR was created before t and not yet merged or closed at t.
+func isOpenAt(pr *model.PullRequest, t time.Time) bool {
+	if !pr.CreatedAt.Before(t) {
+		return false
 	}
+	if pr.MergedAt != nil && pr.MergedAt.Before(t) {
+		return false
+	}
+	if pr.ClosedAt != nil && pr.ClosedAt.Before(t) {
+		return false
+	}
+	return true
 }
 
 // AggregateRange aggregates metrics for a date range
@@ -176,6 +231,15 @@ func (a *Aggregator) CalculateSprintMetrics(
 		contributors[r.Reviewer] = true
 	}
 
+	// PRs opened in the sprint but not merged by its end spill over into the next sprint
+	var spillover []int
+	for _, pr := range sprintPRsOpened {
+		if pr.MergedAt == nil || pr.MergedAt.After(sprint.EndDate) {
+			spillover = append(spillover, pr.Number)
+		}
+	}
+	sort.Ints(spillover)
+
 	// Determine sprint status
 	status := "planned"
 	now := timeutil.Now()
@@ -186,7 +250,7 @@ func (a *Aggregator) CalculateSprintMetrics(
 	}
 
 	// Generate burndown data
-	burndownData := a.generateBurndown(sprint, sprintPRsMerged)
+	burndownData := a.generateBurndown(sprint, sprintPRsOpened)
 
 	return &model.SprintPerformance{
 		SprintID:           sprint.ID,
@@ -204,19 +268,53 @@ func (a *Aggregator) CalculateSprintMetrics(
 		AvgReviewTime:      reviewMetrics.AvgTimeToFirstReview,
 		ActiveContributors: len(contributors),
 		ReviewsSubmitted:   len(sprintReviews),
+		SpilloverCount:     len(spillover),
+		SpilloverPRs:       spillover,
 		BurndownData:       burndownData,
 	}
 }
 
-func (a *Aggregator) generateBurndown(sprint *model.Sprint, mergedPRs []*model.PullRequest) []model.BurndownPoint {
+// PreviousSprint returns the sprint that started most recently before sprint, or nil for the first sprint.
+func PreviousSprint(sprints []*model.Sprint, sprint *model.Sprint) *model.Sprint {
+	var previous *model.Sprint
+	for _, s := range sprints {
+		if s.ID == sprint.ID || !s.StartDate.Before(sprint.StartDate) {
+			continue
+		}
+		if previous == nil || s.StartDate.After(previous.StartDate) {
+			previous = s
+		}
+	}
+	return previous
+}
+
+// CompareWithPreviousSprint sets the velocity (merged PRs) and cycle time changes of current
+// as percentages relative to previous. A change stays 0 when the previous value is 0.
+func CompareWithPreviousSprint(current, previous *model.SprintPerformance) {
+	current.VelocityChange = PercentChange(float64(previous.PRsMerged), float64(current.PRsMerged))
+	current.CycleTimeChange = PercentChange(previous.AvgCycleTime, current.AvgCycleTime)
+}
+
+// PercentChange returns the percentage change from previous to current, or 0 when previous is 0
+// since the change is undefined. Every period comparison (sprints, ?compare=previous) uses it.
+func PercentChange(previous, current float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return (current - previous) / previous * 100
+}
+
+// generateBurndown charts the sprint scope day by day. Planned scope is the PRs opened during the sprint;
+// a planned PR is completed once merged, so PRs still open at the end leave remaining work.
+func (a *Aggregator) generateBurndown(sprint *model.Sprint, plannedPRs []*model.PullRequest) []model.BurndownPoint {
 	var burndownData []model.BurndownPoint
 
-	totalPlanned := len(mergedPRs) // Simplified: using merged PRs as planned
+	totalPlanned := len(plannedPRs)
 	current := sprint.StartDate
 
 	for !current.After(sprint.EndDate) && !current.After(timeutil.Now()) {
 		completed := 0
-		for _, pr := range mergedPRs {
+		for _, pr := range plannedPRs {
 			if pr.MergedAt != nil && !pr.MergedAt.After(current) {
 				completed++
 			}
@@ -0,0 +1,257 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
+)
+
+func TestAggregateRange_OpenPRCount(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	prs := []*model.PullRequest{
+		// Opened before the range, merged on day 2
+		{Number: 1, CreatedAt: day(1, 10), MergedAt: ptr(day(2, 15)), ClosedAt: ptr(day(2, 15))},
+		// Opened day 2, still open
+		{Number: 2, CreatedAt: day(2, 9)},
+		// Opened day 2, closed without merge on day 4
+		{Number: 3, CreatedAt: day(2, 11), ClosedAt: ptr(day(4, 8))},
+		// Opened and merged on day 3
+		{Number: 4, CreatedAt: day(3, 9), MergedAt: ptr(day(3, 17)), ClosedAt: ptr(day(3, 17))},
+		// Opened on day 5, after the range
+		{Number: 5, CreatedAt: day(5, 9)},
+	}
+
+	got := NewAggregator().AggregateRange("100", day(1, 0), day(4, 0), prs, nil, nil)
+
+	want := []int{1, 2, 2, 1} // end of day 1..4
+	if len(got) != len(want) {
+		t.Fatalf("got %d days, want %d", len(got), len(want))
+	}
+	for i, dm := range got {
+		if dm.OpenPRCount != want[i] {
+			t.Errorf("%s OpenPRCount = %d, want %d", dm.Date.Format("2006-01-02"), dm.OpenPRCount, want[i])
+		}
+	}
+}
+
+func TestAggregateDailyMetrics_ConfiguredLocation(t *testing.T) {
+	jst := time.FixedZone("UTC+09:00", 9*3600)
+	timeutil.Init(jst)
+	defer timeutil.Init(time.UTC)
+
+	// 23:00 UTC on Jan 10 is 08:00 on Jan 11 in +09:00
+	merged := time.Date(2026, 1, 10, 23, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{
+		{Number: 1, CreatedAt: merged.Add(-2 * time.Hour), MergedAt: &merged, ClosedAt: &merged},
+	}
+
+	a := NewAggregator()
+	// Dates arrive as UTC midnights
+	jan10 := a.AggregateDailyMetrics("100", time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), prs, nil, nil)
+	jan11 := a.AggregateDailyMetrics("100", time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC), prs, nil, nil)
+
+	if jan10.PRsMerged != 0 || jan11.PRsMerged != 1 {
+		t.Errorf("PRsMerged = %d on Jan 10, %d on Jan 11; want 0, 1", jan10.PRsMerged, jan11.PRsMerged)
+	}
+	if want := time.Date(2026, 1, 11, 0, 0, 0, 0, jst); !jan11.Date.Equal(want) || jan11.ID != "100:2026-01-11" {
+		t.Errorf("Jan 11 bucket = %s (%s), want %s (100:2026-01-11)", jan11.Date, jan11.ID, want)
+	}
+}
+
+func TestAggregateDailyMetrics_ActiveContributors(t *testing.T) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	prs := []*model.PullRequest{
+		// alice opens and merges: 2 activities
+		{Number: 1, Author: "alice", CreatedAt: at(9), MergedAt: ptr(at(15))},
+		{Number: 2, Author: "dependabot[bot]", CreatedAt: at(10)},
+		{Number: 3, Author: "release-robot", CreatedAt: at(11)},
+	}
+	reviews := []*model.Review{
+		// bob reviews once: 1 activity
+		{Reviewer: "bob", SubmittedAt: at(12)},
+		{Reviewer: "ci-bot", SubmittedAt: at(13)},
+	}
+
+	tests := []struct {
+		name string
+		agg  *Aggregator
+		want int
+	}{
+		{"suffixed bots excluded by default", NewAggregator(), 4},
+		{"custom bots excluded", NewAggregator().WithBotUsernames([]string{"release-robot", "ci-*"}), 2},
+		{"minimum activity", NewAggregator().WithBotUsernames([]string{"release-robot", "ci-*"}).WithMinActivity(2), 1},
+		{"minimum below one", NewAggregator().WithMinActivity(0), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.agg.AggregateDailyMetrics("100", day, prs, reviews, nil)
+			if got.ActiveContributors != tt.want {
+				t.Errorf("ActiveContributors = %d, want %d", got.ActiveContributors, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateDailyMetrics_BotPRsExcludedFromCycleTime(t *testing.T) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	merged := func(author string, createdHour, cycleHours int) *model.PullRequest {
+		created := day.Add(time.Duration(createdHour) * time.Hour)
+		mergedAt := created.Add(time.Duration(cycleHours) * time.Hour)
+		return &model.PullRequest{Author: author, CreatedAt: created, MergedAt: &mergedAt, ClosedAt: &mergedAt}
+	}
+	prs := []*model.PullRequest{
+		merged("alice", 8, 2),
+		merged("bob", 9, 4),
+		// Opened days earlier, merged today
+		merged("dependabot[bot]", -200, 210),
+		merged("release-robot", 10, 10),
+	}
+
+	got := NewAggregator().WithBotUsernames([]string{"release-robot"}).AggregateDailyMetrics("100", day, prs, nil, nil)
+	if got.PRsMerged != 4 || got.BotPRsMerged != 2 || got.AveragedPRs() != 2 {
+		t.Errorf("merged %d (bots %d, averaged %d), want 4 (bots 2, averaged 2)", got.PRsMerged, got.BotPRsMerged, got.AveragedPRs())
+	}
+	if got.AvgCycleTime != 3 {
+		t.Errorf("AvgCycleTime = %v, want 3 (bot PRs left out)", got.AvgCycleTime)
+	}
+}
+
+func TestCompareWithPreviousSprint(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	merged := func(created, mergedAt time.Time) *model.PullRequest {
+		return &model.PullRequest{CreatedAt: created, FirstCommitAt: ptr(created), MergedAt: ptr(mergedAt)}
+	}
+
+	first := &model.Sprint{ID: "repo:s1", Name: "Sprint 1", StartDate: day(5, 0), EndDate: day(16, 23)}
+	second := &model.Sprint{ID: "repo:s2", Name: "Sprint 2", StartDate: day(19, 0), EndDate: day(30, 23)}
+	later := &model.Sprint{ID: "repo:s3", Name: "Sprint 3", StartDate: day(31, 0), EndDate: day(31, 23)}
+	sprints := []*model.Sprint{later, first, second}
+
+	if got := PreviousSprint(sprints, second); got != first {
+		t.Errorf("PreviousSprint(second) = %v, want first", got)
+	}
+	if got := PreviousSprint(sprints, first); got != nil {
+		t.Errorf("PreviousSprint(first) = %v, want nil", got)
+	}
+
+	prs := []*model.PullRequest{
+		// Sprint 1: two merges, 10h cycle time each
+		merged(day(6, 9), day(6, 19)),
+		merged(day(7, 9), day(7, 19)),
+		// Sprint 2: three merges, 5h cycle time each
+		merged(day(20, 9), day(20, 14)),
+		merged(day(21, 9), day(21, 14)),
+		merged(day(22, 9), day(22, 14)),
+	}
+
+	a := NewAggregator()
+	previous := a.CalculateSprintMetrics(first, prs, nil)
+	current := a.CalculateSprintMetrics(second, prs, nil)
+	CompareWithPreviousSprint(current, previous)
+
+	if !approxEqual(current.VelocityChange, 50) {
+		t.Errorf("VelocityChange = %v, want 50", current.VelocityChange)
+	}
+	if !approxEqual(current.CycleTimeChange, -50) {
+		t.Errorf("CycleTimeChange = %v, want -50", current.CycleTimeChange)
+	}
+
+	// The first sprint has nothing to compare against
+	if previous.VelocityChange != 0 || previous.CycleTimeChange != 0 {
+		t.Errorf("first sprint changes = %v / %v, want 0 / 0", previous.VelocityChange, previous.CycleTimeChange)
+	}
+	empty := a.CalculateSprintMetrics(later, prs, nil)
+	CompareWithPreviousSprint(current, empty)
+	if current.VelocityChange != 0 || current.CycleTimeChange != 0 {
+		t.Errorf("changes against an empty sprint = %v / %v, want 0 / 0", current.VelocityChange, current.CycleTimeChange)
+	}
+}
+
+func TestCalculateSprintMetrics_BurndownPlannedScope(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 4, d, h, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	sprint := &model.Sprint{ID: "repo:s1", StartDate: day(1, 0), EndDate: day(5, 0)}
+	prs := []*model.PullRequest{
+		{Number: 1, CreatedAt: day(1, 9), MergedAt: ptr(day(2, 12))},
+		{Number: 2, CreatedAt: day(1, 10), MergedAt: ptr(day(3, 12))},
+		{Number: 3, CreatedAt: day(2, 9)},                            // still open
+		{Number: 4, CreatedAt: day(3, 9), MergedAt: ptr(day(9, 12))}, // merged after the sprint
+		// Opened before the sprint: not planned scope
+		{Number: 5, CreatedAt: day(1, 0).AddDate(0, 0, -3), MergedAt: ptr(day(2, 15))},
+	}
+
+	got := NewAggregator().CalculateSprintMetrics(sprint, prs, nil)
+	if len(got.BurndownData) != 5 {
+		t.Fatalf("got %d burndown points, want 5", len(got.BurndownData))
+	}
+
+	wantRemaining := []int{4, 4, 3, 2, 2} // start of day 1..5
+	for i, p := range got.BurndownData {
+		if p.Planned != 4 {
+			t.Errorf("%s Planned = %d, want 4", p.Date.Format("2006-01-02"), p.Planned)
+		}
+		if p.Remaining != wantRemaining[i] || p.Completed != 4-wantRemaining[i] {
+			t.Errorf("%s Remaining/Completed = %d/%d, want %d/%d",
+				p.Date.Format("2006-01-02"), p.Remaining, p.Completed, wantRemaining[i], 4-wantRemaining[i])
+		}
+	}
+	if last := got.BurndownData[len(got.BurndownData)-1]; last.Remaining <= 0 {
+		t.Errorf("final Remaining = %d, want positive for an incomplete sprint", last.Remaining)
+	}
+}
+
+func TestCalculateSprintMetrics_Spillover(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 4, d, h, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	sprint := &model.Sprint{ID: "repo:s1", StartDate: day(1, 0), EndDate: day(12, 23)}
+	prs := []*model.PullRequest{
+		{Number: 12, CreatedAt: day(2, 9), MergedAt: ptr(day(4, 12))},  // merged inside the sprint
+		{Number: 15, CreatedAt: day(10, 9), MergedAt: ptr(day(15, 9))}, // merged after the sprint
+		{Number: 11, CreatedAt: day(3, 9)},                             // never merged
+		{Number: 14, CreatedAt: day(12, 22), MergedAt: ptr(day(12, 23))},
+		// Opened outside the sprint: never spillover of this sprint
+		{Number: 9, CreatedAt: day(1, 0).AddDate(0, 0, -2)},
+		{Number: 20, CreatedAt: day(14, 9)},
+	}
+
+	got := NewAggregator().CalculateSprintMetrics(sprint, prs, nil)
+	if got.SpilloverCount != 2 {
+		t.Errorf("SpilloverCount = %d, want 2", got.SpilloverCount)
+	}
+	if len(got.SpilloverPRs) != 2 || got.SpilloverPRs[0] != 11 || got.SpilloverPRs[1] != 15 {
+		t.Errorf("SpilloverPRs = %v, want [11 15]", got.SpilloverPRs)
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous float64
+		current  float64
+		want     float64
+	}{
+		{"decrease", 50, 44, -12},
+		{"increase", 10, 15, 50},
+		{"unchanged", 8, 8, 0},
+		{"previous zero is undefined", 0, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PercentChange(tt.previous, tt.current); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("PercentChange(%v, %v) = %v, want %v", tt.previous, tt.current, got, tt.want)
+			}
+		})
+	}
+}
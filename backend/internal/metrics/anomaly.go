@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// DefaultAnomalyZThreshold flags days more than two standard deviations above the mean.
+const DefaultAnomalyZThreshold = 2.0
+
+// CalculateCycleTimeAnomalies flags the days in [startDate, endDate] whose average cycle time
+// is more than zThreshold standard deviations above the mean of the period.
+// Days without merged PRs are not part of the series.
+func CalculateCycleTimeAnomalies(daily []*model.DailyMetrics, startDate, endDate time.Time, zThreshold float64) *model.CycleTimeAnomalies {
+	days := dailyCycleTimes(daily, startDate, endDate)
+	mean, stdDev := meanStdDev(days.values)
+
+	result := &model.CycleTimeAnomalies{
+		Period:    "custom",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Timezone:  startDate.Location().String(),
+		Threshold: zThreshold,
+		Anomalies: []model.CycleTimeAnomaly{},
+	}
+	for _, v := range days.values {
+		if !math.IsNaN(v) {
+			result.SampleDays++
+		}
+	}
+	// NaN does not encode to JSON; a period without merges reports zeros
+	if result.SampleDays > 0 {
+		result.Mean = mean
+		result.StdDev = stdDev
+	}
+
+	for _, i := range detectAnomalies(days.values, zThreshold) {
+		result.Anomalies = append(result.Anomalies, model.CycleTimeAnomaly{
+			Date:         days.dates[i],
+			AvgCycleTime: days.values[i],
+			ZScore:       (days.values[i] - mean) / stdDev,
+			PRsMerged:    days.merged[i],
+		})
+	}
+	return result
+}
+
+// detectAnomalies returns the indices of values more than zThreshold population standard
+// deviations above the mean of the series, in order. NaN values are gaps: they are left out
+// of the mean and never flagged. A series without spread has no anomalies.
+func detectAnomalies(series []float64, zThreshold float64) []int {
+	mean, stdDev := meanStdDev(series)
+	if stdDev == 0 || math.IsNaN(stdDev) {
+		return nil
+	}
+	var result []int
+	for i, v := range series {
+		if !math.IsNaN(v) && (v-mean)/stdDev > zThreshold {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// meanStdDev returns the mean and population standard deviation of the non-NaN values,
+// or NaN for both when there are none.
+func meanStdDev(series []float64) (mean, stdDev float64) {
+	var sum float64
+	var n int
+	for _, v := range series {
+		if !math.IsNaN(v) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return math.NaN(), math.NaN()
+	}
+	mean = sum / float64(n)
+
+	var sq float64
+	for _, v := range series {
+		if !math.IsNaN(v) {
+			sq += (v - mean) * (v - mean)
+		}
+	}
+	return mean, math.Sqrt(sq / float64(n))
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		name      string
+		series    []float64
+		threshold float64
+		want      []int
+	}{
+		{"one clear outlier", []float64{10, 12, 11, 9, 10, 11, 60, 10, 12, 11}, 2, []int{6}},
+		{"outlier among gaps", []float64{10, nan, 12, 11, nan, 9, 10, 11, 60, 10}, 2, []int{8}},
+		{"low outliers are not flagged", []float64{50, 52, 51, 49, 50, 51, 1, 50, 52, 51}, 2, nil},
+		{"higher threshold flags nothing", []float64{10, 12, 11, 9, 10, 11, 60, 10, 12, 11}, 3, nil},
+		{"no spread", []float64{5, 5, 5, 5}, 2, nil},
+		{"only gaps", []float64{nan, nan}, 2, nil},
+		{"empty series", nil, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectAnomalies(tt.series, tt.threshold); !slices.Equal(got, tt.want) {
+				t.Errorf("detectAnomalies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateCycleTimeAnomalies(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	var daily []*model.DailyMetrics
+	for d := 1; d <= 10; d++ {
+		daily = append(daily, &model.DailyMetrics{Date: day(d), PRsMerged: 1, AvgCycleTime: 10})
+	}
+	daily[4].AvgCycleTime = 80
+	daily[4].PRsMerged = 3
+
+	// Days 11-14 have no merges and stay out of the series
+	got := CalculateCycleTimeAnomalies(daily, day(1), day(14), DefaultAnomalyZThreshold)
+	if got.SampleDays != 10 {
+		t.Errorf("SampleDays = %d, want 10", got.SampleDays)
+	}
+	if math.Abs(got.Mean-17) > 1e-9 || math.Abs(got.StdDev-21) > 1e-9 {
+		t.Errorf("Mean = %v, StdDev = %v; want 17, 21", got.Mean, got.StdDev)
+	}
+	if len(got.Anomalies) != 1 {
+		t.Fatalf("Anomalies = %+v, want one", got.Anomalies)
+	}
+	a := got.Anomalies[0]
+	if !a.Date.Equal(day(5)) || a.AvgCycleTime != 80 || a.PRsMerged != 3 || math.Abs(a.ZScore-3) > 1e-9 {
+		t.Errorf("anomaly = %+v, want 2026-03-05 at 80h with z-score 3 over 3 PRs", a)
+	}
+}
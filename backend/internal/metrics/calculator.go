@@ -1,65 +1,305 @@
 package metrics
 
 import (
+	"fmt"
+	"math"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+	"github.com/compasstechlab/dora-yaki/internal/timeutil"
 )
 
 // Calculator handles metrics calculations
-type Calculator struct{}
+type Calculator struct {
+	hours           model.HoursFunc // measures PR phase durations; wall-clock by default
+	weights         ScoreWeights
+	thresholds      ScoreThresholds
+	failurePatterns []string // lower-cased title substrings marking a failed change
+	failureLabels   []string // lower-cased PR labels marking a failed change
+}
+
+// CalculatorOptions bundles the configurable behavior of a Calculator.
+type CalculatorOptions struct {
+	// Weights of the productivity score components; invalid weights fall back to DefaultScoreWeights.
+	Weights ScoreWeights
+	// Thresholds grade the productivity score components; unset benchmarks use the defaults.
+	Thresholds ScoreThresholds
+	// FailurePatterns are case-insensitive PR title substrings (e.g. "revert", "hotfix") that mark
+	// a merged PR as a failed change for the change failure rate. None by default.
+	FailurePatterns []string
+	// FailureLabels are PR labels (e.g. "incident", "rollback") that mark a merged PR as a failed
+	// change, matched case-insensitively. None by default.
+	FailureLabels []string
+	// BusinessHours measures durations in DefaultBusinessHours instead of wall-clock time.
+	BusinessHours bool
+}
+
+// DefaultCalculatorOptions returns the options NewCalculator uses.
+func DefaultCalculatorOptions() CalculatorOptions {
+	return CalculatorOptions{
+		Weights:    DefaultScoreWeights(),
+		Thresholds: DefaultScoreThresholds(),
+	}
+}
 
 // NewCalculator creates a new Calculator
 func NewCalculator() *Calculator {
-	return &Calculator{}
+	return NewCalculatorWithOptions(DefaultCalculatorOptions())
+}
+
+// NewCalculatorWithWeights creates a new Calculator with productivity score weights.
+// Invalid weights (see ScoreWeights.Validate) fall back to DefaultScoreWeights.
+func NewCalculatorWithWeights(weights ScoreWeights) *Calculator {
+	opts := DefaultCalculatorOptions()
+	opts.Weights = weights
+	return NewCalculatorWithOptions(opts)
+}
+
+// NewCalculatorWithOptions creates a new Calculator with the given options.
+func NewCalculatorWithOptions(opts CalculatorOptions) *Calculator {
+	weights := opts.Weights
+	if weights.Validate() != nil {
+		weights = DefaultScoreWeights()
+	}
+
+	c := (&Calculator{
+		hours:           model.WallClockHours,
+		weights:         weights,
+		failurePatterns: normalizeMatchers(opts.FailurePatterns),
+		failureLabels:   normalizeMatchers(opts.FailureLabels),
+	}).WithScoreThresholds(opts.Thresholds)
+	if opts.BusinessHours {
+		c = c.WithBusinessHours(DefaultBusinessHours())
+	}
+	return c
+}
+
+// normalizeMatchers lower-cases and trims values, dropping empty ones.
+func normalizeMatchers(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ScoreWeights holds the productivity score component weights.
+type ScoreWeights struct {
+	CycleTime  float64
+	Review     float64
+	Deployment float64
+	Quality    float64
+}
+
+// DefaultScoreWeights returns the default productivity score weights.
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		CycleTime:  0.30,
+		Review:     0.25,
+		Deployment: 0.25,
+		Quality:    0.20,
+	}
+}
+
+// Validate checks that all weights are non-negative and sum to 1.0.
+func (w ScoreWeights) Validate() error {
+	if w.CycleTime < 0 || w.Review < 0 || w.Deployment < 0 || w.Quality < 0 {
+		return fmt.Errorf("score weights must be non-negative: %+v", w)
+	}
+	if sum := w.CycleTime + w.Review + w.Deployment + w.Quality; math.Abs(sum-1) > 1e-6 {
+		return fmt.Errorf("score weights must sum to 1.0, got %g", sum)
+	}
+	return nil
+}
+
+// ScoreThresholds holds the benchmarks productivity score components are graded against.
+// Tier bounds are ascending upper limits: a value at or below the n-th bound earns the n-th tier.
+type ScoreThresholds struct {
+	CycleTimeHours    []float64 // 4 bounds for 100, 80, 60, 40 points; slower scores 20
+	FirstReviewHours  []float64 // 3 bounds for +25, +15, +5 review points
+	ReviewsPerPR      []float64 // [min, max] average earning +25 review points; other non-zero averages earn +10
+	ChangeFailureRate []float64 // 4 bounds (%) for 100, 80, 60, 40 points; higher scores 20
 }
 
-// CalculateCycleTime calculates cycle time metrics for pull requests
+// DefaultScoreThresholds returns the default benchmarks, based on industry figures
+// (elite teams merge within a day and keep change failure rate under 15%).
+func DefaultScoreThresholds() ScoreThresholds {
+	return ScoreThresholds{
+		CycleTimeHours:    []float64{24, 72, 168, 336},
+		FirstReviewHours:  []float64{4, 8, 24},
+		ReviewsPerPR:      []float64{1, 3},
+		ChangeFailureRate: []float64{5, 10, 15, 30},
+	}
+}
+
+// withDefaults fills unset benchmarks from DefaultScoreThresholds.
+func (t ScoreThresholds) withDefaults() ScoreThresholds {
+	d := DefaultScoreThresholds()
+	for _, f := range []struct{ v, def *[]float64 }{
+		{&t.CycleTimeHours, &d.CycleTimeHours},
+		{&t.FirstReviewHours, &d.FirstReviewHours},
+		{&t.ReviewsPerPR, &d.ReviewsPerPR},
+		{&t.ChangeFailureRate, &d.ChangeFailureRate},
+	} {
+		if len(*f.v) == 0 {
+			*f.v = *f.def
+		}
+	}
+	return t
+}
+
+// Validate checks that each set benchmark has the expected number of non-negative, ascending bounds.
+// Unset benchmarks use the defaults.
+func (t ScoreThresholds) Validate() error {
+	for _, f := range []struct {
+		name   string
+		bounds []float64
+		want   int
+	}{
+		{"cycle time hours", t.CycleTimeHours, 4},
+		{"first review hours", t.FirstReviewHours, 3},
+		{"reviews per PR", t.ReviewsPerPR, 2},
+		{"change failure rate", t.ChangeFailureRate, 4},
+	} {
+		if len(f.bounds) == 0 {
+			continue
+		}
+		if len(f.bounds) != f.want {
+			return fmt.Errorf("%s needs %d thresholds, got %d", f.name, f.want, len(f.bounds))
+		}
+		for i, b := range f.bounds {
+			if b < 0 || (i > 0 && b < f.bounds[i-1]) {
+				return fmt.Errorf("%s thresholds must be non-negative and ascending: %v", f.name, f.bounds)
+			}
+		}
+	}
+	return nil
+}
+
+// WithScoreThresholds returns a copy of the calculator that grades productivity score components
+// against t. Unset benchmarks keep the defaults; invalid thresholds (see ScoreThresholds.Validate)
+// fall back to DefaultScoreThresholds.
+func (c *Calculator) WithScoreThresholds(t ScoreThresholds) *Calculator {
+	cc := *c
+	if t.Validate() != nil {
+		cc.thresholds = DefaultScoreThresholds()
+	} else {
+		cc.thresholds = t.withDefaults()
+	}
+	return &cc
+}
+
+// BusinessHours defines the working schedule used for business-hours durations.
+type BusinessHours struct {
+	WorkStart int // hour of day work starts (0-23)
+	WorkEnd   int // hour of day work ends (1-24)
+	Workdays  []time.Weekday
+}
+
+// DefaultBusinessHours returns 9:00-18:00, Monday to Friday.
+func DefaultBusinessHours() BusinessHours {
+	return BusinessHours{
+		WorkStart: 9,
+		WorkEnd:   18,
+		Workdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	}
+}
+
+// WithBusinessHours returns a copy of the calculator that measures cycle time, time to
+// first review and lead time in business hours, excluding weekends and off-hours.
+func (c *Calculator) WithBusinessHours(bh BusinessHours) *Calculator {
+	cc := *c
+	cc.hours = func(start, end time.Time) float64 {
+		return timeutil.BusinessHoursBetween(start, end, bh.WorkStart, bh.WorkEnd, bh.Workdays)
+	}
+	return &cc
+}
+
+// phaseTimes collects the positive PR phase durations (in hours) of a group of PRs.
+type phaseTimes struct {
+	coding, pickup, review, merge []float64
+}
+
+// add records the phases of one PR, skipping those it did not go through.
+func (p *phaseTimes) add(coding, pickup, review, merge float64) {
+	if coding > 0 {
+		p.coding = append(p.coding, coding)
+	}
+	if pickup > 0 {
+		p.pickup = append(p.pickup, pickup)
+	}
+	if review > 0 {
+		p.review = append(p.review, review)
+	}
+	if merge > 0 {
+		p.merge = append(p.merge, merge)
+	}
+}
+
+// authorCycleTimes collects one author's positive cycle times and phase durations (in hours).
+type authorCycleTimes struct {
+	cycle  []float64
+	phases phaseTimes
+}
+
+// CalculateCycleTime calculates cycle time metrics for pull requests merged in the period.
+// Completion is the PR's MergedAt, which GitHub sets when the PR is merged for merge commits,
+// squash merges, and rebase merges alike; the timestamps of the resulting commits are not used.
+// PRs closed without merge in the period are counted as abandoned.
 func (c *Calculator) CalculateCycleTime(prs []*model.PullRequest, startDate, endDate time.Time) *model.CycleTimeMetrics {
-	// Filter merged PRs within the date range
+	// Filter merged and abandoned PRs within the date range
 	var mergedPRs []*model.PullRequest
+	abandoned := 0
 	for _, pr := range prs {
 		if pr.MergedAt != nil && !pr.MergedAt.Before(startDate) && !pr.MergedAt.After(endDate) {
 			mergedPRs = append(mergedPRs, pr)
 		}
+		if pr.MergedAt == nil && pr.ClosedAt != nil && !pr.ClosedAt.Before(startDate) && !pr.ClosedAt.After(endDate) {
+			abandoned++
+		}
 	}
 
 	if len(mergedPRs) == 0 {
 		return &model.CycleTimeMetrics{
-			Period:    "custom",
-			StartDate: startDate,
-			EndDate:   endDate,
-			TotalPRs:  0,
+			Period:           "custom",
+			StartDate:        startDate,
+			EndDate:          endDate,
+			Timezone:         startDate.Location().String(),
+			TotalPRs:         0,
+			AbandonedPRCount: abandoned,
 		}
 	}
 
-	var cycleTimes, codingTimes, pickupTimes, reviewTimes, mergeTimes []float64
+	var cycleTimes []float64
+	var phases phaseTimes
+	warnings := 0
 
 	authorMetricsMap := make(map[string]*model.AuthorMetrics)
+	// Per-author durations, kept whole for the median
+	authorTimes := make(map[string]*authorCycleTimes)
+	sizeCycleTimes := make(map[string][]float64, len(sizeBuckets))
+	sizeCounts := make(map[string]int, len(sizeBuckets))
 
 	for _, pr := range mergedPRs {
 		// Calculate individual times (in hours)
-		cycleTime := pr.CycleTimeHours()
-		codingTime := pr.CodingTimeHours()
-		pickupTime := pr.PickupTimeHours()
-		reviewTime := pr.ReviewTimeHours()
-		mergeTime := pr.MergeTimeHours()
+		cycleTime := pr.CycleTimeHoursWith(c.hours)
+		codingTime := pr.CodingTimeHoursWith(c.hours)
+		pickupTime := pr.PickupTimeHoursWith(c.hours)
+		reviewTime := pr.ReviewTimeHoursWith(c.hours)
+		mergeTime := pr.MergeTimeHoursWith(c.hours)
 
 		if cycleTime > 0 {
 			cycleTimes = append(cycleTimes, cycleTime)
 		}
-		if codingTime > 0 {
-			codingTimes = append(codingTimes, codingTime)
-		}
-		if pickupTime > 0 {
-			pickupTimes = append(pickupTimes, pickupTime)
-		}
-		if reviewTime > 0 {
-			reviewTimes = append(reviewTimes, reviewTime)
-		}
-		if mergeTime > 0 {
-			mergeTimes = append(mergeTimes, mergeTime)
+		phases.add(codingTime, pickupTime, reviewTime, mergeTime)
+		if hasImplausibleDuration(pr) {
+			warnings++
 		}
 
 		// Aggregate by author
@@ -67,21 +307,47 @@ func (c *Calculator) CalculateCycleTime(prs []*model.PullRequest, startDate, end
 			authorMetricsMap[pr.Author] = &model.AuthorMetrics{
 				Author: pr.Author,
 			}
+			authorTimes[pr.Author] = &authorCycleTimes{}
 		}
 		authorMetricsMap[pr.Author].PRCount++
 		authorMetricsMap[pr.Author].Additions += pr.Additions
 		authorMetricsMap[pr.Author].Deletions += pr.Deletions
 		if cycleTime > 0 {
-			authorMetricsMap[pr.Author].AvgCycleTime += cycleTime
+			authorTimes[pr.Author].cycle = append(authorTimes[pr.Author].cycle, cycleTime)
+		}
+		authorTimes[pr.Author].phases.add(codingTime, pickupTime, reviewTime, mergeTime)
+
+		// Aggregate by size
+		size := PRSize(pr)
+		sizeCounts[size]++
+		if cycleTime > 0 {
+			sizeCycleTimes[size] = append(sizeCycleTimes[size], cycleTime)
 		}
 	}
 
-	// Calculate averages for authors
+	bySize := make([]model.SizeBucketMetrics, 0, len(sizeBuckets))
+	for _, b := range sizeBuckets {
+		bySize = append(bySize, model.SizeBucketMetrics{
+			Size:         b.name,
+			Count:        sizeCounts[b.name],
+			AvgCycleTime: average(sizeCycleTimes[b.name]),
+		})
+	}
+
+	// Calculate averages and medians for authors
 	authorMetrics := make([]model.AuthorMetrics, 0, len(authorMetricsMap))
 	for _, am := range authorMetricsMap {
-		if am.PRCount > 0 {
-			am.AvgCycleTime /= float64(am.PRCount)
+		at := authorTimes[am.Author]
+		// The mean is over every merged PR, counting zero-length cycles as 0
+		for _, v := range at.cycle {
+			am.AvgCycleTime += v
 		}
+		am.AvgCycleTime /= float64(am.PRCount)
+		am.MedianCycleTime = median(at.cycle)
+		am.AvgCodingTime = average(at.phases.coding)
+		am.AvgPickupTime = average(at.phases.pickup)
+		am.AvgReviewTime = average(at.phases.review)
+		am.AvgMergeTime = average(at.phases.merge)
 		authorMetrics = append(authorMetrics, *am)
 	}
 
@@ -92,26 +358,96 @@ func (c *Calculator) CalculateCycleTime(prs []*model.PullRequest, startDate, end
 
 	// Aggregate change stats by file extension
 	byFileExtension := c.aggregateFileExtMetrics(mergedPRs)
+	byLanguage := c.aggregateLanguageMetrics(mergedPRs)
 
 	return &model.CycleTimeMetrics{
 		Period:          "custom",
 		StartDate:       startDate,
 		EndDate:         endDate,
+		Timezone:        startDate.Location().String(),
 		TotalPRs:        len(mergedPRs),
 		AvgCycleTime:    average(cycleTimes),
-		AvgCodingTime:   average(codingTimes),
-		AvgPickupTime:   average(pickupTimes),
-		AvgReviewTime:   average(reviewTimes),
-		AvgMergeTime:    average(mergeTimes),
+		AvgCodingTime:   average(phases.coding),
+		AvgPickupTime:   average(phases.pickup),
+		AvgReviewTime:   average(phases.review),
+		AvgMergeTime:    average(phases.merge),
 		MedianCycleTime: median(cycleTimes),
 		P90CycleTime:    percentile(cycleTimes, 90),
 		ByAuthor:        authorMetrics,
 		ByFileExtension: byFileExtension,
+		ByLanguage:      byLanguage,
+		BySize:          bySize,
+
+		AbandonedPRCount:    abandoned,
+		DataQualityWarnings: warnings,
+	}
+}
+
+// maxPlausibleHours is the longest PR duration taken as real; longer ones are flagged.
+const maxPlausibleHours = 365 * 24
+
+// hasImplausibleDuration reports whether any duration of the PR is negative or longer than
+// maxPlausibleHours. Wall-clock time is used: business hours clamp reversed times to 0.
+func hasImplausibleDuration(pr *model.PullRequest) bool {
+	for _, hours := range []float64{
+		pr.CycleTimeHours(),
+		pr.CodingTimeHours(),
+		pr.PickupTimeHours(),
+		pr.ReviewTimeHours(),
+		pr.MergeTimeHours(),
+	} {
+		if hours < 0 || hours > maxPlausibleHours {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeBuckets lists PR size categories by total lines changed, smallest first.
+// A PR belongs to the first bucket whose limit exceeds its size.
+var sizeBuckets = []struct {
+	name  string
+	limit int
+}{
+	{"XS", 10},
+	{"S", 100},
+	{"M", 500},
+	{"L", 1000},
+	{"XL", math.MaxInt},
+}
+
+// PRSize returns the size category (XS, S, M, L, XL) of the PR by additions + deletions.
+func PRSize(pr *model.PullRequest) string {
+	lines := pr.Additions + pr.Deletions
+	for _, b := range sizeBuckets {
+		if lines < b.limit {
+			return b.name
+		}
 	}
+	return sizeBuckets[len(sizeBuckets)-1].name
 }
 
-// CalculateReviewMetrics calculates review analysis metrics
+// ReviewOptions holds per-request options for review metrics calculation.
+type ReviewOptions struct {
+	// IncludeSelfReviews counts reviews submitted by the PR author toward
+	// ApprovalRate, ChangesRequestedRate and AvgReviewsPerPR.
+	IncludeSelfReviews bool
+	// SLAHours is the first-review SLA threshold. Zero uses DefaultReviewSLAHours.
+	SLAHours float64
+}
+
+// DefaultReviewSLAHours is the default first-review SLA threshold.
+const DefaultReviewSLAHours = 24
+
+// CalculateReviewMetrics calculates review analysis metrics with default options
 func (c *Calculator) CalculateReviewMetrics(reviews []*model.Review, prs []*model.PullRequest, startDate, endDate time.Time) *model.ReviewMetrics {
+	return c.CalculateReviewMetricsWithOptions(reviews, prs, startDate, endDate, ReviewOptions{})
+}
+
+// CalculateReviewMetricsWithOptions calculates review analysis metrics.
+// Self-reviews (reviewer == PR author) are counted in SelfReviewCount and excluded
+// from the rate calculations unless opts.IncludeSelfReviews is set.
+func (c *Calculator) CalculateReviewMetricsWithOptions(reviews []*model.Review, prs []*model.PullRequest, startDate, endDate time.Time, opts ReviewOptions) *model.ReviewMetrics {
 	// Filter reviews within date range
 	var filteredReviews []*model.Review
 	for _, review := range reviews {
@@ -120,28 +456,56 @@ func (c *Calculator) CalculateReviewMetrics(reviews []*model.Review, prs []*mode
 		}
 	}
 
+	slaHours := opts.SLAHours
+	if slaHours <= 0 {
+		slaHours = DefaultReviewSLAHours
+	}
+
 	if len(filteredReviews) == 0 {
 		return &model.ReviewMetrics{
 			Period:    "custom",
 			StartDate: startDate,
 			EndDate:   endDate,
+			Timezone:  startDate.Location().String(),
+			SLAHours:  slaHours,
 		}
 	}
 
+	// Map review keys to PR authors for self-review detection
+	prAuthors := make(map[string]string, len(prs))
+	for _, pr := range prs {
+		prAuthors[pr.ReviewKey()] = pr.Author
+	}
+
 	// Count totals
 	totalComments := 0
 	approvedCount := 0
 	changesRequestedCount := 0
+	commentedCount := 0
+	dismissedCount := 0
+	selfReviewCount := 0
+	var countedReviews []*model.Review
 	reviewerStatsMap := make(map[string]*model.ReviewerStats)
 
 	for _, review := range filteredReviews {
 		totalComments += review.CommentsCount
 
-		switch review.State {
-		case "APPROVED":
-			approvedCount++
-		case "CHANGES_REQUESTED":
-			changesRequestedCount++
+		selfReview := isSelfReview(review, prAuthors)
+		if selfReview {
+			selfReviewCount++
+		}
+		if !selfReview || opts.IncludeSelfReviews {
+			countedReviews = append(countedReviews, review)
+			switch review.State {
+			case "APPROVED":
+				approvedCount++
+			case "CHANGES_REQUESTED":
+				changesRequestedCount++
+			case "COMMENTED":
+				commentedCount++
+			case "DISMISSED":
+				dismissedCount++
+			}
 		}
 
 		// Aggregate by reviewer
@@ -170,20 +534,33 @@ func (c *Calculator) CalculateReviewMetrics(reviews []*model.Review, prs []*mode
 		return reviewerStats[i].ReviewCount > reviewerStats[j].ReviewCount
 	})
 
-	// Calculate time to first review
+	// Calculate time to first review and SLA breaches (only PRs that got a review).
+	// SLA breaches count PRs created in the range, like the other review numbers.
 	var timeToFirstReviews []float64
+	reviewedPRs, slaBreachCount := 0, 0
 	for _, pr := range prs {
 		if pr.FirstReviewAt != nil {
-			ttfr := pr.FirstReviewAt.Sub(pr.CreatedAt).Hours()
+			ttfr := pr.PickupTimeHoursWith(c.hours)
 			if ttfr > 0 {
 				timeToFirstReviews = append(timeToFirstReviews, ttfr)
 			}
+			if pr.CreatedAt.Before(startDate) || pr.CreatedAt.After(endDate) {
+				continue
+			}
+			reviewedPRs++
+			if ttfr > slaHours {
+				slaBreachCount++
+			}
 		}
 	}
+	slaBreachRate := 0.0
+	if reviewedPRs > 0 {
+		slaBreachRate = (float64(slaBreachCount) / float64(reviewedPRs)) * 100
+	}
 
 	// Calculate reviews per PR
 	prReviewCount := make(map[string]int)
-	for _, review := range filteredReviews {
+	for _, review := range countedReviews {
 		prReviewCount[review.PullRequestID]++
 	}
 
@@ -195,15 +572,20 @@ func (c *Calculator) CalculateReviewMetrics(reviews []*model.Review, prs []*mode
 	totalReviews := len(filteredReviews)
 	approvalRate := 0.0
 	changesRequestedRate := 0.0
-	if totalReviews > 0 {
-		approvalRate = (float64(approvedCount) / float64(totalReviews)) * 100
-		changesRequestedRate = (float64(changesRequestedCount) / float64(totalReviews)) * 100
+	commentedRate := 0.0
+	dismissedRate := 0.0
+	if len(countedReviews) > 0 {
+		approvalRate = (float64(approvedCount) / float64(len(countedReviews))) * 100
+		changesRequestedRate = (float64(changesRequestedCount) / float64(len(countedReviews))) * 100
+		commentedRate = (float64(commentedCount) / float64(len(countedReviews))) * 100
+		dismissedRate = (float64(dismissedCount) / float64(len(countedReviews))) * 100
 	}
 
 	return &model.ReviewMetrics{
 		Period:               "custom",
 		StartDate:            startDate,
 		EndDate:              endDate,
+		Timezone:             startDate.Location().String(),
 		TotalReviews:         totalReviews,
 		TotalComments:        totalComments,
 		AvgReviewsPerPR:      average(reviewsPerPR),
@@ -211,12 +593,140 @@ func (c *Calculator) CalculateReviewMetrics(reviews []*model.Review, prs []*mode
 		AvgTimeToFirstReview: average(timeToFirstReviews),
 		ApprovalRate:         approvalRate,
 		ChangesRequestedRate: changesRequestedRate,
+		CommentedCount:       commentedCount,
+		CommentedRate:        commentedRate,
+		DismissedCount:       dismissedCount,
+		DismissedRate:        dismissedRate,
+		SelfReviewCount:      selfReviewCount,
+		SLAHours:             slaHours,
+		SLABreachCount:       slaBreachCount,
+		SLABreachRate:        slaBreachRate,
 		ByReviewer:           reviewerStats,
 	}
 }
 
-// CalculateDORAMetrics calculates DORA metrics
+// isSelfReview reports whether the review was submitted by the author of the reviewed PR.
+// Reviews whose PR is not in prAuthors are treated as peer reviews.
+func isSelfReview(review *model.Review, prAuthors map[string]string) bool {
+	author, ok := prAuthors[review.PullRequestID]
+	return ok && author != "" && author == review.Reviewer
+}
+
+// CalculateReviewCoverage counts, per author, the PRs merged in the period that received
+// at least one peer review. Self-reviews do not count as coverage.
+func (c *Calculator) CalculateReviewCoverage(prs []*model.PullRequest, reviews []*model.Review, startDate, endDate time.Time) *model.ReviewCoverageMetrics {
+	prAuthors := make(map[string]string, len(prs))
+	for _, pr := range prs {
+		prAuthors[pr.ReviewKey()] = pr.Author
+	}
+	peerReviewed := make(map[string]bool)
+	for _, review := range reviews {
+		if !isSelfReview(review, prAuthors) {
+			peerReviewed[review.PullRequestID] = true
+		}
+	}
+
+	result := &model.ReviewCoverageMetrics{
+		Period:    "custom",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Timezone:  startDate.Location().String(),
+		ByAuthor:  []model.AuthorReviewCoverage{},
+	}
+	byAuthor := make(map[string]*model.AuthorReviewCoverage)
+	for _, pr := range prs {
+		if pr.MergedAt == nil || pr.MergedAt.Before(startDate) || pr.MergedAt.After(endDate) {
+			continue
+		}
+		ac, ok := byAuthor[pr.Author]
+		if !ok {
+			ac = &model.AuthorReviewCoverage{Author: pr.Author}
+			byAuthor[pr.Author] = ac
+		}
+		ac.MergedPRs++
+		result.MergedPRs++
+		if peerReviewed[pr.ReviewKey()] {
+			ac.ReviewedPRs++
+			result.ReviewedPRs++
+		}
+	}
+
+	for _, ac := range byAuthor {
+		ac.CoverageRate = float64(ac.ReviewedPRs) / float64(ac.MergedPRs) * 100
+		result.ByAuthor = append(result.ByAuthor, *ac)
+	}
+	// Most merged PRs first
+	sort.Slice(result.ByAuthor, func(i, j int) bool {
+		if result.ByAuthor[i].MergedPRs != result.ByAuthor[j].MergedPRs {
+			return result.ByAuthor[i].MergedPRs > result.ByAuthor[j].MergedPRs
+		}
+		return result.ByAuthor[i].Author < result.ByAuthor[j].Author
+	})
+	if result.MergedPRs > 0 {
+		result.CoverageRate = float64(result.ReviewedPRs) / float64(result.MergedPRs) * 100
+	}
+
+	return result
+}
+
+// CalculateCollaboration counts reviews submitted in the period per (author, reviewer) pair.
+// Self-reviews and reviews of PRs not in prs (author unknown) are skipped.
+func (c *Calculator) CalculateCollaboration(prs []*model.PullRequest, reviews []*model.Review, startDate, endDate time.Time) *model.CollaborationMetrics {
+	prAuthors := make(map[string]string, len(prs))
+	for _, pr := range prs {
+		prAuthors[pr.ReviewKey()] = pr.Author
+	}
+
+	type pair struct{ author, reviewer string }
+	counts := make(map[pair]int)
+	for _, review := range reviews {
+		if review.SubmittedAt.Before(startDate) || review.SubmittedAt.After(endDate) {
+			continue
+		}
+		author, ok := prAuthors[review.PullRequestID]
+		if !ok || author == "" || author == review.Reviewer {
+			continue
+		}
+		counts[pair{author, review.Reviewer}]++
+	}
+
+	edges := make([]model.CollaborationEdge, 0, len(counts))
+	for p, count := range counts {
+		edges = append(edges, model.CollaborationEdge{Author: p.author, Reviewer: p.reviewer, Count: count})
+	}
+	// Strongest edges first
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Count != edges[j].Count {
+			return edges[i].Count > edges[j].Count
+		}
+		if edges[i].Author != edges[j].Author {
+			return edges[i].Author < edges[j].Author
+		}
+		return edges[i].Reviewer < edges[j].Reviewer
+	})
+
+	return &model.CollaborationMetrics{
+		Period:    "custom",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Timezone:  startDate.Location().String(),
+		Edges:     edges,
+	}
+}
+
+// DORAOptions holds per-request options for DORA metrics calculation.
+type DORAOptions struct {
+	// Percentiles lists additional lead time percentiles to report (each in (0, 100]).
+	Percentiles []float64
+}
+
+// CalculateDORAMetrics calculates DORA metrics with default options
 func (c *Calculator) CalculateDORAMetrics(prs []*model.PullRequest, deployments []*model.Deployment, startDate, endDate time.Time) *model.DORAMetrics {
+	return c.CalculateDORAMetricsWithOptions(prs, deployments, startDate, endDate, DORAOptions{})
+}
+
+// CalculateDORAMetricsWithOptions calculates DORA metrics
+func (c *Calculator) CalculateDORAMetricsWithOptions(prs []*model.PullRequest, deployments []*model.Deployment, startDate, endDate time.Time, opts DORAOptions) *model.DORAMetrics {
 	// Calculate deployment frequency
 	var filteredDeployments []*model.Deployment
 	for _, d := range deployments {
@@ -232,19 +742,9 @@ func (c *Calculator) CalculateDORAMetrics(prs []*model.PullRequest, deployments
 
 	deploymentCount := len(filteredDeployments)
 	avgDeploysPerDay := float64(deploymentCount) / days
+	deploymentSuccessRate := calculateDeploymentSuccessRate(filteredDeployments)
 
-	// Determine deployment frequency category
-	var deploymentFrequency string
-	switch {
-	case avgDeploysPerDay >= 1:
-		deploymentFrequency = "daily"
-	case avgDeploysPerDay >= 1.0/7:
-		deploymentFrequency = "weekly"
-	case avgDeploysPerDay >= 1.0/30:
-		deploymentFrequency = "monthly"
-	default:
-		deploymentFrequency = "yearly"
-	}
+	deploymentFrequency := deploymentFrequencyCategory(avgDeploysPerDay)
 
 	// Calculate lead time for changes (PR creation to merge)
 	var leadTimes []float64
@@ -252,19 +752,19 @@ func (c *Calculator) CalculateDORAMetrics(prs []*model.PullRequest, deployments
 	for _, pr := range prs {
 		if pr.MergedAt != nil && !pr.MergedAt.Before(startDate) && !pr.MergedAt.After(endDate) {
 			mergedPRs = append(mergedPRs, pr)
-			leadTime := pr.MergedAt.Sub(pr.CreatedAt).Hours()
+			leadTime := c.hours(pr.CreatedAt, *pr.MergedAt)
 			if leadTime > 0 {
 				leadTimes = append(leadTimes, leadTime)
 			}
 		}
 	}
 
-	// Calculate change failure rate (simplified: based on reverted PRs or bug fixes)
+	// Calculate change failure rate (merged PRs with a failure label or title pattern, such as reverts)
 	failedChanges := 0
 	for _, pr := range mergedPRs {
-		// Simple heuristic: if a PR title contains "revert", "fix", or "hotfix", consider it a failed change
-		// In a real implementation, this would be more sophisticated
-		_ = pr // placeholder for actual failure detection logic
+		if c.isFailedChange(pr) {
+			failedChanges++
+		}
 	}
 
 	totalChanges := len(mergedPRs)
@@ -273,33 +773,110 @@ func (c *Calculator) CalculateDORAMetrics(prs []*model.PullRequest, deployments
 		changeFailureRate = (float64(failedChanges) / float64(totalChanges)) * 100
 	}
 
-	return &model.DORAMetrics{
-		Period:              "custom",
-		StartDate:           startDate,
-		EndDate:             endDate,
-		DeploymentCount:     deploymentCount,
-		DeploymentFrequency: deploymentFrequency,
-		AvgDeploysPerDay:    avgDeploysPerDay,
-		AvgLeadTime:         average(leadTimes),
-		MedianLeadTime:      median(leadTimes),
-		P90LeadTime:         percentile(leadTimes, 90),
-		TotalChanges:        totalChanges,
-		FailedChanges:       failedChanges,
-		ChangeFailureRate:   changeFailureRate,
+	result := &model.DORAMetrics{
+		Period:                "custom",
+		StartDate:             startDate,
+		EndDate:               endDate,
+		Timezone:              startDate.Location().String(),
+		DeploymentCount:       deploymentCount,
+		DeploymentFrequency:   deploymentFrequency,
+		AvgDeploysPerDay:      avgDeploysPerDay,
+		ByEnvironment:         deploymentsByEnvironment(filteredDeployments, days),
+		DeploymentSuccessRate: deploymentSuccessRate,
+		AvgLeadTime:           average(leadTimes),
+		MedianLeadTime:        median(leadTimes),
+		P90LeadTime:           percentile(leadTimes, 90),
+		LeadTimePercentiles:   percentileMap(leadTimes, opts.Percentiles),
+		TotalChanges:          totalChanges,
+		FailedChanges:         failedChanges,
+		ChangeFailureRate:     changeFailureRate,
+	}
+	result.PerformanceLevel = ClassifyDORAPerformance(result)
+
+	return result
+}
+
+// isFailedChange reports whether a merged PR carries a failure label or its title matches a failure pattern.
+func (c *Calculator) isFailedChange(pr *model.PullRequest) bool {
+	for _, label := range pr.Labels {
+		if slices.Contains(c.failureLabels, strings.ToLower(label)) {
+			return true
+		}
+	}
+	title := strings.ToLower(pr.Title)
+	for _, p := range c.failurePatterns {
+		if strings.Contains(title, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// deploymentFrequencyCategory buckets an average deploys-per-day rate into a frequency category.
+func deploymentFrequencyCategory(avgDeploysPerDay float64) string {
+	switch {
+	case avgDeploysPerDay >= 1:
+		return "daily"
+	case avgDeploysPerDay >= 1.0/7:
+		return "weekly"
+	case avgDeploysPerDay >= 1.0/30:
+		return "monthly"
+	default:
+		return "yearly"
 	}
 }
 
+// deploymentsByEnvironment computes deployment frequency per environment over a period of the given days.
+// Returns nil when there are no deployments.
+func deploymentsByEnvironment(deployments []*model.Deployment, days float64) map[string]model.EnvDeployStats {
+	if len(deployments) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, d := range deployments {
+		counts[d.Environment]++
+	}
+	result := make(map[string]model.EnvDeployStats, len(counts))
+	for env, count := range counts {
+		avg := float64(count) / days
+		result[env] = model.EnvDeployStats{
+			DeploymentCount:     count,
+			DeploymentFrequency: deploymentFrequencyCategory(avg),
+			AvgDeploysPerDay:    avg,
+		}
+	}
+	return result
+}
+
+// calculateDeploymentSuccessRate returns the percentage of finished deployments that succeeded.
+// Pending deployments are excluded because their outcome is not yet known.
+func calculateDeploymentSuccessRate(deployments []*model.Deployment) float64 {
+	succeeded, finished := 0, 0
+	for _, d := range deployments {
+		switch d.Status {
+		case "success":
+			succeeded++
+			finished++
+		case "failure":
+			finished++
+		}
+	}
+	if finished == 0 {
+		return 0
+	}
+	return (float64(succeeded) / float64(finished)) * 100
+}
+
 // CalculateProductivityScore calculates the overall productivity score
 func (c *Calculator) CalculateProductivityScore(
 	cycleTime *model.CycleTimeMetrics,
 	reviews *model.ReviewMetrics,
 	dora *model.DORAMetrics,
 ) *model.ProductivityScore {
-	// Weight configuration
-	cycleTimeWeight := 0.30
-	reviewWeight := 0.25
-	deploymentWeight := 0.25
-	qualityWeight := 0.20
+	cycleTimeWeight := c.weights.CycleTime
+	reviewWeight := c.weights.Review
+	deploymentWeight := c.weights.Deployment
+	qualityWeight := c.weights.Quality
 
 	// Calculate component scores (0-100)
 	cycleTimeScore := c.scoreCycleTime(cycleTime.AvgCycleTime)
@@ -329,6 +906,9 @@ func (c *Calculator) CalculateProductivityScore(
 	}
 
 	return &model.ProductivityScore{
+		StartDate:       cycleTime.StartDate,
+		EndDate:         cycleTime.EndDate,
+		Timezone:        cycleTime.Timezone,
 		OverallScore:    overallScore,
 		CycleTimeScore:  cycleTimeScore,
 		ReviewScore:     reviewScore,
@@ -345,36 +925,45 @@ func (c *Calculator) CalculateProductivityScore(
 	}
 }
 
-// aggregateFileExtMetrics aggregates file extension stats from merged PRs.
-func (c *Calculator) aggregateFileExtMetrics(prs []*model.PullRequest) []model.FileExtensionMetrics {
-	type extAgg struct {
-		additions int
-		deletions int
-		files     int
-		prCount   int
-	}
-	m := make(map[string]*extAgg)
+// fileStatsAgg accumulates file stats for one grouping key.
+type fileStatsAgg struct {
+	additions int
+	deletions int
+	files     int
+	prCount   int
+}
+
+// aggregateFileStats groups the file stats of prs by key, counting each PR once per key.
+func aggregateFileStats(prs []*model.PullRequest, key func(model.FileExtStats) string) map[string]*fileStatsAgg {
+	m := make(map[string]*fileStatsAgg)
 
 	for _, pr := range prs {
-		// Track extensions in this PR (for PR count)
+		// Track keys in this PR (for PR count)
 		seen := make(map[string]bool)
 		for _, fs := range pr.FileExtStats {
-			ext := fs.Extension
-			a, ok := m[ext]
+			k := key(fs)
+			a, ok := m[k]
 			if !ok {
-				a = &extAgg{}
-				m[ext] = a
+				a = &fileStatsAgg{}
+				m[k] = a
 			}
 			a.additions += fs.Additions
 			a.deletions += fs.Deletions
 			a.files += fs.Files
-			if !seen[ext] {
+			if !seen[k] {
 				a.prCount++
-				seen[ext] = true
+				seen[k] = true
 			}
 		}
 	}
 
+	return m
+}
+
+// aggregateFileExtMetrics aggregates file extension stats from merged PRs.
+func (c *Calculator) aggregateFileExtMetrics(prs []*model.PullRequest) []model.FileExtensionMetrics {
+	m := aggregateFileStats(prs, func(fs model.FileExtStats) string { return fs.Extension })
+
 	result := make([]model.FileExtensionMetrics, 0, len(m))
 	for ext, a := range m {
 		result = append(result, model.FileExtensionMetrics{
@@ -394,42 +983,51 @@ func (c *Calculator) aggregateFileExtMetrics(prs []*model.PullRequest) []model.F
 	return result
 }
 
+// aggregateLanguageMetrics aggregates file stats by language from merged PRs.
+// Stats without a language (unmapped or synced before labelling) are grouped by extension.
+func (c *Calculator) aggregateLanguageMetrics(prs []*model.PullRequest) []model.LanguageMetrics {
+	m := aggregateFileStats(prs, func(fs model.FileExtStats) string {
+		if fs.Language != "" {
+			return fs.Language
+		}
+		return fs.Extension
+	})
+
+	result := make([]model.LanguageMetrics, 0, len(m))
+	for lang, a := range m {
+		result = append(result, model.LanguageMetrics{
+			Language:  lang,
+			Additions: a.additions,
+			Deletions: a.deletions,
+			Files:     a.files,
+			PRCount:   a.prCount,
+		})
+	}
+
+	// Sort by number of changed lines descending
+	sort.Slice(result, func(i, j int) bool {
+		return (result[i].Additions + result[i].Deletions) > (result[j].Additions + result[j].Deletions)
+	})
+
+	return result
+}
+
 // Scoring helper functions
 
 func (c *Calculator) scoreCycleTime(avgHours float64) float64 {
-	// Scoring based on industry benchmarks
-	// Elite: < 24h (1 day)
-	// High: < 168h (1 week)
-	// Medium: < 720h (1 month)
-	// Low: >= 720h
-	switch {
-	case avgHours <= 24:
-		return 100
-	case avgHours <= 72:
-		return 80
-	case avgHours <= 168:
-		return 60
-	case avgHours <= 336:
-		return 40
-	default:
-		return 20
-	}
+	// Default benchmarks: elite within a day, high within a week
+	return tierPoints(avgHours, c.thresholds.CycleTimeHours, []float64{100, 80, 60, 40}, 20)
 }
 
 func (c *Calculator) scoreReview(metrics *model.ReviewMetrics) float64 {
 	score := 50.0
 
-	// Factor in time to first review (target: < 4h)
-	if metrics.AvgTimeToFirstReview <= 4 {
-		score += 25
-	} else if metrics.AvgTimeToFirstReview <= 8 {
-		score += 15
-	} else if metrics.AvgTimeToFirstReview <= 24 {
-		score += 5
-	}
+	// Factor in time to first review (default target: < 4h)
+	score += tierPoints(metrics.AvgTimeToFirstReview, c.thresholds.FirstReviewHours, []float64{25, 15, 5}, 0)
 
-	// Factor in reviews per PR (target: 1-3)
-	if metrics.AvgReviewsPerPR >= 1 && metrics.AvgReviewsPerPR <= 3 {
+	// Factor in reviews per PR (default target: 1-3)
+	minReviews, maxReviews := c.thresholds.ReviewsPerPR[0], c.thresholds.ReviewsPerPR[1]
+	if metrics.AvgReviewsPerPR >= minReviews && metrics.AvgReviewsPerPR <= maxReviews {
 		score += 25
 	} else if metrics.AvgReviewsPerPR > 0 {
 		score += 10
@@ -452,19 +1050,19 @@ func (c *Calculator) scoreDeployment(metrics *model.DORAMetrics) float64 {
 }
 
 func (c *Calculator) scoreQuality(changeFailureRate float64) float64 {
-	// Target: < 15% change failure rate
-	switch {
-	case changeFailureRate <= 5:
-		return 100
-	case changeFailureRate <= 10:
-		return 80
-	case changeFailureRate <= 15:
-		return 60
-	case changeFailureRate <= 30:
-		return 40
-	default:
-		return 20
+	// Default target: < 15% change failure rate
+	return tierPoints(changeFailureRate, c.thresholds.ChangeFailureRate, []float64{100, 80, 60, 40}, 20)
+}
+
+// tierPoints returns the points of the first tier whose upper bound value does not exceed,
+// or fallback when value is above every bound.
+func tierPoints(value float64, bounds, points []float64, fallback float64) float64 {
+	for i, bound := range bounds {
+		if value <= bound {
+			return points[i]
+		}
 	}
+	return fallback
 }
 
 // Statistical helper functions
@@ -480,6 +1078,16 @@ func average(values []float64) float64 {
 	return sum / float64(len(values))
 }
 
+// Median returns the median of values (0 if empty).
+func Median(values []float64) float64 {
+	return median(values)
+}
+
+// Percentile returns the p-th percentile of values using linear interpolation (0 if empty).
+func Percentile(values []float64, p float64) float64 {
+	return percentile(values, p)
+}
+
 func median(values []float64) float64 {
 	if len(values) == 0 {
 		return 0
@@ -495,6 +1103,24 @@ func median(values []float64) float64 {
 	return sorted[mid]
 }
 
+// PercentileKey formats a percentile as a response map key (e.g. 99.9 -> "p99.9").
+func PercentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// percentileMap computes each requested percentile keyed by PercentileKey.
+// Returns nil when no percentiles are requested.
+func percentileMap(values []float64, ps []float64) map[string]float64 {
+	if len(ps) == 0 {
+		return nil
+	}
+	result := make(map[string]float64, len(ps))
+	for _, p := range ps {
+		result[PercentileKey(p)] = percentile(values, p)
+	}
+	return result
+}
+
 func percentile(values []float64, p float64) float64 {
 	if len(values) == 0 {
 		return 0
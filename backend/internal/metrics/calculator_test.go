@@ -0,0 +1,863 @@
+package metrics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// approxEqual compares floats with a small tolerance.
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCalculateReviewMetrics_SelfReviews(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	at := start.Add(48 * time.Hour)
+
+	prs := []*model.PullRequest{
+		{ID: "1", RepositoryID: "100", Number: 1, Author: "alice", CreatedAt: start},
+		{ID: "2", RepositoryID: "100", Number: 2, Author: "bob", CreatedAt: start},
+	}
+	reviews := []*model.Review{
+		// PR #1 (alice): one self comment, one peer approval
+		{ID: "r1", PullRequestID: "100#1", Reviewer: "alice", State: "COMMENTED", SubmittedAt: at},
+		{ID: "r2", PullRequestID: "100#1", Reviewer: "bob", State: "APPROVED", SubmittedAt: at},
+		// PR #2 (bob): self approval, peer changes requested
+		{ID: "r3", PullRequestID: "100#2", Reviewer: "bob", State: "APPROVED", SubmittedAt: at},
+		{ID: "r4", PullRequestID: "100#2", Reviewer: "carol", State: "CHANGES_REQUESTED", SubmittedAt: at},
+	}
+
+	tests := []struct {
+		name                 string
+		opts                 ReviewOptions
+		wantApprovalRate     float64
+		wantChangesRequested float64
+		wantAvgReviewsPerPR  float64
+	}{
+		{
+			name:                 "self-reviews excluded by default",
+			opts:                 ReviewOptions{},
+			wantApprovalRate:     50,
+			wantChangesRequested: 50,
+			wantAvgReviewsPerPR:  1,
+		},
+		{
+			name:                 "self-reviews included with flag",
+			opts:                 ReviewOptions{IncludeSelfReviews: true},
+			wantApprovalRate:     50,
+			wantChangesRequested: 25,
+			wantAvgReviewsPerPR:  2,
+		},
+	}
+
+	c := NewCalculator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.CalculateReviewMetricsWithOptions(reviews, prs, start, end, tt.opts)
+
+			if got.TotalReviews != 4 {
+				t.Errorf("TotalReviews = %d, want 4", got.TotalReviews)
+			}
+			if got.SelfReviewCount != 2 {
+				t.Errorf("SelfReviewCount = %d, want 2", got.SelfReviewCount)
+			}
+			if !approxEqual(got.ApprovalRate, tt.wantApprovalRate) {
+				t.Errorf("ApprovalRate = %v, want %v", got.ApprovalRate, tt.wantApprovalRate)
+			}
+			if !approxEqual(got.ChangesRequestedRate, tt.wantChangesRequested) {
+				t.Errorf("ChangesRequestedRate = %v, want %v", got.ChangesRequestedRate, tt.wantChangesRequested)
+			}
+			if !approxEqual(got.AvgReviewsPerPR, tt.wantAvgReviewsPerPR) {
+				t.Errorf("AvgReviewsPerPR = %v, want %v", got.AvgReviewsPerPR, tt.wantAvgReviewsPerPR)
+			}
+		})
+	}
+}
+
+func TestCalculateReviewMetrics_UnknownPRIsPeerReview(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	reviews := []*model.Review{
+		{ID: "r1", PullRequestID: "100#9", Reviewer: "alice", State: "APPROVED", SubmittedAt: start.Add(time.Hour)},
+	}
+
+	got := NewCalculator().CalculateReviewMetrics(reviews, nil, start, end)
+	if got.SelfReviewCount != 0 {
+		t.Errorf("SelfReviewCount = %d, want 0", got.SelfReviewCount)
+	}
+	if !approxEqual(got.ApprovalRate, 100) {
+		t.Errorf("ApprovalRate = %v, want 100", got.ApprovalRate)
+	}
+}
+
+func TestCalculateReviewCoverage(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	merged := start.Add(72 * time.Hour)
+	at := start.Add(48 * time.Hour)
+
+	prs := []*model.PullRequest{
+		// alice: both PRs peer-reviewed
+		{RepositoryID: "100", Number: 1, Author: "alice", MergedAt: &merged},
+		{RepositoryID: "100", Number: 2, Author: "alice", MergedAt: &merged},
+		// bob: one peer-reviewed, one only self-reviewed, one not merged
+		{RepositoryID: "100", Number: 3, Author: "bob", MergedAt: &merged},
+		{RepositoryID: "100", Number: 4, Author: "bob", MergedAt: &merged},
+		{RepositoryID: "100", Number: 5, Author: "bob"},
+	}
+	reviews := []*model.Review{
+		{PullRequestID: "100#1", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#2", Reviewer: "carol", SubmittedAt: at},
+		{PullRequestID: "100#2", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#3", Reviewer: "alice", SubmittedAt: at},
+		{PullRequestID: "100#4", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#5", Reviewer: "alice", SubmittedAt: at},
+	}
+
+	got := NewCalculator().CalculateReviewCoverage(prs, reviews, start, end)
+
+	if got.MergedPRs != 4 || got.ReviewedPRs != 3 || !approxEqual(got.CoverageRate, 75) {
+		t.Errorf("totals = %d merged, %d reviewed, %v%%; want 4, 3, 75%%", got.MergedPRs, got.ReviewedPRs, got.CoverageRate)
+	}
+	want := []model.AuthorReviewCoverage{
+		{Author: "alice", MergedPRs: 2, ReviewedPRs: 2, CoverageRate: 100},
+		{Author: "bob", MergedPRs: 2, ReviewedPRs: 1, CoverageRate: 50},
+	}
+	if !reflect.DeepEqual(got.ByAuthor, want) {
+		t.Errorf("ByAuthor = %+v, want %+v", got.ByAuthor, want)
+	}
+}
+
+func TestCalculateCollaboration(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	at := start.Add(48 * time.Hour)
+
+	prs := []*model.PullRequest{
+		{RepositoryID: "100", Number: 1, Author: "alice"},
+		{RepositoryID: "100", Number: 2, Author: "alice"},
+		{RepositoryID: "100", Number: 3, Author: "bob"},
+	}
+	reviews := []*model.Review{
+		{PullRequestID: "100#1", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#1", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#2", Reviewer: "bob", SubmittedAt: at},
+		{PullRequestID: "100#2", Reviewer: "carol", SubmittedAt: at},
+		{PullRequestID: "100#2", Reviewer: "alice", SubmittedAt: at}, // self-review
+		{PullRequestID: "100#3", Reviewer: "alice", SubmittedAt: at},
+		{PullRequestID: "100#3", Reviewer: "carol", SubmittedAt: end.Add(time.Hour)}, // after the period
+		{PullRequestID: "100#9", Reviewer: "carol", SubmittedAt: at},                 // unknown PR
+	}
+
+	got := NewCalculator().CalculateCollaboration(prs, reviews, start, end)
+
+	want := []model.CollaborationEdge{
+		{Author: "alice", Reviewer: "bob", Count: 3},
+		{Author: "alice", Reviewer: "carol", Count: 1},
+		{Author: "bob", Reviewer: "alice", Count: 1},
+	}
+	if !reflect.DeepEqual(got.Edges, want) {
+		t.Errorf("Edges = %+v, want %+v", got.Edges, want)
+	}
+}
+
+func TestCalculateDORAMetrics_LeadTimePercentiles(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+
+	// Lead times: 1h, 2h, ..., 11h
+	var prs []*model.PullRequest
+	for i := 1; i <= 11; i++ {
+		created := start.Add(24 * time.Hour)
+		merged := created.Add(time.Duration(i) * time.Hour)
+		prs = append(prs, &model.PullRequest{CreatedAt: created, MergedAt: &merged})
+	}
+
+	got := NewCalculator().CalculateDORAMetricsWithOptions(prs, nil, start, end, DORAOptions{
+		Percentiles: []float64{50, 90, 95, 100},
+	})
+
+	want := map[string]float64{"p50": 6, "p90": 10, "p95": 10.5, "p100": 11}
+	if len(got.LeadTimePercentiles) != len(want) {
+		t.Fatalf("got %d percentiles, want %d: %v", len(got.LeadTimePercentiles), len(want), got.LeadTimePercentiles)
+	}
+	for key, w := range want {
+		if v, ok := got.LeadTimePercentiles[key]; !ok || !approxEqual(v, w) {
+			t.Errorf("LeadTimePercentiles[%q] = %v (present=%v), want %v", key, v, ok, w)
+		}
+	}
+	if !approxEqual(got.LeadTimePercentiles["p90"], got.P90LeadTime) {
+		t.Errorf("p90 = %v, want to match P90LeadTime %v", got.LeadTimePercentiles["p90"], got.P90LeadTime)
+	}
+}
+
+func TestCalculateDORAMetrics_NoPercentilesRequested(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := NewCalculator().CalculateDORAMetrics(nil, nil, start, start.AddDate(0, 1, 0))
+	if got.LeadTimePercentiles != nil {
+		t.Errorf("LeadTimePercentiles = %v, want nil", got.LeadTimePercentiles)
+	}
+}
+
+func TestPercentileKey(t *testing.T) {
+	tests := []struct {
+		p    float64
+		want string
+	}{
+		{50, "p50"},
+		{99.9, "p99.9"},
+		{100, "p100"},
+	}
+	for _, tt := range tests {
+		if got := PercentileKey(tt.p); got != tt.want {
+			t.Errorf("PercentileKey(%v) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateDORAMetrics_ByEnvironment(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 28)
+
+	var deployments []*model.Deployment
+	for i := range 28 {
+		deployments = append(deployments, &model.Deployment{Environment: "staging", CreatedAt: start.AddDate(0, 0, i)})
+	}
+	for i := range 4 {
+		deployments = append(deployments, &model.Deployment{Environment: "production", CreatedAt: start.AddDate(0, 0, 7*i)})
+	}
+	// Outside the period; counted nowhere
+	deployments = append(deployments, &model.Deployment{Environment: "production", CreatedAt: start.AddDate(0, -1, 0)})
+
+	got := NewCalculator().CalculateDORAMetrics(nil, deployments, start, end)
+	if got.DeploymentCount != 32 || got.DeploymentFrequency != "daily" {
+		t.Errorf("overall = %d deploys (%s), want 32 (daily)", got.DeploymentCount, got.DeploymentFrequency)
+	}
+
+	want := map[string]model.EnvDeployStats{
+		"staging":    {DeploymentCount: 28, DeploymentFrequency: "daily", AvgDeploysPerDay: 1},
+		"production": {DeploymentCount: 4, DeploymentFrequency: "weekly", AvgDeploysPerDay: 4.0 / 28},
+	}
+	if len(got.ByEnvironment) != len(want) {
+		t.Fatalf("ByEnvironment = %v, want %d environments", got.ByEnvironment, len(want))
+	}
+	for env, w := range want {
+		g := got.ByEnvironment[env]
+		if g.DeploymentCount != w.DeploymentCount || g.DeploymentFrequency != w.DeploymentFrequency || !approxEqual(g.AvgDeploysPerDay, w.AvgDeploysPerDay) {
+			t.Errorf("%s = %+v, want %+v", env, g, w)
+		}
+	}
+
+	if got := NewCalculator().CalculateDORAMetrics(nil, nil, start, end); got.ByEnvironment != nil {
+		t.Errorf("no deployments: ByEnvironment = %v, want nil", got.ByEnvironment)
+	}
+}
+
+func TestCalculateDORAMetrics_DeploymentSuccessRate(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	at := start.Add(72 * time.Hour)
+
+	tests := []struct {
+		name        string
+		statuses    []string
+		wantRate    float64
+		wantDeploys int
+	}{
+		{"mixed success and failure", []string{"success", "success", "success", "failure"}, 75, 4},
+		{"pending deployments are excluded", []string{"success", "failure", "pending", "pending"}, 50, 4},
+		{"all failed", []string{"failure", "failure"}, 0, 2},
+		{"no finished deployments", []string{"pending"}, 0, 1},
+		{"no deployments", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deployments []*model.Deployment
+			for _, status := range tt.statuses {
+				deployments = append(deployments, &model.Deployment{Status: status, CreatedAt: at})
+			}
+
+			got := NewCalculator().CalculateDORAMetrics(nil, deployments, start, end)
+			if got.DeploymentCount != tt.wantDeploys {
+				t.Errorf("DeploymentCount = %d, want %d", got.DeploymentCount, tt.wantDeploys)
+			}
+			if !approxEqual(got.DeploymentSuccessRate, tt.wantRate) {
+				t.Errorf("DeploymentSuccessRate = %v, want %v", got.DeploymentSuccessRate, tt.wantRate)
+			}
+			// Change failure rate is PR-based and unaffected by deployment statuses
+			if got.ChangeFailureRate != 0 {
+				t.Errorf("ChangeFailureRate = %v, want 0", got.ChangeFailureRate)
+			}
+		})
+	}
+}
+
+func TestCalculateReviewMetrics_SLABreaches(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+
+	prWithFirstReview := func(num int, after time.Duration) *model.PullRequest {
+		pr := &model.PullRequest{RepositoryID: "100", Number: num, Author: "alice", CreatedAt: created}
+		if after > 0 {
+			at := created.Add(after)
+			pr.FirstReviewAt = &at
+		}
+		return pr
+	}
+	outOfRange := prWithFirstReview(5, 72*time.Hour)
+	outOfRange.CreatedAt = start.Add(-7 * 24 * time.Hour) // created before the range: not counted
+	prs := []*model.PullRequest{
+		prWithFirstReview(1, 2*time.Hour),
+		prWithFirstReview(2, 10*time.Hour),
+		prWithFirstReview(3, 30*time.Hour),
+		prWithFirstReview(4, 0), // never reviewed: not counted
+		outOfRange,
+	}
+	reviews := []*model.Review{
+		{ID: "r1", PullRequestID: "100#1", Reviewer: "bob", State: "APPROVED", SubmittedAt: created.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		slaHours  float64
+		wantSLA   float64
+		wantCount int
+		wantRate  float64
+	}{
+		{"default 24h threshold", 0, 24, 1, 100.0 / 3},
+		{"8h threshold", 8, 8, 2, 200.0 / 3},
+		{"threshold above all reviews", 48, 48, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCalculator().CalculateReviewMetricsWithOptions(reviews, prs, start, end, ReviewOptions{SLAHours: tt.slaHours})
+			if got.SLAHours != tt.wantSLA {
+				t.Errorf("SLAHours = %v, want %v", got.SLAHours, tt.wantSLA)
+			}
+			if got.SLABreachCount != tt.wantCount {
+				t.Errorf("SLABreachCount = %d, want %d", got.SLABreachCount, tt.wantCount)
+			}
+			if !approxEqual(got.SLABreachRate, tt.wantRate) {
+				t.Errorf("SLABreachRate = %v, want %v", got.SLABreachRate, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestCalculateReviewMetrics_SLAWithoutReviews(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	for _, tt := range []struct {
+		slaHours float64
+		want     float64
+	}{
+		{0, DefaultReviewSLAHours},
+		{8, 8},
+	} {
+		got := NewCalculator().CalculateReviewMetricsWithOptions(nil, nil, start, end, ReviewOptions{SLAHours: tt.slaHours})
+		if got.SLAHours != tt.want {
+			t.Errorf("SLAHours with option %v = %v, want %v", tt.slaHours, got.SLAHours, tt.want)
+		}
+		if got.SLABreachCount != 0 || got.SLABreachRate != 0 {
+			t.Errorf("SLA breaches = %d (%v%%), want none", got.SLABreachCount, got.SLABreachRate)
+		}
+	}
+}
+
+func TestCalculator_WithBusinessHours(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	// Opened Friday 17:00, reviewed Monday 10:00, merged Monday 12:00 (UTC)
+	created := time.Date(2026, 1, 9, 17, 0, 0, 0, time.UTC)
+	reviewed := time.Date(2026, 1, 12, 10, 0, 0, 0, time.UTC)
+	merged := time.Date(2026, 1, 12, 12, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{
+		{Author: "alice", CreatedAt: created, FirstReviewAt: &reviewed, ApprovedAt: &reviewed, MergedAt: &merged},
+	}
+
+	base := NewCalculator()
+	business := base.WithBusinessHours(DefaultBusinessHours())
+
+	tests := []struct {
+		name       string
+		calc       *Calculator
+		wantCycle  float64
+		wantPickup float64
+		wantLead   float64
+	}{
+		{"wall clock", base, 67, 65, 67},
+		{"business hours skip the weekend", business, 4, 2, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := tt.calc.CalculateCycleTime(prs, start, end)
+			if !approxEqual(ct.AvgCycleTime, tt.wantCycle) {
+				t.Errorf("AvgCycleTime = %v, want %v", ct.AvgCycleTime, tt.wantCycle)
+			}
+			if !approxEqual(ct.AvgPickupTime, tt.wantPickup) {
+				t.Errorf("AvgPickupTime = %v, want %v", ct.AvgPickupTime, tt.wantPickup)
+			}
+			dora := tt.calc.CalculateDORAMetrics(prs, nil, start, end)
+			if !approxEqual(dora.AvgLeadTime, tt.wantLead) {
+				t.Errorf("AvgLeadTime = %v, want %v", dora.AvgLeadTime, tt.wantLead)
+			}
+		})
+	}
+
+	// The original calculator is unchanged
+	if got := base.CalculateCycleTime(prs, start, end).AvgCycleTime; !approxEqual(got, 67) {
+		t.Errorf("base calculator AvgCycleTime = %v, want 67", got)
+	}
+}
+
+func TestPRSize(t *testing.T) {
+	tests := []struct {
+		additions, deletions int
+		want                 string
+	}{
+		{0, 0, "XS"},
+		{5, 4, "XS"},
+		{5, 5, "S"},
+		{99, 0, "S"},
+		{60, 40, "M"},
+		{499, 0, "M"},
+		{500, 0, "L"},
+		{999, 0, "L"},
+		{1000, 0, "XL"},
+		{4000, 2000, "XL"},
+	}
+	for _, tt := range tests {
+		pr := &model.PullRequest{Additions: tt.additions, Deletions: tt.deletions}
+		if got := PRSize(pr); got != tt.want {
+			t.Errorf("PRSize(+%d -%d) = %q, want %q", tt.additions, tt.deletions, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateCycleTime_BySize(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+
+	mergedPR := func(lines int, cycleHours float64) *model.PullRequest {
+		merged := created.Add(time.Duration(cycleHours * float64(time.Hour)))
+		return &model.PullRequest{Additions: lines, CreatedAt: created, MergedAt: &merged}
+	}
+	prs := []*model.PullRequest{
+		mergedPR(3, 2),
+		mergedPR(50, 4),
+		mergedPR(80, 8),
+		mergedPR(1500, 18),
+		mergedPR(2500, 30),
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end).BySize
+	want := []model.SizeBucketMetrics{
+		{Size: "XS", Count: 1, AvgCycleTime: 2},
+		{Size: "S", Count: 2, AvgCycleTime: 6},
+		{Size: "M", Count: 0, AvgCycleTime: 0},
+		{Size: "L", Count: 0, AvgCycleTime: 0},
+		{Size: "XL", Count: 2, AvgCycleTime: 24},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Size != want[i].Size || got[i].Count != want[i].Count || !approxEqual(got[i].AvgCycleTime, want[i].AvgCycleTime) {
+			t.Errorf("BySize[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCalculateCycleTime_ByAuthorBreakdown(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	base := start.Add(24 * time.Hour)
+	at := func(hours int) *time.Time { t := base.Add(time.Duration(hours) * time.Hour); return &t }
+
+	prs := []*model.PullRequest{
+		// alice: coding 2/4, pickup 3/2, review 4/8, merge 1/3; cycle 10/17
+		{Author: "alice", FirstCommitAt: at(0), CreatedAt: *at(2), FirstReviewAt: at(5), ApprovedAt: at(9), MergedAt: at(10)},
+		{Author: "alice", FirstCommitAt: at(0), CreatedAt: *at(4), FirstReviewAt: at(6), ApprovedAt: at(14), MergedAt: at(17)},
+		// bob: merged without commits or reviews recorded, so no phases
+		{Author: "bob", CreatedAt: *at(0), MergedAt: at(6)},
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end).ByAuthor
+	if len(got) != 2 || got[0].Author != "alice" || got[1].Author != "bob" {
+		t.Fatalf("ByAuthor = %+v, want alice then bob", got)
+	}
+
+	alice := got[0]
+	if !approxEqual(alice.AvgCodingTime, 3) || !approxEqual(alice.AvgPickupTime, 2.5) ||
+		!approxEqual(alice.AvgReviewTime, 6) || !approxEqual(alice.AvgMergeTime, 2) {
+		t.Errorf("alice phases = coding %v, pickup %v, review %v, merge %v; want 3, 2.5, 6, 2",
+			alice.AvgCodingTime, alice.AvgPickupTime, alice.AvgReviewTime, alice.AvgMergeTime)
+	}
+	// Every alice PR went through every phase, so the phases add up to the cycle time
+	if sum := alice.AvgCodingTime + alice.AvgPickupTime + alice.AvgReviewTime + alice.AvgMergeTime; !approxEqual(sum, alice.AvgCycleTime) {
+		t.Errorf("alice phases sum to %v, AvgCycleTime = %v", sum, alice.AvgCycleTime)
+	}
+
+	bob := got[1]
+	if !approxEqual(bob.AvgCycleTime, 6) || bob.AvgCodingTime != 0 || bob.AvgPickupTime != 0 || bob.AvgReviewTime != 0 || bob.AvgMergeTime != 0 {
+		t.Errorf("bob = %+v, want cycle 6 and no phases", bob)
+	}
+}
+
+func TestCalculateCycleTime_ByAuthorMedian(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+	mergedPR := func(author string, cycleHours int) *model.PullRequest {
+		merged := created.Add(time.Duration(cycleHours) * time.Hour)
+		return &model.PullRequest{Author: author, CreatedAt: created, MergedAt: &merged}
+	}
+
+	prs := []*model.PullRequest{
+		// alice: one slow PR skews the mean but not the median
+		mergedPR("alice", 2), mergedPR("alice", 3), mergedPR("alice", 4), mergedPR("alice", 200),
+		mergedPR("bob", 5),
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end).ByAuthor
+	if len(got) != 2 || got[0].Author != "alice" || got[1].Author != "bob" {
+		t.Fatalf("ByAuthor = %+v, want alice then bob", got)
+	}
+	if !approxEqual(got[0].AvgCycleTime, 52.25) || !approxEqual(got[0].MedianCycleTime, 3.5) {
+		t.Errorf("alice avg = %v, median = %v; want 52.25 and 3.5", got[0].AvgCycleTime, got[0].MedianCycleTime)
+	}
+	if !approxEqual(got[1].AvgCycleTime, 5) || !approxEqual(got[1].MedianCycleTime, 5) {
+		t.Errorf("bob avg = %v, median = %v; want 5 and 5", got[1].AvgCycleTime, got[1].MedianCycleTime)
+	}
+}
+
+func TestCalculateCycleTime_DataQualityWarnings(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := func(hours int) *time.Time { t := start.Add(time.Duration(hours) * time.Hour); return &t }
+
+	prs := []*model.PullRequest{
+		// Clean: pickup 2h
+		{Number: 1, CreatedAt: *at(24), FirstReviewAt: at(26), MergedAt: at(30)},
+		// Reviewed an hour before it was created (clock skew): negative pickup
+		{Number: 2, CreatedAt: *at(48), FirstReviewAt: at(47), MergedAt: at(50)},
+		// First commit dated two years back by a rewritten history
+		{Number: 3, FirstCommitAt: at(-2 * 365 * 24), CreatedAt: *at(72), MergedAt: at(80)},
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end)
+	if got.TotalPRs != 3 || got.DataQualityWarnings != 2 {
+		t.Errorf("TotalPRs = %d, DataQualityWarnings = %d; want 3, 2", got.TotalPRs, got.DataQualityWarnings)
+	}
+	// The negative pickup is left out of the average
+	if !approxEqual(got.AvgPickupTime, 2) {
+		t.Errorf("AvgPickupTime = %v, want 2", got.AvgPickupTime)
+	}
+
+	// Business hours clamp the reversed times to 0, but the warning still counts them
+	if bh := NewCalculator().WithBusinessHours(DefaultBusinessHours()).CalculateCycleTime(prs[:2], start, end); bh.DataQualityWarnings != 1 {
+		t.Errorf("business hours DataQualityWarnings = %d, want 1", bh.DataQualityWarnings)
+	}
+}
+
+func TestCalculateProductivityScore_Weights(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+	merged := created.Add(4 * time.Hour)
+	prs := []*model.PullRequest{{CreatedAt: created, MergedAt: &merged}}
+
+	score := func(c *Calculator) *model.ProductivityScore {
+		ct := c.CalculateCycleTime(prs, start, end)
+		rm := c.CalculateReviewMetrics(nil, prs, start, end)
+		dm := c.CalculateDORAMetrics(prs, nil, start, end)
+		return c.CalculateProductivityScore(ct, rm, dm)
+	}
+	weightedSum := func(s *model.ProductivityScore, w ScoreWeights) float64 {
+		return s.CycleTimeScore*w.CycleTime + s.ReviewScore*w.Review +
+			s.DeploymentScore*w.Deployment + s.QualityScore*w.Quality
+	}
+
+	defaults := score(NewCalculator())
+
+	t.Run("custom weights change the overall score", func(t *testing.T) {
+		custom := ScoreWeights{CycleTime: 0.7, Review: 0.1, Deployment: 0.1, Quality: 0.1}
+		got := score(NewCalculatorWithWeights(custom))
+		if approxEqual(got.OverallScore, defaults.OverallScore) {
+			t.Fatalf("OverallScore = %v, want different from default %v", got.OverallScore, defaults.OverallScore)
+		}
+		if want := weightedSum(got, custom); !approxEqual(got.OverallScore, want) {
+			t.Errorf("OverallScore = %v, want %v", got.OverallScore, want)
+		}
+		if got.ComponentScores[0].Weight != 0.7 {
+			t.Errorf("cycle time component weight = %v, want 0.7", got.ComponentScores[0].Weight)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		weights ScoreWeights
+	}{
+		{"sum above 1", ScoreWeights{CycleTime: 0.5, Review: 0.5, Deployment: 0.5, Quality: 0.5}},
+		{"sum below 1", ScoreWeights{CycleTime: 0.1}},
+		{"negative weight", ScoreWeights{CycleTime: 1.2, Review: -0.2}},
+		{"all zero", ScoreWeights{}},
+	}
+	for _, tt := range invalid {
+		t.Run("invalid weights fall back to defaults: "+tt.name, func(t *testing.T) {
+			if tt.weights.Validate() == nil {
+				t.Fatalf("Validate(%+v) = nil, want error", tt.weights)
+			}
+			got := score(NewCalculatorWithWeights(tt.weights))
+			if !approxEqual(got.OverallScore, defaults.OverallScore) {
+				t.Errorf("OverallScore = %v, want default %v", got.OverallScore, defaults.OverallScore)
+			}
+		})
+	}
+}
+
+func TestCalculateProductivityScore_Thresholds(t *testing.T) {
+	ct := &model.CycleTimeMetrics{AvgCycleTime: 30}
+	rm := &model.ReviewMetrics{AvgTimeToFirstReview: 6, AvgReviewsPerPR: 4}
+	dm := &model.DORAMetrics{DeploymentFrequency: "weekly", ChangeFailureRate: 12}
+
+	defaults := NewCalculator().CalculateProductivityScore(ct, rm, dm)
+	// 30h cycle time: 80; review: 50 + 15 (6h) + 10 (4 per PR); 12% CFR: 60
+	if defaults.CycleTimeScore != 80 || defaults.ReviewScore != 75 || defaults.QualityScore != 60 {
+		t.Fatalf("default scores = cycle %v, review %v, quality %v; want 80, 75, 60", defaults.CycleTimeScore, defaults.ReviewScore, defaults.QualityScore)
+	}
+
+	custom := NewCalculator().WithScoreThresholds(ScoreThresholds{
+		CycleTimeHours:    []float64{48, 96, 168, 336},
+		FirstReviewHours:  []float64{8, 16, 24},
+		ReviewsPerPR:      []float64{2, 5},
+		ChangeFailureRate: []float64{10, 15, 20, 40},
+	}).CalculateProductivityScore(ct, rm, dm)
+	if custom.CycleTimeScore != 100 || custom.ReviewScore != 100 || custom.QualityScore != 80 {
+		t.Errorf("custom scores = cycle %v, review %v, quality %v; want 100, 100, 80", custom.CycleTimeScore, custom.ReviewScore, custom.QualityScore)
+	}
+	if custom.DeploymentScore != defaults.DeploymentScore {
+		t.Errorf("DeploymentScore = %v, want unchanged %v", custom.DeploymentScore, defaults.DeploymentScore)
+	}
+
+	// Unset benchmarks keep their defaults
+	partial := NewCalculator().WithScoreThresholds(ScoreThresholds{CycleTimeHours: []float64{48, 96, 168, 336}}).CalculateProductivityScore(ct, rm, dm)
+	if partial.CycleTimeScore != 100 || partial.ReviewScore != 75 || partial.QualityScore != 60 {
+		t.Errorf("partial scores = cycle %v, review %v, quality %v; want 100, 75, 60", partial.CycleTimeScore, partial.ReviewScore, partial.QualityScore)
+	}
+
+	invalid := []struct {
+		name       string
+		thresholds ScoreThresholds
+	}{
+		{"wrong count", ScoreThresholds{CycleTimeHours: []float64{48, 96}}},
+		{"descending", ScoreThresholds{ChangeFailureRate: []float64{30, 15, 10, 5}}},
+		{"negative", ScoreThresholds{FirstReviewHours: []float64{-1, 8, 24}}},
+	}
+	for _, tt := range invalid {
+		t.Run("invalid thresholds fall back to defaults: "+tt.name, func(t *testing.T) {
+			if tt.thresholds.Validate() == nil {
+				t.Fatalf("Validate(%+v) = nil, want error", tt.thresholds)
+			}
+			got := NewCalculator().WithScoreThresholds(tt.thresholds).CalculateProductivityScore(ct, rm, dm)
+			if !approxEqual(got.OverallScore, defaults.OverallScore) {
+				t.Errorf("OverallScore = %v, want default %v", got.OverallScore, defaults.OverallScore)
+			}
+		})
+	}
+}
+
+func TestNewCalculatorWithOptions(t *testing.T) {
+	// Friday 17:00 to Monday 10:00: 65 wall-clock hours, 2 business hours
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := time.Date(2026, 1, 9, 17, 0, 0, 0, time.UTC)
+	merged := time.Date(2026, 1, 12, 10, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{
+		{Title: "Add login page", CreatedAt: created, MergedAt: &merged},
+		{Title: "Revert \"Add login page\"", CreatedAt: created, MergedAt: &merged},
+		{Title: "HOTFIX: null session", CreatedAt: created, MergedAt: &merged},
+		{Title: "Update docs", CreatedAt: created, MergedAt: &merged},
+	}
+
+	defaults := NewCalculator()
+	if dm := defaults.CalculateDORAMetrics(prs, nil, start, end); dm.FailedChanges != 0 {
+		t.Errorf("default FailedChanges = %d, want 0 (no failure patterns)", dm.FailedChanges)
+	}
+	if ct := defaults.CalculateCycleTime(prs, start, end); !approxEqual(ct.AvgCycleTime, 65) {
+		t.Errorf("default AvgCycleTime = %v, want 65", ct.AvgCycleTime)
+	}
+
+	c := NewCalculatorWithOptions(CalculatorOptions{
+		Weights:         ScoreWeights{CycleTime: 1},
+		Thresholds:      ScoreThresholds{CycleTimeHours: []float64{1, 2, 3, 4}},
+		FailurePatterns: []string{"revert", " Hotfix "},
+		BusinessHours:   true,
+	})
+
+	dm := c.CalculateDORAMetrics(prs, nil, start, end)
+	if dm.FailedChanges != 2 || !approxEqual(dm.ChangeFailureRate, 50) {
+		t.Errorf("FailedChanges = %d, ChangeFailureRate = %v; want 2 and 50", dm.FailedChanges, dm.ChangeFailureRate)
+	}
+	ct := c.CalculateCycleTime(prs, start, end)
+	if !approxEqual(ct.AvgCycleTime, 2) {
+		t.Errorf("AvgCycleTime = %v, want 2 business hours", ct.AvgCycleTime)
+	}
+	// 2h is in the second cycle time tier (80), and only cycle time is weighted
+	ps := c.CalculateProductivityScore(ct, c.CalculateReviewMetrics(nil, prs, start, end), dm)
+	if ps.CycleTimeScore != 80 || !approxEqual(ps.OverallScore, 80) {
+		t.Errorf("CycleTimeScore = %v, OverallScore = %v; want 80 and 80", ps.CycleTimeScore, ps.OverallScore)
+	}
+}
+
+func TestCalculateDORAMetrics_FailureLabels(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+	merged := created.Add(4 * time.Hour)
+	prs := []*model.PullRequest{
+		// Titles that a keyword heuristic would flag, but no failure label
+		{Title: "Fix typo in README", Labels: []string{"docs"}, CreatedAt: created, MergedAt: &merged},
+		{Title: "Revert flaky test skip", CreatedAt: created, MergedAt: &merged},
+		// Labeled failures with unremarkable titles
+		{Title: "Restore previous session store", Labels: []string{"Rollback"}, CreatedAt: created, MergedAt: &merged},
+		{Title: "Raise connection pool size", Labels: []string{"area/db", "incident"}, CreatedAt: created, MergedAt: &merged},
+		// Open PRs are not changes yet
+		{Title: "Mitigate outage", Labels: []string{"incident"}, CreatedAt: created},
+	}
+
+	c := NewCalculatorWithOptions(CalculatorOptions{FailureLabels: []string{"incident", "rollback", "hotfix"}})
+	got := c.CalculateDORAMetrics(prs, nil, start, end)
+	if got.TotalChanges != 4 || got.FailedChanges != 2 {
+		t.Errorf("TotalChanges = %d, FailedChanges = %d; want 4 and 2", got.TotalChanges, got.FailedChanges)
+	}
+	if !approxEqual(got.ChangeFailureRate, 50) {
+		t.Errorf("ChangeFailureRate = %v, want 50", got.ChangeFailureRate)
+	}
+
+	if got := NewCalculator().CalculateDORAMetrics(prs, nil, start, end); got.FailedChanges != 0 {
+		t.Errorf("FailedChanges without failure labels = %d, want 0", got.FailedChanges)
+	}
+}
+
+func TestCalculateReviewMetrics_StateBreakdown(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.Add(48 * time.Hour)
+
+	states := []string{"APPROVED", "APPROVED", "APPROVED", "CHANGES_REQUESTED", "COMMENTED", "COMMENTED", "COMMENTED", "COMMENTED", "DISMISSED", "DISMISSED"}
+	var reviews []*model.Review
+	for i, state := range states {
+		reviews = append(reviews, &model.Review{PullRequestID: "100#1", Reviewer: "bob", State: state, SubmittedAt: at.Add(time.Duration(i) * time.Minute)})
+	}
+
+	got := NewCalculator().CalculateReviewMetrics(reviews, nil, start, end)
+
+	if got.TotalReviews != 10 {
+		t.Fatalf("TotalReviews = %d, want 10", got.TotalReviews)
+	}
+	if got.CommentedCount != 4 || got.DismissedCount != 2 {
+		t.Errorf("CommentedCount = %d, DismissedCount = %d, want 4 and 2", got.CommentedCount, got.DismissedCount)
+	}
+	rates := []struct {
+		name      string
+		got, want float64
+	}{
+		{"ApprovalRate", got.ApprovalRate, 30},
+		{"ChangesRequestedRate", got.ChangesRequestedRate, 10},
+		{"CommentedRate", got.CommentedRate, 40},
+		{"DismissedRate", got.DismissedRate, 20},
+	}
+	total := 0.0
+	for _, r := range rates {
+		if !approxEqual(r.got, r.want) {
+			t.Errorf("%s = %v, want %v", r.name, r.got, r.want)
+		}
+		total += r.got
+	}
+	if !approxEqual(total, 100) {
+		t.Errorf("rates sum to %v, want 100", total)
+	}
+}
+
+func TestCalculateCycleTime_ByLanguage(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	created := start.Add(24 * time.Hour)
+	merged := created.Add(4 * time.Hour)
+
+	prs := []*model.PullRequest{
+		{CreatedAt: created, MergedAt: &merged, FileExtStats: []model.FileExtStats{
+			{Extension: ".ts", Language: "TypeScript", Additions: 10, Files: 1},
+			{Extension: ".tsx", Language: "TypeScript", Additions: 20, Files: 2},
+		}},
+		{CreatedAt: created, MergedAt: &merged, FileExtStats: []model.FileExtStats{
+			{Extension: ".tsx", Language: "TypeScript", Deletions: 5, Files: 1},
+			{Extension: ".go", Additions: 3, Files: 1}, // synced before language labelling
+		}},
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end)
+	want := []model.LanguageMetrics{
+		{Language: "TypeScript", Additions: 30, Deletions: 5, Files: 4, PRCount: 2},
+		{Language: ".go", Additions: 3, Files: 1, PRCount: 1},
+	}
+	if len(got.ByLanguage) != len(want) {
+		t.Fatalf("ByLanguage = %+v, want %+v", got.ByLanguage, want)
+	}
+	for i := range want {
+		if got.ByLanguage[i] != want[i] {
+			t.Errorf("ByLanguage[%d] = %+v, want %+v", i, got.ByLanguage[i], want[i])
+		}
+	}
+	// Raw extensions remain available separately
+	if len(got.ByFileExtension) != 3 {
+		t.Errorf("ByFileExtension has %d entries, want 3", len(got.ByFileExtension))
+	}
+}
+
+func TestCalculateCycleTime_AbandonedPRs(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	at := func(day int) *time.Time { t := time.Date(2026, 1, day, 12, 0, 0, 0, time.UTC); return &t }
+
+	prs := []*model.PullRequest{
+		// Merged: counted in TotalPRs, not abandoned
+		{Number: 1, CreatedAt: *at(2), FirstCommitAt: at(2), MergedAt: at(3), ClosedAt: at(3)},
+		// Closed without merge in the period: abandoned
+		{Number: 2, CreatedAt: *at(4), ClosedAt: at(6)},
+		{Number: 3, CreatedAt: *at(5), ClosedAt: at(20)},
+		// Closed without merge after the period: not yet abandoned
+		{Number: 4, CreatedAt: *at(10), ClosedAt: func() *time.Time { t := end.Add(time.Hour); return &t }()},
+		// Still open
+		{Number: 5, CreatedAt: *at(12)},
+	}
+
+	got := NewCalculator().CalculateCycleTime(prs, start, end)
+	if got.TotalPRs != 1 {
+		t.Errorf("TotalPRs = %d, want 1", got.TotalPRs)
+	}
+	if got.AbandonedPRCount != 2 {
+		t.Errorf("AbandonedPRCount = %d, want 2", got.AbandonedPRCount)
+	}
+	if !approxEqual(got.AvgCycleTime, 24) {
+		t.Errorf("AvgCycleTime = %v, want 24 (abandoned PRs have no cycle time)", got.AvgCycleTime)
+	}
+
+	// Counted even when nothing was merged
+	if got := NewCalculator().CalculateCycleTime(prs[1:], start, end); got.TotalPRs != 0 || got.AbandonedPRCount != 2 {
+		t.Errorf("no merges: TotalPRs = %d, AbandonedPRCount = %d, want 0 and 2", got.TotalPRs, got.AbandonedPRCount)
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// CalculateChurn summarizes lines added and deleted over the period from daily metrics,
+// with per-author churn taken from the cycle time author breakdown (merged PRs).
+func CalculateChurn(daily []*model.DailyMetrics, authors []model.AuthorMetrics, startDate, endDate time.Time) *model.ChurnMetrics {
+	result := &model.ChurnMetrics{
+		Period:    "custom",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Timezone:  startDate.Location().String(),
+		Daily:     make([]model.DailyChurn, 0, len(daily)),
+		ByAuthor:  make([]model.AuthorChurn, 0, len(authors)),
+	}
+
+	for _, dm := range daily {
+		result.TotalAdditions += dm.TotalAdditions
+		result.TotalDeletions += dm.TotalDeletions
+		result.Daily = append(result.Daily, model.DailyChurn{
+			Date:          dm.Date,
+			Additions:     dm.TotalAdditions,
+			Deletions:     dm.TotalDeletions,
+			Churn:         dm.TotalAdditions + dm.TotalDeletions,
+			DeletionRatio: DeletionRatio(dm.TotalAdditions, dm.TotalDeletions),
+		})
+	}
+	result.TotalChurn = result.TotalAdditions + result.TotalDeletions
+	result.DeletionRatio = DeletionRatio(result.TotalAdditions, result.TotalDeletions)
+
+	for _, am := range authors {
+		result.ByAuthor = append(result.ByAuthor, model.AuthorChurn{
+			Author:        am.Author,
+			PRCount:       am.PRCount,
+			Additions:     am.Additions,
+			Deletions:     am.Deletions,
+			Churn:         am.Additions + am.Deletions,
+			DeletionRatio: DeletionRatio(am.Additions, am.Deletions),
+		})
+	}
+
+	// Highest churn first; ties by author name for a stable order
+	sort.Slice(result.ByAuthor, func(i, j int) bool {
+		if result.ByAuthor[i].Churn != result.ByAuthor[j].Churn {
+			return result.ByAuthor[i].Churn > result.ByAuthor[j].Churn
+		}
+		return result.ByAuthor[i].Author < result.ByAuthor[j].Author
+	})
+
+	return result
+}
+
+// DeletionRatio returns deleted lines per added line.
+// Returns 0 when nothing was added, since the ratio is undefined.
+func DeletionRatio(additions, deletions int) float64 {
+	if additions <= 0 {
+		return 0
+	}
+	return float64(deletions) / float64(additions)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestDeletionRatio(t *testing.T) {
+	tests := []struct {
+		name      string
+		additions int
+		deletions int
+		want      float64
+	}{
+		{"balanced", 100, 100, 1},
+		{"mostly additions", 200, 50, 0.25},
+		{"deletion heavy", 10, 300, 30},
+		{"no deletions", 80, 0, 0},
+		{"nothing added", 0, 120, 0},
+		{"no changes", 0, 0, 0},
+		{"negative additions", -5, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeletionRatio(tt.additions, tt.deletions)
+			if math.IsNaN(got) || math.IsInf(got, 0) || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("DeletionRatio(%d, %d) = %v, want %v", tt.additions, tt.deletions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateChurn(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	daily := []*model.DailyMetrics{
+		{Date: day(5), TotalAdditions: 300, TotalDeletions: 100},
+		{Date: day(6), TotalAdditions: 0, TotalDeletions: 400}, // cleanup day: nothing added
+		{Date: day(7)},
+	}
+	authors := []model.AuthorMetrics{
+		{Author: "bob", PRCount: 1, Additions: 0, Deletions: 400},
+		{Author: "alice", PRCount: 2, Additions: 300, Deletions: 100},
+		{Author: "carol", PRCount: 1, Additions: 250, Deletions: 150},
+	}
+
+	got := CalculateChurn(daily, authors, start, end)
+
+	if got.TotalAdditions != 300 || got.TotalDeletions != 500 || got.TotalChurn != 800 {
+		t.Errorf("totals = +%d -%d churn %d, want +300 -500 churn 800", got.TotalAdditions, got.TotalDeletions, got.TotalChurn)
+	}
+	if math.Abs(got.DeletionRatio-500.0/300) > 1e-9 {
+		t.Errorf("DeletionRatio = %v, want %v", got.DeletionRatio, 500.0/300)
+	}
+
+	if len(got.Daily) != 3 {
+		t.Fatalf("got %d daily points, want 3", len(got.Daily))
+	}
+	wantRatios := []float64{100.0 / 300, 0, 0}
+	for i, d := range got.Daily {
+		if math.Abs(d.DeletionRatio-wantRatios[i]) > 1e-9 {
+			t.Errorf("Daily[%d].DeletionRatio = %v, want %v", i, d.DeletionRatio, wantRatios[i])
+		}
+	}
+	if got.Daily[1].Churn != 400 {
+		t.Errorf("Daily[1].Churn = %d, want 400", got.Daily[1].Churn)
+	}
+
+	// Sorted by churn descending, then by name
+	wantOrder := []string{"alice", "bob", "carol"}
+	for i, a := range got.ByAuthor {
+		if a.Author != wantOrder[i] {
+			t.Errorf("ByAuthor[%d] = %s, want %s", i, a.Author, wantOrder[i])
+		}
+	}
+	if got.ByAuthor[1].DeletionRatio != 0 {
+		t.Errorf("bob DeletionRatio = %v, want 0 (nothing added)", got.ByAuthor[1].DeletionRatio)
+	}
+}
+
+func TestCalculateChurn_Empty(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := CalculateChurn(nil, nil, start, start.AddDate(0, 1, 0))
+
+	if got.TotalChurn != 0 || got.DeletionRatio != 0 {
+		t.Errorf("empty churn = %+v, want zero totals", got)
+	}
+	if got.Daily == nil || got.ByAuthor == nil {
+		t.Error("Daily and ByAuthor should be empty slices, not nil, so they encode as []")
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// onboardingWindow is the ramp-up period counted from a contributor's first PR.
+const onboardingWindow = 30 * 24 * time.Hour
+
+// CalculateOnboarding computes ramp-up metrics from all PRs authored by one contributor.
+// The 30-day window starts when their first PR was opened; a PR counts as merged in the
+// window when it was merged within it.
+func (c *Calculator) CalculateOnboarding(prs []*model.PullRequest) *model.OnboardingMetrics {
+	result := &model.OnboardingMetrics{TotalPRs: len(prs)}
+	if len(prs) == 0 {
+		return result
+	}
+
+	var firstPR, firstMerged *time.Time
+	for _, pr := range prs {
+		if firstPR == nil || pr.CreatedAt.Before(*firstPR) {
+			createdAt := pr.CreatedAt
+			firstPR = &createdAt
+		}
+		if pr.MergedAt != nil {
+			result.TotalMerged++
+			if firstMerged == nil || pr.MergedAt.Before(*firstMerged) {
+				mergedAt := *pr.MergedAt
+				firstMerged = &mergedAt
+			}
+		}
+	}
+	result.FirstPRAt = firstPR
+	result.FirstMergedAt = firstMerged
+	if firstMerged != nil {
+		result.TimeToFirstMerge = c.hours(*firstPR, *firstMerged)
+	}
+
+	windowEnd := firstPR.Add(onboardingWindow)
+	for _, pr := range prs {
+		if pr.CreatedAt.Before(windowEnd) {
+			result.PRsInFirst30Days++
+		}
+		if pr.MergedAt != nil && pr.MergedAt.Before(windowEnd) {
+			result.MergedInFirst30Days++
+		}
+	}
+
+	return result
+}
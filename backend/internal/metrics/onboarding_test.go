@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestCalculateOnboarding(t *testing.T) {
+	// Monday 2026-03-02 09:00 UTC: the contributor's first PR
+	first := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return first.AddDate(0, 0, d) }
+	merged := func(t time.Time) *time.Time { return &t }
+
+	prs := []*model.PullRequest{
+		// Listed out of order; the earliest CreatedAt is the first PR
+		{Number: 3, CreatedAt: day(10), MergedAt: merged(day(12))},
+		{Number: 1, CreatedAt: first},                              // never merged
+		{Number: 2, CreatedAt: day(3), MergedAt: merged(day(5))},   // first merge
+		{Number: 4, CreatedAt: day(28), MergedAt: merged(day(31))}, // opened in window, merged after it
+		{Number: 5, CreatedAt: day(45), MergedAt: merged(day(46))}, // after the window
+	}
+
+	got := NewCalculator().CalculateOnboarding(prs)
+
+	if got.FirstPRAt == nil || !got.FirstPRAt.Equal(first) {
+		t.Errorf("FirstPRAt = %v, want %v", got.FirstPRAt, first)
+	}
+	if got.FirstMergedAt == nil || !got.FirstMergedAt.Equal(day(5)) {
+		t.Errorf("FirstMergedAt = %v, want %v", got.FirstMergedAt, day(5))
+	}
+	if !approxEqual(got.TimeToFirstMerge, 5*24) {
+		t.Errorf("TimeToFirstMerge = %v, want 120", got.TimeToFirstMerge)
+	}
+	if got.PRsInFirst30Days != 4 || got.MergedInFirst30Days != 2 {
+		t.Errorf("first 30 days = %d PRs / %d merged, want 4 / 2", got.PRsInFirst30Days, got.MergedInFirst30Days)
+	}
+	if got.TotalPRs != 5 || got.TotalMerged != 4 {
+		t.Errorf("totals = %d PRs / %d merged, want 5 / 4", got.TotalPRs, got.TotalMerged)
+	}
+
+	// Business hours: first PR Monday 09:00 to first merge Saturday 09:00 is 5 business days × 9h
+	business := NewCalculator().WithBusinessHours(DefaultBusinessHours()).CalculateOnboarding(prs)
+	if !approxEqual(business.TimeToFirstMerge, 45) {
+		t.Errorf("business-hours TimeToFirstMerge = %v, want 45", business.TimeToFirstMerge)
+	}
+}
+
+func TestCalculateOnboarding_NoMergedPRs(t *testing.T) {
+	first := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	prs := []*model.PullRequest{
+		{Number: 1, CreatedAt: first.AddDate(0, 0, 2)},
+		{Number: 2, CreatedAt: first},
+	}
+
+	got := NewCalculator().CalculateOnboarding(prs)
+
+	if got.FirstPRAt == nil || !got.FirstPRAt.Equal(first) {
+		t.Errorf("FirstPRAt = %v, want %v", got.FirstPRAt, first)
+	}
+	if got.FirstMergedAt != nil || got.TimeToFirstMerge != 0 {
+		t.Errorf("first merge = %v / %v hours, want none", got.FirstMergedAt, got.TimeToFirstMerge)
+	}
+	if got.PRsInFirst30Days != 2 || got.MergedInFirst30Days != 0 || got.TotalMerged != 0 {
+		t.Errorf("got %+v, want 2 PRs and no merges", got)
+	}
+}
+
+func TestCalculateOnboarding_NoPRs(t *testing.T) {
+	got := NewCalculator().CalculateOnboarding(nil)
+	if got.FirstPRAt != nil || got.TotalPRs != 0 {
+		t.Errorf("got %+v, want empty metrics", got)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import "github.com/compasstechlab/dora-yaki/internal/domain/model"
+
+// DORA performance bands
+const (
+	PerformanceElite  = "elite"
+	PerformanceHigh   = "high"
+	PerformanceMedium = "medium"
+	PerformanceLow    = "low"
+)
+
+// ClassifyDORAPerformance maps each DORA metric to its performance band using the
+// standard State of DevOps thresholds.
+func ClassifyDORAPerformance(m *model.DORAMetrics) model.PerformanceLevel {
+	level := model.PerformanceLevel{
+		DeployFreq: classifyDeployFrequency(m.AvgDeploysPerDay),
+	}
+	if m.MedianLeadTime > 0 {
+		level.LeadTime = classifyLeadTime(m.MedianLeadTime)
+	}
+	if m.TotalChanges > 0 {
+		level.CFR = classifyChangeFailureRate(m.ChangeFailureRate)
+	}
+	if m.IncidentCount > 0 {
+		level.MTTR = classifyMTTR(m.MedianMTTR)
+	}
+	return level
+}
+
+// classifyDeployFrequency: elite = daily or more, high = weekly, medium = monthly, low = less often.
+func classifyDeployFrequency(deploysPerDay float64) string {
+	switch {
+	case deploysPerDay >= 1:
+		return PerformanceElite
+	case deploysPerDay >= 1.0/7:
+		return PerformanceHigh
+	case deploysPerDay >= 1.0/30:
+		return PerformanceMedium
+	default:
+		return PerformanceLow
+	}
+}
+
+// classifyLeadTime: elite < 1 day, high < 1 week, medium < 1 month, low otherwise.
+func classifyLeadTime(hours float64) string {
+	switch {
+	case hours < 24:
+		return PerformanceElite
+	case hours < 24*7:
+		return PerformanceHigh
+	case hours < 24*30:
+		return PerformanceMedium
+	default:
+		return PerformanceLow
+	}
+}
+
+// classifyChangeFailureRate: elite <= 15%, high <= 30%, medium <= 45%, low otherwise.
+func classifyChangeFailureRate(rate float64) string {
+	switch {
+	case rate <= 15:
+		return PerformanceElite
+	case rate <= 30:
+		return PerformanceHigh
+	case rate <= 45:
+		return PerformanceMedium
+	default:
+		return PerformanceLow
+	}
+}
+
+// classifyMTTR: elite < 1 hour, high < 1 day, medium < 1 week, low otherwise.
+func classifyMTTR(hours float64) string {
+	switch {
+	case hours < 1:
+		return PerformanceElite
+	case hours < 24:
+		return PerformanceHigh
+	case hours < 24*7:
+		return PerformanceMedium
+	default:
+		return PerformanceLow
+	}
+}
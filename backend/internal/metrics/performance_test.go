@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestClassifyBands(t *testing.T) {
+	tests := []struct {
+		name     string
+		classify func(float64) string
+		value    float64
+		want     string
+	}{
+		{"deploys: once a day", classifyDeployFrequency, 1, PerformanceElite},
+		{"deploys: just under daily", classifyDeployFrequency, 0.99, PerformanceHigh},
+		{"deploys: once a week", classifyDeployFrequency, 1.0 / 7, PerformanceHigh},
+		{"deploys: just under weekly", classifyDeployFrequency, 1.0/7 - 0.001, PerformanceMedium},
+		{"deploys: once a month", classifyDeployFrequency, 1.0 / 30, PerformanceMedium},
+		{"deploys: less than monthly", classifyDeployFrequency, 1.0/30 - 0.001, PerformanceLow},
+
+		{"lead time: just under a day", classifyLeadTime, 23.9, PerformanceElite},
+		{"lead time: exactly a day", classifyLeadTime, 24, PerformanceHigh},
+		{"lead time: just under a week", classifyLeadTime, 167.9, PerformanceHigh},
+		{"lead time: just over a week", classifyLeadTime, 168.1, PerformanceMedium},
+		{"lead time: just under a month", classifyLeadTime, 719.9, PerformanceMedium},
+		{"lead time: a month", classifyLeadTime, 720, PerformanceLow},
+
+		{"cfr: 15%", classifyChangeFailureRate, 15, PerformanceElite},
+		{"cfr: just over 15%", classifyChangeFailureRate, 15.1, PerformanceHigh},
+		{"cfr: 30%", classifyChangeFailureRate, 30, PerformanceHigh},
+		{"cfr: 45%", classifyChangeFailureRate, 45, PerformanceMedium},
+		{"cfr: just over 45%", classifyChangeFailureRate, 45.1, PerformanceLow},
+
+		{"mttr: just under an hour", classifyMTTR, 0.9, PerformanceElite},
+		{"mttr: an hour", classifyMTTR, 1, PerformanceHigh},
+		{"mttr: just under a day", classifyMTTR, 23.9, PerformanceHigh},
+		{"mttr: a day", classifyMTTR, 24, PerformanceMedium},
+		{"mttr: a week", classifyMTTR, 168, PerformanceLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.classify(tt.value); got != tt.want {
+				t.Errorf("classify(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyDORAPerformance_NoData(t *testing.T) {
+	got := ClassifyDORAPerformance(&model.DORAMetrics{})
+	want := model.PerformanceLevel{DeployFreq: PerformanceLow}
+	if got != want {
+		t.Errorf("ClassifyDORAPerformance = %+v, want %+v", got, want)
+	}
+}
+
+func TestCalculateDORAMetrics_PerformanceLevel(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+
+	// One deployment per day, lead times of 2 days
+	var deployments []*model.Deployment
+	var prs []*model.PullRequest
+	for i := 0; i < 10; i++ {
+		at := start.AddDate(0, 0, i)
+		deployments = append(deployments, &model.Deployment{Status: "success", CreatedAt: at})
+		merged := at.Add(48 * time.Hour)
+		if merged.After(end) {
+			continue
+		}
+		prs = append(prs, &model.PullRequest{CreatedAt: at, MergedAt: &merged})
+	}
+
+	got := NewCalculator().CalculateDORAMetrics(prs, deployments, start, end).PerformanceLevel
+	want := model.PerformanceLevel{
+		DeployFreq: PerformanceElite,
+		LeadTime:   PerformanceHigh,
+		CFR:        PerformanceElite,
+	}
+	if got != want {
+		t.Errorf("PerformanceLevel = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+// CalculateCycleTimeTrend returns the window-day moving average of daily average cycle time
+// for each day in [startDate, endDate]. Days without merged non-bot PRs are gaps: they have no cycle
+// time of their own and the average covers the other days in the window. The first days of the
+// period average over the days available so far.
+func CalculateCycleTimeTrend(daily []*model.DailyMetrics, startDate, endDate time.Time, window int) *model.CycleTimeTrend {
+	days := dailyCycleTimes(daily, startDate, endDate)
+	averages := movingAverage(days.values, window)
+	points := make([]model.CycleTimeTrendPoint, len(days.dates))
+	for i, day := range days.dates {
+		points[i] = model.CycleTimeTrendPoint{
+			Date:          day,
+			PRsMerged:     days.merged[i],
+			AvgCycleTime:  finiteOrNil(days.values[i]),
+			MovingAverage: finiteOrNil(averages[i]),
+		}
+	}
+
+	return &model.CycleTimeTrend{
+		Period:    "custom",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Timezone:  startDate.Location().String(),
+		Window:    window,
+		Points:    points,
+	}
+}
+
+// cycleTimeSeries is the daily average cycle time for each day of a period.
+type cycleTimeSeries struct {
+	dates  []time.Time
+	values []float64 // NaN on days without merged PRs
+	merged []int     // non-bot PRs merged each day, which the averages cover
+}
+
+// dailyCycleTimes lays daily metrics out on every day in [startDate, endDate].
+func dailyCycleTimes(daily []*model.DailyMetrics, startDate, endDate time.Time) cycleTimeSeries {
+	byDate := make(map[string]*model.DailyMetrics, len(daily))
+	for _, dm := range daily {
+		byDate[dm.Date.In(startDate.Location()).Format(time.DateOnly)] = dm
+	}
+
+	var s cycleTimeSeries
+	first := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	for day := first; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		value, count := math.NaN(), 0
+		if dm, ok := byDate[day.Format(time.DateOnly)]; ok && dm.AveragedPRs() > 0 {
+			value, count = dm.AvgCycleTime, dm.AveragedPRs()
+		}
+		s.dates = append(s.dates, day)
+		s.values = append(s.values, value)
+		s.merged = append(s.merged, count)
+	}
+	return s
+}
+
+// movingAverage returns the trailing moving average of series over window values.
+// NaN values are gaps, skipped by every average that covers them; an average over only
+// gaps is NaN. Leading values, and every value when window exceeds the series, average
+// over the values available so far. A window below 1 is treated as 1.
+func movingAverage(series []float64, window int) []float64 {
+	window = max(window, 1)
+	result := make([]float64, len(series))
+	var sum float64
+	var count int
+	for i, v := range series {
+		if !math.IsNaN(v) {
+			sum += v
+			count++
+		}
+		if i >= window {
+			if old := series[i-window]; !math.IsNaN(old) {
+				sum -= old
+				count--
+			}
+		}
+		if count == 0 {
+			result[i] = math.NaN()
+		} else {
+			result[i] = sum / float64(count)
+		}
+	}
+	return result
+}
+
+// finiteOrNil returns a pointer to v, or nil for NaN.
+func finiteOrNil(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
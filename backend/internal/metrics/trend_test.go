@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/compasstechlab/dora-yaki/internal/domain/model"
+)
+
+func TestMovingAverage(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		name   string
+		series []float64
+		window int
+		want   []float64
+	}{
+		{"leading edge averages what is available", []float64{2, 4, 6, 8}, 3, []float64{2, 3, 4, 6}},
+		{"window of one is the series", []float64{5, 1, 3}, 1, []float64{5, 1, 3}},
+		{"window larger than series", []float64{1, 2, 3}, 10, []float64{1, 1.5, 2}},
+		{"gaps are skipped", []float64{4, nan, 8, nan}, 2, []float64{4, 4, 8, 8}},
+		{"all gaps in window", []float64{3, nan, nan, 6}, 2, []float64{3, 3, nan, 6}},
+		{"non-positive window is one", []float64{1, 9}, 0, []float64{1, 9}},
+		{"empty series", nil, 7, []float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := movingAverage(tt.series, tt.window)
+			if len(got) != len(tt.want) {
+				t.Fatalf("movingAverage() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if math.IsNaN(tt.want[i]) != math.IsNaN(got[i]) || (!math.IsNaN(got[i]) && math.Abs(got[i]-tt.want[i]) > 1e-9) {
+					t.Errorf("movingAverage() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateCycleTimeTrend(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	daily := []*model.DailyMetrics{
+		{Date: day(1), PRsMerged: 2, AvgCycleTime: 10},
+		{Date: day(2), PRsMerged: 0}, // no merges: a gap even with a stored row
+		{Date: day(3), PRsMerged: 1, AvgCycleTime: 20},
+		{Date: day(4), PRsMerged: 1, BotPRsMerged: 1}, // only a bot PR merged: also a gap
+	}
+
+	trend := CalculateCycleTimeTrend(daily, day(1), day(4).Add(23*time.Hour), 2)
+	if trend.Window != 2 || len(trend.Points) != 4 {
+		t.Fatalf("window = %d, points = %d; want 2, 4", trend.Window, len(trend.Points))
+	}
+
+	wantAvg := []float64{10, 0, 20, 0}
+	wantMoving := []float64{10, 10, 20, 20}
+	for i, p := range trend.Points {
+		if !p.Date.Equal(day(i + 1)) {
+			t.Errorf("points[%d].Date = %v, want %v", i, p.Date, day(i+1))
+		}
+		if (p.PRsMerged == 0) != (p.AvgCycleTime == nil) {
+			t.Errorf("points[%d] prsMerged = %d with avgCycleTime %v", i, p.PRsMerged, p.AvgCycleTime)
+		}
+		if p.AvgCycleTime != nil && *p.AvgCycleTime != wantAvg[i] {
+			t.Errorf("points[%d].AvgCycleTime = %v, want %v", i, *p.AvgCycleTime, wantAvg[i])
+		}
+		if p.MovingAverage == nil || *p.MovingAverage != wantMoving[i] {
+			t.Errorf("points[%d].MovingAverage = %v, want %v", i, p.MovingAverage, wantMoving[i])
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package timeutil
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
 
 // Default location is UTC
 var loc *time.Location = time.UTC
@@ -23,8 +27,86 @@ func ParseDate(s string) (time.Time, error) {
 	return time.ParseInLocation("2006-01-02", s, loc)
 }
 
+// ParseRelativeDate parses a date relative to now: "today", "yesterday", or "-N" followed by
+// d (days), w (weeks) or m (months), e.g. "-30d". It returns midnight of that day in the
+// configured location.
+// now を基準とした相対日付（"today", "yesterday", "-30d" など）をパースする。
+func ParseRelativeDate(s string, now time.Time) (time.Time, error) {
+	y, m, d := now.In(loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	switch s {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if len(s) < 3 || s[0] != '-' {
+		return time.Time{}, fmt.Errorf("invalid relative date %q", s)
+	}
+	n, err := strconv.ParseUint(s[1:len(s)-1], 10, 16)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid relative date %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return today.AddDate(0, 0, -int(n)), nil
+	case 'w':
+		return today.AddDate(0, 0, -7*int(n)), nil
+	case 'm':
+		return today.AddDate(0, -int(n), 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid relative date %q", s)
+}
+
 // Location returns the currently configured location.
 // 現在設定されているロケーションを返す。
 func Location() *time.Location {
 	return loc
 }
+
+// ISOWeekKey returns the ISO 8601 week of t in the configured location, e.g. "2026-W06".
+// The year is the ISO week-numbering year, so late-December dates may belong to week 1 of
+// the next year and early-January dates to the last week of the previous year.
+// 設定されたロケーションでの ISO 8601 週キー（例: "2026-W06"）を返す。
+func ISOWeekKey(t time.Time) string {
+	year, week := t.In(loc).ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// BusinessHoursBetween returns the working hours between start and end, counting only
+// workdays between workStart:00 and workEnd:00 in the configured location.
+// start から end までの稼働時間（時間単位）を返す。稼働日の workStart 時〜workEnd 時のみを数える。
+func BusinessHoursBetween(start, end time.Time, workStart, workEnd int, workdays []time.Weekday) float64 {
+	if !end.After(start) || workEnd <= workStart {
+		return 0
+	}
+
+	isWorkday := make(map[time.Weekday]bool, len(workdays))
+	for _, d := range workdays {
+		isWorkday[d] = true
+	}
+
+	start, end = start.In(loc), end.In(loc)
+	var total time.Duration
+	// Iterate by calendar date so that DST days (23h/25h) are handled by time.Date
+	y, m, d := start.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, loc); day.Before(end); day = time.Date(y, m, d+1, 0, 0, 0, 0, loc) {
+		y, m, d = day.Date()
+		if !isWorkday[day.Weekday()] {
+			continue
+		}
+		open := time.Date(y, m, d, workStart, 0, 0, 0, loc)
+		closed := time.Date(y, m, d, workEnd, 0, 0, 0, loc)
+		if open.Before(start) {
+			open = start
+		}
+		if closed.After(end) {
+			closed = end
+		}
+		if closed.After(open) {
+			total += closed.Sub(open)
+		}
+	}
+	return total.Hours()
+}
@@ -0,0 +1,188 @@
+package timeutil
+
+import (
+	"math"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+func TestBusinessHoursBetween(t *testing.T) {
+	tokyo := time.FixedZone("UTC+09:00", 9*3600)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allDays := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+
+	tests := []struct {
+		name      string
+		loc       *time.Location
+		start     time.Time
+		end       time.Time
+		workStart int
+		workEnd   int
+		workdays  []time.Weekday
+		want      float64
+	}{
+		{
+			name:      "within a single working day",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 6, 10, 0, 0, 0, tokyo),
+			end:       time.Date(2026, 1, 6, 13, 30, 0, 0, tokyo),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 3.5,
+		},
+		{
+			name:      "off-hours on both ends are clipped",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 6, 7, 0, 0, 0, tokyo),
+			end:       time.Date(2026, 1, 7, 20, 0, 0, 0, tokyo),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 18,
+		},
+		{
+			name:      "Friday evening to Monday morning spans the weekend",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 9, 17, 0, 0, 0, tokyo),
+			end:       time.Date(2026, 1, 12, 10, 0, 0, 0, tokyo),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 2,
+		},
+		{
+			name:      "entirely on a weekend",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 10, 9, 0, 0, 0, tokyo),
+			end:       time.Date(2026, 1, 11, 18, 0, 0, 0, tokyo),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 0,
+		},
+		{
+			name:      "inputs in another zone are evaluated in the configured location",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC), // 09:00 JST
+			end:       time.Date(2026, 1, 6, 3, 0, 0, 0, time.UTC), // 12:00 JST
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 3,
+		},
+		{
+			name:      "weekend spanning the spring-forward DST transition",
+			loc:       newYork,
+			start:     time.Date(2026, 3, 6, 17, 0, 0, 0, newYork),
+			end:       time.Date(2026, 3, 9, 10, 0, 0, 0, newYork),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 2,
+		},
+		{
+			name:      "spring-forward day has 23 hours",
+			loc:       newYork,
+			start:     time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			end:       time.Date(2026, 3, 9, 0, 0, 0, 0, newYork),
+			workStart: 0, workEnd: 24, workdays: allDays,
+			want: 23,
+		},
+		{
+			name:      "fall-back day has 25 hours",
+			loc:       newYork,
+			start:     time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			end:       time.Date(2026, 11, 2, 0, 0, 0, 0, newYork),
+			workStart: 0, workEnd: 24, workdays: allDays,
+			want: 25,
+		},
+		{
+			name:      "end before start",
+			loc:       tokyo,
+			start:     time.Date(2026, 1, 6, 12, 0, 0, 0, tokyo),
+			end:       time.Date(2026, 1, 6, 10, 0, 0, 0, tokyo),
+			workStart: 9, workEnd: 18, workdays: weekdays,
+			want: 0,
+		},
+	}
+
+	defer Init(time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Init(tt.loc)
+			got := BusinessHoursBetween(tt.start, tt.end, tt.workStart, tt.workEnd, tt.workdays)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("BusinessHoursBetween = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestISOWeekKey(t *testing.T) {
+	tokyo := time.FixedZone("UTC+09:00", 9*3600)
+
+	tests := []struct {
+		name string
+		loc  *time.Location
+		t    time.Time
+		want string
+	}{
+		{"mid-year", time.UTC, time.Date(2026, 2, 4, 12, 0, 0, 0, time.UTC), "2026-W06"},
+		{"Dec 31 in week 53", time.UTC, time.Date(2020, 12, 31, 12, 0, 0, 0, time.UTC), "2020-W53"},
+		{"Jan 1 in previous year's week 53", time.UTC, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC), "2020-W53"},
+		{"Jan 4 starts week 1", time.UTC, time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC), "2021-W01"},
+		{"Dec 29 in next year's week 1", time.UTC, time.Date(2025, 12, 29, 12, 0, 0, 0, time.UTC), "2026-W01"},
+		// Sunday 20:00 UTC is already Monday in +09:00
+		{"configured location", tokyo, time.Date(2021, 1, 3, 20, 0, 0, 0, time.UTC), "2021-W01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Init(tt.loc)
+			defer Init(time.UTC)
+
+			if got := ISOWeekKey(tt.t); got != tt.want {
+				t.Errorf("ISOWeekKey(%s) = %q, want %q", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRelativeDate(t *testing.T) {
+	tokyo := time.FixedZone("UTC+09:00", 9*3600)
+	Init(tokyo)
+	defer Init(time.UTC)
+
+	// 2026-03-31 01:00 in +09:00 is still March 30 in UTC
+	now := time.Date(2026, 3, 30, 16, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, tokyo) }
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "today", want: day(3, 31)},
+		{in: "yesterday", want: day(3, 30)},
+		{in: "-0d", want: day(3, 31)},
+		{in: "-30d", want: day(3, 1)},
+		{in: "-2w", want: day(3, 17)},
+		{in: "-1m", want: day(3, 3)}, // February 31 normalizes like time.AddDate
+		{in: "", wantErr: true},
+		{in: "30d", wantErr: true},
+		{in: "-d", wantErr: true},
+		{in: "--3d", wantErr: true},
+		{in: "-3y", wantErr: true},
+		{in: "2026-03-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRelativeDate(tt.in, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseRelativeDate(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("ParseRelativeDate(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
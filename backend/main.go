@@ -45,6 +45,28 @@ func Init() {
 
 		// Initialize GitHub client
 		ghClient := github.NewClient(cfg.GitHubToken)
+		switch {
+		case cfg.UsesGitHubApp():
+			if cfg.GitHubBaseURL != "" {
+				logger.Error("GitHub App authentication is not supported with GITHUB_BASE_URL")
+				os.Exit(1)
+			}
+			var err error
+			ghClient, err = github.NewAppClient(cfg.GitHubAppID, cfg.GitHubAppInstallationID, []byte(cfg.GitHubAppPrivateKey))
+			if err != nil {
+				logger.Error("failed to create GitHub App client", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("using GitHub App authentication", "appID", cfg.GitHubAppID, "installationID", cfg.GitHubAppInstallationID)
+		case cfg.GitHubBaseURL != "":
+			var err error
+			ghClient, err = github.NewEnterpriseClient(cfg.GitHubToken, cfg.GitHubBaseURL)
+			if err != nil {
+				logger.Error("failed to create GitHub Enterprise client", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("using GitHub Enterprise", "baseURL", cfg.GitHubBaseURL)
+		}
 
 		// Initialize Datastore client
 		var dsClient *datastore.Client
@@ -56,6 +78,7 @@ func Init() {
 				logger.Error("failed to create datastore client", "error", err)
 				os.Exit(1)
 			}
+			dsClient = dsClient.WithLogger(logger)
 		} else {
 			logger.Warn("GCP project ID not resolved (env/metadata), running without datastore")
 		}
@@ -36,7 +36,7 @@ async function request<T>(endpoint: string, options: RequestOptions = {}): Promi
 
 	if (!response.ok) {
 		const error = await response.text();
-		const msg = error || `HTTPエラー: ${response.status}`;
+		const msg = errorMessage(error) || `HTTPエラー: ${response.status}`;
 		if (!silent) addFlash('error', `APIエラー (${response.status}): ${msg}`);
 		throw new Error(msg);
 	}
@@ -49,6 +49,18 @@ async function request<T>(endpoint: string, options: RequestOptions = {}): Promi
 	return response.json();
 }
 
+// Extract the message from a structured error body ({"error": {"code", "message"}}).
+// Falls back to the raw body for non-JSON responses.
+function errorMessage(body: string): string {
+	try {
+		const parsed = JSON.parse(body);
+		if (typeof parsed?.error?.message === 'string') return parsed.error.message;
+	} catch {
+		// not JSON
+	}
+	return body;
+}
+
 // Types
 export interface Repository {
 	id: string;
@@ -56,9 +68,12 @@ export interface Repository {
 	name: string;
 	fullName: string;
 	private: boolean;
+	archived: boolean;
 	createdAt: string;
 	updatedAt: string;
 	lastSyncedAt?: string;
+	lastSyncError?: string;
+	lastSyncErrorAt?: string;
 }
 
 export interface FileExtensionMetrics {
@@ -90,6 +105,11 @@ export interface AuthorMetrics {
 	author: string;
 	prCount: number;
 	avgCycleTime: number;
+	medianCycleTime: number;
+	avgCodingTime: number;
+	avgPickupTime: number;
+	avgReviewTime: number;
+	avgMergeTime: number;
 	additions: number;
 	deletions: number;
 }
@@ -106,6 +126,7 @@ export interface DailyMetrics {
 	prsOpened: number;
 	prsMerged: number;
 	prsClosed: number;
+	botPRsMerged?: number;
 	reviewsSubmitted: number;
 	avgReviewsPerPR: number;
 	totalAdditions: number;
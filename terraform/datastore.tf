@@ -32,6 +32,74 @@ resource "google_firestore_index" "pull_request_repo_created" {
   depends_on = [google_firestore_database.default]
 }
 
+# PullRequest: filter by repository_id + state, sort by updated_at ASC (open/stale PRs)
+resource "google_firestore_index" "pull_request_repo_state_updated" {
+  project     = var.project_id
+  database    = "(default)"
+  collection  = "PullRequest"
+  query_scope = "COLLECTION_GROUP"
+  api_scope   = "DATASTORE_MODE_API"
+
+  fields {
+    field_path = "repository_id"
+    order      = "ASCENDING"
+  }
+
+  fields {
+    field_path = "state"
+    order      = "ASCENDING"
+  }
+
+  fields {
+    field_path = "updated_at"
+    order      = "ASCENDING"
+  }
+
+  depends_on = [google_firestore_database.default]
+}
+
+# PullRequest: filter by repository_id + updated_at range (daily metrics recomputation)
+resource "google_firestore_index" "pull_request_repo_updated" {
+  project     = var.project_id
+  database    = "(default)"
+  collection  = "PullRequest"
+  query_scope = "COLLECTION_GROUP"
+  api_scope   = "DATASTORE_MODE_API"
+
+  fields {
+    field_path = "repository_id"
+    order      = "ASCENDING"
+  }
+
+  fields {
+    field_path = "updated_at"
+    order      = "ASCENDING"
+  }
+
+  depends_on = [google_firestore_database.default]
+}
+
+# PullRequest: filter by repository_id + distinct projection on author (author list)
+resource "google_firestore_index" "pull_request_repo_author" {
+  project     = var.project_id
+  database    = "(default)"
+  collection  = "PullRequest"
+  query_scope = "COLLECTION_GROUP"
+  api_scope   = "DATASTORE_MODE_API"
+
+  fields {
+    field_path = "repository_id"
+    order      = "ASCENDING"
+  }
+
+  fields {
+    field_path = "author"
+    order      = "ASCENDING"
+  }
+
+  depends_on = [google_firestore_database.default]
+}
+
 # Review: filter by repository_id + sort by submitted_at DESC
 resource "google_firestore_index" "review_repo_submitted" {
   project     = var.project_id